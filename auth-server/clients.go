@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuthClient is one OAuth application served by the relay. The default
+// client has an empty Slug; tenant clients are served under /c/{Slug}/.
+type OAuthClient struct {
+	Slug   string
+	Config *oauth2.Config
+	// AllowedDomains restricts which Google accounts may complete a login
+	// (matched against the ID token's hd claim, which only Google Workspace
+	// accounts carry). Empty allows every account.
+	AllowedDomains []string
+}
+
+var (
+	errInvalidClientSlug   = errors.New("invalid client slug (use lowercase letters, digits, '-' or '_')")
+	errDuplicateClientSlug = errors.New("duplicate client slug")
+	errDomainNotAllowed    = errors.New("account domain not allowed")
+)
+
+var clientSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// AddClient registers a tenant client served under /c/{slug}/.
+func (s *Server) AddClient(client *OAuthClient) error {
+	if !clientSlugPattern.MatchString(client.Slug) {
+		return fmt.Errorf("%w: %q", errInvalidClientSlug, client.Slug)
+	}
+	if _, exists := s.clients[client.Slug]; exists {
+		return fmt.Errorf("%w: %q", errDuplicateClientSlug, client.Slug)
+	}
+	s.clients[client.Slug] = client
+	return nil
+}
+
+// storeKey namespaces tenant states in the shared token store so a state can
+// only be polled through the client that issued it.
+func (c *OAuthClient) storeKey(state string) string {
+	if c.Slug == "" {
+		return state
+	}
+	return c.Slug + "/" + state
+}
+
+// checkDomain enforces AllowedDomains using the ID token returned alongside
+// the exchanged token (the CLI always requests the openid and email scopes).
+// The ID token comes straight from Google's token endpoint over TLS, so its
+// claims are read without verifying the signature. Only the hd claim is
+// trusted: a consumer account registered with a company address has that
+// email domain but no hd claim.
+func (c *OAuthClient) checkDomain(token *oauth2.Token) error {
+	if len(c.AllowedDomains) == 0 {
+		return nil
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return fmt.Errorf("%w: no ID token to verify the account", errDomainNotAllowed)
+	}
+
+	claims, err := idTokenClaims(rawIDToken)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errDomainNotAllowed, err)
+	}
+
+	if !claims.EmailVerified {
+		return fmt.Errorf("%w: email %q not verified", errDomainNotAllowed, claims.Email)
+	}
+
+	domain := strings.ToLower(claims.HostedDomain)
+	if domain == "" {
+		return fmt.Errorf("%w: %q is not a Google Workspace account", errDomainNotAllowed, claims.Email)
+	}
+
+	for _, allowed := range c.AllowedDomains {
+		if domain == strings.ToLower(allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", errDomainNotAllowed, domain)
+}
+
+type idClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	HostedDomain  string `json:"hd"`
+}
+
+func idTokenClaims(raw string) (idClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return idClaims{}, errors.New("malformed ID token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return idClaims{}, fmt.Errorf("decode ID token: %w", err)
+	}
+
+	var claims idClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return idClaims{}, fmt.Errorf("parse ID token: %w", err)
+	}
+	return claims, nil
+}
+
+// loadClientsDir loads one tenant client per *.json credential file in dir.
+// The slug is taken from the file name ("<slug>.json" or the workit-style
+// "credentials-<slug>.json"). A client without a redirect_url gets
+// <baseURL>/c/<slug>/callback.
+func loadClientsDir(dir string, baseURL string) ([]*OAuthClient, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read clients dir: %w", err)
+	}
+
+	var clients []*OAuthClient
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		slug := strings.TrimPrefix(strings.TrimSuffix(name, ".json"), "credentials-")
+		if !clientSlugPattern.MatchString(slug) {
+			return nil, fmt.Errorf("%s: %w", name, errInvalidClientSlug)
+		}
+
+		creds, err := loadCredentialsFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		redirectURL := creds.redirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimSuffix(baseURL, "/") + "/c/" + slug + "/callback"
+		}
+
+		clients = append(clients, &OAuthClient{
+			Slug:           slug,
+			Config:         newOAuthConfig(creds.clientID, creds.clientSecret, redirectURL),
+			AllowedDomains: creds.allowedDomains,
+		})
+	}
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Slug < clients[j].Slug })
+
+	return clients, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+func fakeIDToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func TestLoadClientsDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"acme.json":             `{"web":{"client_id":"acme-id","client_secret":"acme-secret"},"allowed_domains":["acme.com"]}`,
+		"credentials-labs.json": `{"client_id":"labs-id","client_secret":"labs-secret","redirect_url":"https://labs.example.com/cb"}`,
+		"README.md":             "ignored",
+		"installed-one_1.json":  `{"installed":{"client_id":"inst-id","client_secret":"inst-secret"}}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	clients, err := loadClientsDir(dir, "https://relay.example.com/")
+	if err != nil {
+		t.Fatalf("loadClientsDir: %v", err)
+	}
+	if len(clients) != 3 {
+		t.Fatalf("Expected 3 clients, got %d", len(clients))
+	}
+
+	acme, inst, labs := clients[0], clients[1], clients[2]
+	if acme.Slug != "acme" || acme.Config.ClientID != "acme-id" || acme.Config.RedirectURL != "https://relay.example.com/c/acme/callback" {
+		t.Errorf("Unexpected acme client: %+v %+v", acme, acme.Config)
+	}
+	if len(acme.AllowedDomains) != 1 || acme.AllowedDomains[0] != "acme.com" {
+		t.Errorf("Unexpected acme domains: %v", acme.AllowedDomains)
+	}
+	if inst.Slug != "installed-one_1" || inst.Config.ClientSecret != "inst-secret" {
+		t.Errorf("Unexpected installed client: %+v", inst)
+	}
+	if labs.Slug != "labs" || labs.Config.RedirectURL != "https://labs.example.com/cb" {
+		t.Errorf("Unexpected labs client: %+v %+v", labs, labs.Config)
+	}
+}
+
+func TestLoadClientsDir_InvalidSlug(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Bad Name.json"), []byte(`{"client_id":"x","client_secret":"y"}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := loadClientsDir(dir, "http://localhost:8080"); !errors.Is(err, errInvalidClientSlug) {
+		t.Fatalf("Expected errInvalidClientSlug, got %v", err)
+	}
+}
+
+func TestServer_TenantClientRouting(t *testing.T) {
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "", "", "")
+
+	if err := server.AddClient(&OAuthClient{Slug: "acme", Config: newOAuthConfig("acme-id", "acme-secret", "https://relay/c/acme/callback")}); err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+	if err := server.AddClient(&OAuthClient{Slug: "acme", Config: newOAuthConfig("x", "y", "z")}); !errors.Is(err, errDuplicateClientSlug) {
+		t.Fatalf("Expected errDuplicateClientSlug, got %v", err)
+	}
+
+	var exchangedWith string
+	server.exchangeFunc = func(_ context.Context, client *OAuthClient, _ string) (*oauth2.Token, error) {
+		exchangedWith = client.Config.ClientID
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, nil
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/acme/callback?code=c&state=tenant-state", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected callback status 200, got %d", w.Code)
+	}
+	if exchangedWith != "acme-id" {
+		t.Errorf("Expected exchange with acme-id, got %q", exchangedWith)
+	}
+
+	// No default client: the unprefixed routes do not serve tenant states.
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token/tenant-state", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected unprefixed token status 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/other/status/tenant-state", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown client status 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/acme/status/tenant-state", nil))
+	var status map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil || status["status"] != "ready" {
+		t.Fatalf("Expected ready status, got %v (%v)", status, err)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/acme/token/tenant-state", nil))
+	var resp relayproto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.RefreshToken != "refresh" {
+		t.Fatalf("Expected tenant token, got %+v (%v)", resp, err)
+	}
+}
+
+func TestServer_TenantAllowedDomains(t *testing.T) {
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "", "", "")
+	if err := server.AddClient(&OAuthClient{
+		Slug:           "acme",
+		Config:         newOAuthConfig("acme-id", "acme-secret", "https://relay/c/acme/callback"),
+		AllowedDomains: []string{"Acme.com"},
+	}); err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+
+	idTokens := map[string]string{
+		"allowed":    fakeIDToken(t, map[string]any{"email": "a@acme.com", "email_verified": true, "hd": "acme.com"}),
+		"gmail":      fakeIDToken(t, map[string]any{"email": "a@gmail.com", "email_verified": true}),
+		"consumer":   fakeIDToken(t, map[string]any{"email": "a@acme.com", "email_verified": true}),
+		"unverified": fakeIDToken(t, map[string]any{"email": "a@acme.com", "email_verified": false, "hd": "acme.com"}),
+		"no-token":   "",
+	}
+	server.exchangeFunc = func(_ context.Context, _ *OAuthClient, code string) (*oauth2.Token, error) {
+		tok := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}
+		if idTokens[code] == "" {
+			return tok, nil
+		}
+		return tok.WithExtra(map[string]any{"id_token": idTokens[code]}), nil
+	}
+
+	for code, want := range map[string]int{
+		"allowed":    http.StatusOK,
+		"gmail":      http.StatusForbidden,
+		"consumer":   http.StatusForbidden,
+		"unverified": http.StatusForbidden,
+		"no-token":   http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/c/acme/callback?code="+code+"&state=state-"+code, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", code, want, w.Code)
+		}
+	}
+
+	if status := store.Status("acme/state-gmail"); status != TokenStatusNotFound {
+		t.Errorf("Expected rejected login not to be stored, got %v", status)
+	}
+}
@@ -2,6 +2,27 @@ module github.com/automagik-dev/workit/auth-server
 
 go 1.25
 
-require golang.org/x/oauth2 v0.34.0
+require (
+	filippo.io/age v1.2.1
+	github.com/automagik-dev/workit v0.0.0-00010101000000-000000000000
+	golang.org/x/oauth2 v0.34.0
+	golang.org/x/time v0.14.0
+	modernc.org/sqlite v1.37.1
+)
 
-require cloud.google.com/go/compute/metadata v0.3.0 // indirect
+require (
+	cloud.google.com/go/compute/metadata v0.9.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/crypto v0.47.0 // indirect
+	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	modernc.org/libc v1.65.7 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+)
+
+replace github.com/automagik-dev/workit => ../
@@ -5,25 +5,68 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"filippo.io/age"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
 )
 
 // Server holds the HTTP server configuration and dependencies.
 type Server struct {
-	store        *TokenStore
-	oauthConfig  *oauth2.Config
+	store TokenStore
+	// defaultClient serves the unprefixed /callback, /token/ and /status/
+	// routes. It is nil when the relay only serves tenant clients.
+	defaultClient *OAuthClient
+	// clients holds the tenant clients served under /c/{slug}/, by slug.
+	clients      map[string]*OAuthClient
 	mux          *http.ServeMux
-	exchangeFunc func(ctx context.Context, code string) (*oauth2.Token, error)
+	logger       *slog.Logger
+	metrics      *Metrics
+	ipLimiter    *keyedLimiter
+	stateLimiter *keyedLimiter
+	trustProxy   bool
+	exchangeFunc func(ctx context.Context, client *OAuthClient, code string) (*oauth2.Token, error)
+	// pkceExchangeFunc exchanges a code with the CLI-supplied PKCE verifier
+	// (end-to-end encrypted handoffs).
+	pkceExchangeFunc func(ctx context.Context, client *OAuthClient, code string, verifier string) (*oauth2.Token, error)
+}
+
+// NewServer creates a new Server with the given configuration. When clientID
+// is empty the server has no default client and only serves the tenant
+// clients registered with AddClient.
+func NewServer(store TokenStore, clientID, clientSecret, redirectURL string) *Server {
+	s := &Server{
+		store:   store,
+		clients: make(map[string]*OAuthClient),
+		mux:     http.NewServeMux(),
+		logger:  slog.Default(),
+		metrics: NewMetrics(),
+	}
+
+	if clientID != "" {
+		s.defaultClient = &OAuthClient{Config: newOAuthConfig(clientID, clientSecret, redirectURL)}
+	}
+
+	// Default exchange functions use the client's real OAuth config
+	s.exchangeFunc = func(ctx context.Context, client *OAuthClient, code string) (*oauth2.Token, error) {
+		return client.Config.Exchange(ctx, code, oauth2.AccessTypeOffline)
+	}
+	s.pkceExchangeFunc = func(ctx context.Context, client *OAuthClient, code string, verifier string) (*oauth2.Token, error) {
+		return client.Config.Exchange(ctx, code, oauth2.AccessTypeOffline, oauth2.VerifierOption(verifier))
+	}
+
+	s.registerRoutes()
+	return s
 }
 
-// NewServer creates a new Server with the given configuration.
-func NewServer(store *TokenStore, clientID, clientSecret, redirectURL string) *Server {
-	config := &oauth2.Config{
+func newOAuthConfig(clientID, clientSecret, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		Endpoint:     google.Endpoint,
@@ -36,28 +79,26 @@ func NewServer(store *TokenStore, clientID, clientSecret, redirectURL string) *S
 			"https://www.googleapis.com/auth/calendar",
 		},
 	}
-
-	s := &Server{
-		store:       store,
-		oauthConfig: config,
-		mux:         http.NewServeMux(),
-	}
-
-	// Default exchange function uses the real OAuth config
-	s.exchangeFunc = func(ctx context.Context, code string) (*oauth2.Token, error) {
-		return config.Exchange(ctx, code, oauth2.AccessTypeOffline)
-	}
-
-	s.registerRoutes()
-	return s
 }
 
 // registerRoutes sets up all HTTP routes.
 func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("/health", s.handleHealth)
-	s.mux.HandleFunc("/callback", s.handleCallback)
-	s.mux.HandleFunc("/token/", s.handleToken)
-	s.mux.HandleFunc("/status/", s.handleStatus)
+	s.mux.HandleFunc("/health", s.instrument("health", s.handleHealth))
+	s.mux.HandleFunc("/metrics", s.instrument("metrics", s.handleMetrics))
+	s.mux.HandleFunc("/callback", s.instrument("callback", s.handleCallback))
+	s.mux.HandleFunc("/token/", s.instrument("token", s.rateLimited(s.handleToken)))
+	s.mux.HandleFunc("/status/", s.instrument("status", s.rateLimited(s.handleStatus)))
+
+	// Tenant clients: the CLI's callback_server is https://<relay>/c/<slug>.
+	s.mux.HandleFunc("/c/{client}/health", s.instrument("health", s.handleClientHealth))
+	s.mux.HandleFunc("/c/{client}/callback", s.instrument("callback", s.handleClientCallback))
+	s.mux.HandleFunc("/c/{client}/token/{state}", s.instrument("token", s.rateLimited(s.handleClientToken)))
+	s.mux.HandleFunc("/c/{client}/status/{state}", s.instrument("status", s.rateLimited(s.handleClientStatus)))
+}
+
+// SetLogger replaces the server's structured logger.
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
 }
 
 // ServeHTTP implements the http.Handler interface.
@@ -67,8 +108,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // HealthResponse represents the JSON response from /health.
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+	Status    string   `json:"status"`
+	Timestamp string   `json:"timestamp"`
+	Features  []string `json:"features"`
 }
 
 // handleHealth returns a simple health check response.
@@ -81,6 +123,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	resp := HealthResponse{
 		Status:    "ok",
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Features:  []string{relayproto.FeatureHandoff},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -89,8 +132,36 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleCallback processes the OAuth callback from Google.
+// handleClientHealth serves /health under a tenant prefix, so a CLI pointed
+// at https://<relay>/c/<slug> can probe the relay's features.
+func (s *Server) handleClientHealth(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.clients[r.PathValue("client")]; !ok {
+		writeUnknownClient(w)
+		return
+	}
+	s.handleHealth(w, r)
+}
+
+// handleCallback processes the OAuth callback from Google for the default client.
 func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if s.defaultClient == nil {
+		s.renderErrorPage(w, "Unknown OAuth client", http.StatusNotFound)
+		return
+	}
+	s.serveCallback(w, r, s.defaultClient)
+}
+
+// handleClientCallback processes the OAuth callback for a tenant client.
+func (s *Server) handleClientCallback(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.clients[r.PathValue("client")]
+	if !ok {
+		s.renderErrorPage(w, "Unknown OAuth client", http.StatusNotFound)
+		return
+	}
+	s.serveCallback(w, r, client)
+}
+
+func (s *Server) serveCallback(w http.ResponseWriter, r *http.Request, client *OAuthClient) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -116,34 +187,88 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, e2e, err := relayproto.ParseState(state)
+	if err != nil {
+		s.renderErrorPage(w, "Invalid state parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	logger := s.logger.With("client", clientLabel(client), "state", state)
+	s.metrics.loginStarted(client)
+
+	if e2e {
+		// End-to-end encrypted handoff: the code was requested with a PKCE
+		// challenge, so it can only be exchanged once the polling CLI presents
+		// its verifier. Keep just the code until then.
+		if err := s.store.StoreCode(client.storeKey(state), code); err != nil {
+			logger.ErrorContext(ctx, "failed to store authorization code", "error", err)
+			s.renderErrorPage(w, "Failed to store authorization", http.StatusInternalServerError)
+			return
+		}
+		logger.InfoContext(ctx, "authorization code stored")
+		s.renderSuccessPage(w, state)
+		return
+	}
+
 	// Exchange the authorization code for tokens
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	token, err := s.exchangeFunc(ctx, code)
+	start := time.Now()
+	token, err := s.exchangeFunc(exchangeCtx, client, code)
+	s.metrics.exchanged(client, time.Since(start), err)
 	if err != nil {
-		log.Printf("Token exchange failed for state %s: %v", state, err)
+		logger.ErrorContext(ctx, "token exchange failed", "error", err)
 		s.renderErrorPage(w, "Failed to exchange authorization code for token", http.StatusInternalServerError)
 		return
 	}
 
+	if err := client.checkDomain(token); err != nil {
+		logger.WarnContext(ctx, "rejected login", "error", err)
+		s.renderErrorPage(w, "This account's domain is not allowed for this application", http.StatusForbidden)
+		return
+	}
+
 	// Store the token
-	s.store.Store(state, token)
-	log.Printf("Token stored for state: %s", state)
+	if err := s.store.Store(client.storeKey(state), token); err != nil {
+		logger.ErrorContext(ctx, "failed to store token", "error", err)
+		s.renderErrorPage(w, "Failed to store token", http.StatusInternalServerError)
+		return
+	}
+	logger.InfoContext(ctx, "token stored")
 
 	// Return success HTML page
 	s.renderSuccessPage(w, state)
 }
 
-// handleToken returns the token for the given state.
+// handleToken returns the token for the given state of the default client.
 func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if s.defaultClient == nil {
+		writeUnknownClient(w)
+		return
+	}
+
+	// Extract state from path: /token/{state}
+	s.serveToken(w, r, s.defaultClient, strings.TrimPrefix(r.URL.Path, "/token/"))
+}
+
+// handleClientToken returns the token for the given state of a tenant client.
+func (s *Server) handleClientToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.clients[r.PathValue("client")]
+	if !ok {
+		writeUnknownClient(w)
+		return
+	}
+	s.serveToken(w, r, client, r.PathValue("state"))
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, client *OAuthClient, state string) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract state from path: /token/{state}
-	state := strings.TrimPrefix(r.URL.Path, "/token/")
 	if state == "" {
 		http.Error(w, `{"error": "Missing state parameter"}`, http.StatusBadRequest)
 		return
@@ -151,58 +276,162 @@ func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	token, status := s.store.Get(state)
-
-	switch status {
-	case TokenStatusReady:
-		resp := TokenResponse{
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-			TokenType:    token.TokenType,
-			Expiry:       token.Expiry.Format(time.RFC3339),
-		}
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Printf("Error encoding token response: %v", err)
+	recipient, e2e, err := relayproto.ParseState(state)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"error":   "invalid_state",
+			"message": "Malformed state parameter",
+		}); err != nil {
+			log.Printf("Error encoding invalid state response: %v", err)
 		}
+		return
+	}
+
+	if e2e {
+		s.handleEncryptedToken(w, r, client, state, recipient)
+		return
+	}
+
+	token, status := s.store.Get(client.storeKey(state))
+	if status != TokenStatusReady {
+		writeTokenStatus(w, status)
+		return
+	}
+
+	resp := relayproto.NewTokenResponse(token)
+	s.metrics.loginCompleted(client)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding token response: %v", err)
+	}
+}
 
-	case TokenStatusPending:
-		w.WriteHeader(http.StatusAccepted)
+// handleEncryptedToken serves an end-to-end encrypted handoff: it exchanges
+// the stored code with the caller's PKCE verifier and returns the token
+// encrypted to the age recipient embedded in the state.
+func (s *Server) handleEncryptedToken(w http.ResponseWriter, r *http.Request, client *OAuthClient, state string, recipient *age.X25519Recipient) {
+	verifier := strings.TrimSpace(r.Header.Get(relayproto.CodeVerifierHeader))
+	if verifier == "" {
+		w.WriteHeader(http.StatusBadRequest)
 		if err := json.NewEncoder(w).Encode(map[string]string{
-			"status":  "pending",
-			"message": "Token not yet available, please try again",
+			"error":   "missing_verifier",
+			"message": "Encrypted handoff requires the " + relayproto.CodeVerifierHeader + " header",
 		}); err != nil {
-			log.Printf("Error encoding pending response: %v", err)
+			log.Printf("Error encoding missing verifier response: %v", err)
 		}
+		return
+	}
 
-	case TokenStatusConsumed:
-		w.WriteHeader(http.StatusGone)
+	// The code is only consumed once the exchange succeeds, so a poll with a
+	// wrong verifier does not destroy the login.
+	code, status := s.store.PeekCode(client.storeKey(state))
+	if status != TokenStatusReady {
+		writeTokenStatus(w, status)
+		return
+	}
+
+	logger := s.logger.With("client", clientLabel(client), "state", state)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	token, err := s.pkceExchangeFunc(ctx, client, code, verifier)
+	s.metrics.exchanged(client, time.Since(start), err)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "token exchange failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
 		if err := json.NewEncoder(w).Encode(map[string]string{
-			"error":   "consumed",
-			"message": "Token has already been retrieved",
+			"error":   "exchange_failed",
+			"message": "Failed to exchange authorization code (PKCE verification failed or code expired)",
 		}); err != nil {
-			log.Printf("Error encoding consumed response: %v", err)
+			log.Printf("Error encoding exchange failure response: %v", err)
 		}
+		return
+	}
 
-	case TokenStatusNotFound:
-		w.WriteHeader(http.StatusNotFound)
+	if !s.store.ConsumeCode(client.storeKey(state)) {
+		// A concurrent poll consumed the code first.
+		writeTokenStatus(w, TokenStatusConsumed)
+		return
+	}
+
+	if err := client.checkDomain(token); err != nil {
+		logger.WarnContext(r.Context(), "rejected login", "error", err)
+		w.WriteHeader(http.StatusForbidden)
 		if err := json.NewEncoder(w).Encode(map[string]string{
-			"error":   "not_found",
-			"message": "Token not found or expired",
+			"error":   "domain_not_allowed",
+			"message": "This account's domain is not allowed for this application",
 		}); err != nil {
-			log.Printf("Error encoding not found response: %v", err)
+			log.Printf("Error encoding domain rejection response: %v", err)
 		}
+		return
+	}
+
+	resp, err := relayproto.EncryptToken(recipient, relayproto.NewTokenResponse(token))
+	if err != nil {
+		logger.ErrorContext(r.Context(), "token encryption failed", "error", err)
+		http.Error(w, `{"error": "encryption_failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.metrics.loginCompleted(client)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding encrypted token response: %v", err)
+	}
+}
+
+// writeTokenStatus writes the JSON body for a /token/ lookup that did not
+// yield a token.
+func writeTokenStatus(w http.ResponseWriter, status TokenStatus) {
+	code, body := relayproto.StatusResponse(status.wire())
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding %s response: %v", status.wire(), err)
+	}
+}
+
+// writeUnknownClient writes the JSON body for a poll against a client the
+// relay does not serve.
+func writeUnknownClient(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"error":   "unknown_client",
+		"message": "This relay does not serve the requested OAuth client",
+	}); err != nil {
+		log.Printf("Error encoding unknown client response: %v", err)
 	}
 }
 
-// handleStatus checks the status of a token without consuming it.
+// handleStatus checks the status of a token of the default client without
+// consuming it.
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.defaultClient == nil {
+		writeUnknownClient(w)
+		return
+	}
+
+	// Extract state from path: /status/{state}
+	s.serveStatus(w, r, s.defaultClient, strings.TrimPrefix(r.URL.Path, "/status/"))
+}
+
+// handleClientStatus checks the status of a token of a tenant client.
+func (s *Server) handleClientStatus(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.clients[r.PathValue("client")]
+	if !ok {
+		writeUnknownClient(w)
+		return
+	}
+	s.serveStatus(w, r, client, r.PathValue("state"))
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request, client *OAuthClient, state string) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract state from path: /status/{state}
-	state := strings.TrimPrefix(r.URL.Path, "/status/")
 	if state == "" {
 		http.Error(w, `{"error": "Missing state parameter"}`, http.StatusBadRequest)
 		return
@@ -210,35 +439,15 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	status := s.store.Status(state)
-
-	var statusStr string
-	switch status {
-	case TokenStatusReady:
-		statusStr = "ready"
-	case TokenStatusPending:
-		statusStr = "pending"
-	case TokenStatusConsumed:
-		statusStr = "consumed"
-	case TokenStatusNotFound:
-		statusStr = "not_found"
-	}
+	status := s.store.Status(client.storeKey(state)).wire()
 
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status": statusStr,
+	if err := json.NewEncoder(w).Encode(map[string]relayproto.Status{
+		"status": status,
 	}); err != nil {
 		log.Printf("Error encoding status response: %v", err)
 	}
 }
 
-// TokenResponse represents the JSON response containing OAuth tokens.
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	TokenType    string `json:"token_type"`
-	Expiry       string `json:"expiry"`
-}
-
 // renderSuccessPage renders an HTML success page for the OAuth callback.
 func (s *Server) renderSuccessPage(w http.ResponseWriter, state string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
 	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
 )
 
 func TestHealthEndpoint(t *testing.T) {
@@ -32,6 +35,24 @@ func TestHealthEndpoint(t *testing.T) {
 	if resp.Status != "ok" {
 		t.Errorf("Expected status 'ok', got '%s'", resp.Status)
 	}
+	if !slices.Contains(resp.Features, relayproto.FeatureHandoff) {
+		t.Errorf("Expected features to list %q, got %v", relayproto.FeatureHandoff, resp.Features)
+	}
+}
+
+func TestHealthEndpoint_TenantPrefix(t *testing.T) {
+	server := NewServer(NewTokenStore(15*time.Minute), "", "", "")
+	if err := server.AddClient(&OAuthClient{Slug: "acme", Config: newOAuthConfig("id", "secret", "https://relay/c/acme/callback")}); err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+
+	for path, want := range map[string]int{"/c/acme/health": http.StatusOK, "/c/other/health": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
 }
 
 func TestHealthEndpoint_WrongMethod(t *testing.T) {
@@ -84,7 +105,7 @@ func TestTokenEndpoint_Ready(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
-	var resp TokenResponse
+	var resp relayproto.TokenResponse
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
@@ -226,7 +247,7 @@ func TestCallbackEndpoint_Success(t *testing.T) {
 	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
 
 	// Mock the exchange function
-	server.exchangeFunc = func(ctx context.Context, code string) (*oauth2.Token, error) {
+	server.exchangeFunc = func(ctx context.Context, _ *OAuthClient, code string) (*oauth2.Token, error) {
 		return &oauth2.Token{
 			AccessToken:  "exchanged-access-token",
 			RefreshToken: "exchanged-refresh-token",
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+func TestEncryptedHandoff(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+
+	state := "nonce123." + identity.Recipient().String()
+
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+
+	server.exchangeFunc = func(context.Context, *OAuthClient, string) (*oauth2.Token, error) {
+		t.Fatal("plaintext exchange must not run for encrypted handoffs")
+		return nil, nil
+	}
+
+	var gotVerifier string
+
+	server.pkceExchangeFunc = func(_ context.Context, _ *OAuthClient, code string, verifier string) (*oauth2.Token, error) {
+		if code != "auth-code" {
+			t.Fatalf("unexpected code %q", code)
+		}
+		gotVerifier = verifier
+
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/callback?code=auth-code&state="+state, nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback status %d", w.Code)
+	}
+
+	if entry := store.tokens[state]; entry == nil || entry.Token != nil || entry.Code != "auth-code" {
+		t.Fatalf("expected only the code to be stored, got %#v", entry)
+	}
+
+	// Polls without the verifier are rejected and do not consume the code.
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token/"+state, nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without verifier, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/token/"+state, nil)
+	req.Header.Set(relayproto.CodeVerifierHeader, "verifier")
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || gotVerifier != "verifier" {
+		t.Fatalf("expected 200 with verifier, got %d (%q)", w.Code, gotVerifier)
+	}
+
+	if bytes.Contains(w.Body.Bytes(), []byte("refresh")) {
+		t.Fatalf("response leaks plaintext token: %s", w.Body.String())
+	}
+
+	var enc relayproto.EncryptedTokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&enc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	tok, err := relayproto.DecryptToken(identity, enc.Encrypted)
+	if err != nil || tok.RefreshToken != "refresh" {
+		t.Fatalf("unexpected token %+v (%v)", tok, err)
+	}
+
+	// Single use.
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Fatalf("expected 410 on second poll, got %d", w.Code)
+	}
+}
+
+func TestEncryptedHandoff_ExchangeFailureKeepsCode(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	state := "n." + identity.Recipient().String()
+
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+	server.pkceExchangeFunc = func(_ context.Context, _ *OAuthClient, _ string, verifier string) (*oauth2.Token, error) {
+		if verifier != "verifier" {
+			return nil, errors.New("invalid_grant")
+		}
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, nil
+	}
+
+	store.StoreCode(state, "auth-code")
+
+	poll := func(verifier string) int {
+		req := httptest.NewRequest(http.MethodGet, "/token/"+state, nil)
+		req.Header.Set(relayproto.CodeVerifierHeader, verifier)
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	if code := poll("wrong"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", code)
+	}
+
+	// A wrong verifier does not destroy the login.
+	if code := poll("verifier"); code != http.StatusOK {
+		t.Fatalf("expected 200 with the right verifier, got %d", code)
+	}
+
+	if _, status := store.PeekCode(state); status != TokenStatusConsumed {
+		t.Fatalf("expected code consumed after the exchange, got %v", status)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -22,6 +24,10 @@ const (
 	DefaultTTL = 15 * time.Minute
 	// CleanupInterval is how often to run the token cleanup routine.
 	CleanupInterval = 1 * time.Minute
+	// DefaultIPRateLimit and DefaultStateRateLimit bound /token/ and /status/
+	// polling in requests per second. The CLI polls each state every 2s.
+	DefaultIPRateLimit    = 10
+	DefaultStateRateLimit = 1
 )
 
 func main() {
@@ -32,6 +38,14 @@ func main() {
 	redirectURL := flag.String("redirect-url", "", "OAuth redirect URL (defaults to http://localhost:{port}/callback)")
 	credentialsFile := flag.String("credentials-file", "", "Path to OAuth credentials JSON file (workit format)")
 	ttl := flag.Duration("ttl", DefaultTTL, "Token time-to-live")
+	clientsDir := flag.String("clients-dir", "", "Directory of credential files for additional OAuth clients, served under /c/{slug}/")
+	storePath := flag.String("store-path", "", "Path to a SQLite database for durable token storage (default: in-memory)")
+	logFormat := flag.String("log-format", "", "Log format: json or text (default json)")
+	ipRate := flag.Float64("rate-limit-ip", DefaultIPRateLimit, "Polls per second allowed per client IP on /token/ and /status/ (0 disables)")
+	ipBurst := flag.Int("rate-limit-ip-burst", 2*DefaultIPRateLimit, "Burst size for the per-IP rate limit")
+	stateRate := flag.Float64("rate-limit-state", DefaultStateRateLimit, "Polls per second allowed per state on /token/ and /status/ (0 disables)")
+	stateBurst := flag.Int("rate-limit-state-burst", 5, "Burst size for the per-state rate limit")
+	trustProxy := flag.Bool("trust-proxy", false, "Take the client IP from X-Forwarded-For (set when running behind a load balancer)")
 	flag.Parse()
 
 	// Allow environment variables to override flags
@@ -44,6 +58,23 @@ func main() {
 	if *redirectURL == "" {
 		*redirectURL = os.Getenv("WK_REDIRECT_URL")
 	}
+	if *storePath == "" {
+		*storePath = os.Getenv("WK_STORE_PATH")
+	}
+	if *clientsDir == "" {
+		*clientsDir = os.Getenv("WK_CLIENTS_DIR")
+	}
+	if *logFormat == "" {
+		*logFormat = os.Getenv("WK_LOG_FORMAT")
+	}
+	if !*trustProxy {
+		*trustProxy = os.Getenv("WK_TRUST_PROXY") == "true" || os.Getenv("WK_TRUST_PROXY") == "1"
+	}
+
+	// Structured logs; the standard log package is routed through the same
+	// handler so every line has the same format.
+	logger := newLogger(os.Stderr, *logFormat)
+	slog.SetDefault(logger)
 
 	// Load credentials from file if specified (fills empty client ID/secret)
 	if *credentialsFile != "" {
@@ -60,26 +91,49 @@ func main() {
 		log.Printf("Loaded credentials from %s", *credentialsFile)
 	}
 
-	// Validate required configuration
-	if *clientID == "" {
-		log.Fatal("OAuth client ID is required (--client-id, WK_CLIENT_ID, or --credentials-file)")
-	}
-	if *clientSecret == "" {
-		log.Fatal("OAuth client secret is required (--client-secret, WK_CLIENT_SECRET, or --credentials-file)")
-	}
-
 	// Default redirect URL if not specified
 	if *redirectURL == "" {
 		*redirectURL = fmt.Sprintf("http://localhost:%d/callback", *port)
 	}
 
+	// Load tenant clients; their default redirect URLs share the base of
+	// the default redirect URL.
+	var clients []*OAuthClient
+	if *clientsDir != "" {
+		var err error
+		clients, err = loadClientsDir(*clientsDir, strings.TrimSuffix(*redirectURL, "/callback"))
+		if err != nil {
+			log.Fatalf("Failed to load clients from %s: %v", *clientsDir, err)
+		}
+	}
+
+	// Validate required configuration. The default client is optional when
+	// --clients-dir provides at least one tenant.
+	if *clientID == "" && len(clients) == 0 {
+		log.Fatal("OAuth client ID is required (--client-id, WK_CLIENT_ID, --credentials-file, or --clients-dir)")
+	}
+	if *clientID != "" && *clientSecret == "" {
+		log.Fatal("OAuth client secret is required (--client-secret, WK_CLIENT_SECRET, or --credentials-file)")
+	}
+
 	// Create token store with TTL and start cleanup
-	store := NewTokenStore(*ttl)
-	store.StartCleanup(CleanupInterval)
-	defer store.StopCleanup()
+	store, err := openTokenStore(*storePath, *ttl)
+	if err != nil {
+		log.Fatalf("Failed to open token store %s: %v", *storePath, err)
+	}
+	defer store.Close()
 
 	// Create server
 	server := NewServer(store, *clientID, *clientSecret, *redirectURL)
+	server.SetLogger(logger)
+	server.ConfigureRateLimits(*ipRate, *ipBurst, *stateRate, *stateBurst, *trustProxy)
+	store.StartCleanup(CleanupInterval, server.metrics.loginsExpiredAdd)
+	defer store.StopCleanup()
+	for _, client := range clients {
+		if err := server.AddClient(client); err != nil {
+			log.Fatalf("Failed to register client %s: %v", client.Slug, err)
+		}
+	}
 
 	// Create HTTP server
 	httpServer := &http.Server{
@@ -106,8 +160,18 @@ func main() {
 
 	// Start server
 	log.Printf("Auth callback server starting on port %d", *port)
-	log.Printf("Redirect URL: %s", *redirectURL)
+	if *clientID != "" {
+		log.Printf("Redirect URL: %s", *redirectURL)
+	}
+	for _, client := range clients {
+		log.Printf("Client %s: redirect URL %s, allowed domains %v", client.Slug, client.Config.RedirectURL, client.AllowedDomains)
+	}
 	log.Printf("Token TTL: %s", *ttl)
+	if *storePath != "" {
+		log.Printf("Token store: %s", *storePath)
+	} else {
+		log.Printf("Token store: in-memory")
+	}
 
 	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server error: %v", err)
@@ -117,11 +181,26 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// openTokenStore returns the SQLite store when path is set, otherwise the
+// default in-memory store.
+func openTokenStore(path string, ttl time.Duration) (TokenStore, error) {
+	if path == "" {
+		return NewTokenStore(ttl), nil
+	}
+	return NewSQLiteTokenStore(path, ttl)
+}
+
 type oauthCredentials struct {
-	clientID     string
-	clientSecret string
+	clientID       string
+	clientSecret   string
+	redirectURL    string
+	allowedDomains []string
 }
 
+// credentialsFileFormat accepts Google's downloaded client JSON
+// (installed/web) and workit's stored {client_id, client_secret} format.
+// redirect_url and allowed_domains are relay-only extensions used by
+// --clients-dir tenants.
 type credentialsFileFormat struct {
 	Installed *struct {
 		ClientID     string `json:"client_id"`
@@ -131,6 +210,10 @@ type credentialsFileFormat struct {
 		ClientID     string `json:"client_id"`
 		ClientSecret string `json:"client_secret"`
 	} `json:"web"`
+	ClientID       string   `json:"client_id"`
+	ClientSecret   string   `json:"client_secret"`
+	RedirectURL    string   `json:"redirect_url"`
+	AllowedDomains []string `json:"allowed_domains"`
 }
 
 func loadCredentialsFile(path string) (oauthCredentials, error) {
@@ -144,13 +227,18 @@ func loadCredentialsFile(path string) (oauthCredentials, error) {
 		return oauthCredentials{}, fmt.Errorf("parse JSON: %w", err)
 	}
 
-	if f.Web != nil && f.Web.ClientID != "" {
-		return oauthCredentials{clientID: f.Web.ClientID, clientSecret: f.Web.ClientSecret}, nil
-	}
-
-	if f.Installed != nil && f.Installed.ClientID != "" {
-		return oauthCredentials{clientID: f.Installed.ClientID, clientSecret: f.Installed.ClientSecret}, nil
+	creds := oauthCredentials{redirectURL: f.RedirectURL, allowedDomains: f.AllowedDomains}
+
+	switch {
+	case f.Web != nil && f.Web.ClientID != "":
+		creds.clientID, creds.clientSecret = f.Web.ClientID, f.Web.ClientSecret
+	case f.Installed != nil && f.Installed.ClientID != "":
+		creds.clientID, creds.clientSecret = f.Installed.ClientID, f.Installed.ClientSecret
+	case f.ClientID != "":
+		creds.clientID, creds.clientSecret = f.ClientID, f.ClientSecret
+	default:
+		return oauthCredentials{}, fmt.Errorf("no client credentials found in file")
 	}
 
-	return oauthCredentials{}, fmt.Errorf("no client credentials found in file")
+	return creds, nil
 }
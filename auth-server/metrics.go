@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the Prometheus default histogram buckets, in seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects relay counters and latency histograms and renders them in
+// the Prometheus text exposition format on /metrics.
+type Metrics struct {
+	mu sync.Mutex
+
+	loginsStarted   map[string]uint64 // by client
+	loginsCompleted map[string]uint64 // by client
+	loginsExpired   uint64
+	exchangeErrors  map[string]uint64 // by client
+	rateLimits      map[string]uint64 // by limit (ip, state)
+	requests        map[[2]string]uint64
+	requestLatency  map[string]*histogram // by route
+	exchangeLatency *histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// NewMetrics creates an empty metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		loginsStarted:   make(map[string]uint64),
+		loginsCompleted: make(map[string]uint64),
+		exchangeErrors:  make(map[string]uint64),
+		rateLimits:      make(map[string]uint64),
+		requests:        make(map[[2]string]uint64),
+		requestLatency:  make(map[string]*histogram),
+		exchangeLatency: newHistogram(),
+	}
+}
+
+// clientLabel returns the metrics label for a client.
+func clientLabel(client *OAuthClient) string {
+	if client == nil || client.Slug == "" {
+		return "default"
+	}
+	return client.Slug
+}
+
+func (m *Metrics) loginStarted(client *OAuthClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loginsStarted[clientLabel(client)]++
+}
+
+func (m *Metrics) loginCompleted(client *OAuthClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loginsCompleted[clientLabel(client)]++
+}
+
+// loginsExpiredAdd records entries the store's cleanup removed before the
+// CLI collected them.
+func (m *Metrics) loginsExpiredAdd(n int) {
+	if n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loginsExpired += uint64(n)
+}
+
+func (m *Metrics) exchanged(client *OAuthClient, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exchangeLatency.observe(d.Seconds())
+	if err != nil {
+		m.exchangeErrors[clientLabel(client)]++
+	}
+}
+
+func (m *Metrics) rateLimited(limit string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimits[limit]++
+}
+
+func (m *Metrics) request(route string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{route, strconv.Itoa(code)}]++
+	h, ok := m.requestLatency[route]
+	if !ok {
+		h = newHistogram()
+		m.requestLatency[route] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.metrics.write(w); err != nil {
+		log.Printf("Error writing metrics: %v", err)
+	}
+}
+
+func (m *Metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	writeCounterVec(&b, "wk_auth_logins_started_total", "OAuth callbacks received (logins that reached the relay).", "client", m.loginsStarted)
+	writeCounterVec(&b, "wk_auth_logins_completed_total", "Tokens handed to a polling CLI.", "client", m.loginsCompleted)
+
+	b.WriteString("# HELP wk_auth_logins_expired_total Logins removed by TTL cleanup before the CLI collected them.\n")
+	b.WriteString("# TYPE wk_auth_logins_expired_total counter\n")
+	fmt.Fprintf(&b, "wk_auth_logins_expired_total %d\n", m.loginsExpired)
+
+	writeCounterVec(&b, "wk_auth_exchange_errors_total", "Failed authorization code exchanges with Google.", "client", m.exchangeErrors)
+	writeCounterVec(&b, "wk_auth_rate_limited_total", "Polls rejected by rate limiting.", "limit", m.rateLimits)
+
+	b.WriteString("# HELP wk_auth_http_requests_total HTTP requests by route and status code.\n")
+	b.WriteString("# TYPE wk_auth_http_requests_total counter\n")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&b, "wk_auth_http_requests_total{route=%q,code=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+
+	b.WriteString("# HELP wk_auth_http_request_duration_seconds HTTP request latency by route.\n")
+	b.WriteString("# TYPE wk_auth_http_request_duration_seconds histogram\n")
+	routes := make([]string, 0, len(m.requestLatency))
+	for route := range m.requestLatency {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		writeHistogram(&b, "wk_auth_http_request_duration_seconds", fmt.Sprintf("route=%q,", route), m.requestLatency[route])
+	}
+
+	b.WriteString("# HELP wk_auth_exchange_duration_seconds Authorization code exchange latency.\n")
+	b.WriteString("# TYPE wk_auth_exchange_duration_seconds histogram\n")
+	writeHistogram(&b, "wk_auth_exchange_duration_seconds", "", m.exchangeLatency)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCounterVec(b *strings.Builder, name, help, label string, values map[string]uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s{%s=%q} %d\n", name, label, k, values[k])
+	}
+}
+
+// writeHistogram writes one histogram series; labels is either empty or a
+// "name=\"value\"," prefix for the le label.
+func writeHistogram(b *strings.Builder, name, labels string, h *histogram) {
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket{%sle=%q} %d\n", name, labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + strings.TrimSuffix(labels, ",") + "}"
+	}
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, suffix, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, suffix, h.count)
+}
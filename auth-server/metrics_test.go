@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestServer_Metrics(t *testing.T) {
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+	server.exchangeFunc = func(_ context.Context, _ *OAuthClient, code string) (*oauth2.Token, error) {
+		if code == "bad" {
+			return nil, errors.New("invalid_grant")
+		}
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, nil
+	}
+
+	for _, path := range []string{
+		"/callback?code=good&state=s1",
+		"/callback?code=bad&state=s2",
+		"/token/s1",
+	} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	server.metrics.loginsExpiredAdd(3)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`wk_auth_logins_started_total{client="default"} 2`,
+		`wk_auth_logins_completed_total{client="default"} 1`,
+		`wk_auth_logins_expired_total 3`,
+		`wk_auth_exchange_errors_total{client="default"} 1`,
+		`wk_auth_http_requests_total{route="callback",code="500"} 1`,
+		`wk_auth_http_requests_total{route="token",code="200"} 1`,
+		`wk_auth_http_request_duration_seconds_bucket{route="token",le="+Inf"} 1`,
+		`wk_auth_http_request_duration_seconds_count{route="callback"} 2`,
+		`wk_auth_exchange_duration_seconds_count 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "s1") {
+		t.Error("Metrics must not contain states")
+	}
+}
+
+func TestServer_RequestIDLogs(t *testing.T) {
+	var logs bytes.Buffer
+
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+	server.SetLogger(newLogger(&logs, "json"))
+
+	req := httptest.NewRequest(http.MethodGet, "/status/some-state", nil)
+	req.Header.Set(requestIDHeader, "lb-1234")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestIDHeader); got != "lb-1234" {
+		t.Errorf("Expected load balancer request ID to be echoed, got %q", got)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON log line, got %q: %v", logs.String(), err)
+	}
+	if line["request_id"] != "lb-1234" || line["route"] != "status" || line["status"] != float64(http.StatusOK) {
+		t.Errorf("Unexpected access log: %v", line)
+	}
+
+	// Unusable incoming IDs are replaced with a generated one.
+	req = httptest.NewRequest(http.MethodGet, "/status/some-state", nil)
+	req.Header.Set(requestIDHeader, "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if got := w.Header().Get(requestIDHeader); !requestIDPattern.MatchString(got) || got == "bad id\nwith newline" {
+		t.Errorf("Expected a generated request ID, got %q", got)
+	}
+}
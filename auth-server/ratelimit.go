@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiterIdleTTL is how long an unused per-key limiter is kept.
+	limiterIdleTTL = 10 * time.Minute
+	// limiterSweepInterval is how often idle limiters are swept.
+	limiterSweepInterval = time.Minute
+)
+
+// keyedLimiter is a set of token-bucket limiters keyed by client IP or state.
+// A nil *keyedLimiter allows everything.
+type keyedLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	entries   map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newKeyedLimiter returns a limiter allowing perSecond requests per key with
+// the given burst, or nil (unlimited) when perSecond is not positive.
+func newKeyedLimiter(perSecond float64, burst int) *keyedLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &keyedLimiter{
+		limit:     rate.Limit(perSecond),
+		burst:     burst,
+		entries:   make(map[string]*limiterEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether a request for key may proceed now.
+func (l *keyedLimiter) allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > limiterSweepInterval {
+		for k, e := range l.entries {
+			if now.Sub(e.lastSeen) > limiterIdleTTL {
+				delete(l.entries, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.entries[key] = entry
+	}
+	entry.lastSeen = now
+
+	return entry.limiter.AllowN(now, 1)
+}
+
+// ConfigureRateLimits enables per-IP and per-state rate limiting of the
+// /token/ and /status/ polling endpoints (0 disables a limit). With
+// trustProxy the client IP is taken from the X-Forwarded-For entry appended by
+// the load balancer instead of the connection's remote address.
+func (s *Server) ConfigureRateLimits(perIP float64, ipBurst int, perState float64, stateBurst int, trustProxy bool) {
+	s.ipLimiter = newKeyedLimiter(perIP, ipBurst)
+	s.stateLimiter = newKeyedLimiter(perState, stateBurst)
+	s.trustProxy = trustProxy
+}
+
+// rateLimited wraps a polling handler with the per-IP and per-state limits.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.ipLimiter.allow(s.clientIP(r)) {
+			s.metrics.rateLimited("ip")
+			writeRateLimited(w)
+			return
+		}
+		if state := pollState(r); state != "" && !s.stateLimiter.allow(state) {
+			s.metrics.rateLimited("state")
+			writeRateLimited(w)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientIP returns the caller's IP address used for rate limiting and logs.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			// The last entry was appended by our load balancer; earlier
+			// entries are client-supplied and can be spoofed.
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// pollState extracts the state from /token/{state}, /status/{state} and the
+// tenant equivalents.
+func pollState(r *http.Request) string {
+	if state := r.PathValue("state"); state != "" {
+		return state
+	}
+	for _, prefix := range []string{"/token/", "/status/"} {
+		if state, ok := strings.CutPrefix(r.URL.Path, prefix); ok {
+			return state
+		}
+	}
+	return ""
+}
+
+// writeRateLimited writes the 429 response for a throttled poll. The CLI
+// keeps polling on unexpected statuses, so throttled polls simply retry.
+func writeRateLimited(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.Write([]byte(`{"error":"rate_limited","message":"Too many requests, slow down"}` + "\n"))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	unlimited := newKeyedLimiter(0, 1)
+	for range 100 {
+		if !unlimited.allow("k") {
+			t.Fatal("Expected a disabled limiter to allow every request")
+		}
+	}
+
+	l := newKeyedLimiter(1, 2)
+	if !l.allow("a") || !l.allow("a") {
+		t.Fatal("Expected the burst to be allowed")
+	}
+	if l.allow("a") {
+		t.Error("Expected the third immediate request to be limited")
+	}
+	if !l.allow("b") {
+		t.Error("Expected other keys to have their own bucket")
+	}
+}
+
+func TestServer_RateLimitsPolling(t *testing.T) {
+	store := NewTokenStore(15 * time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+	server.ConfigureRateLimits(0, 0, 1, 2, false)
+
+	codes := make([]int, 0, 3)
+	for range 3 {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status/busy-state", nil))
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("Expected 200, 200, 429 for one state, got %v", codes)
+	}
+
+	// A different state from the same IP is unaffected by the state limit.
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token/other-state", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another state, got %d", w.Code)
+	}
+
+	// Per-IP limit, with the client IP taken from the load balancer's entry.
+	server.ConfigureRateLimits(1, 1, 0, 0, true)
+	poll := func(xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/token/ip-state", nil)
+		req.Header.Set("X-Forwarded-For", xff)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := poll("1.2.3.4, 10.0.0.1"); code != http.StatusNotFound {
+		t.Fatalf("Expected first poll to pass, got %d", code)
+	}
+	if code := poll("9.9.9.9, 10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected spoofed leading X-Forwarded-For to share the limit, got %d", code)
+	}
+	if code := poll("10.0.0.2"); code != http.StatusNotFound {
+		t.Errorf("Expected a different client IP to pass, got %d", code)
+	}
+
+	// Health checks are never limited.
+	for range 5 {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected health 200, got %d", w.Code)
+		}
+	}
+}
@@ -6,26 +6,65 @@ import (
 	"time"
 
 	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
 )
 
 // TokenEntry holds the OAuth token along with metadata for TTL management.
+// For end-to-end encrypted handoffs the relay keeps only the authorization
+// Code (useless without the CLI's PKCE verifier) instead of a Token.
 type TokenEntry struct {
 	Token     *oauth2.Token
+	Code      string
 	CreatedAt time.Time
 	Consumed  bool
 }
 
-// TokenStore provides thread-safe in-memory storage for OAuth tokens with TTL.
-type TokenStore struct {
+// TokenStore holds OAuth tokens (or, for encrypted handoffs, authorization
+// codes) keyed by state until the polling CLI consumes them. Entries expire
+// after the store's TTL.
+//
+// Write methods report storage failures. Read methods report storage
+// failures as TokenStatusNotFound (after logging) so pollers simply retry.
+type TokenStore interface {
+	// Store saves a token with the given state key.
+	Store(state string, token *oauth2.Token) error
+	// StoreCode saves an authorization code awaiting a PKCE-verified exchange.
+	StoreCode(state string, code string) error
+	// MarkPending creates a placeholder entry for a state awaiting a token.
+	MarkPending(state string) error
+	// Get retrieves and consumes a token for the given state.
+	Get(state string) (*oauth2.Token, TokenStatus)
+	// PeekCode retrieves the authorization code for the given state without
+	// consuming it, so a failed exchange (e.g. a wrong verifier) can be retried.
+	PeekCode(state string) (string, TokenStatus)
+	// ConsumeCode marks the code for the given state as used after a
+	// successful exchange. It reports false if another poller consumed it first.
+	ConsumeCode(state string) bool
+	// Status checks the status of a token without consuming it.
+	Status(state string) TokenStatus
+	// StartCleanup starts a background loop that removes expired entries.
+	// onExpired, when set, receives the number of removed entries that were
+	// never consumed.
+	StartCleanup(interval time.Duration, onExpired func(n int))
+	// StopCleanup stops the cleanup loop.
+	StopCleanup()
+	// Close releases the store's resources.
+	Close() error
+}
+
+// MemoryTokenStore provides thread-safe in-memory storage for OAuth tokens
+// with TTL. It is the default store; entries are lost on restart.
+type MemoryTokenStore struct {
 	mu       sync.RWMutex
 	tokens   map[string]*TokenEntry
 	ttl      time.Duration
 	stopChan chan struct{}
 }
 
-// NewTokenStore creates a new TokenStore with the specified TTL.
-func NewTokenStore(ttl time.Duration) *TokenStore {
-	return &TokenStore{
+// NewTokenStore creates the default in-memory TokenStore with the specified TTL.
+func NewTokenStore(ttl time.Duration) *MemoryTokenStore {
+	return &MemoryTokenStore{
 		tokens:   make(map[string]*TokenEntry),
 		ttl:      ttl,
 		stopChan: make(chan struct{}),
@@ -33,7 +72,7 @@ func NewTokenStore(ttl time.Duration) *TokenStore {
 }
 
 // Store saves a token with the given state key.
-func (s *TokenStore) Store(state string, token *oauth2.Token) {
+func (s *MemoryTokenStore) Store(state string, token *oauth2.Token) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.tokens[state] = &TokenEntry{
@@ -41,6 +80,19 @@ func (s *TokenStore) Store(state string, token *oauth2.Token) {
 		CreatedAt: time.Now(),
 		Consumed:  false,
 	}
+	return nil
+}
+
+// StoreCode saves an authorization code awaiting a PKCE-verified exchange.
+func (s *MemoryTokenStore) StoreCode(state string, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[state] = &TokenEntry{
+		Code:      code,
+		CreatedAt: time.Now(),
+		Consumed:  false,
+	}
+	return nil
 }
 
 // TokenStatus represents the status of a token lookup.
@@ -57,9 +109,23 @@ const (
 	TokenStatusConsumed
 )
 
+// wire returns the status reported to pollers.
+func (s TokenStatus) wire() relayproto.Status {
+	switch s {
+	case TokenStatusReady:
+		return relayproto.StatusReady
+	case TokenStatusPending:
+		return relayproto.StatusPending
+	case TokenStatusConsumed:
+		return relayproto.StatusConsumed
+	default:
+		return relayproto.StatusNotFound
+	}
+}
+
 // Get retrieves and consumes a token for the given state.
 // Returns the token and its status.
-func (s *TokenStore) Get(state string) (*oauth2.Token, TokenStatus) {
+func (s *MemoryTokenStore) Get(state string) (*oauth2.Token, TokenStatus) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -90,7 +156,7 @@ func (s *TokenStore) Get(state string) (*oauth2.Token, TokenStatus) {
 }
 
 // Status checks the status of a token without consuming it.
-func (s *TokenStore) Status(state string) TokenStatus {
+func (s *MemoryTokenStore) Status(state string) TokenStatus {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -108,16 +174,59 @@ func (s *TokenStore) Status(state string) TokenStatus {
 		return TokenStatusConsumed
 	}
 
-	if entry.Token != nil {
+	if entry.Token != nil || entry.Code != "" {
 		return TokenStatusReady
 	}
 
 	return TokenStatusPending
 }
 
+// PeekCode retrieves the authorization code for the given state without
+// consuming it.
+func (s *MemoryTokenStore) PeekCode(state string) (string, TokenStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.tokens[state]
+	if !exists {
+		return "", TokenStatusNotFound
+	}
+
+	if time.Since(entry.CreatedAt) > s.ttl {
+		delete(s.tokens, state)
+		return "", TokenStatusNotFound
+	}
+
+	if entry.Consumed {
+		return "", TokenStatusConsumed
+	}
+
+	if entry.Code == "" {
+		return "", TokenStatusPending
+	}
+
+	return entry.Code, TokenStatusReady
+}
+
+// ConsumeCode marks the code for the given state as used and forgets it.
+func (s *MemoryTokenStore) ConsumeCode(state string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.tokens[state]
+	if !exists || entry.Consumed || entry.Code == "" {
+		return false
+	}
+
+	entry.Code = ""
+	entry.Consumed = true
+
+	return true
+}
+
 // MarkPending creates a placeholder entry for a state that is awaiting a token.
 // This allows distinguishing between "pending" and "not found" states.
-func (s *TokenStore) MarkPending(state string) {
+func (s *MemoryTokenStore) MarkPending(state string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.tokens[state] = &TokenEntry{
@@ -125,17 +234,20 @@ func (s *TokenStore) MarkPending(state string) {
 		CreatedAt: time.Now(),
 		Consumed:  false,
 	}
+	return nil
 }
 
 // StartCleanup starts a background goroutine that periodically removes expired entries.
-func (s *TokenStore) StartCleanup(interval time.Duration) {
+func (s *MemoryTokenStore) StartCleanup(interval time.Duration, onExpired func(n int)) {
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				s.cleanup()
+				if n := s.cleanup(); n > 0 && onExpired != nil {
+					onExpired(n)
+				}
 			case <-s.stopChan:
 				return
 			}
@@ -144,19 +256,30 @@ func (s *TokenStore) StartCleanup(interval time.Duration) {
 }
 
 // StopCleanup stops the background cleanup goroutine.
-func (s *TokenStore) StopCleanup() {
+func (s *MemoryTokenStore) StopCleanup() {
 	close(s.stopChan)
 }
 
-// cleanup removes all expired entries from the store.
-func (s *TokenStore) cleanup() {
+// Close is a no-op for the in-memory store.
+func (s *MemoryTokenStore) Close() error {
+	return nil
+}
+
+// cleanup removes all expired entries from the store and returns how many of
+// them were never consumed.
+func (s *MemoryTokenStore) cleanup() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	expired := 0
 	now := time.Now()
 	for state, entry := range s.tokens {
 		if now.Sub(entry.CreatedAt) > s.ttl {
+			if !entry.Consumed {
+				expired++
+			}
 			delete(s.tokens, state)
 		}
 	}
+	return expired
 }
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/oauth2"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteTokenStore is a TokenStore backed by a SQLite database, so pending
+// handoffs survive relay restarts and redeploys. It follows the same TTL,
+// pending and single-use semantics as MemoryTokenStore.
+type SQLiteTokenStore struct {
+	db       *sql.DB
+	ttl      time.Duration
+	stopChan chan struct{}
+}
+
+// NewSQLiteTokenStore opens (or creates) the token database at path.
+func NewSQLiteTokenStore(path string, ttl time.Duration) (*SQLiteTokenStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	store := &SQLiteTokenStore{
+		db:       db,
+		ttl:      ttl,
+		stopChan: make(chan struct{}),
+	}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	return store, nil
+}
+
+// migrate creates the database schema if it doesn't exist.
+func (s *SQLiteTokenStore) migrate() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS tokens (
+		state TEXT PRIMARY KEY,
+		token TEXT,
+		code TEXT,
+		created_at INTEGER NOT NULL,
+		consumed INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tokens_created_at ON tokens(created_at);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// put replaces the entry for state with a fresh, unconsumed one.
+func (s *SQLiteTokenStore) put(state string, token, code sql.NullString) error {
+	_, err := s.db.Exec(`
+		INSERT INTO tokens (state, token, code, created_at, consumed)
+		VALUES (?, ?, ?, ?, 0)
+		ON CONFLICT(state) DO UPDATE SET
+			token = excluded.token,
+			code = excluded.code,
+			created_at = excluded.created_at,
+			consumed = 0
+	`, state, token, code, time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("store entry: %w", err)
+	}
+	return nil
+}
+
+// Store saves a token with the given state key.
+func (s *SQLiteTokenStore) Store(state string, token *oauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("encode token: %w", err)
+	}
+	return s.put(state, sql.NullString{String: string(data), Valid: true}, sql.NullString{})
+}
+
+// StoreCode saves an authorization code awaiting a PKCE-verified exchange.
+func (s *SQLiteTokenStore) StoreCode(state string, code string) error {
+	return s.put(state, sql.NullString{}, sql.NullString{String: code, Valid: true})
+}
+
+// MarkPending creates a placeholder entry for a state that is awaiting a token.
+func (s *SQLiteTokenStore) MarkPending(state string) error {
+	return s.put(state, sql.NullString{}, sql.NullString{})
+}
+
+// sqliteEntry is a row of the tokens table.
+type sqliteEntry struct {
+	token     sql.NullString
+	code      sql.NullString
+	createdAt int64
+	consumed  bool
+}
+
+func (s *SQLiteTokenStore) expired(e sqliteEntry) bool {
+	return time.Since(time.Unix(0, e.createdAt)) > s.ttl
+}
+
+func (s *SQLiteTokenStore) load(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, state string,
+) (sqliteEntry, bool, error) {
+	var e sqliteEntry
+	err := q.QueryRow(`SELECT token, code, created_at, consumed FROM tokens WHERE state = ?`, state).
+		Scan(&e.token, &e.code, &e.createdAt, &e.consumed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqliteEntry{}, false, nil
+	}
+	if err != nil {
+		return sqliteEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+// Get retrieves and consumes a token for the given state.
+func (s *SQLiteTokenStore) Get(state string) (*oauth2.Token, TokenStatus) {
+	var token *oauth2.Token
+
+	status, err := s.consume(state, func(e sqliteEntry) (TokenStatus, error) {
+		if !e.token.Valid {
+			return TokenStatusPending, nil
+		}
+		token = &oauth2.Token{}
+		if err := json.Unmarshal([]byte(e.token.String), token); err != nil {
+			return TokenStatusNotFound, fmt.Errorf("decode token: %w", err)
+		}
+		return TokenStatusReady, nil
+	})
+	if err != nil {
+		log.Printf("Token store: get %s: %v", state, err)
+		return nil, TokenStatusNotFound
+	}
+	if status != TokenStatusReady {
+		return nil, status
+	}
+	return token, TokenStatusReady
+}
+
+// PeekCode retrieves the authorization code for the given state without
+// consuming it.
+func (s *SQLiteTokenStore) PeekCode(state string) (string, TokenStatus) {
+	e, exists, err := s.load(s.db, state)
+	if err != nil {
+		log.Printf("Token store: peek code %s: %v", state, err)
+		return "", TokenStatusNotFound
+	}
+	if !exists || s.expired(e) {
+		return "", TokenStatusNotFound
+	}
+
+	if e.consumed {
+		return "", TokenStatusConsumed
+	}
+
+	if !e.code.Valid || e.code.String == "" {
+		return "", TokenStatusPending
+	}
+	return e.code.String, TokenStatusReady
+}
+
+// ConsumeCode marks the code for the given state as used and clears it.
+func (s *SQLiteTokenStore) ConsumeCode(state string) bool {
+	res, err := s.db.Exec(`UPDATE tokens SET consumed = 1, code = NULL WHERE state = ? AND consumed = 0 AND code IS NOT NULL`, state)
+	if err != nil {
+		log.Printf("Token store: consume code %s: %v", state, err)
+		return false
+	}
+	n, err := res.RowsAffected()
+	return err == nil && n == 1
+}
+
+// consume runs the shared single-use lookup in a transaction. check decides
+// whether the live entry is ready; ready entries are marked consumed and have
+// their secrets cleared so they do not linger on disk until cleanup.
+func (s *SQLiteTokenStore) consume(state string, check func(sqliteEntry) (TokenStatus, error)) (TokenStatus, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return TokenStatusNotFound, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	e, exists, err := s.load(tx, state)
+	if err != nil {
+		return TokenStatusNotFound, fmt.Errorf("load entry: %w", err)
+	}
+	if !exists {
+		return TokenStatusNotFound, nil
+	}
+
+	if s.expired(e) {
+		if _, err := tx.Exec(`DELETE FROM tokens WHERE state = ?`, state); err != nil {
+			return TokenStatusNotFound, fmt.Errorf("delete expired entry: %w", err)
+		}
+		return TokenStatusNotFound, tx.Commit()
+	}
+
+	if e.consumed {
+		return TokenStatusConsumed, nil
+	}
+
+	status, err := check(e)
+	if err != nil || status != TokenStatusReady {
+		return status, err
+	}
+
+	res, err := tx.Exec(`UPDATE tokens SET consumed = 1, token = NULL, code = NULL WHERE state = ? AND consumed = 0`, state)
+	if err != nil {
+		return TokenStatusNotFound, fmt.Errorf("mark consumed: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		// Another poller consumed the entry first.
+		return TokenStatusConsumed, nil //nolint:nilerr // lost the race, not a storage failure
+	}
+
+	if err := tx.Commit(); err != nil {
+		return TokenStatusNotFound, fmt.Errorf("commit: %w", err)
+	}
+	return TokenStatusReady, nil
+}
+
+// Status checks the status of a token without consuming it.
+func (s *SQLiteTokenStore) Status(state string) TokenStatus {
+	e, exists, err := s.load(s.db, state)
+	if err != nil {
+		log.Printf("Token store: status %s: %v", state, err)
+		return TokenStatusNotFound
+	}
+	if !exists || s.expired(e) {
+		return TokenStatusNotFound
+	}
+
+	if e.consumed {
+		return TokenStatusConsumed
+	}
+
+	if e.token.Valid || (e.code.Valid && e.code.String != "") {
+		return TokenStatusReady
+	}
+
+	return TokenStatusPending
+}
+
+// StartCleanup starts a background goroutine that periodically removes expired entries.
+func (s *SQLiteTokenStore) StartCleanup(interval time.Duration, onExpired func(n int)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				n, err := s.cleanup()
+				if err != nil {
+					log.Printf("Token store: cleanup: %v", err)
+				}
+				if n > 0 && onExpired != nil {
+					onExpired(n)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup stops the background cleanup goroutine.
+func (s *SQLiteTokenStore) StopCleanup() {
+	close(s.stopChan)
+}
+
+// Close closes the database connection.
+func (s *SQLiteTokenStore) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// cleanup removes all expired entries from the store and returns how many of
+// them were never consumed.
+func (s *SQLiteTokenStore) cleanup() (int, error) {
+	cutoff := time.Now().Add(-s.ttl).UnixNano()
+
+	res, err := s.db.Exec(`DELETE FROM tokens WHERE created_at < ? AND consumed = 0`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	expired, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM tokens WHERE created_at < ?`, cutoff); err != nil {
+		return int(expired), err
+	}
+	return int(expired), nil
+}
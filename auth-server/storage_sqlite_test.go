@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+func newTestSQLiteStore(t *testing.T, path string, ttl time.Duration) *SQLiteTokenStore {
+	t.Helper()
+
+	store, err := NewSQLiteTokenStore(path, ttl)
+	if err != nil {
+		t.Fatalf("NewSQLiteTokenStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestSQLiteTokenStore_StoreAndGet(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"), 15*time.Minute)
+
+	if err := store.MarkPending("state-abc"); err != nil {
+		t.Fatalf("MarkPending: %v", err)
+	}
+	if status := store.Status("state-abc"); status != TokenStatusPending {
+		t.Fatalf("Expected TokenStatusPending, got %v", status)
+	}
+	if _, status := store.Get("state-abc"); status != TokenStatusPending {
+		t.Fatalf("Expected Get to report TokenStatusPending, got %v", status)
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  "access-123",
+		RefreshToken: "refresh-456",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour).Round(time.Second),
+	}
+	if err := store.Store("state-abc", token); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if status := store.Status("state-abc"); status != TokenStatusReady {
+		t.Fatalf("Expected TokenStatusReady, got %v", status)
+	}
+
+	got, status := store.Get("state-abc")
+	if status != TokenStatusReady {
+		t.Fatalf("Expected TokenStatusReady, got %v", status)
+	}
+	if got.AccessToken != "access-123" || got.RefreshToken != "refresh-456" || !got.Expiry.Equal(token.Expiry) {
+		t.Errorf("Unexpected token: %#v", got)
+	}
+
+	if _, status := store.Get("state-abc"); status != TokenStatusConsumed {
+		t.Errorf("Expected TokenStatusConsumed, got %v", status)
+	}
+	if status := store.Status("state-abc"); status != TokenStatusConsumed {
+		t.Errorf("Expected Status TokenStatusConsumed, got %v", status)
+	}
+
+	var stored *string
+	if err := store.db.QueryRow(`SELECT token FROM tokens WHERE state = ?`, "state-abc").Scan(&stored); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if stored != nil {
+		t.Errorf("Expected consumed token to be cleared, got %q", *stored)
+	}
+
+	if _, status := store.Get("nonexistent"); status != TokenStatusNotFound {
+		t.Errorf("Expected TokenStatusNotFound, got %v", status)
+	}
+}
+
+func TestSQLiteTokenStore_PeekAndConsumeCode(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"), 15*time.Minute)
+
+	if err := store.StoreCode("state-code", "auth-code"); err != nil {
+		t.Fatalf("StoreCode: %v", err)
+	}
+	if status := store.Status("state-code"); status != TokenStatusReady {
+		t.Fatalf("Expected TokenStatusReady, got %v", status)
+	}
+
+	// Peeking does not consume, so a failed exchange can be retried.
+	for range 2 {
+		code, status := store.PeekCode("state-code")
+		if status != TokenStatusReady || code != "auth-code" {
+			t.Fatalf("PeekCode = %q, %v", code, status)
+		}
+	}
+
+	if !store.ConsumeCode("state-code") {
+		t.Fatal("ConsumeCode = false, want true")
+	}
+	if store.ConsumeCode("state-code") {
+		t.Error("second ConsumeCode = true, want false")
+	}
+	if _, status := store.PeekCode("state-code"); status != TokenStatusConsumed {
+		t.Errorf("Expected TokenStatusConsumed, got %v", status)
+	}
+}
+
+func TestSQLiteTokenStore_TTLAndCleanup(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"), 50*time.Millisecond)
+
+	if err := store.Store("expiring", &oauth2.Token{AccessToken: "a"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.MarkPending("swept"); err != nil {
+		t.Fatalf("MarkPending: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if status := store.Status("expiring"); status != TokenStatusNotFound {
+		t.Errorf("Expected expired Status TokenStatusNotFound, got %v", status)
+	}
+	if _, status := store.Get("expiring"); status != TokenStatusNotFound {
+		t.Errorf("Expected expired Get TokenStatusNotFound, got %v", status)
+	}
+
+	expired, err := store.cleanup()
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	// "expiring" was already dropped by the Get above; only "swept" is left.
+	if expired != 1 {
+		t.Errorf("Expected 1 unconsumed entry expired, got %d", expired)
+	}
+
+	var n int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM tokens`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected cleanup to remove all rows, %d left", n)
+	}
+}
+
+func TestSQLiteTokenStore_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.db")
+
+	first, err := NewSQLiteTokenStore(path, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewSQLiteTokenStore: %v", err)
+	}
+	if err := first.StoreCode("state-restart", "code-1"); err != nil {
+		t.Fatalf("StoreCode: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	second := newTestSQLiteStore(t, path, 15*time.Minute)
+
+	code, status := second.PeekCode("state-restart")
+	if status != TokenStatusReady || code != "code-1" {
+		t.Fatalf("PeekCode after reopen = %q, %v", code, status)
+	}
+}
+
+func TestServer_SQLiteStoreCallbackAndPoll(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "tokens.db"), 15*time.Minute)
+	server := NewServer(store, "client-id", "client-secret", "http://localhost/callback")
+	server.exchangeFunc = func(ctx context.Context, _ *OAuthClient, code string) (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer"}, nil
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/callback?code=auth-code&state=sqlite-state", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected callback status 200, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token/sqlite-state", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected token status 200, got %d", w.Code)
+	}
+
+	var resp relayproto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RefreshToken != "refresh" {
+		t.Errorf("Expected refresh token 'refresh', got %q", resp.RefreshToken)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/automagik-dev/workit
 go 1.25
 
 require (
+	filippo.io/age v1.2.1
 	github.com/99designs/keyring v1.2.2
 	github.com/alecthomas/kong v1.13.0
 	github.com/beevik/etree v1.6.0
@@ -13,6 +14,7 @@ require (
 	golang.org/x/net v0.49.0
 	golang.org/x/oauth2 v0.34.0
 	golang.org/x/term v0.39.0
+	golang.org/x/time v0.14.0
 	google.golang.org/api v0.260.0
 	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.37.1
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/automagik-dev/workit/internal/errfmt"
+	"github.com/automagik-dev/workit/internal/googleapi"
+)
+
+var newAdminDirectoryService = googleapi.NewAdminDirectory
+
+// adminCustomer addresses the Workspace account the admin belongs to.
+const adminCustomer = "my_customer"
+
+type AdminCmd struct {
+	Users    AdminUsersCmd    `cmd:"" name:"users" aliases:"user" help:"Workspace users"`
+	OrgUnits AdminOrgUnitsCmd `cmd:"" name:"orgunits" aliases:"orgunit,ou" help:"Organizational units"`
+	Groups   AdminGroupsCmd   `cmd:"" name:"groups" aliases:"group" help:"Groups and group members"`
+	Roles    AdminRolesCmd    `cmd:"" name:"roles" aliases:"role" help:"Admin roles and role assignments"`
+}
+
+// wrapAdminError adds remediation hints for the Directory API's common
+// failures: the API being disabled, or a non-admin (or under-scoped) account.
+func wrapAdminError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if googleapi.IsAPINotEnabledError(err) {
+		return errfmt.NewUserFacingError(googleapi.WrapAPIEnablementError(err, "admin").Error(), err)
+	}
+
+	errStr := err.Error()
+	if strings.Contains(errStr, "Not Authorized to access this resource/api") ||
+		strings.Contains(errStr, "insufficientPermissions") ||
+		strings.Contains(errStr, "insufficient authentication scopes") {
+		return errfmt.NewUserFacingError("Admin SDK access denied; the account must be a Workspace admin with the needed privileges, authorized for the admin service: wk auth add <account> --services admin", err)
+	}
+
+	return err
+}
+
+// orgUnitPathParam converts "/Sales/East" to the "Sales/East" form the
+// Directory API expects in org unit request paths. IDs ("id:...") pass through.
+func orgUnitPathParam(path string) string {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "id:") {
+		return path
+	}
+
+	return strings.Trim(path, "/")
+}
+
+// orgUnitPathField converts a user-supplied org unit path to the absolute
+// "/Sales/East" form used in resource fields.
+func orgUnitPathField(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" || strings.HasPrefix(path, "id:") {
+		return path
+	}
+
+	return "/" + strings.Trim(path, "/")
+}
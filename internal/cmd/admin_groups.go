@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+type AdminGroupsCmd struct {
+	List    AdminGroupsListCmd    `cmd:"" name:"list" aliases:"ls" help:"List groups"`
+	Get     AdminGroupsGetCmd     `cmd:"" name:"get" aliases:"info,show" help:"Get a group"`
+	Create  AdminGroupsCreateCmd  `cmd:"" name:"create" aliases:"add,new" help:"Create a group"`
+	Update  AdminGroupsUpdateCmd  `cmd:"" name:"update" aliases:"edit,set" help:"Update a group's name or description"`
+	Delete  AdminGroupsDeleteCmd  `cmd:"" name:"delete" aliases:"rm,del" help:"Delete a group"`
+	Members AdminGroupsMembersCmd `cmd:"" name:"members" aliases:"member" help:"Group members"`
+}
+
+type AdminGroupsListCmd struct {
+	Query     string `name:"query" aliases:"q" help:"Directory search query (e.g. \"email:eng*\")"`
+	Domain    string `name:"domain" help:"Only groups in this domain (default: every domain of the account)"`
+	User      string `name:"user" help:"Only groups this user or group is a member of"`
+	Max       int64  `name:"max" aliases:"limit" help:"Max results" default:"100"`
+	Page      string `name:"page" aliases:"cursor" help:"Page token"`
+	All       bool   `name:"all" aliases:"all-pages,allpages" help:"Fetch all pages"`
+	FailEmpty bool   `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminGroupsListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	effectiveMax, effectivePage := applyPagination(flags, c.Max, c.Page)
+
+	fetch := func(pageToken string) ([]*admin.Group, string, error) {
+		call := svc.Groups.List().MaxResults(effectiveMax).Context(ctx)
+		// The API rejects customer alongside userKey; userKey alone spans the account.
+		switch {
+		case strings.TrimSpace(c.Domain) != "":
+			call = call.Domain(strings.TrimSpace(c.Domain))
+		case strings.TrimSpace(c.User) == "":
+			call = call.Customer(adminCustomer)
+		}
+		if user := strings.TrimSpace(c.User); user != "" {
+			call = call.UserKey(user)
+		}
+		if query := strings.TrimSpace(c.Query); query != "" {
+			call = call.Query(query)
+		}
+		if strings.TrimSpace(pageToken) != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, "", wrapAdminError(err)
+		}
+		return resp.Groups, resp.NextPageToken, nil
+	}
+
+	var groups []*admin.Group
+	nextPageToken := ""
+	if c.All {
+		all, err := collectAllPages(effectivePage, fetch)
+		if err != nil {
+			return err
+		}
+		groups = all
+	} else {
+		groups, nextPageToken, err = fetch(effectivePage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"groups":        groups,
+			"nextPageToken": nextPageToken,
+		}); err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(groups) == 0 {
+		u.Err().Println("No groups found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "EMAIL\tNAME\tMEMBERS")
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n",
+			sanitizeTab(g.Email),
+			sanitizeTab(g.Name),
+			g.DirectMembersCount,
+		)
+	}
+	printNextPageHint(u, nextPageToken)
+	return nil
+}
+
+type AdminGroupsGetCmd struct {
+	Group string `arg:"" name:"group" help:"Group email or ID"`
+}
+
+func (c *AdminGroupsGetCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	if groupKey == "" {
+		return usage("group required")
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	g, err := svc.Groups.Get(groupKey).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"group": g})
+	}
+
+	return writeAdminGroup(u, g)
+}
+
+type AdminGroupsCreateCmd struct {
+	Email       string `arg:"" name:"email" help:"Group email address"`
+	Name        string `name:"name" help:"Display name (default: the email's local part)"`
+	Description string `name:"description" aliases:"desc" help:"Description"`
+}
+
+func (c *AdminGroupsCreateCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	email := strings.TrimSpace(c.Email)
+	if !strings.Contains(email, "@") {
+		return usagef("invalid group email %q", c.Email)
+	}
+
+	g := &admin.Group{
+		Email:       email,
+		Name:        strings.TrimSpace(c.Name),
+		Description: strings.TrimSpace(c.Description),
+	}
+	if g.Name == "" {
+		g.Name, _, _ = strings.Cut(email, "@")
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.groups.create", g); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	created, err := svc.Groups.Insert(g).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"group": created})
+	}
+
+	return writeAdminGroup(u, created)
+}
+
+type AdminGroupsUpdateCmd struct {
+	Group       string `arg:"" name:"group" help:"Group email or ID"`
+	Name        string `name:"name" help:"New display name"`
+	Description string `name:"description" aliases:"desc" help:"Description (empty clears)"`
+}
+
+func (c *AdminGroupsUpdateCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	if groupKey == "" {
+		return usage("group required")
+	}
+
+	patch := &admin.Group{}
+	changed := make([]string, 0, 2)
+
+	if flagProvided(kctx, "name") {
+		patch.Name = strings.TrimSpace(c.Name)
+		if patch.Name == "" {
+			return usage("empty --name")
+		}
+		changed = append(changed, "name")
+	}
+	if flagProvided(kctx, "description") {
+		patch.Description = strings.TrimSpace(c.Description)
+		patch.ForceSendFields = append(patch.ForceSendFields, "Description")
+		changed = append(changed, "description")
+	}
+
+	if len(changed) == 0 {
+		return usage("nothing to update (pass --name or --description)")
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.groups.update", map[string]any{
+		"group":  groupKey,
+		"fields": changed,
+		"patch":  patch,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	updated, err := svc.Groups.Patch(groupKey, patch).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"group": updated, "updated": changed})
+	}
+
+	return writeAdminGroup(u, updated)
+}
+
+type AdminGroupsDeleteCmd struct {
+	Group string `arg:"" name:"group" help:"Group email or ID"`
+}
+
+func (c *AdminGroupsDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	if groupKey == "" {
+		return usage("group required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete group %s", groupKey)); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if err := svc.Groups.Delete(groupKey).Context(ctx).Do(); err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("deleted", true),
+		kv("group", groupKey),
+	)
+}
+
+type AdminGroupsMembersCmd struct {
+	List   AdminGroupsMembersListCmd   `cmd:"" name:"list" aliases:"ls" default:"withargs" help:"List members of a group"`
+	Add    AdminGroupsMembersAddCmd    `cmd:"" name:"add" aliases:"insert" help:"Add a member to a group"`
+	Remove AdminGroupsMembersRemoveCmd `cmd:"" name:"remove" aliases:"rm,delete" help:"Remove a member from a group"`
+}
+
+type AdminGroupsMembersListCmd struct {
+	Group     string `arg:"" name:"group" help:"Group email or ID"`
+	Role      string `name:"role" help:"Only members with this role: OWNER|MANAGER|MEMBER"`
+	Max       int64  `name:"max" aliases:"limit" help:"Max results" default:"200"`
+	Page      string `name:"page" aliases:"cursor" help:"Page token"`
+	All       bool   `name:"all" aliases:"all-pages,allpages" help:"Fetch all pages"`
+	FailEmpty bool   `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminGroupsMembersListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	if groupKey == "" {
+		return usage("group required")
+	}
+
+	role := ""
+	if strings.TrimSpace(c.Role) != "" {
+		if role, err = parseGroupRole(c.Role); err != nil {
+			return err
+		}
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	effectiveMax, effectivePage := applyPagination(flags, c.Max, c.Page)
+
+	fetch := func(pageToken string) ([]*admin.Member, string, error) {
+		call := svc.Members.List(groupKey).MaxResults(effectiveMax).Context(ctx)
+		if role != "" {
+			call = call.Roles(role)
+		}
+		if strings.TrimSpace(pageToken) != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, "", wrapAdminError(err)
+		}
+		return resp.Members, resp.NextPageToken, nil
+	}
+
+	var members []*admin.Member
+	nextPageToken := ""
+	if c.All {
+		all, err := collectAllPages(effectivePage, fetch)
+		if err != nil {
+			return err
+		}
+		members = all
+	} else {
+		members, nextPageToken, err = fetch(effectivePage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"group":         groupKey,
+			"members":       members,
+			"nextPageToken": nextPageToken,
+		}); err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(members) == 0 {
+		u.Err().Println("No members found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "EMAIL\tROLE\tTYPE\tSTATUS")
+	for _, m := range members {
+		if m == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			sanitizeTab(m.Email),
+			sanitizeTab(m.Role),
+			sanitizeTab(m.Type),
+			sanitizeTab(m.Status),
+		)
+	}
+	printNextPageHint(u, nextPageToken)
+	return nil
+}
+
+type AdminGroupsMembersAddCmd struct {
+	Group  string `arg:"" name:"group" help:"Group email or ID"`
+	Member string `arg:"" name:"member" help:"Member email (user or group)"`
+	Role   string `name:"role" help:"Member role: OWNER|MANAGER|MEMBER" default:"MEMBER"`
+}
+
+func (c *AdminGroupsMembersAddCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	memberEmail := strings.TrimSpace(c.Member)
+	if groupKey == "" || memberEmail == "" {
+		return usage("group and member required")
+	}
+
+	role, err := parseGroupRole(c.Role)
+	if err != nil {
+		return err
+	}
+
+	m := &admin.Member{Email: memberEmail, Role: role}
+
+	if err := dryRunExit(ctx, flags, "admin.groups.members.add", map[string]any{
+		"group":  groupKey,
+		"member": m,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	added, err := svc.Members.Insert(groupKey, m).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"group": groupKey, "member": added})
+	}
+
+	u.Out().Printf("group\t%s", groupKey)
+	u.Out().Printf("member\t%s", added.Email)
+	u.Out().Printf("role\t%s", added.Role)
+	return nil
+}
+
+type AdminGroupsMembersRemoveCmd struct {
+	Group  string `arg:"" name:"group" help:"Group email or ID"`
+	Member string `arg:"" name:"member" help:"Member email or ID"`
+}
+
+func (c *AdminGroupsMembersRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupKey := strings.TrimSpace(c.Group)
+	memberKey := strings.TrimSpace(c.Member)
+	if groupKey == "" || memberKey == "" {
+		return usage("group and member required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove %s from group %s", memberKey, groupKey)); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if err := svc.Members.Delete(groupKey, memberKey).Context(ctx).Do(); err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("removed", true),
+		kv("group", groupKey),
+		kv("member", memberKey),
+	)
+}
+
+func writeAdminGroup(u *ui.UI, g *admin.Group) error {
+	u.Out().Printf("email\t%s", g.Email)
+	u.Out().Printf("id\t%s", g.Id)
+	u.Out().Printf("name\t%s", g.Name)
+	if g.Description != "" {
+		u.Out().Printf("description\t%s", g.Description)
+	}
+	u.Out().Printf("members\t%d", g.DirectMembersCount)
+	return nil
+}
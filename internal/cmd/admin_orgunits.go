@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+type AdminOrgUnitsCmd struct {
+	List   AdminOrgUnitsListCmd   `cmd:"" name:"list" aliases:"ls" help:"List org units"`
+	Get    AdminOrgUnitsGetCmd    `cmd:"" name:"get" aliases:"info,show" help:"Get an org unit"`
+	Create AdminOrgUnitsCreateCmd `cmd:"" name:"create" aliases:"add,new" help:"Create an org unit"`
+	Update AdminOrgUnitsUpdateCmd `cmd:"" name:"update" aliases:"edit,set" help:"Rename, move or describe an org unit"`
+	Delete AdminOrgUnitsDeleteCmd `cmd:"" name:"delete" aliases:"rm,del" help:"Delete an empty org unit"`
+}
+
+type AdminOrgUnitsListCmd struct {
+	Parent    string `name:"parent" help:"Parent org unit path (default: /)"`
+	Children  bool   `name:"children" help:"Only direct children of --parent (default: all descendants)"`
+	FailEmpty bool   `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminOrgUnitsListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	listType := "all"
+	if c.Children {
+		listType = "children"
+	}
+
+	call := svc.Orgunits.List(adminCustomer).Type(listType).Context(ctx)
+	if parent := orgUnitPathField(c.Parent); parent != "" {
+		call = call.OrgUnitPath(parent)
+	}
+
+	resp, err := call.Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	units := resp.OrganizationUnits
+	sort.Slice(units, func(i, j int) bool { return units[i].OrgUnitPath < units[j].OrgUnitPath })
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"orgUnits": units}); err != nil {
+			return err
+		}
+		if len(units) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(units) == 0 {
+		u.Err().Println("No org units found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "PATH\tID\tDESCRIPTION")
+	for _, ou := range units {
+		if ou == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			sanitizeTab(ou.OrgUnitPath),
+			sanitizeTab(ou.OrgUnitId),
+			sanitizeTab(ou.Description),
+		)
+	}
+	return nil
+}
+
+type AdminOrgUnitsGetCmd struct {
+	Path string `arg:"" name:"path" help:"Org unit path (e.g. /Sales/East) or id:<orgUnitId>"`
+}
+
+func (c *AdminOrgUnitsGetCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	path := orgUnitPathParam(c.Path)
+	if path == "" {
+		return usage("org unit path required (the root / cannot be fetched)")
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	ou, err := svc.Orgunits.Get(adminCustomer, path).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"orgUnit": ou})
+	}
+
+	return writeAdminOrgUnit(u, ou)
+}
+
+type AdminOrgUnitsCreateCmd struct {
+	Name        string `arg:"" name:"name" help:"Org unit name"`
+	Parent      string `name:"parent" help:"Parent org unit path" default:"/"`
+	Description string `name:"description" aliases:"desc" help:"Description"`
+}
+
+func (c *AdminOrgUnitsCreateCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(c.Name)
+	if name == "" || strings.Contains(name, "/") {
+		return usage("org unit name required (without slashes; use --parent for nesting)")
+	}
+
+	ou := &admin.OrgUnit{
+		Name:              name,
+		ParentOrgUnitPath: orgUnitPathField(c.Parent),
+		Description:       strings.TrimSpace(c.Description),
+	}
+	if ou.ParentOrgUnitPath == "" {
+		ou.ParentOrgUnitPath = "/"
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.orgunits.create", ou); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	created, err := svc.Orgunits.Insert(adminCustomer, ou).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"orgUnit": created})
+	}
+
+	return writeAdminOrgUnit(u, created)
+}
+
+type AdminOrgUnitsUpdateCmd struct {
+	Path        string `arg:"" name:"path" help:"Org unit path (e.g. /Sales/East) or id:<orgUnitId>"`
+	Name        string `name:"name" help:"New name"`
+	Parent      string `name:"parent" help:"Move under this parent path"`
+	Description string `name:"description" aliases:"desc" help:"Description (empty clears)"`
+}
+
+func (c *AdminOrgUnitsUpdateCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	path := orgUnitPathParam(c.Path)
+	if path == "" {
+		return usage("org unit path required")
+	}
+
+	patch := &admin.OrgUnit{}
+	changed := make([]string, 0, 3)
+
+	if flagProvided(kctx, "name") {
+		patch.Name = strings.TrimSpace(c.Name)
+		if patch.Name == "" || strings.Contains(patch.Name, "/") {
+			return usage("invalid --name (must be non-empty, without slashes)")
+		}
+		changed = append(changed, "name")
+	}
+	if flagProvided(kctx, "parent") {
+		patch.ParentOrgUnitPath = orgUnitPathField(c.Parent)
+		if patch.ParentOrgUnitPath == "" {
+			patch.ParentOrgUnitPath = "/"
+		}
+		changed = append(changed, "parentOrgUnitPath")
+	}
+	if flagProvided(kctx, "description") {
+		patch.Description = strings.TrimSpace(c.Description)
+		patch.ForceSendFields = append(patch.ForceSendFields, "Description")
+		changed = append(changed, "description")
+	}
+
+	if len(changed) == 0 {
+		return usage("nothing to update (pass --name, --parent or --description)")
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.orgunits.update", map[string]any{
+		"orgUnit": path,
+		"fields":  changed,
+		"patch":   patch,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	updated, err := svc.Orgunits.Patch(adminCustomer, path, patch).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"orgUnit": updated, "updated": changed})
+	}
+
+	return writeAdminOrgUnit(u, updated)
+}
+
+type AdminOrgUnitsDeleteCmd struct {
+	Path string `arg:"" name:"path" help:"Org unit path (e.g. /Sales/East) or id:<orgUnitId>"`
+}
+
+func (c *AdminOrgUnitsDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	path := orgUnitPathParam(c.Path)
+	if path == "" {
+		return usage("org unit path required (the root / cannot be deleted)")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete org unit %s", orgUnitPathField(path))); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if err := svc.Orgunits.Delete(adminCustomer, path).Context(ctx).Do(); err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("deleted", true),
+		kv("orgUnit", orgUnitPathField(path)),
+	)
+}
+
+func writeAdminOrgUnit(u *ui.UI, ou *admin.OrgUnit) error {
+	u.Out().Printf("path\t%s", ou.OrgUnitPath)
+	u.Out().Printf("id\t%s", ou.OrgUnitId)
+	u.Out().Printf("name\t%s", ou.Name)
+	u.Out().Printf("parent\t%s", ou.ParentOrgUnitPath)
+	if ou.Description != "" {
+		u.Out().Printf("description\t%s", ou.Description)
+	}
+	return nil
+}
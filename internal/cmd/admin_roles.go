@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+type AdminRolesCmd struct {
+	List        AdminRolesListCmd        `cmd:"" name:"list" aliases:"ls" help:"List admin roles"`
+	Assignments AdminRolesAssignmentsCmd `cmd:"" name:"assignments" help:"List role assignments"`
+	Assign      AdminRolesAssignCmd      `cmd:"" name:"assign" help:"Assign an admin role to a user"`
+	Unassign    AdminRolesUnassignCmd    `cmd:"" name:"unassign" help:"Remove a role assignment"`
+}
+
+type AdminRolesListCmd struct {
+	FailEmpty bool `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminRolesListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	roles, err := listAdminRoles(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"roles": roles}); err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(roles) == 0 {
+		u.Err().Println("No roles found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ID\tNAME\tSYSTEM\tSUPER_ADMIN")
+	for _, r := range roles {
+		if r == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%t\t%t\n",
+			r.RoleId,
+			sanitizeTab(r.RoleName),
+			r.IsSystemRole,
+			r.IsSuperAdminRole,
+		)
+	}
+	return nil
+}
+
+type AdminRolesAssignmentsCmd struct {
+	User      string `name:"user" help:"Only assignments of this user (email or ID)"`
+	Role      string `name:"role" help:"Only assignments of this role (name or ID)"`
+	FailEmpty bool   `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminRolesAssignmentsCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	roles, err := listAdminRoles(ctx, svc)
+	if err != nil {
+		return err
+	}
+	roleNames := make(map[int64]string, len(roles))
+	for _, r := range roles {
+		roleNames[r.RoleId] = r.RoleName
+	}
+
+	call := svc.RoleAssignments.List(adminCustomer).Context(ctx)
+	if user := strings.TrimSpace(c.User); user != "" {
+		call = call.UserKey(user)
+	}
+	if strings.TrimSpace(c.Role) != "" {
+		role, err := findAdminRole(roles, c.Role)
+		if err != nil {
+			return err
+		}
+		call = call.RoleId(strconv.FormatInt(role.RoleId, 10))
+	}
+
+	var assignments []*admin.RoleAssignment
+	err = call.Pages(ctx, func(resp *admin.RoleAssignments) error {
+		assignments = append(assignments, resp.Items...)
+		return nil
+	})
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		items := make([]map[string]any, 0, len(assignments))
+		for _, a := range assignments {
+			items = append(items, map[string]any{
+				"roleAssignmentId": strconv.FormatInt(a.RoleAssignmentId, 10),
+				"roleId":           strconv.FormatInt(a.RoleId, 10),
+				"roleName":         roleNames[a.RoleId],
+				"assignedTo":       a.AssignedTo,
+				"assigneeType":     a.AssigneeType,
+				"scopeType":        a.ScopeType,
+				"orgUnitId":        a.OrgUnitId,
+			})
+		}
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"assignments": items}); err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(assignments) == 0 {
+		u.Err().Println("No role assignments found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "ID\tROLE\tASSIGNED_TO\tSCOPE\tORG_UNIT_ID")
+	for _, a := range assignments {
+		if a == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			a.RoleAssignmentId,
+			sanitizeTab(roleNames[a.RoleId]),
+			sanitizeTab(a.AssignedTo),
+			sanitizeTab(a.ScopeType),
+			sanitizeTab(a.OrgUnitId),
+		)
+	}
+	return nil
+}
+
+type AdminRolesAssignCmd struct {
+	User    string `arg:"" name:"user" help:"User email or ID"`
+	Role    string `name:"role" required:"" help:"Role name (e.g. \"Help Desk Admin\") or ID"`
+	OrgUnit string `name:"org-unit" aliases:"ou" help:"Limit the assignment to this org unit (default: the whole account)"`
+}
+
+func (c *AdminRolesAssignCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	userKey := strings.TrimSpace(c.User)
+	if userKey == "" {
+		return usage("user required")
+	}
+	if strings.TrimSpace(c.Role) == "" {
+		return usage("empty --role")
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	roles, err := listAdminRoles(ctx, svc)
+	if err != nil {
+		return err
+	}
+	role, err := findAdminRole(roles, c.Role)
+	if err != nil {
+		return err
+	}
+
+	// Role assignments take the user's immutable ID, not an email.
+	usr, err := svc.Users.Get(userKey).Fields("id,primaryEmail").Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	ra := &admin.RoleAssignment{
+		RoleId:     role.RoleId,
+		AssignedTo: usr.Id,
+		ScopeType:  "CUSTOMER",
+	}
+
+	if ouPath := orgUnitPathParam(c.OrgUnit); ouPath != "" {
+		ou, err := svc.Orgunits.Get(adminCustomer, ouPath).Fields("orgUnitId").Context(ctx).Do()
+		if err != nil {
+			return wrapAdminError(err)
+		}
+		ra.ScopeType = "ORG_UNIT"
+		ra.OrgUnitId = strings.TrimPrefix(ou.OrgUnitId, "id:")
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.roles.assign", map[string]any{
+		"user":       usr.PrimaryEmail,
+		"role":       role.RoleName,
+		"assignment": ra,
+	}); err != nil {
+		return err
+	}
+
+	created, err := svc.RoleAssignments.Insert(adminCustomer, ra).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("roleAssignmentId", strconv.FormatInt(created.RoleAssignmentId, 10)),
+		kv("user", usr.PrimaryEmail),
+		kv("role", role.RoleName),
+		kv("scopeType", created.ScopeType),
+		kv("orgUnitId", created.OrgUnitId),
+	)
+}
+
+type AdminRolesUnassignCmd struct {
+	AssignmentID string `arg:"" name:"assignmentId" help:"Role assignment ID (see: wk admin roles assignments)"`
+}
+
+func (c *AdminRolesUnassignCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	id := strings.TrimSpace(c.AssignmentID)
+	if id == "" {
+		return usage("assignmentId required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove role assignment %s", id)); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if err := svc.RoleAssignments.Delete(adminCustomer, id).Context(ctx).Do(); err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("removed", true),
+		kv("roleAssignmentId", id),
+	)
+}
+
+func listAdminRoles(ctx context.Context, svc *admin.Service) ([]*admin.Role, error) {
+	var roles []*admin.Role
+	err := svc.Roles.List(adminCustomer).Context(ctx).Pages(ctx, func(resp *admin.Roles) error {
+		roles = append(roles, resp.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapAdminError(err)
+	}
+	return roles, nil
+}
+
+// findAdminRole matches a role by numeric ID or case-insensitive name.
+func findAdminRole(roles []*admin.Role, nameOrID string) (*admin.Role, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+	id, idErr := strconv.ParseInt(nameOrID, 10, 64)
+	for _, r := range roles {
+		if r == nil {
+			continue
+		}
+		if (idErr == nil && r.RoleId == id) || strings.EqualFold(r.RoleName, nameOrID) {
+			return r, nil
+		}
+	}
+	return nil, usagef("unknown role %q (see: wk admin roles list)", nameOrID)
+}
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+)
+
+type adminRequest struct {
+	method string
+	path   string
+	query  string
+	body   map[string]any
+}
+
+// stubAdminDirectory serves handler for every Directory API call and records
+// the requests it saw.
+func stubAdminDirectory(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, path string)) func() []adminRequest {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		reqs []adminRequest
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/admin/directory/v1")
+
+		rec := adminRequest{method: r.Method, path: path, query: r.URL.RawQuery}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			_ = json.Unmarshal(b, &rec.body)
+		}
+		mu.Lock()
+		reqs = append(reqs, rec)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r, path)
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := admin.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	orig := newAdminDirectoryService
+	t.Cleanup(func() { newAdminDirectoryService = orig })
+	newAdminDirectoryService = func(context.Context, string) (*admin.Service, error) { return svc, nil }
+
+	return func() []adminRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]adminRequest(nil), reqs...)
+	}
+}
+
+func runAdmin(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var err error
+	out := captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			err = Execute(append([]string{"--json", "--account", "admin@example.com"}, args...))
+		})
+	})
+	return out, err
+}
+
+func TestAdminUsersList_OrgUnitQuery(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		if path != "/users" || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"users": []map[string]any{
+				{"primaryEmail": "jane@example.com", "orgUnitPath": "/Sales"},
+			},
+		})
+	})
+
+	out, err := runAdmin(t, "admin", "users", "list", "--org-unit", "Sales/", "--query", "isAdmin=false")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var parsed struct {
+		Users []admin.User `json:"users"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	if len(parsed.Users) != 1 || parsed.Users[0].PrimaryEmail != "jane@example.com" {
+		t.Fatalf("unexpected users: %#v", parsed.Users)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	if !strings.Contains(reqs[0].query, "customer=my_customer") {
+		t.Fatalf("expected customer scope, got %q", reqs[0].query)
+	}
+	if !strings.Contains(reqs[0].query, "query=isAdmin%3Dfalse+orgUnitPath%3D%27%2FSales%27") {
+		t.Fatalf("expected org unit in query, got %q", reqs[0].query)
+	}
+}
+
+func TestAdminUsersCreate_GeneratesPassword(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		if path != "/users" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "123", "primaryEmail": "new@example.com"})
+	})
+
+	out, err := runAdmin(t, "admin", "users", "create", "new@example.com", "--given-name", "New", "--family-name", "Hire", "--org-unit", "Sales")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var parsed struct {
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	if len(parsed.Password) != 20 {
+		t.Fatalf("expected generated password in output, got %q", out)
+	}
+
+	body := requests()[0].body
+	if body["password"] != parsed.Password {
+		t.Fatalf("sent password %v, printed %q", body["password"], parsed.Password)
+	}
+	if body["orgUnitPath"] != "/Sales" || body["changePasswordAtNextLogin"] != true {
+		t.Fatalf("unexpected body: %#v", body)
+	}
+}
+
+func TestAdminUsersSuspend(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		if path != "/users/jane@example.com" || r.Method != http.MethodPatch {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"primaryEmail": "jane@example.com", "suspended": true})
+	})
+
+	if _, err := runAdmin(t, "--force", "admin", "users", "suspend", "jane@example.com"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].body["suspended"] != true {
+		t.Fatalf("unexpected requests: %#v", reqs)
+	}
+}
+
+func TestAdminUsersUnsuspend_RequiresConfirmation(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		if path != "/users/jane@example.com" || r.Method != http.MethodPatch {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"primaryEmail": "jane@example.com", "suspended": false})
+	})
+
+	_, err := runAdmin(t, "--no-input", "admin", "users", "unsuspend", "jane@example.com")
+	if err == nil || !strings.Contains(err.Error(), "without --force") {
+		t.Fatalf("expected confirmation refusal, got %v", err)
+	}
+	if reqs := requests(); len(reqs) != 0 {
+		t.Fatalf("unconfirmed unsuspend sent requests: %#v", reqs)
+	}
+
+	if _, err := runAdmin(t, "--force", "admin", "users", "unsuspend", "jane@example.com"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].body["suspended"] != false {
+		t.Fatalf("unexpected requests: %#v", reqs)
+	}
+}
+
+func TestAdminOrgUnitPaths(t *testing.T) {
+	tests := []struct {
+		in, param, field string
+	}{
+		{"/Sales/East", "Sales/East", "/Sales/East"},
+		{"Sales/East/", "Sales/East", "/Sales/East"},
+		{"/", "", "/"},
+		{"", "", ""},
+		{"id:03ph8a2z", "id:03ph8a2z", "id:03ph8a2z"},
+	}
+	for _, tt := range tests {
+		if got := orgUnitPathParam(tt.in); got != tt.param {
+			t.Errorf("orgUnitPathParam(%q) = %q, want %q", tt.in, got, tt.param)
+		}
+		if got := orgUnitPathField(tt.in); got != tt.field {
+			t.Errorf("orgUnitPathField(%q) = %q, want %q", tt.in, got, tt.field)
+		}
+	}
+}
+
+func TestAdminGroupsMembersAdd(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		if path != "/groups/eng@example.com/members" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"email": "jane@example.com", "role": "MANAGER"})
+	})
+
+	if _, err := runAdmin(t, "admin", "groups", "members", "add", "eng@example.com", "jane@example.com", "--role", "manager"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	body := requests()[0].body
+	if body["email"] != "jane@example.com" || body["role"] != "MANAGER" {
+		t.Fatalf("unexpected body: %#v", body)
+	}
+
+	if _, err := runAdmin(t, "admin", "groups", "members", "add", "eng@example.com", "jane@example.com", "--role", "boss"); err == nil || ExitCode(err) != 2 {
+		t.Fatalf("expected usage error for invalid role, got %v", err)
+	}
+}
+
+func TestAdminRolesAssign_OrgUnitScope(t *testing.T) {
+	requests := stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, path string) {
+		switch {
+		case path == "/customer/my_customer/roles" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": []map[string]any{
+				{"roleId": "11", "roleName": "_SEED_ADMIN_ROLE"},
+				{"roleId": "42", "roleName": "Help Desk Admin"},
+			}})
+		case path == "/users/jane@example.com" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "u-1", "primaryEmail": "jane@example.com"})
+		case path == "/customer/my_customer/orgunits/Sales" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"orgUnitId": "id:ou-9"})
+		case path == "/customer/my_customer/roleassignments" && r.Method == http.MethodPost:
+			_ = json.NewEncoder(w).Encode(map[string]any{"roleAssignmentId": "7", "roleId": "42", "assignedTo": "u-1", "scopeType": "ORG_UNIT", "orgUnitId": "ou-9"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	out, err := runAdmin(t, "admin", "roles", "assign", "jane@example.com", "--role", "help desk admin", "--org-unit", "/Sales")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out, `"roleAssignmentId": "7"`) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	var insert adminRequest
+	for _, req := range requests() {
+		if req.method == http.MethodPost {
+			insert = req
+		}
+	}
+	if insert.body["roleId"] != "42" || insert.body["assignedTo"] != "u-1" ||
+		insert.body["scopeType"] != "ORG_UNIT" || insert.body["orgUnitId"] != "ou-9" {
+		t.Fatalf("unexpected assignment: %#v", insert.body)
+	}
+}
+
+func TestAdminWriteCommands_ReadOnly(t *testing.T) {
+	stubAdminDirectory(t, func(w http.ResponseWriter, r *http.Request, _ string) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	})
+
+	for _, args := range [][]string{
+		{"admin", "users", "suspend", "jane@example.com"},
+		{"admin", "users", "create", "jane@example.com"},
+		{"admin", "roles", "assign", "jane@example.com", "--role", "x"},
+		{"admin", "groups", "members", "add", "eng@example.com", "jane@example.com"},
+	} {
+		_, err := runAdmin(t, append([]string{"--read-only"}, args...)...)
+		if err == nil || !strings.Contains(err.Error(), "read-only mode") {
+			t.Fatalf("%v: expected read-only rejection, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+type AdminUsersCmd struct {
+	List      AdminUsersListCmd      `cmd:"" name:"list" aliases:"ls" help:"List users"`
+	Get       AdminUsersGetCmd       `cmd:"" name:"get" aliases:"info,show" help:"Get a user"`
+	Create    AdminUsersCreateCmd    `cmd:"" name:"create" aliases:"add,new" help:"Create a user"`
+	Update    AdminUsersUpdateCmd    `cmd:"" name:"update" aliases:"edit,set" help:"Update a user"`
+	Suspend   AdminUsersSuspendCmd   `cmd:"" name:"suspend" help:"Suspend a user"`
+	Unsuspend AdminUsersUnsuspendCmd `cmd:"" name:"unsuspend" aliases:"restore" help:"Unsuspend a user"`
+}
+
+type AdminUsersListCmd struct {
+	Query     string `name:"query" aliases:"q" help:"Directory search query (e.g. \"name:Jane\", \"isAdmin=true\")"`
+	OrgUnit   string `name:"org-unit" aliases:"ou" help:"Only users in this org unit (e.g. /Sales)"`
+	Domain    string `name:"domain" help:"Only users in this domain (default: every domain of the account)"`
+	Max       int64  `name:"max" aliases:"limit" help:"Max results" default:"100"`
+	Page      string `name:"page" aliases:"cursor" help:"Page token"`
+	All       bool   `name:"all" aliases:"all-pages,allpages" help:"Fetch all pages"`
+	FailEmpty bool   `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *AdminUsersListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	query := strings.TrimSpace(c.Query)
+	if ou := orgUnitPathField(c.OrgUnit); ou != "" {
+		query = strings.TrimSpace(query + " orgUnitPath='" + ou + "'")
+	}
+
+	effectiveMax, effectivePage := applyPagination(flags, c.Max, c.Page)
+
+	fetch := func(pageToken string) ([]*admin.User, string, error) {
+		call := svc.Users.List().MaxResults(effectiveMax).OrderBy("email").Context(ctx)
+		if domain := strings.TrimSpace(c.Domain); domain != "" {
+			call = call.Domain(domain)
+		} else {
+			call = call.Customer(adminCustomer)
+		}
+		if query != "" {
+			call = call.Query(query)
+		}
+		if strings.TrimSpace(pageToken) != "" {
+			call = call.PageToken(pageToken)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return nil, "", wrapAdminError(err)
+		}
+		return resp.Users, resp.NextPageToken, nil
+	}
+
+	var users []*admin.User
+	nextPageToken := ""
+	if c.All {
+		all, err := collectAllPages(effectivePage, fetch)
+		if err != nil {
+			return err
+		}
+		users = all
+	} else {
+		users, nextPageToken, err = fetch(effectivePage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"users":         users,
+			"nextPageToken": nextPageToken,
+		}); err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(users) == 0 {
+		u.Err().Println("No users found")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "EMAIL\tNAME\tORG_UNIT\tADMIN\tSUSPENDED")
+	for _, usr := range users {
+		if usr == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\n",
+			sanitizeTab(usr.PrimaryEmail),
+			sanitizeTab(adminUserName(usr)),
+			sanitizeTab(usr.OrgUnitPath),
+			usr.IsAdmin,
+			usr.Suspended,
+		)
+	}
+	printNextPageHint(u, nextPageToken)
+	return nil
+}
+
+type AdminUsersGetCmd struct {
+	User string `arg:"" name:"user" help:"User email or ID"`
+}
+
+func (c *AdminUsersGetCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	userKey := strings.TrimSpace(c.User)
+	if userKey == "" {
+		return usage("user required")
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	usr, err := svc.Users.Get(userKey).Projection("full").Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"user": usr})
+	}
+
+	return writeAdminUser(u, usr)
+}
+
+type AdminUsersCreateCmd struct {
+	Email          string `arg:"" name:"email" help:"Primary email of the new user"`
+	GivenName      string `name:"given-name" aliases:"given,first" help:"Given name (required)"`
+	FamilyName     string `name:"family-name" aliases:"family,last" help:"Family name (required)"`
+	Password       string `name:"password" help:"Initial password (default: generate one and print it)"`
+	OrgUnit        string `name:"org-unit" aliases:"ou" help:"Org unit path (default: /)"`
+	RecoveryEmail  string `name:"recovery-email" help:"Recovery email address"`
+	ChangePassword bool   `name:"change-password" help:"Require a password change at next login" default:"true" negatable:""`
+}
+
+func (c *AdminUsersCreateCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	email := strings.TrimSpace(c.Email)
+	if email == "" || !strings.Contains(email, "@") {
+		return usage("email required")
+	}
+	if strings.TrimSpace(c.GivenName) == "" || strings.TrimSpace(c.FamilyName) == "" {
+		return usage("required: --given-name and --family-name")
+	}
+
+	password := c.Password
+	generated := password == ""
+
+	usr := &admin.User{
+		PrimaryEmail: email,
+		Name: &admin.UserName{
+			GivenName:  strings.TrimSpace(c.GivenName),
+			FamilyName: strings.TrimSpace(c.FamilyName),
+		},
+		OrgUnitPath:               orgUnitPathField(c.OrgUnit),
+		RecoveryEmail:             strings.TrimSpace(c.RecoveryEmail),
+		ChangePasswordAtNextLogin: c.ChangePassword,
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.users.create", map[string]any{
+		"user":               usr,
+		"generated_password": generated,
+	}); err != nil {
+		return err
+	}
+
+	if generated {
+		if password, err = generateAdminPassword(); err != nil {
+			return err
+		}
+	}
+	usr.Password = password
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	created, err := svc.Users.Insert(usr).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		out := map[string]any{"user": created}
+		if generated {
+			out["password"] = password
+		}
+		return outfmt.WriteJSON(ctx, os.Stdout, out)
+	}
+
+	u.Out().Printf("email\t%s", created.PrimaryEmail)
+	u.Out().Printf("id\t%s", created.Id)
+	u.Out().Printf("org_unit\t%s", created.OrgUnitPath)
+	if generated {
+		u.Out().Printf("password\t%s", password)
+		u.Err().Println("Share the generated password securely; it is not shown again.")
+	}
+	return nil
+}
+
+type AdminUsersUpdateCmd struct {
+	User           string `arg:"" name:"user" help:"User email or ID"`
+	GivenName      string `name:"given-name" aliases:"given,first" help:"Given name"`
+	FamilyName     string `name:"family-name" aliases:"family,last" help:"Family name"`
+	PrimaryEmail   string `name:"primary-email" aliases:"rename" help:"New primary email (the old one becomes an alias)"`
+	OrgUnit        string `name:"org-unit" aliases:"ou" help:"Move to this org unit path"`
+	Password       string `name:"password" help:"New password"`
+	RecoveryEmail  string `name:"recovery-email" help:"Recovery email address (empty clears)"`
+	ChangePassword bool   `name:"change-password" help:"Require a password change at next login" negatable:""`
+}
+
+func (c *AdminUsersUpdateCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	userKey := strings.TrimSpace(c.User)
+	if userKey == "" {
+		return usage("user required")
+	}
+
+	patch := &admin.User{}
+	changed := make([]string, 0, 4)
+
+	if flagProvidedAny(kctx, "given-name", "family-name") {
+		// Patch merges nested objects, so an omitted name part is kept.
+		patch.Name = &admin.UserName{
+			GivenName:  strings.TrimSpace(c.GivenName),
+			FamilyName: strings.TrimSpace(c.FamilyName),
+		}
+		changed = append(changed, "name")
+	}
+	if flagProvided(kctx, "primary-email") {
+		patch.PrimaryEmail = strings.TrimSpace(c.PrimaryEmail)
+		if patch.PrimaryEmail == "" {
+			return usage("empty --primary-email")
+		}
+		changed = append(changed, "primaryEmail")
+	}
+	if flagProvided(kctx, "org-unit") {
+		patch.OrgUnitPath = orgUnitPathField(c.OrgUnit)
+		if patch.OrgUnitPath == "" {
+			return usage("empty --org-unit")
+		}
+		changed = append(changed, "orgUnitPath")
+	}
+	if flagProvided(kctx, "password") {
+		if c.Password == "" {
+			return usage("empty --password")
+		}
+		patch.Password = c.Password
+		changed = append(changed, "password")
+	}
+	if flagProvided(kctx, "recovery-email") {
+		patch.RecoveryEmail = strings.TrimSpace(c.RecoveryEmail)
+		patch.ForceSendFields = append(patch.ForceSendFields, "RecoveryEmail")
+		changed = append(changed, "recoveryEmail")
+	}
+	if flagProvided(kctx, "change-password") {
+		patch.ChangePasswordAtNextLogin = c.ChangePassword
+		patch.ForceSendFields = append(patch.ForceSendFields, "ChangePasswordAtNextLogin")
+		changed = append(changed, "changePasswordAtNextLogin")
+	}
+
+	if len(changed) == 0 {
+		return usage("nothing to update (pass at least one field flag)")
+	}
+
+	if err := dryRunExit(ctx, flags, "admin.users.update", map[string]any{
+		"user":   userKey,
+		"fields": changed,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	updated, err := svc.Users.Patch(userKey, patch).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{"user": updated, "updated": changed})
+	}
+
+	u.Out().Printf("email\t%s", updated.PrimaryEmail)
+	u.Out().Printf("updated\t%s", strings.Join(changed, ","))
+	return nil
+}
+
+type AdminUsersSuspendCmd struct {
+	User string `arg:"" name:"user" help:"User email or ID"`
+}
+
+func (c *AdminUsersSuspendCmd) Run(ctx context.Context, flags *RootFlags) error {
+	userKey := strings.TrimSpace(c.User)
+	if userKey == "" {
+		return usage("user required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("suspend user %s", userKey)); err != nil {
+		return err
+	}
+
+	return setAdminUserSuspended(ctx, flags, userKey, true)
+}
+
+type AdminUsersUnsuspendCmd struct {
+	User string `arg:"" name:"user" help:"User email or ID"`
+}
+
+func (c *AdminUsersUnsuspendCmd) Run(ctx context.Context, flags *RootFlags) error {
+	userKey := strings.TrimSpace(c.User)
+	if userKey == "" {
+		return usage("user required")
+	}
+
+	// Restoring access is as sensitive as removing it.
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("unsuspend user %s", userKey)); err != nil {
+		return err
+	}
+
+	return setAdminUserSuspended(ctx, flags, userKey, false)
+}
+
+func setAdminUserSuspended(ctx context.Context, flags *RootFlags, userKey string, suspended bool) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newAdminDirectoryService(ctx, account)
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	updated, err := svc.Users.Patch(userKey, &admin.User{
+		Suspended:       suspended,
+		ForceSendFields: []string{"Suspended"},
+	}).Context(ctx).Do()
+	if err != nil {
+		return wrapAdminError(err)
+	}
+
+	return writeResult(ctx, u,
+		kv("email", updated.PrimaryEmail),
+		kv("suspended", updated.Suspended),
+	)
+}
+
+func writeAdminUser(u *ui.UI, usr *admin.User) error {
+	u.Out().Printf("email\t%s", usr.PrimaryEmail)
+	u.Out().Printf("id\t%s", usr.Id)
+	u.Out().Printf("name\t%s", adminUserName(usr))
+	u.Out().Printf("org_unit\t%s", usr.OrgUnitPath)
+	u.Out().Printf("admin\t%t", usr.IsAdmin)
+	u.Out().Printf("suspended\t%t", usr.Suspended)
+	if usr.SuspensionReason != "" {
+		u.Out().Printf("suspension_reason\t%s", usr.SuspensionReason)
+	}
+	u.Out().Printf("2sv_enrolled\t%t", usr.IsEnrolledIn2Sv)
+	if usr.LastLoginTime != "" {
+		u.Out().Printf("last_login\t%s", usr.LastLoginTime)
+	}
+	if usr.CreationTime != "" {
+		u.Out().Printf("created\t%s", usr.CreationTime)
+	}
+	for _, alias := range usr.Aliases {
+		u.Out().Printf("alias\t%s", alias)
+	}
+	return nil
+}
+
+func adminUserName(usr *admin.User) string {
+	if usr == nil || usr.Name == nil {
+		return ""
+	}
+	if usr.Name.FullName != "" {
+		return usr.Name.FullName
+	}
+	return strings.TrimSpace(usr.Name.GivenName + " " + usr.Name.FamilyName)
+}
+
+const adminPasswordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789-_.!"
+
+// generateAdminPassword returns a random 20-character initial password.
+func generateAdminPassword() (string, error) {
+	var b strings.Builder
+	limit := big.NewInt(int64(len(adminPasswordAlphabet)))
+	for range 20 {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("generate password: %w", err)
+		}
+		b.WriteByte(adminPasswordAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
@@ -31,6 +31,7 @@ var (
 	pollForToken         = googleauth.PollForToken
 	callbackServerURLFn  = googleauth.CallbackServerURL
 	manualAuthURL        = googleauth.ManualAuthURL
+	deviceAuthorize      = googleauth.DeviceAuthorize
 )
 
 func ensureKeychainAccessIfNeeded() error {
@@ -38,7 +39,7 @@ func ensureKeychainAccessIfNeeded() error {
 	if err != nil {
 		return fmt.Errorf("resolve keyring backend: %w", err)
 	}
-	if backendInfo.Value == strFile {
+	if backendInfo.Value == strFile || secrets.IsExecBackend(backendInfo.Value) {
 		return nil
 	}
 	return ensureKeychainAccess()
@@ -62,10 +63,13 @@ type AuthCmd struct {
 	List        AuthListCmd           `cmd:"" name:"list" help:"List stored accounts"`
 	Aliases     AuthAliasCmd          `cmd:"" name:"alias" help:"Manage account aliases"`
 	Status      AuthStatusCmd         `cmd:"" name:"status" help:"Show auth configuration and keyring backend"`
+	Doctor      AuthDoctorCmd         `cmd:"" name:"doctor" help:"Verify every stored token end to end and print remediation commands"`
 	Keyring     AuthKeyringCmd        `cmd:"" name:"keyring" help:"Configure keyring backend"`
 	Remove      AuthRemoveCmd         `cmd:"" name:"remove" help:"Remove a stored refresh token"`
 	Tokens      AuthTokensCmd         `cmd:"" name:"tokens" help:"Manage stored refresh tokens"`
+	Bundle      AuthBundleCmd         `cmd:"" name:"bundle" help:"Export/import encrypted account bundles (tokens, clients, aliases)"`
 	Manage      AuthManageCmd         `cmd:"" name:"manage" help:"Open accounts manager in browser" aliases:"login"`
+	Relay       AuthRelayCmd          `cmd:"" name:"relay" help:"Run a self-hosted auth relay (callback server) for headless logins"`
 	ServiceAcct AuthServiceAccountCmd `cmd:"" name:"service-account" help:"Configure service account (Workspace only; domain-wide delegation)"`
 	Keep        AuthKeepCmd           `cmd:"" name:"keep" help:"Configure service account for Google Keep (Workspace only)"`
 }
@@ -494,6 +498,10 @@ type AuthAddCmd struct {
 	CallbackServer string        `name:"callback-server" help:"Callback server URL for headless auth"`
 	PollTimeout    time.Duration `name:"poll-timeout" help:"Timeout for polling callback server" default:"5m"`
 	NoPoll         bool          `name:"no-poll" help:"In headless mode, output URL without polling (use 'wk auth poll' later)"`
+	RequireE2E     bool          `name:"require-e2e" help:"In headless mode, fail instead of falling back to a plaintext token handoff when the callback server lacks end-to-end encryption (also WK_HANDOFF_ENCRYPTION=required or config handoff_encryption)"`
+
+	// Device authorization grant (RFC 8628).
+	Device bool `name:"device" help:"Device code flow: show a code to enter at google.com/device (needs a 'TVs and Limited Input devices' OAuth client)"`
 
 	ForceConsent bool   `name:"force-consent" help:"Force consent screen to obtain a refresh token"`
 	ServicesCSV  string `name:"services" help:"Services to authorize: user|all or comma-separated ${auth_services} (Keep uses service account: wk auth service-account set)" default:"all"`
@@ -544,7 +552,18 @@ func (c *AuthAddCmd) Run(ctx context.Context, flags *RootFlags) error {
 	manual := c.Manual || c.Remote || authURL != "" || authCode != ""
 
 	// Resolve auth mode: explicit flags > config > auto-detect
-	resolved := googleauth.ResolveAuthMode(ctx, c.Headless, manual, c.CallbackServer)
+	var resolved googleauth.AuthModeResult
+	if c.Device {
+		if manual || c.Headless || c.Step != 0 {
+			return usage("cannot combine --device with headless/manual/remote auth flags")
+		}
+		resolved = googleauth.AuthModeResult{Mode: googleauth.AuthModeDevice, Source: "flag"}
+	} else {
+		resolved = googleauth.ResolveAuthMode(ctx, c.Headless, manual, c.CallbackServer)
+	}
+	if resolved.Mode == googleauth.AuthModeDevice {
+		return c.runDevice(ctx, flags, client, services, scopes)
+	}
 	if resolved.Mode == googleauth.AuthModeHeadless {
 		if manual || c.Step != 0 || c.Timeout != 0 {
 			return usage("cannot combine --headless with manual/remote auth flags")
@@ -607,57 +626,7 @@ func (c *AuthAddCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return googleauth.WrapOAuthError(err)
 	}
 
-	authorizedEmail, err := fetchAuthorizedEmail(ctx, client, refreshToken, scopes, 15*time.Second)
-	if err != nil {
-		return fmt.Errorf("fetch authorized email: %w", err)
-	}
-	if normalizeEmail(authorizedEmail) != normalizeEmail(c.Email) {
-		return fmt.Errorf("authorized as %s, expected %s", authorizedEmail, c.Email)
-	}
-
-	store, err := openSecretsStore()
-	if err != nil {
-		return err
-	}
-	serviceNames := make([]string, 0, len(services))
-	for _, svc := range services {
-		serviceNames = append(serviceNames, string(svc))
-	}
-	sort.Strings(serviceNames)
-
-	if err := store.MergeToken(client, authorizedEmail, secrets.Token{
-		Client:       client,
-		Email:        authorizedEmail,
-		Services:     serviceNames,
-		Scopes:       scopes,
-		RefreshToken: refreshToken,
-	}); err != nil {
-		return err
-	}
-	if override != "" {
-		cfg, err := config.ReadConfig()
-		if err != nil {
-			return err
-		}
-		if err := config.SetAccountClient(&cfg, authorizedEmail, client); err != nil {
-			return err
-		}
-		if err := config.WriteConfig(cfg); err != nil {
-			return err
-		}
-	}
-	if outfmt.IsJSON(ctx) {
-		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
-			"stored":   true,
-			"email":    authorizedEmail,
-			"services": serviceNames,
-			"client":   client,
-		})
-	}
-	u.Out().Printf("email\t%s", authorizedEmail)
-	u.Out().Printf("services\t%s", strings.Join(serviceNames, ","))
-	u.Out().Printf("client\t%s", client)
-	return nil
+	return c.storeAuthorizedToken(ctx, client, services, scopes, refreshToken)
 }
 
 func (c *AuthAddCmd) handleRemoteAuthStep(
@@ -749,11 +718,12 @@ func (c *AuthAddCmd) runHeadless(ctx context.Context, client string, services []
 
 	// Generate headless auth info
 	info, err := headlessAuthorize(ctx, googleauth.HeadlessOptions{
-		Services:       services,
-		Scopes:         scopes,
-		ForceConsent:   c.ForceConsent,
-		Client:         client,
-		CallbackServer: callbackServer,
+		Services:         services,
+		Scopes:           scopes,
+		ForceConsent:     c.ForceConsent,
+		Client:           client,
+		CallbackServer:   callbackServer,
+		RequireEncrypted: c.RequireE2E,
 	})
 	if err != nil {
 		return err
@@ -766,10 +736,14 @@ func (c *AuthAddCmd) runHeadless(ctx context.Context, client string, services []
 			"state":      info.State,
 			"poll_url":   info.PollURL,
 			"expires_in": info.ExpiresIn,
+			"encrypted":  info.Encrypted,
 		}); writeErr != nil {
 			return writeErr
 		}
 	} else {
+		if !info.Encrypted {
+			u.Err().Println("Warning: the callback server does not support encrypted handoffs; the token passes through it in the clear. Upgrade the auth-server.")
+		}
 		u.Err().Println("Visit this URL to authorize:")
 		u.Err().Println(info.AuthURL)
 		u.Err().Println("")
@@ -814,7 +788,67 @@ func (c *AuthAddCmd) runHeadless(ctx context.Context, client string, services []
 		return googleauth.WrapOAuthError(err)
 	}
 
-	// Verify the email matches (if possible)
+	return c.storeAuthorizedToken(ctx, client, services, scopes, refreshToken)
+}
+
+func (c *AuthAddCmd) runDevice(ctx context.Context, flags *RootFlags, client string, services []googleauth.Service, scopes []string) error {
+	u := ui.FromContext(ctx)
+
+	if dryRunErr := dryRunExit(ctx, flags, "auth.add", map[string]any{
+		"email":    strings.TrimSpace(c.Email),
+		"client":   client,
+		"services": services,
+		"scopes":   scopes,
+		"mode":     googleauth.AuthModeDevice,
+		"readonly": c.Readonly,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	// Device flow is meant for headless servers; same keyring setup as --headless.
+	if err := setup.SetupKeyringIfNeeded(os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: keyring auto-setup failed: %v\n", err)
+	}
+
+	if keychainErr := ensureKeychainAccessIfNeeded(); keychainErr != nil {
+		return fmt.Errorf("keychain access: %w", keychainErr)
+	}
+
+	var writeErr error
+	refreshToken, err := deviceAuthorize(ctx, googleauth.DeviceOptions{
+		Scopes:  scopes,
+		Client:  client,
+		Timeout: c.Timeout,
+		OnCode: func(info googleauth.DeviceCodeInfo) {
+			if outfmt.IsJSON(ctx) {
+				writeErr = outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+					"user_code":        info.UserCode,
+					"verification_url": info.VerificationURL,
+					"expires_in":       info.ExpiresIn,
+				})
+				return
+			}
+			u.Err().Printf("Open %s and enter code: %s", info.VerificationURL, info.UserCode)
+			u.Err().Printf("Expires in: %d seconds", info.ExpiresIn)
+			u.Err().Println("Waiting for authorization...")
+		},
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.storeAuthorizedToken(ctx, client, services, scopes, refreshToken)
+}
+
+// storeAuthorizedToken verifies that refreshToken belongs to c.Email, merges
+// it into the secrets store, records the client mapping for --client
+// overrides, and prints the result. Every auth add flow ends here.
+func (c *AuthAddCmd) storeAuthorizedToken(ctx context.Context, client string, services []googleauth.Service, scopes []string, refreshToken string) error {
+	u := ui.FromContext(ctx)
+
 	authorizedEmail, err := fetchAuthorizedEmail(ctx, client, refreshToken, scopes, 15*time.Second)
 	if err != nil {
 		return fmt.Errorf("fetch authorized email: %w", err)
@@ -823,7 +857,6 @@ func (c *AuthAddCmd) runHeadless(ctx context.Context, client string, services []
 		return fmt.Errorf("authorized as %s, expected %s", authorizedEmail, c.Email)
 	}
 
-	// Store the token
 	store, err := openSecretsStore()
 	if err != nil {
 		return err
@@ -655,3 +655,75 @@ func containsStringInSlice(items []string, want string) bool {
 	}
 	return false
 }
+
+func TestAuthAddCmd_DeviceMode(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home+"/xdg-config")
+	t.Setenv("WK_KEYRING_PASSWORD", "test")
+
+	if err := config.WriteConfig(config.File{AuthMode: googleauth.AuthModeDevice}); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	origDevice := deviceAuthorize
+	origAuth := authorizeGoogle
+	origOpen := openSecretsStore
+	origKeychain := ensureKeychainAccess
+	origFetch := fetchAuthorizedEmail
+	t.Cleanup(func() {
+		deviceAuthorize = origDevice
+		authorizeGoogle = origAuth
+		openSecretsStore = origOpen
+		ensureKeychainAccess = origKeychain
+		fetchAuthorizedEmail = origFetch
+	})
+
+	ensureKeychainAccess = func() error { return nil }
+
+	store := newMemSecretsStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+
+	authorizeGoogle = func(context.Context, googleauth.AuthorizeOptions) (string, error) {
+		t.Fatal("browser flow should not run in device mode")
+		return "", nil
+	}
+	deviceAuthorize = func(_ context.Context, opts googleauth.DeviceOptions) (string, error) {
+		opts.OnCode(googleauth.DeviceCodeInfo{UserCode: "ABCD-EFGH", VerificationURL: "https://www.google.com/device", ExpiresIn: 1800})
+		return "device-rt", nil
+	}
+	fetchAuthorizedEmail = func(context.Context, string, string, []string, time.Duration) (string, error) {
+		return "user@example.com", nil
+	}
+
+	var stderr string
+	out := captureStdout(t, func() {
+		stderr = captureStderr(t, func() {
+			if err := Execute([]string{"auth", "add", "user@example.com", "--services", "drive"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+
+	if !strings.Contains(stderr, "ABCD-EFGH") || !strings.Contains(stderr, "https://www.google.com/device") {
+		t.Fatalf("expected user code prompt, got %q", stderr)
+	}
+	if !strings.Contains(out, "email\tuser@example.com") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	tok, err := store.GetToken(config.DefaultClientName, "user@example.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "device-rt" || len(tok.Services) != 1 || tok.Services[0] != "drive" {
+		t.Fatalf("unexpected token: %#v", tok)
+	}
+
+	_ = captureStderr(t, func() {
+		err = Execute([]string{"auth", "add", "user@example.com", "--device", "--manual"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot combine --device") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
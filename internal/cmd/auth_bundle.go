@@ -0,0 +1,598 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/term"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/secrets"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+const (
+	authBundleVersion       = 1
+	authBundlePassphraseEnv = "WK_BUNDLE_PASSPHRASE" //nolint:gosec // env var name, not a credential
+)
+
+var (
+	errBundlePassphraseMismatch = errors.New("passphrases do not match")
+	errBundleEmptyPassphrase    = errors.New("empty bundle passphrase")
+	errBundleUnsupported        = errors.New("unsupported bundle version")
+
+	readBundlePassphrase = readBundlePassphraseFromTerminal
+)
+
+// authBundle is the plaintext payload of an encrypted account bundle.
+// It carries everything needed to make a set of accounts usable on another
+// machine: OAuth client credentials, refresh tokens and the config mappings
+// that route accounts to clients.
+type authBundle struct {
+	Version        int                `json:"version"`
+	CreatedAt      time.Time          `json:"created_at"`
+	Clients        []authBundleClient `json:"clients,omitempty"`
+	Tokens         []authBundleToken  `json:"tokens"`
+	AccountAliases map[string]string  `json:"account_aliases,omitempty"`
+	AccountClients map[string]string  `json:"account_clients,omitempty"`
+	ClientDomains  map[string]string  `json:"client_domains,omitempty"`
+}
+
+type authBundleClient struct {
+	Name         string `json:"name"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type authBundleToken struct {
+	Client       string    `json:"client"`
+	Email        string    `json:"email"`
+	Services     []string  `json:"services,omitempty"`
+	Scopes       []string  `json:"scopes,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+type AuthBundleCmd struct {
+	Export AuthBundleExportCmd `cmd:"" name:"export" help:"Export accounts, tokens and client credentials into an encrypted bundle (contains secrets)"`
+	Import AuthBundleImportCmd `cmd:"" name:"import" help:"Import an encrypted bundle into the keyring and config (contains secrets)"`
+}
+
+type AuthBundleExportCmd struct {
+	Accounts      string                 `name:"accounts" help:"Comma-separated account emails or aliases to include (default: all stored tokens)"`
+	Output        OutputPathRequiredFlag `embed:""`
+	Overwrite     bool                   `name:"overwrite" help:"Overwrite output file if it exists"`
+	Recipients    []string               `name:"recipient" short:"r" help:"age recipient (age1...) to encrypt to; repeatable. Default: passphrase encryption"`
+	PassphraseEnv string                 `name:"passphrase-env" help:"Environment variable holding the bundle passphrase" default:"WK_BUNDLE_PASSPHRASE"`
+}
+
+func (c *AuthBundleExportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	outPath := strings.TrimSpace(c.Output.Path)
+	if outPath == "" {
+		return usage("empty outPath")
+	}
+	outPath, err := config.ExpandPath(outPath)
+	if err != nil {
+		return err
+	}
+
+	recipients, err := parseBundleRecipients(c.Recipients)
+	if err != nil {
+		return err
+	}
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	bundle, err := buildAuthBundle(store, cfg, splitCommaList(c.Accounts))
+	if err != nil {
+		return err
+	}
+
+	emails := make([]string, 0, len(bundle.Tokens))
+	for _, tok := range bundle.Tokens {
+		emails = append(emails, tok.Email)
+	}
+	clients := make([]string, 0, len(bundle.Clients))
+	for _, cl := range bundle.Clients {
+		clients = append(clients, cl.Name)
+	}
+
+	if dryRunErr := dryRunExit(ctx, flags, "auth.bundle.export", map[string]any{
+		"path":     outPath,
+		"accounts": emails,
+		"clients":  clients,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	if !c.Overwrite {
+		if _, statErr := os.Lstat(outPath); statErr == nil {
+			return fmt.Errorf("%s already exists (use --overwrite to replace it)", outPath)
+		}
+	}
+
+	if len(recipients) == 0 {
+		pass, passErr := bundlePassphrase(flags, c.PassphraseEnv, true)
+		if passErr != nil {
+			return passErr
+		}
+		r, recErr := age.NewScryptRecipient(pass)
+		if recErr != nil {
+			return fmt.Errorf("bundle passphrase: %w", recErr)
+		}
+		recipients = []age.Recipient{r}
+	}
+
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("encode bundle: %w", err)
+	}
+
+	// Encrypt in memory and write through a temp file, so a failed export
+	// never leaves a partial bundle behind.
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return fmt.Errorf("encrypt bundle: %w", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("encrypt bundle: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("encrypt bundle: %w", err)
+	}
+	if err := writeFileAtomic(outPath, buf.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+
+	u.Err().Println("WARNING: bundle contains refresh tokens and client secrets (keep it safe and delete it when done)")
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"exported": true,
+			"path":     outPath,
+			"accounts": emails,
+			"clients":  clients,
+		})
+	}
+	u.Out().Printf("exported\ttrue")
+	u.Out().Printf("path\t%s", outPath)
+	u.Out().Printf("accounts\t%s", strings.Join(emails, ","))
+	u.Out().Printf("clients\t%s", strings.Join(clients, ","))
+	return nil
+}
+
+type AuthBundleImportCmd struct {
+	InPath        string `arg:"" name:"inPath" help:"Bundle path or '-' for stdin"`
+	Identity      string `name:"identity" short:"i" help:"age identity file to decrypt with (default: passphrase decryption)"`
+	PassphraseEnv string `name:"passphrase-env" help:"Environment variable holding the bundle passphrase" default:"WK_BUNDLE_PASSPHRASE"`
+	Overwrite     bool   `name:"overwrite" help:"Replace existing client credentials, aliases and mappings that differ from the bundle"`
+}
+
+func (c *AuthBundleImportCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	inPath := c.InPath
+	var raw []byte
+	var err error
+	if inPath == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		inPath, err = config.ExpandPath(inPath)
+		if err != nil {
+			return err
+		}
+		raw, err = os.ReadFile(inPath) //nolint:gosec // user-provided path
+	}
+	if err != nil {
+		return err
+	}
+
+	var identities []age.Identity
+	if strings.TrimSpace(c.Identity) != "" {
+		identities, err = readBundleIdentities(c.Identity)
+		if err != nil {
+			return err
+		}
+	} else {
+		pass, passErr := bundlePassphrase(flags, c.PassphraseEnv, false)
+		if passErr != nil {
+			return passErr
+		}
+		id, idErr := age.NewScryptIdentity(pass)
+		if idErr != nil {
+			return fmt.Errorf("bundle passphrase: %w", idErr)
+		}
+		identities = []age.Identity{id}
+	}
+
+	bundle, err := decryptAuthBundle(raw, identities)
+	if err != nil {
+		return err
+	}
+
+	emails := make([]string, 0, len(bundle.Tokens))
+	for _, tok := range bundle.Tokens {
+		emails = append(emails, tok.Email)
+	}
+	clients := make([]string, 0, len(bundle.Clients))
+	for _, cl := range bundle.Clients {
+		clients = append(clients, cl.Name)
+	}
+
+	if dryRunErr := dryRunExit(ctx, flags, "auth.bundle.import", map[string]any{
+		"accounts":  emails,
+		"clients":   clients,
+		"aliases":   bundle.AccountAliases,
+		"overwrite": c.Overwrite,
+	}); dryRunErr != nil {
+		return dryRunErr
+	}
+
+	// Pre-flight: ensure keychain is accessible before storing tokens
+	if keychainErr := ensureKeychainAccessIfNeeded(); keychainErr != nil {
+		return fmt.Errorf("keychain access: %w", keychainErr)
+	}
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+
+	result, err := applyAuthBundle(store, bundle, c.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	u.Err().Println("Imported bundle into keyring and config")
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"imported": true,
+			"accounts": emails,
+			"clients":  result.ClientsWritten,
+			"skipped":  result.Skipped,
+		})
+	}
+	u.Out().Printf("imported\ttrue")
+	u.Out().Printf("accounts\t%s", strings.Join(emails, ","))
+	u.Out().Printf("clients\t%s", strings.Join(result.ClientsWritten, ","))
+	for _, s := range result.Skipped {
+		u.Err().Printf("skipped\t%s (exists; use --overwrite to replace)", s)
+	}
+	return nil
+}
+
+// buildAuthBundle collects the tokens for the requested accounts (all tokens
+// when accounts is empty) together with the client credentials and config
+// mappings they depend on.
+func buildAuthBundle(store secrets.Store, cfg config.File, accounts []string) (authBundle, error) {
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return authBundle{}, err
+	}
+
+	want := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		email := a
+		if resolved, ok, aliasErr := resolveAccountAlias(a); aliasErr != nil {
+			return authBundle{}, aliasErr
+		} else if ok {
+			email = resolved
+		}
+		want[normalizeEmail(email)] = false
+	}
+
+	bundle := authBundle{
+		Version:   authBundleVersion,
+		CreatedAt: time.Now().UTC(),
+		Tokens:    make([]authBundleToken, 0, len(tokens)),
+	}
+	usedClients := make(map[string]bool)
+	usedEmails := make(map[string]bool)
+	for _, tok := range tokens {
+		email := normalizeEmail(tok.Email)
+		if email == "" {
+			continue
+		}
+		if len(want) > 0 {
+			if _, ok := want[email]; !ok {
+				continue
+			}
+			want[email] = true
+		}
+		client, clientErr := config.NormalizeClientNameOrDefault(tok.Client)
+		if clientErr != nil {
+			return authBundle{}, clientErr
+		}
+		bundle.Tokens = append(bundle.Tokens, authBundleToken{
+			Client:       client,
+			Email:        email,
+			Services:     tok.Services,
+			Scopes:       tok.Scopes,
+			CreatedAt:    tok.CreatedAt,
+			RefreshToken: tok.RefreshToken,
+		})
+		usedClients[client] = true
+		usedEmails[email] = true
+	}
+
+	missing := make([]string, 0)
+	for email, found := range want {
+		if !found {
+			missing = append(missing, email)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return authBundle{}, usagef("no stored token for: %s", strings.Join(missing, ", "))
+	}
+	if len(bundle.Tokens) == 0 {
+		return authBundle{}, usage("no tokens to export")
+	}
+	sort.Slice(bundle.Tokens, func(i, j int) bool {
+		return secrets.TokenKey(bundle.Tokens[i].Client, bundle.Tokens[i].Email) <
+			secrets.TokenKey(bundle.Tokens[j].Client, bundle.Tokens[j].Email)
+	})
+
+	creds, err := config.ListClientCredentials()
+	if err != nil {
+		return authBundle{}, err
+	}
+	for _, info := range creds {
+		if !usedClients[info.Client] {
+			continue
+		}
+		cc, readErr := config.ReadClientCredentialsFor(info.Client)
+		if readErr != nil {
+			return authBundle{}, fmt.Errorf("read client credentials %s: %w", info.Client, readErr)
+		}
+		bundle.Clients = append(bundle.Clients, authBundleClient{
+			Name:         info.Client,
+			ClientID:     cc.ClientID,
+			ClientSecret: cc.ClientSecret,
+		})
+	}
+
+	for alias, email := range cfg.AccountAliases {
+		if usedEmails[normalizeEmail(email)] {
+			if bundle.AccountAliases == nil {
+				bundle.AccountAliases = make(map[string]string)
+			}
+			bundle.AccountAliases[alias] = normalizeEmail(email)
+		}
+	}
+	for email, client := range cfg.AccountClients {
+		if usedEmails[normalizeEmail(email)] {
+			if bundle.AccountClients == nil {
+				bundle.AccountClients = make(map[string]string)
+			}
+			bundle.AccountClients[normalizeEmail(email)] = client
+		}
+	}
+	for domain, client := range cfg.ClientDomains {
+		normalized, normErr := config.NormalizeClientNameOrDefault(client)
+		if normErr != nil || !usedClients[normalized] {
+			continue
+		}
+		if bundle.ClientDomains == nil {
+			bundle.ClientDomains = make(map[string]string)
+		}
+		bundle.ClientDomains[domain] = normalized
+	}
+
+	return bundle, nil
+}
+
+type authBundleImportResult struct {
+	ClientsWritten []string
+	Skipped        []string
+}
+
+// applyAuthBundle writes the bundle contents into the local config and
+// secrets store. Tokens are merged (scopes/services unioned); credentials and
+// config mappings that already exist with different values are only replaced
+// when overwrite is set. Tokens of a skipped client are skipped too: they were
+// issued to the bundle's OAuth client and would not refresh with the local one.
+func applyAuthBundle(store secrets.Store, bundle authBundle, overwrite bool) (authBundleImportResult, error) {
+	var result authBundleImportResult
+	skippedClients := make(map[string]bool)
+
+	for _, cl := range bundle.Clients {
+		existing, err := config.ClientCredentialsExists(cl.Name)
+		if err != nil {
+			return result, err
+		}
+		if existing && !overwrite {
+			current, readErr := config.ReadClientCredentialsFor(cl.Name)
+			if readErr == nil && (current.ClientID != cl.ClientID || current.ClientSecret != cl.ClientSecret) {
+				result.Skipped = append(result.Skipped, "client:"+cl.Name)
+				skippedClients[cl.Name] = true
+				continue
+			}
+		}
+		if err := config.WriteClientCredentialsFor(cl.Name, config.ClientCredentials{
+			ClientID:     cl.ClientID,
+			ClientSecret: cl.ClientSecret,
+		}); err != nil {
+			return result, err
+		}
+		result.ClientsWritten = append(result.ClientsWritten, cl.Name)
+	}
+
+	for _, tok := range bundle.Tokens {
+		if skippedClients[tok.Client] {
+			result.Skipped = append(result.Skipped, "token:"+tok.Client+"/"+tok.Email)
+			continue
+		}
+		if err := store.MergeToken(tok.Client, tok.Email, secrets.Token{
+			Client:       tok.Client,
+			Email:        tok.Email,
+			Services:     tok.Services,
+			Scopes:       tok.Scopes,
+			CreatedAt:    tok.CreatedAt,
+			RefreshToken: tok.RefreshToken,
+		}); err != nil {
+			return result, err
+		}
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return result, err
+	}
+	for alias, email := range bundle.AccountAliases {
+		alias, email = config.NormalizeAccountAlias(alias), normalizeEmail(email)
+		if alias == "" {
+			continue
+		}
+		if current, ok := cfg.AccountAliases[alias]; ok && current != email && !overwrite {
+			result.Skipped = append(result.Skipped, "alias:"+alias)
+			continue
+		}
+		if cfg.AccountAliases == nil {
+			cfg.AccountAliases = make(map[string]string)
+		}
+		cfg.AccountAliases[alias] = email
+	}
+	for email, client := range bundle.AccountClients {
+		if current, ok := config.AccountClient(cfg, email); ok && current != client && !overwrite {
+			result.Skipped = append(result.Skipped, "account_client:"+email)
+			continue
+		}
+		if err := config.SetAccountClient(&cfg, email, client); err != nil {
+			return result, err
+		}
+	}
+	for domain, client := range bundle.ClientDomains {
+		if current, ok := config.ClientForDomain(cfg, domain); ok && current != client && !overwrite {
+			result.Skipped = append(result.Skipped, "client_domain:"+domain)
+			continue
+		}
+		if err := config.SetClientDomain(&cfg, domain, client); err != nil {
+			return result, err
+		}
+	}
+	if err := config.WriteConfig(cfg); err != nil {
+		return result, err
+	}
+
+	sort.Strings(result.Skipped)
+	return result, nil
+}
+
+func decryptAuthBundle(raw []byte, identities []age.Identity) (authBundle, error) {
+	r, err := age.Decrypt(bytes.NewReader(raw), identities...)
+	if err != nil {
+		return authBundle{}, fmt.Errorf("decrypt bundle: %w", err)
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return authBundle{}, fmt.Errorf("decrypt bundle: %w", err)
+	}
+
+	var bundle authBundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		return authBundle{}, fmt.Errorf("decode bundle: %w", err)
+	}
+	if bundle.Version != authBundleVersion {
+		return authBundle{}, fmt.Errorf("%w: %d", errBundleUnsupported, bundle.Version)
+	}
+	for _, tok := range bundle.Tokens {
+		if strings.TrimSpace(tok.Email) == "" || strings.TrimSpace(tok.RefreshToken) == "" {
+			return authBundle{}, usage("bundle contains a token without email or refresh_token")
+		}
+	}
+	return bundle, nil
+}
+
+func parseBundleRecipients(values []string) ([]age.Recipient, error) {
+	out := make([]age.Recipient, 0, len(values))
+	for _, v := range values {
+		for _, s := range splitCommaList(v) {
+			r, err := age.ParseX25519Recipient(s)
+			if err != nil {
+				return nil, usagef("invalid --recipient %q: %v", s, err)
+			}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func readBundleIdentities(path string) ([]age.Identity, error) {
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse identity file: %w", err)
+	}
+	return ids, nil
+}
+
+// bundlePassphrase resolves the bundle passphrase from the named environment
+// variable, falling back to an interactive prompt when a TTY is available.
+func bundlePassphrase(flags *RootFlags, envName string, confirm bool) (string, error) {
+	envName = strings.TrimSpace(envName)
+	if envName == "" {
+		envName = authBundlePassphraseEnv
+	}
+	if v, ok := os.LookupEnv(envName); ok {
+		if v == "" {
+			return "", errBundleEmptyPassphrase
+		}
+		return v, nil
+	}
+	if (flags != nil && flags.NoInput) || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", usagef("bundle passphrase required: set %s (or use --recipient/--identity)", envName)
+	}
+	return readBundlePassphrase(confirm)
+}
+
+func readBundlePassphraseFromTerminal(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	_, _ = fmt.Fprint(os.Stderr, "Bundle passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	if len(pass) == 0 {
+		return "", errBundleEmptyPassphrase
+	}
+	if confirm {
+		_, _ = fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, againErr := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if againErr != nil {
+			return "", fmt.Errorf("read passphrase: %w", againErr)
+		}
+		if !bytes.Equal(pass, again) {
+			return "", errBundlePassphraseMismatch
+		}
+	}
+	return string(pass), nil
+}
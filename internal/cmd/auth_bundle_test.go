@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/secrets"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+func setupAuthBundleTest(t *testing.T) (context.Context, *memStore) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	origOpen := openSecretsStore
+	origEnsure := ensureKeychainAccess
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		ensureKeychainAccess = origEnsure
+	})
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	ensureKeychainAccess = func() error { return nil }
+
+	u, err := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui.New: %v", err)
+	}
+	return outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true}), store
+}
+
+func seedAuthBundleSource(t *testing.T, store *memStore) {
+	t.Helper()
+
+	if err := config.WriteClientCredentialsFor("work", config.ClientCredentials{ClientID: "id-work", ClientSecret: "secret-work"}); err != nil {
+		t.Fatalf("WriteClientCredentialsFor: %v", err)
+	}
+	cfg := config.File{}
+	if err := config.SetAccountClient(&cfg, "a@work.com", "work"); err != nil {
+		t.Fatalf("SetAccountClient: %v", err)
+	}
+	if err := config.SetClientDomain(&cfg, "work.com", "work"); err != nil {
+		t.Fatalf("SetClientDomain: %v", err)
+	}
+	cfg.AccountAliases = map[string]string{"work": "a@work.com", "home": "b@gmail.com"}
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.SetToken("work", "a@work.com", secrets.Token{
+		Client: "work", Email: "a@work.com", RefreshToken: "rt-a",
+		Services: []string{"gmail"}, Scopes: []string{"s1"}, CreatedAt: created,
+	}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	if err := store.SetToken(config.DefaultClientName, "b@gmail.com", secrets.Token{
+		Client: config.DefaultClientName, Email: "b@gmail.com", RefreshToken: "rt-b",
+	}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+}
+
+func TestAuthBundleExportImport_Passphrase(t *testing.T) {
+	ctx, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+	t.Setenv(authBundlePassphraseEnv, "correct horse battery staple")
+
+	outPath := filepath.Join(t.TempDir(), "bundle.wkb")
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleExportCmd{}, []string{"--accounts", "work", "--out", outPath}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+
+	raw, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	if strings.Contains(string(raw), "rt-a") || strings.Contains(string(raw), "secret-work") {
+		t.Fatalf("bundle is not encrypted")
+	}
+
+	// Fresh machine: new config dir and empty keyring.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	newStore := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return newStore, nil }
+
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleImportCmd{}, []string{outPath}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("import: %v", err)
+		}
+	})
+
+	tok, err := newStore.GetToken("work", "a@work.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "rt-a" || len(tok.Services) != 1 || tok.Services[0] != "gmail" {
+		t.Fatalf("unexpected token: %#v", tok)
+	}
+	if _, err := newStore.GetToken(config.DefaultClientName, "b@gmail.com"); err == nil {
+		t.Fatalf("expected b@gmail.com to be excluded from bundle")
+	}
+
+	creds, err := config.ReadClientCredentialsFor("work")
+	if err != nil || creds.ClientSecret != "secret-work" {
+		t.Fatalf("unexpected creds: %#v err=%v", creds, err)
+	}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.AccountAliases["work"] != "a@work.com" {
+		t.Fatalf("alias not imported: %#v", cfg.AccountAliases)
+	}
+	if _, ok := cfg.AccountAliases["home"]; ok {
+		t.Fatalf("unrelated alias imported: %#v", cfg.AccountAliases)
+	}
+	if cfg.AccountClients["a@work.com"] != "work" || cfg.ClientDomains["work.com"] != "work" {
+		t.Fatalf("mappings not imported: %#v %#v", cfg.AccountClients, cfg.ClientDomains)
+	}
+}
+
+func TestAuthBundleImport_WrongPassphrase(t *testing.T) {
+	ctx, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+	t.Setenv(authBundlePassphraseEnv, "one")
+
+	outPath := filepath.Join(t.TempDir(), "bundle.wkb")
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleExportCmd{}, []string{"--out", outPath}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+
+	t.Setenv(authBundlePassphraseEnv, "two")
+	err := runKong(t, &AuthBundleImportCmd{}, []string{outPath}, ctx, &RootFlags{})
+	if err == nil || !strings.Contains(err.Error(), "decrypt bundle") {
+		t.Fatalf("expected decrypt error, got %v", err)
+	}
+}
+
+func TestAuthBundleExportImport_Recipient(t *testing.T) {
+	ctx, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	idPath := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(idPath, []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("write identity: %v", err)
+	}
+
+	outPath := filepath.Join(t.TempDir(), "bundle.wkb")
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleExportCmd{}, []string{"--out", outPath, "--recipient", id.Recipient().String()}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("export: %v", err)
+		}
+	})
+
+	newStore := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return newStore, nil }
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleImportCmd{}, []string{outPath, "--identity", idPath}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("import: %v", err)
+		}
+	})
+
+	toks, err := newStore.ListTokens()
+	if err != nil {
+		t.Fatalf("ListTokens: %v", err)
+	}
+	if len(toks) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(toks))
+	}
+}
+
+func TestAuthBundleExport_UnknownAccount(t *testing.T) {
+	ctx, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+	t.Setenv(authBundlePassphraseEnv, "pw")
+
+	outPath := filepath.Join(t.TempDir(), "bundle.wkb")
+	err := runKong(t, &AuthBundleExportCmd{}, []string{"--accounts", "nobody@example.com", "--out", outPath}, ctx, &RootFlags{})
+	if err == nil || !strings.Contains(err.Error(), "nobody@example.com") {
+		t.Fatalf("expected missing account error, got %v", err)
+	}
+	if _, statErr := os.Stat(outPath); !os.IsNotExist(statErr) {
+		t.Fatalf("bundle should not be written on error")
+	}
+}
+
+func TestAuthBundleImport_MismatchedClientKeepsToken(t *testing.T) {
+	_, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+
+	bundle := authBundle{
+		Version: 1,
+		Clients: []authBundleClient{{Name: "work", ClientID: "id-other", ClientSecret: "secret-other"}},
+		Tokens: []authBundleToken{{
+			Client: "work", Email: "a@work.com", RefreshToken: "rt-other", Services: []string{"drive"},
+		}},
+	}
+
+	result, err := applyAuthBundle(store, bundle, false)
+	if err != nil {
+		t.Fatalf("applyAuthBundle: %v", err)
+	}
+
+	tok, err := store.GetToken("work", "a@work.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "rt-a" || len(tok.Services) != 1 || tok.Services[0] != "gmail" {
+		t.Fatalf("existing token changed: %#v", tok)
+	}
+	creds, err := config.ReadClientCredentialsFor("work")
+	if err != nil || creds.ClientID != "id-work" {
+		t.Fatalf("existing credentials changed: %#v err=%v", creds, err)
+	}
+	want := []string{"client:work", "token:work/a@work.com"}
+	if strings.Join(result.Skipped, ",") != strings.Join(want, ",") {
+		t.Fatalf("skipped = %v, want %v", result.Skipped, want)
+	}
+}
+
+func TestAuthBundleExport_ExistingFile(t *testing.T) {
+	ctx, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+	t.Setenv(authBundlePassphraseEnv, "pw")
+
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "bundle.wkb")
+	if err := os.WriteFile(outPath, []byte("old"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	err := runKong(t, &AuthBundleExportCmd{}, []string{"--out", outPath}, ctx, &RootFlags{})
+	if err == nil || !strings.Contains(err.Error(), "--overwrite") {
+		t.Fatalf("expected exists error, got %v", err)
+	}
+	if raw, _ := os.ReadFile(outPath); string(raw) != "old" {
+		t.Fatalf("existing file changed: %q", raw)
+	}
+
+	_ = captureStdout(t, func() {
+		if err := runKong(t, &AuthBundleExportCmd{}, []string{"--out", outPath, "--overwrite"}, ctx, &RootFlags{}); err != nil {
+			t.Fatalf("export --overwrite: %v", err)
+		}
+	})
+	if raw, _ := os.ReadFile(outPath); !strings.HasPrefix(string(raw), "age-encryption.org/") {
+		t.Fatalf("bundle not replaced: %q", raw)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("dir entries = %v, %v; want only the bundle", entries, err)
+	}
+}
+
+func TestAuthBundleImport_AliasConflictIsNormalized(t *testing.T) {
+	_, store := setupAuthBundleTest(t)
+	seedAuthBundleSource(t, store)
+
+	bundle := authBundle{
+		Version:        1,
+		AccountAliases: map[string]string{" Work ": "other@work.com", "New": " C@Work.com "},
+	}
+
+	result, err := applyAuthBundle(store, bundle, false)
+	if err != nil {
+		t.Fatalf("applyAuthBundle: %v", err)
+	}
+	if strings.Join(result.Skipped, ",") != "alias:work" {
+		t.Fatalf("skipped = %v, want [alias:work]", result.Skipped)
+	}
+
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if cfg.AccountAliases["work"] != "a@work.com" || cfg.AccountAliases["new"] != "c@work.com" {
+		t.Fatalf("aliases = %#v", cfg.AccountAliases)
+	}
+	if _, ok := cfg.AccountAliases[" Work "]; ok {
+		t.Fatalf("unnormalized alias stored: %#v", cfg.AccountAliases)
+	}
+}
@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/googleauth"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/secrets"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+var (
+	inspectRefreshToken         = googleauth.InspectRefreshToken
+	readClientCredentialsForCmd = config.ReadClientCredentialsFor
+)
+
+const (
+	doctorSeverityError   = "error"
+	doctorSeverityWarning = "warning"
+
+	doctorCheckCredentials = "credentials"
+	doctorCheckRefresh     = "refresh"
+	doctorCheckEmail       = "email"
+	doctorCheckScopes      = "scopes"
+	doctorCheckServices    = "services"
+	doctorCheckDefault     = "default_account"
+	doctorCheckAlias       = "alias"
+)
+
+type doctorFinding struct {
+	Severity    string   `json:"severity"`
+	Check       string   `json:"check"`
+	Account     string   `json:"account,omitempty"`
+	Client      string   `json:"client,omitempty"`
+	Message     string   `json:"message"`
+	Missing     []string `json:"missing_scopes,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+type doctorAccount struct {
+	Email    string   `json:"email"`
+	Client   string   `json:"client"`
+	Services []string `json:"services,omitempty"`
+	OK       bool     `json:"ok"`
+}
+
+type AuthDoctorCmd struct {
+	Timeout time.Duration `name:"timeout" help:"Per-token refresh/tokeninfo timeout" default:"15s"`
+}
+
+func (c *AuthDoctorCmd) Run(ctx context.Context, _ *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	serviceAccountEmails, err := config.ListServiceAccountEmails()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return secrets.TokenKey(tokens[i].Client, tokens[i].Email) < secrets.TokenKey(tokens[j].Client, tokens[j].Email)
+	})
+
+	findings := make([]doctorFinding, 0)
+	accounts := make([]doctorAccount, 0, len(tokens))
+	for _, tok := range tokens {
+		if strings.TrimSpace(tok.Email) == "" {
+			continue
+		}
+		tokFindings := c.checkToken(ctx, tok)
+		accounts = append(accounts, doctorAccount{
+			Email:    normalizeEmail(tok.Email),
+			Client:   tok.Client,
+			Services: tok.Services,
+			OK:       !hasDoctorError(tokFindings),
+		})
+		findings = append(findings, tokFindings...)
+	}
+
+	findings = append(findings, checkDoctorAccountRouting(store, cfg, tokens, serviceAccountEmails)...)
+
+	ok := !hasDoctorError(findings)
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"ok":       ok,
+			"accounts": accounts,
+			"findings": findings,
+		}); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(ctx, u, accounts, findings)
+	}
+
+	if !ok {
+		return &ExitError{Code: exitCodeAuthRequired, Err: fmt.Errorf("auth doctor found %d problem(s)", countDoctorErrors(findings))}
+	}
+	return nil
+}
+
+// checkToken verifies a single stored token end to end: client credentials
+// present, refresh succeeds, tokeninfo email matches and the granted scopes
+// cover what the token's services need.
+func (c *AuthDoctorCmd) checkToken(ctx context.Context, tok secrets.Token) []doctorFinding {
+	email := normalizeEmail(tok.Email)
+	client := tok.Client
+	if client == "" {
+		client = config.DefaultClientName
+	}
+	reauth := doctorReauthCommand(email, client, tok.Services)
+
+	var out []doctorFinding
+	add := func(f doctorFinding) {
+		f.Account = email
+		f.Client = client
+		out = append(out, f)
+	}
+
+	if _, err := readClientCredentialsForCmd(client); err != nil {
+		var credErr *config.CredentialsMissingError
+		if errors.As(err, &credErr) {
+			add(doctorFinding{
+				Severity:    doctorSeverityError,
+				Check:       doctorCheckCredentials,
+				Message:     fmt.Sprintf("OAuth client credentials for client %q are missing (%s)", client, credErr.Path),
+				Remediation: doctorCommand(client, "auth credentials <credentials.json>"),
+			})
+			return out
+		}
+		add(doctorFinding{
+			Severity:    doctorSeverityError,
+			Check:       doctorCheckCredentials,
+			Message:     fmt.Sprintf("cannot read OAuth client credentials for client %q: %v", client, err),
+			Remediation: doctorCommand(client, "auth credentials <credentials.json>"),
+		})
+		return out
+	}
+
+	requiredServices := make([]googleauth.Service, 0, len(tok.Services))
+	for _, name := range tok.Services {
+		svc, err := googleauth.ParseService(name)
+		if err != nil {
+			add(doctorFinding{
+				Severity: doctorSeverityWarning,
+				Check:    doctorCheckServices,
+				Message:  fmt.Sprintf("stored service %q is not recognized by this version of wk", name),
+			})
+			continue
+		}
+		requiredServices = append(requiredServices, svc)
+	}
+
+	info, err := inspectRefreshToken(ctx, client, tok.RefreshToken, c.Timeout)
+	if err != nil {
+		switch {
+		case googleauth.IsInvalidGrant(err):
+			add(doctorFinding{
+				Severity:    doctorSeverityError,
+				Check:       doctorCheckRefresh,
+				Message:     "refresh token revoked or expired (invalid_grant)",
+				Remediation: reauth,
+			})
+		case googleauth.IsInvalidClient(err):
+			add(doctorFinding{
+				Severity:    doctorSeverityError,
+				Check:       doctorCheckRefresh,
+				Message:     fmt.Sprintf("OAuth client %q rejected by Google (deleted client or rotated secret)", client),
+				Remediation: doctorCommand(client, "auth credentials <credentials.json>") + " && " + reauth,
+			})
+		default:
+			add(doctorFinding{
+				Severity:    doctorSeverityWarning,
+				Check:       doctorCheckRefresh,
+				Message:     fmt.Sprintf("could not verify token: %v", err),
+				Remediation: doctorCommand("", "auth doctor"),
+			})
+		}
+		return out
+	}
+
+	if info.Email != "" && normalizeEmail(info.Email) != email {
+		add(doctorFinding{
+			Severity:    doctorSeverityError,
+			Check:       doctorCheckEmail,
+			Message:     fmt.Sprintf("token is stored for %s but belongs to %s", email, normalizeEmail(info.Email)),
+			Remediation: doctorCommand(client, "auth tokens delete "+email) + " && " + reauth,
+		})
+	}
+
+	required, err := doctorRequiredScopes(tok, requiredServices)
+	if err != nil {
+		add(doctorFinding{
+			Severity: doctorSeverityWarning,
+			Check:    doctorCheckScopes,
+			Message:  fmt.Sprintf("cannot compute required scopes: %v", err),
+		})
+		return out
+	}
+	if missing := googleauth.MissingScopes(required, info.Scopes); len(missing) > 0 {
+		add(doctorFinding{
+			Severity:    doctorSeverityError,
+			Check:       doctorCheckScopes,
+			Message:     fmt.Sprintf("%d required scope(s) not granted", len(missing)),
+			Missing:     missing,
+			Remediation: reauth,
+		})
+	}
+
+	return out
+}
+
+// doctorRequiredScopes returns the scopes a token must hold. Tokens record the
+// exact scopes requested at authorization time (which reflect --readonly and
+// --drive-scope); older tokens without that record fall back to the default
+// scopes of their stored services.
+func doctorRequiredScopes(tok secrets.Token, services []googleauth.Service) ([]string, error) {
+	if len(tok.Scopes) > 0 {
+		return tok.Scopes, nil
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+	return googleauth.ScopesForServices(services)
+}
+
+// checkDoctorAccountRouting verifies that per-client default accounts and
+// account aliases point at accounts that can actually authenticate.
+func checkDoctorAccountRouting(store secrets.Store, cfg config.File, tokens []secrets.Token, serviceAccountEmails []string) []doctorFinding {
+	var out []doctorFinding
+
+	byEmail := make(map[string]bool)
+	byClientEmail := make(map[string]bool)
+	clients := make(map[string]bool)
+	for _, tok := range tokens {
+		email := normalizeEmail(tok.Email)
+		if email == "" {
+			continue
+		}
+		byEmail[email] = true
+		byClientEmail[secrets.TokenKey(tok.Client, email)] = true
+		clients[tok.Client] = true
+	}
+	for _, email := range serviceAccountEmails {
+		byEmail[normalizeEmail(email)] = true
+	}
+
+	clientNames := make([]string, 0, len(clients))
+	for client := range clients {
+		clientNames = append(clientNames, client)
+	}
+	sort.Strings(clientNames)
+
+	for _, client := range clientNames {
+		def, err := store.GetDefaultAccount(client)
+		if err != nil {
+			out = append(out, doctorFinding{
+				Severity: doctorSeverityWarning,
+				Check:    doctorCheckDefault,
+				Client:   client,
+				Message:  fmt.Sprintf("cannot read default account: %v", err),
+			})
+			continue
+		}
+		def = normalizeEmail(def)
+		if def == "" || byClientEmail[secrets.TokenKey(client, def)] || byEmail[def] {
+			continue
+		}
+		out = append(out, doctorFinding{
+			Severity:    doctorSeverityError,
+			Check:       doctorCheckDefault,
+			Account:     def,
+			Client:      client,
+			Message:     fmt.Sprintf("default account %s has no stored token", def),
+			Remediation: doctorCommand(client, "auth add "+def) + "  # or pick another default with: wk auth manage",
+		})
+	}
+
+	aliases := make([]string, 0, len(cfg.AccountAliases))
+	for alias := range cfg.AccountAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		email := normalizeEmail(cfg.AccountAliases[alias])
+		if email != "" && byEmail[email] {
+			continue
+		}
+		msg := fmt.Sprintf("alias %q points to %s, which has no stored token or service account", alias, email)
+		if email == "" {
+			msg = fmt.Sprintf("alias %q has an empty email", alias)
+		}
+		out = append(out, doctorFinding{
+			Severity:    doctorSeverityError,
+			Check:       doctorCheckAlias,
+			Account:     email,
+			Message:     msg,
+			Remediation: fmt.Sprintf("wk auth alias unset %s  # or authorize it: wk auth add %s", alias, email),
+		})
+	}
+
+	return out
+}
+
+func doctorReauthCommand(email string, client string, services []string) string {
+	args := "auth add " + email
+	if len(services) > 0 {
+		sorted := append([]string(nil), services...)
+		sort.Strings(sorted)
+		args += " --services " + strings.Join(sorted, ",")
+	}
+	args += " --force-consent"
+	return doctorCommand(client, args)
+}
+
+func doctorCommand(client string, args string) string {
+	if client != "" && client != config.DefaultClientName {
+		return "wk --client " + client + " " + args
+	}
+	return "wk " + args
+}
+
+func hasDoctorError(findings []doctorFinding) bool {
+	return countDoctorErrors(findings) > 0
+}
+
+func countDoctorErrors(findings []doctorFinding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == doctorSeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func printDoctorReport(ctx context.Context, u *ui.UI, accounts []doctorAccount, findings []doctorFinding) {
+	if len(accounts) == 0 {
+		u.Err().Println("No tokens stored")
+	} else {
+		w, flush := tableWriter(ctx)
+		_, _ = fmt.Fprintln(w, "EMAIL\tCLIENT\tSTATUS")
+		for _, a := range accounts {
+			status := "ok"
+			if !a.OK {
+				status = "problem"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", a.Email, a.Client, status)
+		}
+		flush()
+	}
+
+	if len(findings) == 0 {
+		u.Err().Println("No problems found")
+		return
+	}
+
+	u.Err().Println("")
+	for _, f := range findings {
+		subject := f.Account
+		if subject == "" {
+			subject = f.Client
+		}
+		u.Err().Printf("%s [%s] %s: %s", strings.ToUpper(f.Severity), f.Check, subject, f.Message)
+		if len(f.Missing) > 0 {
+			u.Err().Printf("  missing: %s", strings.Join(f.Missing, " "))
+		}
+		if f.Remediation != "" {
+			u.Err().Printf("  fix: %s", f.Remediation)
+		}
+	}
+}
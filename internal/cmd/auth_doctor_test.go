@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/googleauth"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/secrets"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+func TestAuthDoctor_JSONFindings(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("WK_CLIENT_ID", "")
+	t.Setenv("WK_CLIENT_SECRET", "")
+
+	origOpen := openSecretsStore
+	origInspect := inspectRefreshToken
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		inspectRefreshToken = origInspect
+	})
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+
+	if err := config.WriteClientCredentials(config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}); err != nil {
+		t.Fatalf("WriteClientCredentials: %v", err)
+	}
+	if err := config.SetAccountAlias("ghost", "ghost@example.com"); err != nil {
+		t.Fatalf("SetAccountAlias: %v", err)
+	}
+
+	tokens := []secrets.Token{
+		{Client: "default", Email: "good@example.com", RefreshToken: "good", Services: []string{"tasks"}},
+		{Client: "default", Email: "revoked@example.com", RefreshToken: "revoked", Services: []string{"tasks"}},
+		{Client: "default", Email: "narrow@example.com", RefreshToken: "narrow", Services: []string{"drive", "tasks"}},
+		{Client: "orphan", Email: "orphan@example.com", RefreshToken: "orphan", Services: []string{"gmail"}},
+	}
+	for _, tok := range tokens {
+		if err := store.SetToken(tok.Client, tok.Email, tok); err != nil {
+			t.Fatalf("SetToken: %v", err)
+		}
+	}
+
+	inspectRefreshToken = func(_ context.Context, _ string, refreshToken string, _ time.Duration) (googleauth.TokenInfo, error) {
+		switch refreshToken {
+		case "revoked":
+			return googleauth.TokenInfo{}, &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+		case "narrow":
+			return googleauth.TokenInfo{Email: "narrow@example.com", Scopes: []string{"https://www.googleapis.com/auth/tasks"}}, nil
+		default:
+			return googleauth.TokenInfo{Email: "good@example.com", Scopes: []string{"https://www.googleapis.com/auth/tasks"}}, nil
+		}
+	}
+
+	u, uiErr := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if uiErr != nil {
+		t.Fatalf("ui.New: %v", uiErr)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true})
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runKong(t, &AuthDoctorCmd{}, []string{}, ctx, &RootFlags{})
+	})
+	if ExitCode(runErr) != exitCodeAuthRequired {
+		t.Fatalf("expected auth-required exit code, got %v", runErr)
+	}
+
+	var payload struct {
+		OK       bool            `json:"ok"`
+		Accounts []doctorAccount `json:"accounts"`
+		Findings []doctorFinding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(out), &payload); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	if payload.OK || len(payload.Accounts) != 4 {
+		t.Fatalf("unexpected payload: %#v", payload)
+	}
+
+	byCheck := map[string]doctorFinding{}
+	for _, f := range payload.Findings {
+		byCheck[f.Check+":"+f.Account] = f
+	}
+
+	if f, ok := byCheck["refresh:revoked@example.com"]; !ok || !strings.Contains(f.Remediation, "wk auth add revoked@example.com --services tasks --force-consent") {
+		t.Fatalf("missing revoked finding: %#v", payload.Findings)
+	}
+	if f, ok := byCheck["scopes:narrow@example.com"]; !ok || len(f.Missing) != 1 || f.Missing[0] != "https://www.googleapis.com/auth/drive" {
+		t.Fatalf("missing scope finding: %#v", payload.Findings)
+	}
+	if f, ok := byCheck["credentials:orphan@example.com"]; !ok || !strings.HasPrefix(f.Remediation, "wk --client orphan auth credentials") {
+		t.Fatalf("missing credentials finding: %#v", payload.Findings)
+	}
+	if _, ok := byCheck["alias:ghost@example.com"]; !ok {
+		t.Fatalf("missing alias finding: %#v", payload.Findings)
+	}
+	if _, ok := byCheck["refresh:good@example.com"]; ok {
+		t.Fatalf("unexpected finding for good token: %#v", payload.Findings)
+	}
+}
@@ -14,7 +14,7 @@ import (
 )
 
 type AuthKeyringCmd struct {
-	Backend  string `arg:"" optional:"" name:"backend" help:"Keyring backend: auto|keychain|file"`
+	Backend  string `arg:"" optional:"" name:"backend" help:"Keyring backend: auto|keychain|file|exec:<cmd>"`
 	Backend2 string `arg:"" optional:"" name:"backend2" help:"(compat) Use: wk auth keyring set <backend>"`
 }
 
@@ -23,7 +23,10 @@ func (c *AuthKeyringCmd) Run(ctx context.Context, flags *RootFlags) error {
 
 	const keyringPasswordEnv = "WK_KEYRING_PASSWORD" //nolint:gosec // env var name, not a credential
 
-	backend := strings.ToLower(strings.TrimSpace(c.Backend))
+	backend := strings.TrimSpace(c.Backend)
+	if !secrets.IsExecBackend(backend) {
+		backend = strings.ToLower(backend)
+	}
 	backend2 := strings.ToLower(strings.TrimSpace(c.Backend2))
 
 	// Backwards compat for earlier suggestion: `wk auth keyring set <backend>`.
@@ -54,7 +57,7 @@ func (c *AuthKeyringCmd) Run(ctx context.Context, flags *RootFlags) error {
 		u.Out().Printf("path\t%s", path)
 		u.Out().Printf("keyring_backend\t%s", info.Value)
 		u.Out().Printf("source\t%s", info.Source)
-		u.Err().Println("Hint: wk auth keyring <auto|keychain|file>")
+		u.Err().Println("Hint: wk auth keyring <auto|keychain|file|exec:<cmd>>")
 		return nil
 	}
 
@@ -71,8 +74,16 @@ func (c *AuthKeyringCmd) Run(ctx context.Context, flags *RootFlags) error {
 		"keychain": {},
 		strFile:    {},
 	}
-	if _, ok := allowed[backend]; !ok {
-		return usagef("invalid backend: %q (expected auto, keychain, or file)", c.Backend)
+	if secrets.IsExecBackend(backend) {
+		if strings.TrimSpace(backend[len("exec:"):]) == "" {
+			return usage("exec backend requires a helper command (exec:<cmd>)")
+		}
+		if _, err := secrets.ExecHelperArgv(backend); err != nil {
+			return usage(err.Error())
+		}
+		backend = "exec:" + strings.TrimSpace(backend[len("exec:"):])
+	} else if _, ok := allowed[backend]; !ok {
+		return usagef("invalid backend: %q (expected auto, keychain, file, or exec:<cmd>)", c.Backend)
 	}
 
 	path, _ := config.ConfigPath()
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/automagik-dev/workit/internal/config"
@@ -136,3 +137,22 @@ func TestAuthKeyringSet_InvalidBackend(t *testing.T) {
 		t.Fatalf("expected usage exit 2, got: %v", err)
 	}
 }
+
+func TestAuthKeyringSet_QuotedExecBackend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	u, err := ui.New(ui.Options{Stdout: &stdout, Stderr: &stderr, Color: "never"})
+	if err != nil {
+		t.Fatalf("ui new: %v", err)
+	}
+	ctx := outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{})
+
+	err = runKong(t, &AuthKeyringCmd{}, []string{`exec:"/opt/my helper" get`}, ctx, nil)
+
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.Code != 2 || !strings.Contains(err.Error(), "quotes") {
+		t.Fatalf("expected usage error about quotes, got: %v", err)
+	}
+}
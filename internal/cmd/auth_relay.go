@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/authclient"
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/googleauth"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+type AuthRelayCmd struct {
+	Serve AuthRelayServeCmd `cmd:"" name:"serve" help:"Run a self-hosted auth relay for headless logins"`
+}
+
+type AuthRelayServeCmd struct {
+	Credentials string        `name:"credentials" help:"Path to OAuth client credentials JSON (default: stored credentials for --client)"`
+	Port        int           `name:"port" help:"Port to listen on" default:"8089"`
+	Bind        string        `name:"bind" help:"Address to bind" default:"0.0.0.0"`
+	PublicURL   string        `name:"public-url" help:"URL clients reach the relay at (default: http://<hostname>:<port>); used as callback_server and for the redirect URL"`
+	TTL         time.Duration `name:"ttl" help:"How long an unclaimed token is kept" default:"15m"`
+}
+
+func (c *AuthRelayServeCmd) Run(ctx context.Context, _ *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	creds, err := c.loadCredentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.Port < 0 || c.Port > 65535 {
+		return usagef("invalid --port %d", c.Port)
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(strings.TrimSpace(c.Bind), strconv.Itoa(c.Port)))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	publicURL := strings.TrimSuffix(strings.TrimSpace(c.PublicURL), "/")
+	if publicURL == "" {
+		publicURL = defaultRelayPublicURL(c.Bind, ln.Addr())
+	}
+
+	relay, err := googleauth.NewRelay(googleauth.RelayOptions{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		PublicURL:    publicURL,
+		TTL:          c.TTL,
+	})
+	if err != nil {
+		_ = ln.Close()
+		return err
+	}
+
+	redirectURL := googleauth.RelayRedirectURL(publicURL)
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"listen":          ln.Addr().String(),
+			"callback_server": publicURL,
+			"redirect_url":    redirectURL,
+		}); err != nil {
+			_ = ln.Close()
+			return err
+		}
+	} else {
+		u.Out().Printf("listen\t%s", ln.Addr().String())
+		u.Out().Printf("callback_server\t%s", publicURL)
+		u.Out().Printf("redirect_url\t%s", redirectURL)
+		u.Err().Printf("Add %s as an authorized redirect URI of the OAuth client.", redirectURL)
+		u.Err().Println("Clients must use the same OAuth client credentials. Point them at this relay with:")
+		u.Err().Printf("  wk config set callback_server %s", publicURL)
+		u.Err().Println("Press Ctrl+C to stop")
+	}
+
+	srv := &http.Server{
+		Handler:           relay,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+
+		return fmt.Errorf("relay server: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+
+		return nil
+	}
+}
+
+// loadCredentials reads --credentials (Google's downloaded JSON or workit's
+// stored format) or falls back to the stored credentials for --client.
+func (c *AuthRelayServeCmd) loadCredentials(ctx context.Context) (config.ClientCredentials, error) {
+	path := strings.TrimSpace(c.Credentials)
+	if path == "" {
+		client, err := normalizeClientForFlag(authclient.ClientOverrideFromContext(ctx))
+		if err != nil {
+			return config.ClientCredentials{}, err
+		}
+
+		return config.ReadClientCredentialsFor(client)
+	}
+
+	path, err := config.ExpandPath(path)
+	if err != nil {
+		return config.ClientCredentials{}, err
+	}
+
+	b, err := os.ReadFile(path) //nolint:gosec // user-provided path
+	if err != nil {
+		return config.ClientCredentials{}, err
+	}
+
+	if creds, parseErr := config.ParseGoogleOAuthClientJSON(b); parseErr == nil {
+		return creds, nil
+	}
+
+	var creds config.ClientCredentials
+	if err := json.Unmarshal(b, &creds); err != nil || creds.ClientID == "" || creds.ClientSecret == "" {
+		return config.ClientCredentials{}, fmt.Errorf("%s: no OAuth client credentials found", path)
+	}
+
+	return creds, nil
+}
+
+// defaultRelayPublicURL derives the URL clients use when --public-url is not
+// set: the bound address, or this machine's hostname for wildcard binds.
+func defaultRelayPublicURL(bind string, addr net.Addr) string {
+	port := ""
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		port = strconv.Itoa(tcp.Port)
+	}
+
+	host := strings.TrimSpace(bind)
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		if name, err := os.Hostname(); err == nil && name != "" {
+			host = name
+		} else {
+			host = "localhost"
+		}
+	}
+
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+func TestAuthRelayServe_PrintsCallbackServer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	credPath := filepath.Join(home, "client.json")
+	if err := os.WriteFile(credPath, []byte(`{"installed":{"client_id":"id","client_secret":"secret"}}`), 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	u, uiErr := ui.New(ui.Options{Stdout: os.Stdout, Stderr: os.Stderr, Color: "never"})
+	if uiErr != nil {
+		t.Fatalf("ui.New: %v", uiErr)
+	}
+	ctx, cancel := context.WithCancel(outfmt.WithMode(ui.WithUI(context.Background(), u), outfmt.Mode{JSON: true}))
+	defer cancel()
+
+	healthy := make(chan bool, 1)
+	go func() {
+		defer cancel()
+		url := "http://127.0.0.1:" + strconv.Itoa(port) + "/health"
+		for range 100 {
+			if resp, err := http.Get(url); err == nil {
+				_ = resp.Body.Close()
+				healthy <- resp.StatusCode == http.StatusOK
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		healthy <- false
+	}()
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runKong(t, &AuthRelayServeCmd{}, []string{
+			"--credentials", credPath,
+			"--bind", "127.0.0.1",
+			"--port", strconv.Itoa(port),
+			"--public-url", "https://relay.example.com/",
+		}, ctx, &RootFlags{})
+	})
+	if runErr != nil {
+		t.Fatalf("relay serve: %v", runErr)
+	}
+	if !<-healthy {
+		t.Fatal("relay did not answer /health")
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(out), &payload); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	if payload["callback_server"] != "https://relay.example.com" || payload["redirect_url"] != "https://relay.example.com/callback" {
+		t.Fatalf("unexpected payload: %v", payload)
+	}
+}
+
+func TestAuthRelayServe_MissingCredentials(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	credPath := filepath.Join(home, "empty.json")
+	if err := os.WriteFile(credPath, []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+
+	if err := runKong(t, &AuthRelayServeCmd{}, []string{"--credentials", credPath}, context.Background(), &RootFlags{}); err == nil {
+		t.Fatal("expected an error for credentials without a client")
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	gogapi "github.com/automagik-dev/workit/internal/googleapi"
+	"github.com/automagik-dev/workit/internal/googleauth"
+	"github.com/automagik-dev/workit/internal/input"
+	"github.com/automagik-dev/workit/internal/secrets"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+const scopeUpgradeTimeout = 5 * time.Minute
+
+// desirePathServices maps top-level action shortcuts to the service whose
+// scopes they need, complementing googleauth.CommandServiceMap.
+var desirePathServices = map[string]googleauth.Service{
+	"send":     googleauth.ServiceGmail,
+	"ls":       googleauth.ServiceDrive,
+	"search":   googleauth.ServiceDrive,
+	"download": googleauth.ServiceDrive,
+	"upload":   googleauth.ServiceDrive,
+	"me":       googleauth.ServicePeople,
+	"whoami":   googleauth.ServicePeople,
+}
+
+var promptScopeUpgrade = promptScopeUpgradeInteractive
+
+// serviceForCommand resolves the OAuth service a command path (as returned by
+// kong.Context.Command) needs.
+func serviceForCommand(command string) (googleauth.Service, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", false
+	}
+	name := strings.ToLower(fields[0])
+	if svc, ok := googleauth.CommandServiceMap[name]; ok {
+		return svc, true
+	}
+	svc, ok := desirePathServices[name]
+	return svc, ok
+}
+
+// resolveScopeUpgrade inspects a failed command. When the failure is a
+// Google API "insufficient scopes" 403 for an OAuth account, it either runs
+// an incremental authorization for the missing service (when allowed) and
+// reports retry=true for read-only commands, or returns a
+// ScopeUpgradeRequiredError that tells the user exactly which command
+// restores access.
+func resolveScopeUpgrade(ctx context.Context, flags *RootFlags, command string, runErr error) (retry bool, err error) {
+	if !gogapi.IsInsufficientScopeError(runErr) {
+		return false, runErr
+	}
+	svc, ok := serviceForCommand(command)
+	if !ok {
+		return false, runErr
+	}
+	email, accountErr := requireAccount(flags)
+	if accountErr != nil {
+		return false, runErr
+	}
+	if _, _, saOK := bestServiceAccountPathAndMtime(normalizeEmail(email)); saOK {
+		// Service accounts get their scopes from the domain-wide delegation
+		// allowlist; re-consenting cannot fix them.
+		return false, runErr
+	}
+	client, clientErr := resolveClientForEmail(email, flags, "")
+	if clientErr != nil {
+		return false, runErr
+	}
+
+	var existing []string
+	if store, storeErr := openSecretsStore(); storeErr == nil {
+		if tok, getErr := store.GetToken(client, email); getErr == nil {
+			existing = tok.Services
+		}
+	}
+
+	upgradeErr := &gogapi.ScopeUpgradeRequiredError{
+		Service:  string(svc),
+		Email:    email,
+		Client:   client,
+		Services: existing,
+		Cause:    runErr,
+	}
+
+	if flags == nil || !flags.AutoUpgradeScopes {
+		if flags == nil || flags.NoInput || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return false, upgradeErr
+		}
+		accepted, promptErr := promptScopeUpgrade(ctx, email, svc)
+		if promptErr != nil || !accepted {
+			return false, upgradeErr
+		}
+	}
+
+	if err := upgradeTokenScopes(ctx, email, client, svc); err != nil {
+		return false, fmt.Errorf("incremental authorization for %s: %w", svc, err)
+	}
+
+	// A write command may have partly run before the 403 (e.g. a batch
+	// send), so running it again is left to the user.
+	if isWriteCommand(command) {
+		return false, fmt.Errorf("granted %s access for %s; re-run the command (write commands are not retried automatically, in case it partly ran)", svc, email)
+	}
+	if u := ui.FromContext(ctx); u != nil {
+		u.Err().Printf("Granted %s access for %s; retrying", svc, email)
+	}
+	return true, nil
+}
+
+func promptScopeUpgradeInteractive(ctx context.Context, email string, svc googleauth.Service) (bool, error) {
+	prompt := fmt.Sprintf("%s has not granted %s access. Authorize it now (keeps existing access)? [y/N]: ", email, svc)
+	line, err := input.PromptLine(ctx, prompt)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	ans := strings.TrimSpace(strings.ToLower(line))
+	return ans == "y" || ans == "yes", nil
+}
+
+// upgradeTokenScopes runs an incremental OAuth authorization that requests
+// only the scopes for svc. Authorization URLs always carry
+// include_granted_scopes=true, so the new refresh token covers both the
+// previously granted and the new scopes; the stored token metadata is merged
+// with MergeTokenFields.
+func upgradeTokenScopes(ctx context.Context, email string, client string, svc googleauth.Service) error {
+	u := ui.FromContext(ctx)
+
+	scopes, err := googleauth.ScopesForManage([]googleauth.Service{svc})
+	if err != nil {
+		return err
+	}
+
+	if keychainErr := ensureKeychainAccessIfNeeded(); keychainErr != nil {
+		return fmt.Errorf("keychain access: %w", keychainErr)
+	}
+
+	resolved := googleauth.ResolveAuthMode(ctx, false, false, "")
+
+	var refreshToken string
+	switch resolved.Mode {
+	case googleauth.AuthModeHeadless:
+		info, headlessErr := headlessAuthorize(ctx, googleauth.HeadlessOptions{
+			Services:       []googleauth.Service{svc},
+			Scopes:         scopes,
+			ForceConsent:   true,
+			Client:         client,
+			CallbackServer: resolved.CallbackServer,
+		})
+		if headlessErr != nil {
+			return headlessErr
+		}
+		if u != nil {
+			u.Err().Printf("Visit this URL to grant %s access to %s:", svc, email)
+			u.Err().Println(info.AuthURL)
+			u.Err().Println("Waiting for authorization...")
+		}
+		refreshToken, err = pollForToken(ctx, resolved.CallbackServer, info.State, scopeUpgradeTimeout)
+	case googleauth.AuthModeDevice:
+		refreshToken, err = deviceAuthorize(ctx, googleauth.DeviceOptions{
+			Scopes:  scopes,
+			Client:  client,
+			Timeout: scopeUpgradeTimeout,
+			OnCode: func(info googleauth.DeviceCodeInfo) {
+				if u != nil {
+					u.Err().Printf("Open %s and enter code %s to grant %s access to %s", info.VerificationURL, info.UserCode, svc, email)
+					u.Err().Println("Waiting for authorization...")
+				}
+			},
+		})
+	default:
+		refreshToken, err = authorizeGoogle(ctx, googleauth.AuthorizeOptions{
+			Services:     []googleauth.Service{svc},
+			Scopes:       scopes,
+			Manual:       resolved.Mode == googleauth.AuthModeManual,
+			ForceConsent: true,
+			Timeout:      scopeUpgradeTimeout,
+			Client:       client,
+		})
+	}
+	if err != nil {
+		return googleauth.WrapOAuthError(err)
+	}
+
+	authorizedEmail, err := fetchAuthorizedEmail(ctx, client, refreshToken, scopes, 15*time.Second)
+	if err != nil {
+		return fmt.Errorf("fetch authorized email: %w", err)
+	}
+	if normalizeEmail(authorizedEmail) != normalizeEmail(email) {
+		return fmt.Errorf("authorized as %s, expected %s", authorizedEmail, email)
+	}
+
+	store, err := openSecretsStore()
+	if err != nil {
+		return err
+	}
+	if err := store.MergeToken(client, authorizedEmail, secrets.Token{
+		Client:       client,
+		Email:        authorizedEmail,
+		Services:     []string{string(svc)},
+		Scopes:       scopes,
+		RefreshToken: refreshToken,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
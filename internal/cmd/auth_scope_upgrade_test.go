@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ggoogleapi "google.golang.org/api/googleapi"
+
+	"github.com/automagik-dev/workit/internal/config"
+	gogapi "github.com/automagik-dev/workit/internal/googleapi"
+	"github.com/automagik-dev/workit/internal/googleauth"
+	"github.com/automagik-dev/workit/internal/secrets"
+)
+
+func insufficientScopeErr() error {
+	return &ggoogleapi.Error{
+		Code:    http.StatusForbidden,
+		Message: "Request had insufficient authentication scopes.",
+		Errors:  []ggoogleapi.ErrorItem{{Reason: "insufficientPermissions"}},
+	}
+}
+
+func TestServiceForCommand(t *testing.T) {
+	cases := map[string]googleauth.Service{
+		"drive ls":             googleauth.ServiceDrive,
+		"calendar events <id>": googleauth.ServiceCalendar,
+		"send":                 googleauth.ServiceGmail,
+	}
+	for cmd, want := range cases {
+		if got, ok := serviceForCommand(cmd); !ok || got != want {
+			t.Fatalf("serviceForCommand(%q) = %q, %v; want %q", cmd, got, ok, want)
+		}
+	}
+
+	if _, ok := serviceForCommand("auth status"); ok {
+		t.Fatalf("expected no service for auth")
+	}
+}
+
+func TestResolveScopeUpgrade_ReturnsActionableError(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	origOpen := openSecretsStore
+	t.Cleanup(func() { openSecretsStore = origOpen })
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	_ = store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{Email: "a@b.com", RefreshToken: "rt", Services: []string{"gmail"}})
+
+	retry, err := resolveScopeUpgrade(context.Background(), &RootFlags{Account: "a@b.com", NoInput: true}, "drive ls", insufficientScopeErr())
+	if retry {
+		t.Fatalf("unexpected retry")
+	}
+
+	var scopeErr *gogapi.ScopeUpgradeRequiredError
+	if !errors.As(err, &scopeErr) {
+		t.Fatalf("expected ScopeUpgradeRequiredError, got %v", err)
+	}
+	if scopeErr.Service != "drive" || !slices.Equal(scopeErr.Services, []string{"gmail"}) {
+		t.Fatalf("unexpected error: %#v", scopeErr)
+	}
+	if ExitCode(stableExitCode(err)) != exitCodeAuthRequired {
+		t.Fatalf("expected auth-required exit code")
+	}
+
+	other := errors.New("boom")
+	if retry, err := resolveScopeUpgrade(context.Background(), &RootFlags{Account: "a@b.com"}, "drive ls", other); retry || err != other {
+		t.Fatalf("expected passthrough, got %v %v", retry, err)
+	}
+}
+
+func TestResolveScopeUpgrade_AutoUpgradeMergesToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if err := config.WriteConfig(config.File{AuthMode: googleauth.AuthModeBrowser}); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	origOpen := openSecretsStore
+	origAuth := authorizeGoogle
+	origFetch := fetchAuthorizedEmail
+	origKeychain := ensureKeychainAccess
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		authorizeGoogle = origAuth
+		fetchAuthorizedEmail = origFetch
+		ensureKeychainAccess = origKeychain
+	})
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	ensureKeychainAccess = func() error { return nil }
+	_ = store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{Email: "a@b.com", RefreshToken: "old", Services: []string{"gmail"}})
+
+	authorizeGoogle = func(_ context.Context, opts googleauth.AuthorizeOptions) (string, error) {
+		if !opts.ForceConsent || !slices.Equal(opts.Services, []googleauth.Service{googleauth.ServiceDrive}) {
+			t.Fatalf("unexpected options: %#v", opts)
+		}
+		return "new", nil
+	}
+	fetchAuthorizedEmail = func(context.Context, string, string, []string, time.Duration) (string, error) {
+		return "a@b.com", nil
+	}
+
+	retry, err := resolveScopeUpgrade(context.Background(), &RootFlags{Account: "a@b.com", AutoUpgradeScopes: true}, "drive ls", insufficientScopeErr())
+	if err != nil || !retry {
+		t.Fatalf("expected retry, got %v %v", retry, err)
+	}
+
+	tok, err := store.GetToken(config.DefaultClientName, "a@b.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "new" || !slices.Contains(tok.Services, "gmail") || !slices.Contains(tok.Services, "drive") {
+		t.Fatalf("unexpected token: %#v", tok)
+	}
+}
+
+func TestResolveScopeUpgrade_WriteCommandNotRetried(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if err := config.WriteConfig(config.File{AuthMode: googleauth.AuthModeBrowser}); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	origOpen := openSecretsStore
+	origAuth := authorizeGoogle
+	origFetch := fetchAuthorizedEmail
+	origKeychain := ensureKeychainAccess
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		authorizeGoogle = origAuth
+		fetchAuthorizedEmail = origFetch
+		ensureKeychainAccess = origKeychain
+	})
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	ensureKeychainAccess = func() error { return nil }
+	_ = store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{Email: "a@b.com", RefreshToken: "old", Services: []string{"drive"}})
+
+	authorizeGoogle = func(context.Context, googleauth.AuthorizeOptions) (string, error) { return "new", nil }
+	fetchAuthorizedEmail = func(context.Context, string, string, []string, time.Duration) (string, error) {
+		return "a@b.com", nil
+	}
+
+	retry, err := resolveScopeUpgrade(context.Background(), &RootFlags{Account: "a@b.com", AutoUpgradeScopes: true}, "gmail send", insufficientScopeErr())
+	if retry || err == nil || !strings.Contains(err.Error(), "re-run the command") {
+		t.Fatalf("expected no retry and a re-run hint, got %v %v", retry, err)
+	}
+
+	// The scopes are still granted for the re-run.
+	tok, err := store.GetToken(config.DefaultClientName, "a@b.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "new" || !slices.Contains(tok.Services, "gmail") {
+		t.Fatalf("unexpected token: %#v", tok)
+	}
+}
+
+func TestResolveScopeUpgrade_DeviceModeUsesDeviceFlow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if err := config.WriteConfig(config.File{AuthMode: googleauth.AuthModeDevice}); err != nil {
+		t.Fatalf("WriteConfig: %v", err)
+	}
+
+	origOpen := openSecretsStore
+	origAuth := authorizeGoogle
+	origDevice := deviceAuthorize
+	origFetch := fetchAuthorizedEmail
+	origKeychain := ensureKeychainAccess
+	t.Cleanup(func() {
+		openSecretsStore = origOpen
+		authorizeGoogle = origAuth
+		deviceAuthorize = origDevice
+		fetchAuthorizedEmail = origFetch
+		ensureKeychainAccess = origKeychain
+	})
+
+	store := newMemStore()
+	openSecretsStore = func() (secrets.Store, error) { return store, nil }
+	ensureKeychainAccess = func() error { return nil }
+	_ = store.SetToken(config.DefaultClientName, "a@b.com", secrets.Token{Email: "a@b.com", RefreshToken: "old", Services: []string{"gmail"}})
+
+	authorizeGoogle = func(context.Context, googleauth.AuthorizeOptions) (string, error) {
+		t.Fatal("browser flow started in device mode")
+		return "", nil
+	}
+	deviceAuthorize = func(_ context.Context, opts googleauth.DeviceOptions) (string, error) {
+		if len(opts.Scopes) == 0 || opts.OnCode == nil {
+			t.Fatalf("unexpected options: %#v", opts)
+		}
+		opts.OnCode(googleauth.DeviceCodeInfo{UserCode: "ABCD-EFGH", VerificationURL: "https://www.google.com/device"})
+		return "new", nil
+	}
+	fetchAuthorizedEmail = func(context.Context, string, string, []string, time.Duration) (string, error) {
+		return "a@b.com", nil
+	}
+
+	retry, err := resolveScopeUpgrade(context.Background(), &RootFlags{Account: "a@b.com", AutoUpgradeScopes: true}, "drive ls", insufficientScopeErr())
+	if err != nil || !retry {
+		t.Fatalf("expected retry, got %v %v", retry, err)
+	}
+
+	tok, err := store.GetToken(config.DefaultClientName, "a@b.com")
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if tok.RefreshToken != "new" || !slices.Contains(tok.Services, "drive") {
+		t.Fatalf("unexpected token: %#v", tok)
+	}
+}
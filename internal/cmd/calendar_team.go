@@ -68,10 +68,19 @@ func (c *CalendarTeamCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return wrapCloudIdentityError(err, account)
 	}
 
-	memberEmails, err := collectGroupMemberEmails(ctx, cloudSvc, groupEmail)
+	exp, err := expandGroupMembers(ctx, cloudSvc, groupEmail)
 	if err != nil {
 		return fmt.Errorf("failed to list group members: %w", err)
 	}
+	for _, cycle := range exp.Cycles {
+		u.Err().Printf("warning: group cycle %s", strings.Join(cycle, " -> "))
+	}
+
+	memberEmails := make([]string, 0, len(exp.Members))
+	for _, m := range exp.Members {
+		memberEmails = append(memberEmails, m.Email)
+	}
+	sort.Strings(memberEmails)
 
 	if len(memberEmails) == 0 {
 		u.Err().Printf("No user members in group %s", groupEmail)
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/automagik-dev/workit/internal/errfmt"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// Fan-out runs one parsed read command once per account, concurrently, and
+// merges every run's JSON output into a single document:
+//
+//	{"results": [...], "errors": [{"<field>": ..., "error": ...}], "<noun>": N, "failed": N}
+//
+// Result objects are tagged with the account under field; lists are flattened
+// with each item tagged.
+type fanOut struct {
+	flag        string // flag that requested the fan-out, for messages
+	field       string // result key naming the account: "user" or "account"
+	noun        string // plural for counts and messages
+	concurrency int
+	// resultsOnly unwraps each run's primary list so the items of all runs
+	// merge into one list.
+	resultsOnly bool
+	// sortByTime orders merged items by their timestamp field, if they have one.
+	sortByTime bool
+	// failOnPartial makes the command fail when any run failed; otherwise it
+	// only fails when every run did.
+	failOnPartial bool
+}
+
+type fanOutResult struct {
+	account string
+	values  []any
+	err     error
+}
+
+// check rejects write commands and invalid concurrency before any run.
+func (f fanOut) check(kctx *kong.Context) error {
+	if err := enforceReadOnly(kctx, true); err != nil {
+		return usagef("%s only runs read commands; %q writes", f.flag, kctx.Command())
+	}
+
+	if f.concurrency < 1 {
+		return usagef("invalid --concurrency %d (must be at least 1)", f.concurrency)
+	}
+
+	return nil
+}
+
+func (f fanOut) run(ctx context.Context, args []string, accounts []string) error {
+	results := make([]fanOutResult, len(accounts))
+	sem := make(chan struct{}, f.concurrency)
+
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			values, err := runAsAccount(ctx, args, account, f.resultsOnly)
+			results[i] = fanOutResult{account: account, values: values, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return f.write(ctx, results)
+}
+
+// runAsAccount parses args into a fresh command tree, so concurrent runs share
+// no flag state, and runs it as account with its JSON output collected.
+func runAsAccount(ctx context.Context, args []string, account string, resultsOnly bool) ([]any, error) {
+	parser, cli, err := newParser(baseDescription())
+	if err != nil {
+		return nil, err
+	}
+
+	kctx, err := parser.Parse(args)
+	if err != nil {
+		return nil, wrapParseError(err)
+	}
+
+	cli.Account = account
+	cli.ForEachUser = ""
+
+	// Per-run transforms shape each result; jq runs once on the merge.
+	t, _ := outfmt.JSONTransformFromContext(ctx)
+	ctx = outfmt.WithJSONTransform(ctx, outfmt.JSONTransform{ResultsOnly: t.ResultsOnly || resultsOnly, Select: t.Select})
+
+	collector := &outfmt.Collector{}
+	ctx = outfmt.WithCollector(ctx, collector)
+
+	u, err := ui.New(ui.Options{Stdout: io.Discard, Stderr: io.Discard, Color: colorNever})
+	if err != nil {
+		return nil, err
+	}
+	ctx = ui.WithUI(ctx, u)
+
+	kctx.BindTo(ctx, (*context.Context)(nil))
+	kctx.Bind(&cli.RootFlags)
+
+	if err := kctx.Run(); err != nil && ExitCode(err) != 0 {
+		return collector.Values(), stableExitCode(err)
+	}
+
+	return collector.Values(), nil
+}
+
+func (f fanOut) write(ctx context.Context, results []fanOutResult) error {
+	merged := make([]any, 0, len(results))
+	failures := make([]map[string]any, 0)
+
+	var firstErr error
+
+	failed := 0
+
+	for _, r := range results {
+		for _, v := range r.values {
+			items, err := tagResult(v, f.field, r.account)
+			if err != nil {
+				return err
+			}
+
+			merged = append(merged, items...)
+		}
+
+		if r.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = r.err
+			}
+
+			failures = append(failures, map[string]any{
+				f.field: r.account,
+				"error": strings.TrimSpace(errfmt.Format(r.err)),
+			})
+		}
+	}
+
+	if f.sortByTime {
+		sortItemsByTime(merged)
+	}
+
+	// Results-only and select were applied per run; only jq is left.
+	if t, ok := outfmt.JSONTransformFromContext(ctx); ok {
+		ctx = outfmt.WithJSONTransform(ctx, outfmt.JSONTransform{JQ: t.JQ})
+	}
+
+	if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+		"results": merged,
+		"errors":  failures,
+		f.noun:    len(results),
+		"failed":  failed,
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(results):
+		return fmt.Errorf("%s: all %d %s failed: %w", f.flag, failed, f.noun, firstErr)
+	case f.failOnPartial:
+		return fmt.Errorf("%s: %d of %d %s failed", f.flag, failed, len(results), f.noun)
+	default:
+		return nil
+	}
+}
+
+func tagResult(v any, field string, account string) ([]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode result for %s: %w", account, err)
+	}
+
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, fmt.Errorf("decode result for %s: %w", account, err)
+	}
+
+	tag := func(item any) any {
+		if m, ok := item.(map[string]any); ok {
+			m[field] = account
+			return m
+		}
+
+		return map[string]any{field: account, "value": item}
+	}
+
+	if list, ok := generic.([]any); ok {
+		out := make([]any, 0, len(list))
+		for _, item := range list {
+			out = append(out, tag(item))
+		}
+
+		return out, nil
+	}
+
+	return []any{tag(generic)}, nil
+}
+
+// fanOutTimeFields are the timestamp fields merged lists are ordered by, in
+// preference order. Event starts sort oldest first, like calendar listings;
+// everything else newest first, like Gmail and Drive.
+var fanOutTimeFields = []struct {
+	path      string
+	ascending bool
+}{
+	{path: "date"},
+	{path: "start.dateTime", ascending: true},
+	{path: "start.date", ascending: true},
+	{path: "modifiedTime"},
+	{path: "updated"},
+	{path: "updateTime"},
+	{path: "createdTime"},
+	{path: "createTime"},
+}
+
+var fanOutTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04", "2006-01-02"}
+
+// sortItemsByTime orders items by the first time field any of them carries.
+// Items without a parsable value keep their relative order at the end.
+func sortItemsByTime(items []any) {
+	for _, field := range fanOutTimeFields {
+		times := make([]time.Time, len(items))
+		found := false
+
+		for i, item := range items {
+			if t, ok := itemTime(item, field.path); ok {
+				times[i] = t
+				found = true
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		idx := make([]int, len(items))
+		for i := range idx {
+			idx[i] = i
+		}
+
+		sort.SliceStable(idx, func(a, b int) bool {
+			ta, tb := times[idx[a]], times[idx[b]]
+			switch {
+			case ta.IsZero() || tb.IsZero():
+				return !ta.IsZero() && tb.IsZero()
+			case field.ascending:
+				return ta.Before(tb)
+			default:
+				return ta.After(tb)
+			}
+		})
+
+		sorted := make([]any, len(items))
+		for i, j := range idx {
+			sorted[i] = items[j]
+		}
+		copy(items, sorted)
+
+		return
+	}
+}
+
+func itemTime(item any, path string) (time.Time, bool) {
+	cur := item
+	for _, seg := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return time.Time{}, false
+		}
+
+		cur = m[seg]
+	}
+
+	s, ok := cur.(string)
+	if !ok || strings.TrimSpace(s) == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range fanOutTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/googleapi"
+)
+
+// Domain-wide fan-out.
+//
+// `wk --for-each-user <users> <command>` runs a read command once per
+// Workspace user. --account names the admin whose stored service account key
+// (with domain-wide delegation) impersonates each user in turn; the user list
+// comes from a file or an Admin SDK Directory query. Every run's JSON output is
+// captured and merged into one document whose items carry a "user" field.
+
+var newAdminDirectoryUsersService = googleapi.NewAdminDirectoryUsersReadonly
+
+// orgUnitQueryAlias rewrites the friendlier orgUnit= to the Directory API's
+// orgUnitPath= field.
+var orgUnitQueryAlias = regexp.MustCompile(`(?i)\borgUnit([=:])`)
+
+func runForEachUser(ctx context.Context, kctx *kong.Context, args []string, flags *RootFlags) error {
+	f := fanOut{flag: "--for-each-user", field: "user", noun: "users", concurrency: flags.Concurrency, failOnPartial: true}
+	if err := f.check(kctx); err != nil {
+		return err
+	}
+
+	adminEmail, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	key, err := readDomainWideKey(adminEmail)
+	if err != nil {
+		return err
+	}
+
+	ctx = googleapi.WithServiceAccountKey(ctx, key)
+
+	users, err := resolveForEachUsers(ctx, adminEmail, flags.ForEachUser)
+	if err != nil {
+		return err
+	}
+
+	return f.run(ctx, args, users)
+}
+
+// readDomainWideKey loads the service account key stored for the admin
+// account via `wk auth service-account set`.
+func readDomainWideKey(adminEmail string) ([]byte, error) {
+	path, err := config.ServiceAccountPath(adminEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // stored in user config dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, usagef("--for-each-user needs a service account with domain-wide delegation for %s (run `wk auth service-account set %s --key <key.json>`)", adminEmail, adminEmail)
+		}
+
+		return nil, fmt.Errorf("read service account: %w", err)
+	}
+
+	if _, err := parseServiceAccountJSON(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// resolveForEachUsers expands the --for-each-user value: @file (or @- for
+// stdin) lists emails one per line or comma-separated; anything else is a
+// Directory users query, with "all" or "*" meaning every active user.
+func resolveForEachUsers(ctx context.Context, adminEmail string, spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+
+	var users []string
+
+	if strings.HasPrefix(spec, "@") {
+		b, err := resolveInlineOrFileBytes(spec)
+		if err != nil {
+			return nil, fmt.Errorf("read --for-each-user list: %w", err)
+		}
+
+		users = parseUserList(string(b))
+	} else {
+		query := spec
+		if query == "all" || query == "*" {
+			query = ""
+		}
+
+		var err error
+		if users, err = listDirectoryUsers(ctx, adminEmail, orgUnitQueryAlias.ReplaceAllString(query, "orgUnitPath$1")); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(users) == 0 {
+		return nil, usagef("--for-each-user %q matched no users", spec)
+	}
+
+	return users, nil
+}
+
+func parseUserList(s string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, email := range splitCommaList(line) {
+			key := strings.ToLower(email)
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			out = append(out, email)
+		}
+	}
+
+	return out
+}
+
+// listDirectoryUsers returns the primary emails of active users matching
+// query, impersonating the admin account.
+func listDirectoryUsers(ctx context.Context, adminEmail string, query string) ([]string, error) {
+	svc, err := newAdminDirectoryUsersService(ctx, adminEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	call := svc.Users.List().Customer("my_customer").OrderBy("email").MaxResults(500)
+	if query != "" {
+		call = call.Query(query)
+	}
+
+	var users []string
+
+	err = call.Pages(ctx, func(resp *admin.Users) error {
+		for _, u := range resp.Users {
+			if u == nil || u.Suspended || strings.TrimSpace(u.PrimaryEmail) == "" {
+				continue
+			}
+
+			users = append(users, u.PrimaryEmail)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list directory users: %w", googleapi.WrapAPIEnablementError(err, "admin"))
+	}
+
+	return users, nil
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+// setupForEachUser stores a service account key for admin@example.com and
+// stubs the directory people service so `calendar users` reports which
+// account it ran as.
+func setupForEachUser(t *testing.T, fail map[string]bool) func() []string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	if _, err := config.EnsureDir(); err != nil {
+		t.Fatalf("EnsureDir: %v", err)
+	}
+
+	saPath, err := config.ServiceAccountPath("admin@example.com")
+	if err != nil {
+		t.Fatalf("ServiceAccountPath: %v", err)
+	}
+
+	if err := os.WriteFile(saPath, []byte(`{"type":"service_account","client_email":"svc@example.com"}`), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("X-Test-User")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"people": []map[string]any{
+				{"emailAddresses": []map[string]any{{"value": "colleague-of-" + user}}},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	origPeople := newPeopleDirectoryService
+	t.Cleanup(func() { newPeopleDirectoryService = origPeople })
+
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+
+	newPeopleDirectoryService = func(_ context.Context, account string) (*people.Service, error) {
+		mu.Lock()
+		seen = append(seen, account)
+		mu.Unlock()
+
+		if fail[account] {
+			return nil, errors.New("delegation denied")
+		}
+
+		return people.NewService(context.Background(),
+			option.WithoutAuthentication(),
+			option.WithHTTPClient(&http.Client{Transport: headerTransport{user: account}}),
+			option.WithEndpoint(srv.URL+"/"),
+		)
+	}
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		out := append([]string(nil), seen...)
+		sort.Strings(out)
+
+		return out
+	}
+}
+
+type headerTransport struct{ user string }
+
+func (h headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Test-User", h.user)
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func writeUserList(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "users.txt")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write users: %v", err)
+	}
+
+	return path
+}
+
+func TestForEachUser_FileMergesResults(t *testing.T) {
+	seen := setupForEachUser(t, nil)
+	list := writeUserList(t, "# sales\na@example.com\nb@example.com, A@example.com\n\n")
+
+	out := captureStdout(t, func() {
+		if err := Execute([]string{"--account", "admin@example.com", "--for-each-user", "@" + list, "calendar", "users"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	})
+
+	if got := seen(); strings.Join(got, ",") != "a@example.com,b@example.com" {
+		t.Fatalf("ran as %v", got)
+	}
+
+	var parsed struct {
+		Results []map[string]any `json:"results"`
+		Errors  []map[string]any `json:"errors"`
+		Users   int              `json:"users"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\n%s", err, out)
+	}
+
+	if parsed.Users != 2 || len(parsed.Results) != 2 || len(parsed.Errors) != 0 {
+		t.Fatalf("unexpected merge: %s", out)
+	}
+
+	for i, want := range []string{"a@example.com", "b@example.com"} {
+		if parsed.Results[i]["user"] != want {
+			t.Fatalf("result %d user = %v, want %s", i, parsed.Results[i]["user"], want)
+		}
+	}
+}
+
+func TestForEachUser_ResultsOnlyFlattensItems(t *testing.T) {
+	setupForEachUser(t, nil)
+	list := writeUserList(t, "a@example.com\nb@example.com\n")
+
+	out := captureStdout(t, func() {
+		if err := Execute([]string{"--account", "admin@example.com", "--for-each-user", "@" + list, "--results-only", "--concurrency", "1", "calendar", "users"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	})
+
+	var parsed struct {
+		Results []map[string]any `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\n%s", err, out)
+	}
+
+	if len(parsed.Results) != 2 {
+		t.Fatalf("unexpected results: %s", out)
+	}
+
+	if parsed.Results[1]["email"] != "colleague-of-b@example.com" || parsed.Results[1]["user"] != "b@example.com" {
+		t.Fatalf("unexpected item: %v", parsed.Results[1])
+	}
+}
+
+func TestForEachUser_PartialFailure(t *testing.T) {
+	setupForEachUser(t, map[string]bool{"b@example.com": true})
+	list := writeUserList(t, "a@example.com\nb@example.com\n")
+
+	var runErr error
+
+	out := captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			runErr = Execute([]string{"--account", "admin@example.com", "--for-each-user", "@" + list, "calendar", "users"})
+		})
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "1 of 2 users failed") {
+		t.Fatalf("expected partial failure, got %v", runErr)
+	}
+
+	var parsed struct {
+		Results []map[string]any `json:"results"`
+		Errors  []map[string]any `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\n%s", err, out)
+	}
+
+	if len(parsed.Results) != 1 || len(parsed.Errors) != 1 || parsed.Errors[0]["user"] != "b@example.com" {
+		t.Fatalf("unexpected merge: %s", out)
+	}
+}
+
+func TestForEachUser_RejectsWriteCommands(t *testing.T) {
+	setupForEachUser(t, nil)
+	list := writeUserList(t, "a@example.com\n")
+
+	var runErr error
+
+	_ = captureStderr(t, func() {
+		runErr = Execute([]string{"--account", "admin@example.com", "--for-each-user", "@" + list, "gmail", "send", "--to", "x@example.com", "--subject", "s", "--body", "b"})
+	})
+	if ExitCode(runErr) != 2 || !strings.Contains(runErr.Error(), "only runs read commands") {
+		t.Fatalf("expected usage error, got %v", runErr)
+	}
+}
+
+func TestForEachUser_RequiresServiceAccount(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	list := writeUserList(t, "a@example.com\n")
+
+	var runErr error
+
+	_ = captureStderr(t, func() {
+		runErr = Execute([]string{"--account", "nobody@example.com", "--for-each-user", "@" + list, "calendar", "users"})
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "domain-wide delegation") {
+		t.Fatalf("expected service account error, got %v", runErr)
+	}
+}
+
+func TestResolveForEachUsers_DirectoryQuery(t *testing.T) {
+	var gotQuery string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"users": []map[string]any{
+				{"primaryEmail": "a@example.com"},
+				{"primaryEmail": "gone@example.com", "suspended": true},
+				{"primaryEmail": "b@example.com"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	orig := newAdminDirectoryUsersService
+	t.Cleanup(func() { newAdminDirectoryUsersService = orig })
+
+	var gotAdmin string
+
+	newAdminDirectoryUsersService = func(_ context.Context, email string) (*admin.Service, error) {
+		gotAdmin = email
+
+		return admin.NewService(context.Background(),
+			option.WithoutAuthentication(),
+			option.WithHTTPClient(srv.Client()),
+			option.WithEndpoint(srv.URL+"/"),
+		)
+	}
+
+	users, err := resolveForEachUsers(context.Background(), "admin@example.com", "orgUnit=/Sales")
+	if err != nil {
+		t.Fatalf("resolveForEachUsers: %v", err)
+	}
+
+	if gotAdmin != "admin@example.com" || gotQuery != "orgUnitPath=/Sales" {
+		t.Fatalf("admin=%q query=%q", gotAdmin, gotQuery)
+	}
+
+	if strings.Join(users, ",") != "a@example.com,b@example.com" {
+		t.Fatalf("users = %v", users)
+	}
+}
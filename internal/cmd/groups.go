@@ -24,8 +24,18 @@ const (
 )
 
 type GroupsCmd struct {
-	List    GroupsListCmd    `cmd:"" name:"list" aliases:"ls" help:"List groups you belong to"`
-	Members GroupsMembersCmd `cmd:"" name:"members" help:"List members of a group"`
+	List    GroupsListCmd        `cmd:"" name:"list" aliases:"ls" help:"List groups you belong to"`
+	Members GroupsMembersRootCmd `cmd:"" name:"members" aliases:"member" help:"List and manage members of a group"`
+	Create  GroupsCreateCmd      `cmd:"" name:"create" aliases:"add,new" help:"Create a group"`
+	Update  GroupsUpdateCmd      `cmd:"" name:"update" aliases:"edit,set" help:"Update a group's name or description"`
+	Delete  GroupsDeleteCmd      `cmd:"" name:"delete" aliases:"rm,del" help:"Delete a group"`
+}
+
+type GroupsMembersRootCmd struct {
+	List       GroupsMembersCmd           `cmd:"" name:"list" aliases:"ls" default:"withargs" help:"List members of a group"`
+	Add        GroupsMembersAddCmd        `cmd:"" name:"add" aliases:"insert" help:"Add a member to a group"`
+	Remove     GroupsMembersRemoveCmd     `cmd:"" name:"remove" aliases:"rm,delete" help:"Remove a member from a group"`
+	UpdateRole GroupsMembersUpdateRoleCmd `cmd:"" name:"update-role" aliases:"set-role" help:"Change a member's role"`
 }
 
 type GroupsListCmd struct {
@@ -141,7 +151,7 @@ func wrapCloudIdentityError(err error, account string) error {
 	errStr := err.Error()
 	if strings.Contains(errStr, "insufficientPermissions") ||
 		strings.Contains(errStr, "insufficient authentication scopes") {
-		return errfmt.NewUserFacingError("Insufficient permissions for Cloud Identity API; re-authenticate with the cloud-identity.groups.readonly scope: wk auth add <account> --services groups", err)
+		return errfmt.NewUserFacingError("Insufficient permissions for Cloud Identity API; re-authenticate for the groups service (cloud-identity.groups; changes also need group owner/manager or admin rights): wk auth add <account> --services groups", err)
 	}
 	if strings.Contains(errStr, "invalid argument") || strings.Contains(errStr, "badRequest") {
 		if isConsumerAccount(account) {
@@ -166,6 +176,7 @@ func getRelationType(relationType string) string {
 
 type GroupsMembersCmd struct {
 	GroupEmail string `arg:"" name:"groupEmail" help:"Group email (e.g., engineering@company.com)"`
+	Transitive bool   `name:"transitive" aliases:"recursive" help:"Expand nested groups into their user members (fetches all pages)"`
 	Max        int64  `name:"max" aliases:"limit" help:"Max results" default:"100"`
 	Page       string `name:"page" aliases:"cursor" help:"Page token"`
 	All        bool   `name:"all" aliases:"all-pages,allpages" help:"Fetch all pages"`
@@ -189,6 +200,10 @@ func (c *GroupsMembersCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return wrapCloudIdentityError(err, account)
 	}
 
+	if c.Transitive {
+		return c.runTransitive(ctx, u, svc, groupEmail)
+	}
+
 	// First, look up the group by email to get its resource name
 	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
 	if err != nil {
@@ -310,6 +325,16 @@ func getMemberRole(roles []*cloudidentity.MembershipRole) string {
 	return groupRoleMember
 }
 
+// parseGroupRole validates a --role value (any case) as OWNER, MANAGER or MEMBER.
+func parseGroupRole(role string) (string, error) {
+	switch r := strings.ToUpper(strings.TrimSpace(role)); r {
+	case groupRoleOwner, groupRoleManager, groupRoleMember:
+		return r, nil
+	default:
+		return "", usagef("invalid --role %q (expected OWNER, MANAGER or MEMBER)", role)
+	}
+}
+
 // truncate shortens a string to maxLen, adding "..." if truncated.
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -321,60 +346,179 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
-func collectGroupMemberEmails(ctx context.Context, svc *cloudidentity.Service, groupEmail string) ([]string, error) {
-	seenGroups := make(map[string]bool)
-	emails := make(map[string]bool)
-	if err := collectGroupMemberEmailsRecursive(ctx, svc, groupEmail, seenGroups, emails); err != nil {
-		return nil, err
+func (c *GroupsMembersCmd) runTransitive(ctx context.Context, u *ui.UI, svc *cloudidentity.Service, groupEmail string) error {
+	exp, err := expandGroupMembers(ctx, svc, groupEmail)
+	if err != nil {
+		return err
 	}
 
-	results := make([]string, 0, len(emails))
-	for email := range emails {
-		results = append(results, email)
+	for _, cycle := range exp.Cycles {
+		u.Err().Printf("warning: group cycle %s", strings.Join(cycle, " -> "))
 	}
-	sort.Strings(results)
-	return results, nil
-}
 
-func collectGroupMemberEmailsRecursive(ctx context.Context, svc *cloudidentity.Service, groupEmail string, seenGroups map[string]bool, emails map[string]bool) error {
-	groupEmail = strings.TrimSpace(groupEmail)
-	if groupEmail == "" {
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"members": exp.Members,
+			"groups":  exp.Groups,
+			"cycles":  exp.Cycles,
+		}); err != nil {
+			return err
+		}
+		if len(exp.Members) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
 		return nil
 	}
-	if seenGroups[groupEmail] {
-		return nil
+
+	if len(exp.Members) == 0 {
+		u.Err().Printf("No user members in group %s", groupEmail)
+		return failEmptyExit(c.FailEmpty)
 	}
-	seenGroups[groupEmail] = true
 
-	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
-	if err != nil {
-		return fmt.Errorf("lookup group %q: %w", groupEmail, err)
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "EMAIL\tROLE\tVIA\tDEPTH")
+	for _, m := range exp.Members {
+		via := m.Via
+		if via == "" {
+			via = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n",
+			sanitizeTab(m.Email),
+			sanitizeTab(m.Role),
+			sanitizeTab(via),
+			m.Depth,
+		)
 	}
+	return nil
+}
 
-	memberships, err := listGroupMemberships(ctx, svc, groupName, 200)
-	if err != nil {
-		return fmt.Errorf("list members for %q: %w", groupEmail, err)
+// groupMember is a user reached by expanding a group's nested groups.
+type groupMember struct {
+	Email string `json:"email"`
+	// Role is the member's role in the group they belong to directly.
+	Role string `json:"role"`
+	// Via is the nested group the member was first found in; empty for
+	// direct members.
+	Via   string `json:"via,omitempty"`
+	Depth int    `json:"depth"`
+}
+
+type groupExpansion struct {
+	Members []groupMember `json:"members"`
+	// Groups lists the nested groups that were expanded.
+	Groups []string `json:"groups"`
+	// Cycles lists each group membership loop found, as the group path
+	// from the first repeated group back to itself.
+	Cycles [][]string `json:"cycles"`
+}
+
+// expandGroupMembers resolves a group's user members through nested groups,
+// breadth first so each user is reported through their shortest path. A group
+// reached through several paths is expanded once; a group that contains
+// itself (directly or through others) is reported as a cycle, not followed.
+func expandGroupMembers(ctx context.Context, svc *cloudidentity.Service, groupEmail string) (*groupExpansion, error) {
+	type pending struct {
+		email string
+		path  []string // groups above this one, root first
 	}
 
-	for _, m := range memberships {
-		if m == nil || m.PreferredMemberKey == nil {
-			continue
+	exp := &groupExpansion{Members: []groupMember{}, Groups: []string{}, Cycles: [][]string{}}
+	root := strings.TrimSpace(groupEmail)
+	queued := map[string]bool{strings.ToLower(root): true}
+	seenUsers := make(map[string]bool)
+	queue := []pending{{email: root}}
+
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+
+		groupName, err := lookupGroupByEmail(ctx, svc, g.email)
+		if err != nil {
+			return nil, fmt.Errorf("lookup group %q: %w", g.email, err)
 		}
-		email := strings.TrimSpace(m.PreferredMemberKey.Id)
-		if email == "" || !strings.Contains(email, "@") {
-			continue
+
+		memberships, err := listGroupMemberships(ctx, svc, groupName, 200)
+		if err != nil {
+			return nil, fmt.Errorf("list members for %q: %w", g.email, err)
 		}
-		switch m.Type {
-		case "GROUP":
-			if err := collectGroupMemberEmailsRecursive(ctx, svc, email, seenGroups, emails); err != nil {
-				return err
+
+		path := append(append([]string{}, g.path...), g.email)
+		via := ""
+		if len(g.path) > 0 {
+			via = g.email
+		}
+
+		for _, m := range memberships {
+			if m == nil || m.PreferredMemberKey == nil {
+				continue
+			}
+			email := strings.TrimSpace(m.PreferredMemberKey.Id)
+			if email == "" || !strings.Contains(email, "@") {
+				continue
+			}
+			key := strings.ToLower(email)
+
+			switch m.Type {
+			case "GROUP":
+				if i := indexFold(path, email); i >= 0 {
+					exp.Cycles = append(exp.Cycles, append(append([]string{}, path[i:]...), email))
+					continue
+				}
+				if queued[key] {
+					continue
+				}
+				queued[key] = true
+				exp.Groups = append(exp.Groups, email)
+				queue = append(queue, pending{email: email, path: path})
+			case "USER", "":
+				if seenUsers[key] {
+					continue
+				}
+				seenUsers[key] = true
+				exp.Members = append(exp.Members, groupMember{
+					Email: email,
+					Role:  getMemberRole(m.Roles),
+					Via:   via,
+					Depth: len(g.path),
+				})
 			}
-		case "USER", "":
-			emails[email] = true
 		}
 	}
 
-	return nil
+	sort.SliceStable(exp.Members, func(i, j int) bool {
+		if exp.Members[i].Depth != exp.Members[j].Depth {
+			return exp.Members[i].Depth < exp.Members[j].Depth
+		}
+		return exp.Members[i].Email < exp.Members[j].Email
+	})
+	sort.Strings(exp.Groups)
+	return exp, nil
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// collectGroupMemberEmails returns the sorted user emails of a group,
+// including members of nested groups.
+func collectGroupMemberEmails(ctx context.Context, svc *cloudidentity.Service, groupEmail string) ([]string, error) {
+	exp, err := expandGroupMembers(ctx, svc, groupEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, 0, len(exp.Members))
+	for _, m := range exp.Members {
+		results = append(results, m.Email)
+	}
+	sort.Strings(results)
+	return results, nil
 }
 
 func listGroupMemberships(ctx context.Context, svc *cloudidentity.Service, groupName string, pageSize int64) ([]*cloudidentity.Membership, error) {
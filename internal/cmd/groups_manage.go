@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"google.golang.org/api/cloudidentity/v1"
+
+	"github.com/automagik-dev/workit/internal/googleapi"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+var newCloudIdentityManageService = googleapi.NewCloudIdentityGroupsManage
+
+// groupDiscussionForumLabel marks a Cloud Identity group as a Google Group
+// (email list), the kind `groups list` shows.
+const groupDiscussionForumLabel = "cloudidentity.googleapis.com/groups.discussion_forum"
+
+type GroupsCreateCmd struct {
+	Email       string `arg:"" name:"email" help:"Group email address"`
+	Name        string `name:"name" help:"Display name (default: the email's local part)"`
+	Description string `name:"description" aliases:"desc" help:"Description"`
+	Customer    string `name:"customer" help:"Workspace customer ID (e.g. C01abc23d; Admin console > Account settings)"`
+}
+
+func (c *GroupsCreateCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	email := strings.TrimSpace(c.Email)
+	if !strings.Contains(email, "@") {
+		return usagef("invalid group email %q", c.Email)
+	}
+	customer := strings.TrimPrefix(strings.TrimSpace(c.Customer), "customers/")
+	if customer == "" {
+		return usage("--customer required (Workspace customer ID, e.g. C01abc23d; see Admin console > Account settings)")
+	}
+
+	g := &cloudidentity.Group{
+		Parent:      "customers/" + customer,
+		GroupKey:    &cloudidentity.EntityKey{Id: email},
+		DisplayName: strings.TrimSpace(c.Name),
+		Description: strings.TrimSpace(c.Description),
+		Labels:      map[string]string{groupDiscussionForumLabel: ""},
+	}
+	if g.DisplayName == "" {
+		g.DisplayName, _, _ = strings.Cut(email, "@")
+	}
+
+	if err := dryRunExit(ctx, flags, "groups.create", g); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	op, err := svc.Groups.Create(g).InitialGroupConfig("WITH_INITIAL_OWNER").Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	created := &cloudidentity.Group{}
+	if err := decodeGroupOperation(op, created); err != nil {
+		return err
+	}
+
+	return writeResult(ctx, u,
+		kv("email", email),
+		kv("name", created.Name),
+		kv("displayName", g.DisplayName),
+		kv("done", op.Done),
+	)
+}
+
+type GroupsUpdateCmd struct {
+	GroupEmail  string `arg:"" name:"groupEmail" help:"Group email"`
+	Name        string `name:"name" help:"New display name"`
+	Description string `name:"description" aliases:"desc" help:"Description (empty clears)"`
+}
+
+func (c *GroupsUpdateCmd) Run(ctx context.Context, kctx *kong.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupEmail := strings.TrimSpace(c.GroupEmail)
+	if groupEmail == "" {
+		return usage("group email required")
+	}
+
+	patch := &cloudidentity.Group{}
+	var mask []string
+
+	if flagProvided(kctx, "name") {
+		patch.DisplayName = strings.TrimSpace(c.Name)
+		if patch.DisplayName == "" {
+			return usage("empty --name")
+		}
+		mask = append(mask, "display_name")
+	}
+	if flagProvided(kctx, "description") {
+		patch.Description = strings.TrimSpace(c.Description)
+		patch.ForceSendFields = append(patch.ForceSendFields, "Description")
+		mask = append(mask, "description")
+	}
+
+	if len(mask) == 0 {
+		return usage("nothing to update (pass --name or --description)")
+	}
+
+	if err := dryRunExit(ctx, flags, "groups.update", map[string]any{
+		"group":      groupEmail,
+		"updateMask": strings.Join(mask, ","),
+		"patch":      patch,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
+	if err != nil {
+		return fmt.Errorf("failed to find group %q: %w", groupEmail, wrapCloudIdentityError(err, account))
+	}
+
+	op, err := svc.Groups.Patch(groupName, patch).UpdateMask(strings.Join(mask, ",")).Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+	if err := decodeGroupOperation(op, nil); err != nil {
+		return err
+	}
+
+	return writeResult(ctx, u,
+		kv("email", groupEmail),
+		kv("name", groupName),
+		kv("updated", mask),
+	)
+}
+
+type GroupsDeleteCmd struct {
+	GroupEmail string `arg:"" name:"groupEmail" help:"Group email"`
+}
+
+func (c *GroupsDeleteCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupEmail := strings.TrimSpace(c.GroupEmail)
+	if groupEmail == "" {
+		return usage("group email required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete group %s", groupEmail)); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
+	if err != nil {
+		return fmt.Errorf("failed to find group %q: %w", groupEmail, wrapCloudIdentityError(err, account))
+	}
+
+	op, err := svc.Groups.Delete(groupName).Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+	if err := decodeGroupOperation(op, nil); err != nil {
+		return err
+	}
+
+	return writeResult(ctx, u,
+		kv("deleted", true),
+		kv("email", groupEmail),
+	)
+}
+
+type GroupsMembersAddCmd struct {
+	GroupEmail string `arg:"" name:"groupEmail" help:"Group email"`
+	Member     string `arg:"" name:"member" help:"Member email (user or group)"`
+	Role       string `name:"role" help:"Member role: OWNER|MANAGER|MEMBER" default:"MEMBER"`
+}
+
+func (c *GroupsMembersAddCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupEmail := strings.TrimSpace(c.GroupEmail)
+	member := strings.TrimSpace(c.Member)
+	if groupEmail == "" || member == "" {
+		return usage("group email and member required")
+	}
+
+	role, err := parseGroupRole(c.Role)
+	if err != nil {
+		return err
+	}
+
+	// Every membership holds MEMBER; OWNER and MANAGER are granted on top.
+	roles := []*cloudidentity.MembershipRole{{Name: groupRoleMember}}
+	if role != groupRoleMember {
+		roles = append(roles, &cloudidentity.MembershipRole{Name: role})
+	}
+
+	if err := dryRunExit(ctx, flags, "groups.members.add", map[string]any{
+		"group":  groupEmail,
+		"member": member,
+		"role":   role,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
+	if err != nil {
+		return fmt.Errorf("failed to find group %q: %w", groupEmail, wrapCloudIdentityError(err, account))
+	}
+
+	op, err := svc.Groups.Memberships.Create(groupName, &cloudidentity.Membership{
+		PreferredMemberKey: &cloudidentity.EntityKey{Id: member},
+		Roles:              roles,
+	}).Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+	if err := decodeGroupOperation(op, nil); err != nil {
+		return err
+	}
+
+	return writeResult(ctx, u,
+		kv("group", groupEmail),
+		kv("member", member),
+		kv("role", role),
+	)
+}
+
+type GroupsMembersRemoveCmd struct {
+	GroupEmail string `arg:"" name:"groupEmail" help:"Group email"`
+	Member     string `arg:"" name:"member" help:"Member email"`
+}
+
+func (c *GroupsMembersRemoveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupEmail := strings.TrimSpace(c.GroupEmail)
+	member := strings.TrimSpace(c.Member)
+	if groupEmail == "" || member == "" {
+		return usage("group email and member required")
+	}
+
+	if err := confirmDestructive(ctx, flags, fmt.Sprintf("remove %s from group %s", member, groupEmail)); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	membershipName, err := lookupMembership(ctx, svc, groupEmail, member)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	op, err := svc.Groups.Memberships.Delete(membershipName).Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+	if err := decodeGroupOperation(op, nil); err != nil {
+		return err
+	}
+
+	return writeResult(ctx, u,
+		kv("removed", true),
+		kv("group", groupEmail),
+		kv("member", member),
+	)
+}
+
+type GroupsMembersUpdateRoleCmd struct {
+	GroupEmail string `arg:"" name:"groupEmail" help:"Group email"`
+	Member     string `arg:"" name:"member" help:"Member email"`
+	Role       string `name:"role" required:"" help:"New role: OWNER|MANAGER|MEMBER"`
+}
+
+func (c *GroupsMembersUpdateRoleCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+	account, err := requireAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	groupEmail := strings.TrimSpace(c.GroupEmail)
+	member := strings.TrimSpace(c.Member)
+	if groupEmail == "" || member == "" {
+		return usage("group email and member required")
+	}
+
+	role, err := parseGroupRole(c.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := dryRunExit(ctx, flags, "groups.members.update-role", map[string]any{
+		"group":  groupEmail,
+		"member": member,
+		"role":   role,
+	}); err != nil {
+		return err
+	}
+
+	svc, err := newCloudIdentityManageService(ctx, account)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	membershipName, err := lookupMembership(ctx, svc, groupEmail, member)
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	current, err := svc.Groups.Memberships.Get(membershipName).Context(ctx).Do()
+	if err != nil {
+		return wrapCloudIdentityError(err, account)
+	}
+
+	req := membershipRoleChange(current.Roles, role)
+	previous := getMemberRole(current.Roles)
+
+	if len(req.AddRoles) > 0 || len(req.RemoveRoles) > 0 {
+		if _, err := svc.Groups.Memberships.ModifyMembershipRoles(membershipName, req).Context(ctx).Do(); err != nil {
+			return wrapCloudIdentityError(err, account)
+		}
+	}
+
+	return writeResult(ctx, u,
+		kv("group", groupEmail),
+		kv("member", member),
+		kv("previousRole", previous),
+		kv("role", role),
+	)
+}
+
+// membershipRoleChange computes the role edits that leave a membership with
+// exactly role (plus the MEMBER base role every membership keeps).
+func membershipRoleChange(current []*cloudidentity.MembershipRole, role string) *cloudidentity.ModifyMembershipRolesRequest {
+	have := make(map[string]bool, len(current))
+	for _, r := range current {
+		if r != nil {
+			have[r.Name] = true
+		}
+	}
+
+	req := &cloudidentity.ModifyMembershipRolesRequest{}
+	for _, name := range []string{groupRoleMember, groupRoleManager, groupRoleOwner} {
+		want := name == groupRoleMember || name == role
+		switch {
+		case want && !have[name]:
+			req.AddRoles = append(req.AddRoles, &cloudidentity.MembershipRole{Name: name})
+		case !want && have[name]:
+			req.RemoveRoles = append(req.RemoveRoles, name)
+		}
+	}
+	return req
+}
+
+// lookupMembership finds the membership resource name of member in a group.
+func lookupMembership(ctx context.Context, svc *cloudidentity.Service, groupEmail, member string) (string, error) {
+	groupName, err := lookupGroupByEmail(ctx, svc, groupEmail)
+	if err != nil {
+		return "", fmt.Errorf("failed to find group %q: %w", groupEmail, err)
+	}
+
+	resp, err := svc.Groups.Memberships.Lookup(groupName).MemberKeyId(member).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s in group %q: %w", member, groupEmail, err)
+	}
+	return resp.Name, nil
+}
+
+// decodeGroupOperation surfaces a failed long-running operation and, when
+// out is non-nil and the operation finished, decodes its response into out.
+func decodeGroupOperation(op *cloudidentity.Operation, out any) error {
+	if op == nil {
+		return nil
+	}
+	if op.Error != nil {
+		return fmt.Errorf("cloud identity operation failed: %s (code %d)", op.Error.Message, op.Error.Code)
+	}
+	if out == nil || !op.Done || len(op.Response) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(op.Response, out); err != nil {
+		return fmt.Errorf("decode operation response: %w", err)
+	}
+	return nil
+}
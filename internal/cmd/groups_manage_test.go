@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/option"
+)
+
+type cloudIdentityRequest struct {
+	method string
+	path   string
+	body   map[string]any
+}
+
+// stubCloudIdentity points both the read and manage Cloud Identity services
+// at handler and records the requests it saw.
+func stubCloudIdentity(t *testing.T, handler http.HandlerFunc) func() []cloudIdentityRequest {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		reqs []cloudIdentityRequest
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := cloudIdentityRequest{method: r.Method, path: r.URL.Path}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			_ = json.Unmarshal(b, &rec.body)
+		}
+		mu.Lock()
+		reqs = append(reqs, rec)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := cloudidentity.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+
+	origRead, origManage := newCloudIdentityService, newCloudIdentityManageService
+	t.Cleanup(func() {
+		newCloudIdentityService = origRead
+		newCloudIdentityManageService = origManage
+	})
+	newCloudIdentityService = func(context.Context, string) (*cloudidentity.Service, error) { return svc, nil }
+	newCloudIdentityManageService = func(context.Context, string) (*cloudidentity.Service, error) { return svc, nil }
+
+	return func() []cloudIdentityRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]cloudIdentityRequest(nil), reqs...)
+	}
+}
+
+func runGroups(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+
+	var (
+		err    error
+		stderr string
+	)
+	out := captureStdout(t, func() {
+		stderr = captureStderr(t, func() {
+			err = Execute(append([]string{"--json", "--account", "a@example.com", "groups"}, args...))
+		})
+	})
+	return out, stderr, err
+}
+
+func TestGroupsCreate_RequiresCustomerAndSendsLabels(t *testing.T) {
+	requests := stubCloudIdentity(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/groups" && r.Method == http.MethodPost {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"done":     true,
+				"response": map[string]any{"name": "groups/new1"},
+			})
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	if _, _, err := runGroups(t, "create", "eng@example.com"); err == nil || ExitCode(err) != 2 {
+		t.Fatalf("expected usage error without --customer, got %v", err)
+	}
+
+	out, _, err := runGroups(t, "create", "eng@example.com", "--customer", "customers/C0abc", "--description", "Engineering")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out, `"name": "groups/new1"`) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	body := reqs[0].body
+	labels, _ := body["labels"].(map[string]any)
+	if body["parent"] != "customers/C0abc" || body["displayName"] != "eng" || labels == nil {
+		t.Fatalf("unexpected create body: %#v", body)
+	}
+	if _, ok := labels[groupDiscussionForumLabel]; !ok {
+		t.Fatalf("missing discussion forum label: %#v", labels)
+	}
+}
+
+func TestGroupsMembersAdd_OwnerKeepsMemberRole(t *testing.T) {
+	requests := stubCloudIdentity(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "groups:lookup"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "groups/g1"})
+		case r.URL.Path == "/v1/groups/g1/memberships" && r.Method == http.MethodPost:
+			_ = json.NewEncoder(w).Encode(map[string]any{"done": true})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	if _, _, err := runGroups(t, "members", "add", "eng@example.com", "jane@example.com", "--role", "owner"); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var create cloudIdentityRequest
+	for _, req := range requests() {
+		if req.method == http.MethodPost {
+			create = req
+		}
+	}
+	b, _ := json.Marshal(create.body["roles"])
+	if string(b) != `[{"name":"MEMBER"},{"name":"OWNER"}]` {
+		t.Fatalf("unexpected roles: %s", b)
+	}
+}
+
+func TestMembershipRoleChange(t *testing.T) {
+	roles := func(names ...string) []*cloudidentity.MembershipRole {
+		out := make([]*cloudidentity.MembershipRole, 0, len(names))
+		for _, n := range names {
+			out = append(out, &cloudidentity.MembershipRole{Name: n})
+		}
+		return out
+	}
+
+	req := membershipRoleChange(roles("MEMBER", "OWNER"), "MANAGER")
+	if len(req.AddRoles) != 1 || req.AddRoles[0].Name != "MANAGER" {
+		t.Fatalf("unexpected add roles: %#v", req.AddRoles)
+	}
+	if strings.Join(req.RemoveRoles, ",") != "OWNER" {
+		t.Fatalf("unexpected remove roles: %v", req.RemoveRoles)
+	}
+
+	req = membershipRoleChange(roles("MEMBER", "MANAGER"), "MEMBER")
+	if len(req.AddRoles) != 0 || strings.Join(req.RemoveRoles, ",") != "MANAGER" {
+		t.Fatalf("unexpected demotion: %#v", req)
+	}
+
+	req = membershipRoleChange(roles("MEMBER"), "MEMBER")
+	if len(req.AddRoles) != 0 || len(req.RemoveRoles) != 0 {
+		t.Fatalf("expected no change: %#v", req)
+	}
+}
+
+func TestGroupsMembersTransitive_DetectsCycles(t *testing.T) {
+	members := map[string][]map[string]any{
+		"ga": {
+			{"preferredMemberKey": map[string]any{"id": "group-b@example.com"}, "type": "GROUP"},
+			{"preferredMemberKey": map[string]any{"id": "group-c@example.com"}, "type": "GROUP"},
+			{"preferredMemberKey": map[string]any{"id": "alice@example.com"}, "type": "USER", "roles": []map[string]any{{"name": "MEMBER"}, {"name": "OWNER"}}},
+		},
+		"gb": {
+			{"preferredMemberKey": map[string]any{"id": "group-a@example.com"}, "type": "GROUP"},
+			{"preferredMemberKey": map[string]any{"id": "group-c@example.com"}, "type": "GROUP"},
+			{"preferredMemberKey": map[string]any{"id": "bob@example.com"}, "type": "USER"},
+		},
+		"gc": {
+			{"preferredMemberKey": map[string]any{"id": "carol@example.com"}, "type": "USER"},
+			{"preferredMemberKey": map[string]any{"id": "alice@example.com"}, "type": "USER"},
+		},
+	}
+
+	lookups := 0
+	stubCloudIdentity(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "groups:lookup") {
+			lookups++
+			id := r.URL.Query().Get("groupKey.id")
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "groups/g" + id[len("group-"):len("group-")+1]})
+			return
+		}
+		for name, ms := range members {
+			if r.URL.Path == "/v1/groups/"+name+"/memberships" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"memberships": ms})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+
+	out, stderr, err := runGroups(t, "members", "group-a@example.com", "--transitive")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var parsed struct {
+		Members []groupMember `json:"members"`
+		Groups  []string      `json:"groups"`
+		Cycles  [][]string    `json:"cycles"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+
+	got := make([]string, 0, len(parsed.Members))
+	for _, m := range parsed.Members {
+		got = append(got, m.Email+"|"+m.Role+"|"+m.Via)
+	}
+	want := "alice@example.com|OWNER|,bob@example.com|MEMBER|group-b@example.com,carol@example.com|MEMBER|group-c@example.com"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("members = %v\nwant %s", got, want)
+	}
+	if strings.Join(parsed.Groups, ",") != "group-b@example.com,group-c@example.com" {
+		t.Fatalf("unexpected groups: %v", parsed.Groups)
+	}
+	if len(parsed.Cycles) != 1 || strings.Join(parsed.Cycles[0], ">") != "group-a@example.com>group-b@example.com>group-a@example.com" {
+		t.Fatalf("unexpected cycles: %v", parsed.Cycles)
+	}
+	if !strings.Contains(stderr, "group cycle group-a@example.com -> group-b@example.com -> group-a@example.com") {
+		t.Fatalf("expected cycle warning, got %q", stderr)
+	}
+	// group-c is reachable twice but expanded once.
+	if lookups != 3 {
+		t.Fatalf("expected 3 group lookups, got %d", lookups)
+	}
+}
+
+func TestGroupsWriteCommands_ReadOnly(t *testing.T) {
+	stubCloudIdentity(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	})
+
+	for _, args := range [][]string{
+		{"create", "eng@example.com", "--customer", "C0abc"},
+		{"delete", "eng@example.com"},
+		{"members", "add", "eng@example.com", "jane@example.com"},
+		{"members", "update-role", "eng@example.com", "jane@example.com", "--role", "OWNER"},
+	} {
+		_, _, err := runGroups(t, append([]string{"--read-only"}, args...)...)
+		if err == nil || !strings.Contains(err.Error(), "read-only mode") {
+			t.Fatalf("%v: expected read-only rejection, got %v", args, err)
+		}
+	}
+}
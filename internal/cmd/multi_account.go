@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+// Multi-account fan-out.
+//
+// `--account a,b,c` (emails or aliases) or `--account @all` (every stored
+// token) runs a read command once per account and merges the results, tagged
+// with "account". Lists are merged into one and ordered by time; an account
+// that fails is reported under "errors" without failing the run.
+
+const allAccountsSpec = "@all"
+
+// isMultiAccount reports whether an --account value names several accounts.
+func isMultiAccount(value string) bool {
+	value = strings.TrimSpace(value)
+
+	return strings.EqualFold(value, allAccountsSpec) || strings.Contains(value, ",")
+}
+
+func runMultiAccount(ctx context.Context, kctx *kong.Context, args []string, flags *RootFlags) error {
+	f := fanOut{flag: "--account", field: "account", noun: "accounts", concurrency: flags.Concurrency, resultsOnly: true, sortByTime: true}
+	if err := f.check(kctx); err != nil {
+		return err
+	}
+
+	accounts, err := resolveMultiAccount(flags)
+	if err != nil {
+		return err
+	}
+
+	return f.run(ctx, args, accounts)
+}
+
+func resolveMultiAccount(flags *RootFlags) ([]string, error) {
+	value := strings.TrimSpace(flags.Account)
+
+	var names []string
+
+	if strings.EqualFold(value, allAccountsSpec) {
+		stored, err := storedAccountEmails(flags.Client)
+		if err != nil {
+			return nil, err
+		}
+
+		names = stored
+	} else {
+		names = splitCommaList(value)
+	}
+
+	seen := make(map[string]bool, len(names))
+	accounts := make([]string, 0, len(names))
+
+	for _, name := range names {
+		email := name
+		if resolved, ok, err := resolveAccountAlias(name); err != nil {
+			return nil, err
+		} else if ok {
+			email = resolved
+		} else if !strings.Contains(name, "@") {
+			return nil, usagef("unknown account %q in --account (use emails or aliases)", name)
+		}
+
+		key := strings.ToLower(email)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		accounts = append(accounts, email)
+	}
+
+	if len(accounts) == 0 {
+		return nil, usagef("--account %q matched no accounts", value)
+	}
+
+	return accounts, nil
+}
+
+// storedAccountEmails lists the accounts with a stored token, limited to the
+// --client bucket when one is set.
+func storedAccountEmails(client string) ([]string, error) {
+	store, err := openSecretsStoreForAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := store.ListTokens()
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	if client = strings.TrimSpace(client); client != "" {
+		if client, err = config.NormalizeClientName(client); err != nil {
+			return nil, err
+		}
+	}
+
+	emails := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		email := strings.TrimSpace(tok.Email)
+		if email == "" || (client != "" && tok.Client != client) {
+			continue
+		}
+
+		emails = append(emails, email)
+	}
+
+	sort.Strings(emails)
+
+	return emails, nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/automagik-dev/workit/internal/secrets"
+)
+
+func TestMultiAccount_MergesAndTagsResults(t *testing.T) {
+	seen := setupForEachUser(t, map[string]bool{"bad@example.com": true})
+
+	var runErr error
+
+	out := captureStdout(t, func() {
+		runErr = Execute([]string{"--account", "a@example.com, bad@example.com,b@example.com", "calendar", "users"})
+	})
+	if runErr != nil {
+		t.Fatalf("partial failure should not fail the run: %v", runErr)
+	}
+
+	if got := seen(); strings.Join(got, ",") != "a@example.com,b@example.com,bad@example.com" {
+		t.Fatalf("ran as %v", got)
+	}
+
+	var parsed struct {
+		Results  []map[string]any `json:"results"`
+		Errors   []map[string]any `json:"errors"`
+		Accounts int              `json:"accounts"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("json parse: %v\n%s", err, out)
+	}
+
+	if parsed.Accounts != 3 || len(parsed.Results) != 2 || len(parsed.Errors) != 1 || parsed.Errors[0]["account"] != "bad@example.com" {
+		t.Fatalf("unexpected merge: %s", out)
+	}
+
+	// Lists are unwrapped, so each item carries its account.
+	if parsed.Results[0]["email"] != "colleague-of-a@example.com" || parsed.Results[0]["account"] != "a@example.com" {
+		t.Fatalf("unexpected item: %v", parsed.Results[0])
+	}
+}
+
+func TestMultiAccount_AllFailed(t *testing.T) {
+	setupForEachUser(t, map[string]bool{"a@example.com": true, "b@example.com": true})
+
+	var runErr error
+
+	_ = captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			runErr = Execute([]string{"--account", "a@example.com,b@example.com", "calendar", "users"})
+		})
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "all 2 accounts failed") {
+		t.Fatalf("expected failure, got %v", runErr)
+	}
+}
+
+func TestResolveMultiAccount_All(t *testing.T) {
+	orig := openSecretsStoreForAccount
+	t.Cleanup(func() { openSecretsStoreForAccount = orig })
+
+	store := newMemSecretsStore()
+	for _, tok := range []secrets.Token{
+		{Client: "default", Email: "work@example.com", RefreshToken: "rt"},
+		{Client: "default", Email: "home@example.com", RefreshToken: "rt"},
+		{Client: "acme", Email: "me@acme.com", RefreshToken: "rt"},
+		{Client: "acme", Email: "work@example.com", RefreshToken: "rt"},
+	} {
+		if err := store.SetToken(tok.Client, tok.Email, tok); err != nil {
+			t.Fatalf("SetToken: %v", err)
+		}
+	}
+	openSecretsStoreForAccount = func() (secrets.Store, error) { return store, nil }
+
+	got, err := resolveMultiAccount(&RootFlags{Account: "@all"})
+	if err != nil {
+		t.Fatalf("resolveMultiAccount: %v", err)
+	}
+
+	if strings.Join(got, ",") != "home@example.com,me@acme.com,work@example.com" {
+		t.Fatalf("@all = %v", got)
+	}
+
+	got, err = resolveMultiAccount(&RootFlags{Account: "@all", Client: "acme"})
+	if err != nil {
+		t.Fatalf("resolveMultiAccount: %v", err)
+	}
+
+	if strings.Join(got, ",") != "me@acme.com,work@example.com" {
+		t.Fatalf("@all --client acme = %v", got)
+	}
+}
+
+func TestSortItemsByTime(t *testing.T) {
+	items := []any{
+		map[string]any{"id": "old", "date": "2026-01-02 10:00"},
+		map[string]any{"id": "none"},
+		map[string]any{"id": "new", "date": "2026-03-01 09:30"},
+	}
+	sortItemsByTime(items)
+
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		ids = append(ids, it.(map[string]any)["id"].(string))
+	}
+
+	if strings.Join(ids, ",") != "new,old,none" {
+		t.Fatalf("mail order = %v", ids)
+	}
+
+	events := []any{
+		map[string]any{"id": "later", "start": map[string]any{"dateTime": "2026-05-02T10:00:00Z"}},
+		map[string]any{"id": "sooner", "start": map[string]any{"dateTime": "2026-05-01T10:00:00+02:00"}},
+	}
+	sortItemsByTime(events)
+
+	if events[0].(map[string]any)["id"] != "sooner" {
+		t.Fatalf("events should sort oldest first: %v", events)
+	}
+}
@@ -51,6 +51,9 @@ var writeCommands = map[string]map[string]bool{
 	"appscript": {
 		"run": true, "create": true,
 	},
+	"groups": {
+		"create": true, "update": true, "delete": true,
+	},
 }
 
 // writeDesirePaths are top-level desire paths that are write operations.
@@ -79,6 +82,8 @@ var nestedWriteVerbs = map[string]bool{
 	"share": true, "unshare": true,
 	// State-change verbs
 	"archive": true, "unarchive": true,
+	"suspend": true, "unsuspend": true,
+	"assign": true, "unassign": true, "update-role": true,
 	"publish": true, "unpublish": true,
 	"submit": true, "accept": true, "decline": true,
 	"join": true, "leave": true,
@@ -99,7 +104,19 @@ func enforceReadOnly(kctx *kong.Context, readOnly bool) error {
 		return nil
 	}
 
-	cmd := strings.Fields(kctx.Command())
+	return writeCommandError(kctx.Command())
+}
+
+// isWriteCommand reports whether a command path (as returned by
+// kong.Context.Command) is a write operation.
+func isWriteCommand(command string) bool {
+	return writeCommandError(command) != nil
+}
+
+// writeCommandError returns the read-only mode error for a write command
+// path, or nil for a read-only one.
+func writeCommandError(command string) error {
+	cmd := strings.Fields(command)
 	if len(cmd) == 0 {
 		return nil
 	}
@@ -319,3 +319,21 @@ func TestEnforceReadOnly_ErrorIsExitCode2(t *testing.T) {
 		t.Fatalf("expected exit code 2, got %d", code)
 	}
 }
+
+func TestIsWriteCommand(t *testing.T) {
+	cases := map[string]bool{
+		"gmail send":                 true,
+		"send":                       true,
+		"drive upload <localPath>":   true,
+		"chat messages send <space>": true,
+		"drive ls":                   false,
+		"gmail get <messageId>":      false,
+		"calendar events <id>":       false,
+		"":                           false,
+	}
+	for cmd, want := range cases {
+		if got := isWriteCommand(cmd); got != want {
+			t.Errorf("isWriteCommand(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
@@ -28,24 +28,27 @@ const (
 )
 
 type RootFlags struct {
-	Color          string `help:"Color output: auto|always|never" default:"${color}"`
-	Account        string `help:"Account email for API commands (gmail/calendar/chat/classroom/drive/docs/slides/contacts/tasks/people/sheets/forms/appscript)" aliases:"acct" short:"a"`
-	Client         string `help:"OAuth client name (selects stored credentials + token bucket)" default:"${client}"`
-	EnableCommands string `help:"Comma-separated list of enabled top-level commands (restricts CLI)" default:"${enabled_commands}"`
-	CommandTier    string `name:"command-tier" help:"Command visibility tier: core|extended|complete (default: complete)" default:"${command_tier}" enum:"core,extended,complete"`
-	JSON           bool   `help:"Output JSON to stdout (best for scripting)" default:"${json}" aliases:"machine" short:"j"`
-	Plain          bool   `help:"Output stable, parseable text to stdout (TSV; no colors)" default:"${plain}" aliases:"tsv" short:"p"`
-	ResultsOnly    bool   `name:"results-only" help:"In JSON mode, emit only the primary result (drops envelope fields like nextPageToken)"`
-	Select         string `name:"select" aliases:"pick,project" help:"In JSON mode, select comma-separated fields (best-effort; supports dot paths). Desire path: use --fields for most commands."`
-	JQ             string `name:"jq" help:"Apply jq expression to JSON output"`
-	MaxResults     int    `name:"max-results" help:"Maximum number of results to return (maps to pageSize/maxResults per service)" default:"0"`
-	PageToken      string `name:"page-token" help:"Page token for pagination (maps to pageToken per service)"`
-	GenerateInput  bool   `name:"generate-input" help:"Print JSON input template for the command and exit" aliases:"gen-input"`
-	DryRun         bool   `help:"Do not make changes; print intended actions and exit successfully" aliases:"noop,preview,dryrun" short:"n"`
-	Force          bool   `help:"Skip confirmations for destructive commands" aliases:"yes,assume-yes" short:"y"`
-	ReadOnly       bool   `name:"read-only" help:"Hide write commands and request read-only OAuth scopes" default:"${read_only}"`
-	NoInput        bool   `help:"Never prompt; fail instead (useful for CI)" aliases:"non-interactive,noninteractive"`
-	Verbose        bool   `help:"Enable verbose logging" short:"v"`
+	Color             string `help:"Color output: auto|always|never" default:"${color}"`
+	Account           string `help:"Account email for API commands (gmail/calendar/chat/classroom/drive/docs/slides/contacts/tasks/people/sheets/forms/appscript); a,b,c or @all runs a read command for each account and merges the results" aliases:"acct" short:"a"`
+	Client            string `help:"OAuth client name (selects stored credentials + token bucket)" default:"${client}"`
+	EnableCommands    string `help:"Comma-separated list of enabled top-level commands (restricts CLI)" default:"${enabled_commands}"`
+	CommandTier       string `name:"command-tier" help:"Command visibility tier: core|extended|complete (default: complete)" default:"${command_tier}" enum:"core,extended,complete"`
+	JSON              bool   `help:"Output JSON to stdout (best for scripting)" default:"${json}" aliases:"machine" short:"j"`
+	Plain             bool   `help:"Output stable, parseable text to stdout (TSV; no colors)" default:"${plain}" aliases:"tsv" short:"p"`
+	ResultsOnly       bool   `name:"results-only" help:"In JSON mode, emit only the primary result (drops envelope fields like nextPageToken)"`
+	Select            string `name:"select" aliases:"pick,project" help:"In JSON mode, select comma-separated fields (best-effort; supports dot paths). Desire path: use --fields for most commands."`
+	JQ                string `name:"jq" help:"Apply jq expression to JSON output"`
+	MaxResults        int    `name:"max-results" help:"Maximum number of results to return (maps to pageSize/maxResults per service)" default:"0"`
+	PageToken         string `name:"page-token" help:"Page token for pagination (maps to pageToken per service)"`
+	GenerateInput     bool   `name:"generate-input" help:"Print JSON input template for the command and exit" aliases:"gen-input"`
+	DryRun            bool   `help:"Do not make changes; print intended actions and exit successfully" aliases:"noop,preview,dryrun" short:"n"`
+	Force             bool   `help:"Skip confirmations for destructive commands" aliases:"yes,assume-yes" short:"y"`
+	ReadOnly          bool   `name:"read-only" help:"Hide write commands and request read-only OAuth scopes" default:"${read_only}"`
+	NoInput           bool   `help:"Never prompt; fail instead (useful for CI)" aliases:"non-interactive,noninteractive"`
+	AutoUpgradeScopes bool   `name:"auto-upgrade-scopes" help:"When a command fails for missing OAuth scopes, run incremental authorization for that service and retry" default:"${auto_upgrade_scopes}"`
+	Verbose           bool   `help:"Enable verbose logging" short:"v"`
+	ForEachUser       string `name:"for-each-user" help:"Run a read command for every matching Workspace user via the --account's domain-wide service account: a Directory query (e.g. orgUnit=/Sales, or all) or @file of emails; results are merged as JSON with a user field"`
+	Concurrency       int    `name:"concurrency" help:"Maximum parallel runs for --for-each-user or several --account values" default:"4"`
 }
 
 type CLI struct {
@@ -68,6 +71,7 @@ type CLI struct {
 
 	Auth       AuthCmd               `cmd:"" help:"Auth and credentials"`
 	Groups     GroupsCmd             `cmd:"" aliases:"group" help:"Google Groups"`
+	Admin      AdminCmd              `cmd:"" help:"Google Workspace Admin (Directory: users, org units, groups, roles)"`
 	Drive      DriveCmd              `cmd:"" aliases:"drv" help:"Google Drive"`
 	Docs       DocsCmd               `cmd:"" aliases:"doc" help:"Google Docs (export via Drive)"`
 	Docx       DocxCmd               `cmd:"" help:"DOCX document operations (local files)"`
@@ -184,6 +188,25 @@ func Execute(args []string) (err error) {
 		cli.JSON = true
 	}
 
+	// Fan-out (--for-each-user, or several accounts in --account) merges the
+	// per-account results into one JSON document.
+	multiAccount := isMultiAccount(cli.Account)
+	if cli.ForEachUser != "" || multiAccount {
+		fanOutFlag := "--for-each-user"
+		if multiAccount {
+			fanOutFlag = "--account " + strings.TrimSpace(cli.Account)
+		}
+		if cli.ForEachUser != "" && multiAccount {
+			_, _ = fmt.Fprintln(os.Stderr, "error: --for-each-user takes a single admin --account")
+			return &ExitError{Code: 2, Err: errors.New("--for-each-user takes a single admin --account")}
+		}
+		if cli.Plain {
+			_, _ = fmt.Fprintf(os.Stderr, "error: %s requires --json output (incompatible with --plain)\n", fanOutFlag)
+			return &ExitError{Code: 2, Err: fmt.Errorf("%s requires --json output", fanOutFlag)}
+		}
+		cli.JSON = true
+	}
+
 	logLevel := slog.LevelWarn
 	if cli.Verbose {
 		logLevel = slog.LevelDebug
@@ -233,7 +256,21 @@ func Execute(args []string) (err error) {
 	kctx.BindTo(ctx, (*context.Context)(nil))
 	kctx.Bind(&cli.RootFlags)
 
-	err = kctx.Run()
+	switch {
+	case cli.ForEachUser != "":
+		err = runForEachUser(ctx, kctx, args, &cli.RootFlags)
+	case multiAccount:
+		err = runMultiAccount(ctx, kctx, args, &cli.RootFlags)
+	default:
+		if err = kctx.Run(); err != nil {
+			// A 403 for missing OAuth scopes can be fixed by incremental
+			// authorization; when that succeeds, retry read-only commands once.
+			var retry bool
+			if retry, err = resolveScopeUpgrade(ctx, &cli.RootFlags, kctx.Command(), err); retry {
+				err = kctx.Run()
+			}
+		}
+	}
 	if err == nil {
 		return nil
 	}
@@ -321,7 +358,7 @@ func isCalendarEventsCommand(args []string) bool {
 func globalFlagTakesValue(flag string) bool {
 	switch flag {
 	case "--color", "--account", "--acct", "--client", "--enable-commands", "--command-tier", "--select", "--pick", "--project", "--jq", "-a",
-		"--max-results", "--page-token":
+		"--max-results", "--page-token", "--for-each-user", "--concurrency":
 		return true
 	default:
 		return false
@@ -363,16 +400,17 @@ func boolString(v bool) string {
 func newParser(description string) (*kong.Kong, *CLI, error) {
 	envMode := outfmt.FromEnv()
 	vars := kong.Vars{
-		"auth_services":    googleauth.UserServiceCSV(),
-		"color":            envOr("WK_COLOR", "auto"),
-		"calendar_weekday": envOr("WK_CALENDAR_WEEKDAY", "false"),
-		"client":           envOr("WK_CLIENT", ""),
-		"enabled_commands": envOr("WK_ENABLE_COMMANDS", ""),
-		"command_tier":     envOr("WK_COMMAND_TIER", "complete"),
-		"read_only":        boolString(envBool("WK_READ_ONLY")),
-		"json":             boolString(envMode.JSON),
-		"plain":            boolString(envMode.Plain),
-		"version":          VersionString(),
+		"auth_services":       googleauth.UserServiceCSV(),
+		"color":               envOr("WK_COLOR", "auto"),
+		"calendar_weekday":    envOr("WK_CALENDAR_WEEKDAY", "false"),
+		"client":              envOr("WK_CLIENT", ""),
+		"enabled_commands":    envOr("WK_ENABLE_COMMANDS", ""),
+		"command_tier":        envOr("WK_COMMAND_TIER", "complete"),
+		"read_only":           boolString(envBool("WK_READ_ONLY")),
+		"auto_upgrade_scopes": boolString(envBool("WK_AUTO_UPGRADE_SCOPES")),
+		"json":                boolString(envMode.JSON),
+		"plain":               boolString(envMode.Plain),
+		"version":             VersionString(),
 	}
 
 	cli := &CLI{}
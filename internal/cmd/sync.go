@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,19 +18,40 @@ import (
 
 // SyncCmd is the top-level command for Drive sync operations.
 type SyncCmd struct {
-	Init   SyncInitCmd   `cmd:"" help:"Initialize sync between a local folder and Drive folder"`
-	List   SyncListCmd   `cmd:"" help:"List all sync configurations"`
-	Remove SyncRemoveCmd `cmd:"" help:"Remove a sync configuration"`
-	Status SyncStatusCmd `cmd:"" help:"Show sync status for all configurations"`
-	Start  SyncStartCmd  `cmd:"" help:"Start sync daemon"`
-	Stop   SyncStopCmd   `cmd:"" help:"Stop sync daemon"`
+	Init      SyncInitCmd      `cmd:"" help:"Initialize sync between a local folder and Drive folder"`
+	List      SyncListCmd      `cmd:"" help:"List all sync configurations"`
+	Remove    SyncRemoveCmd    `cmd:"" help:"Remove a sync configuration"`
+	Status    SyncStatusCmd    `cmd:"" help:"Show sync status for all configurations"`
+	Start     SyncStartCmd     `cmd:"" help:"Start sync daemon"`
+	Stop      SyncStopCmd      `cmd:"" help:"Stop sync daemon"`
+	Plan      SyncPlanCmd      `cmd:"" help:"Print the actions a one-shot sync would take, as JSON"`
+	Push      SyncPushCmd      `cmd:"" help:"Apply local changes to Drive once (uploads and remote deletes)"`
+	Pull      SyncPullCmd      `cmd:"" help:"Apply Drive changes locally once (downloads and local deletes)"`
+	Run       SyncRunCmd       `cmd:"" help:"Sync both ways once with --once, resolving conflicts"`
+	Resume    SyncResumeCmd    `cmd:"" help:"Resume a sync paused by mass-deletion protection, applying or restoring the held deletions"`
+	Conflicts SyncConflictsCmd `cmd:"" help:"List and resolve files changed both locally and on Drive"`
+	Trash     SyncTrashCmd     `cmd:"" help:"List and restore files deleted on Drive (kept in .wk-trash)"`
+	ExportKey SyncExportKeyCmd `cmd:"" name:"export-key" help:"Print the encryption key of an encrypted sync, to back it up or use it on another machine"`
+	Service   SyncServiceCmd   `cmd:"" name:"install-service" help:"Print or install a systemd user unit that runs 'sync start --all'"`
 }
 
 // SyncInitCmd initializes a new sync configuration.
 type SyncInitCmd struct {
-	LocalPath   string `arg:"" name:"local-path" help:"Local directory path to sync"`
-	DriveFolder string `name:"drive-folder" required:"" help:"Drive folder name or ID"`
-	DriveID     string `name:"drive-id" help:"Shared drive ID (optional)"`
+	LocalPath        string   `arg:"" name:"local-path" help:"Local directory path to sync"`
+	DriveFolder      string   `name:"drive-folder" required:"" help:"Drive folder name or ID"`
+	DriveID          string   `name:"drive-id" help:"Shared drive ID (optional)"`
+	Exclude          []string `name:"exclude" help:"Ignore paths matching this .wkignore-style pattern (can be repeated)"`
+	Include          []string `name:"include" help:"Sync paths matching this pattern even if otherwise ignored (can be repeated)"`
+	NativeDocs       string   `name:"native-docs" help:"How to sync Google Docs/Sheets/Slides: skip|office (.docx/.xlsx/.pptx)|text (.md/.csv)|link (read-only stubs)" default:"skip"`
+	MaxDeletes       int      `name:"max-deletes" help:"Pause sync when more than this many files would be deleted within a minute (0 disables)" default:"50"`
+	MaxDeletePercent int      `name:"max-delete-percent" help:"Pause sync when more than this percentage of tracked files would be deleted within a minute (0 disables)" default:"0"`
+	TrashDays        int      `name:"trash-days" help:"Days to keep files deleted on Drive in the local .wk-trash folder (0 keeps them)" default:"30"`
+	Workers          int      `name:"workers" help:"Number of files to upload or download at once" default:"4"`
+	UploadLimit      string   `name:"upload-limit" help:"Cap upload bandwidth in bytes per second, e.g. 512K or 2M (0 is unlimited)" default:"0"`
+	DownloadLimit    string   `name:"download-limit" help:"Cap download bandwidth in bytes per second, e.g. 512K or 2M (0 is unlimited)" default:"0"`
+	Encrypt          bool     `name:"encrypt" help:"Encrypt file contents on Drive with a key kept in the secrets store"`
+	EncryptNames     bool     `name:"encrypt-names" help:"Encrypt file and folder names on Drive too (implies --encrypt)"`
+	EncryptionKey    string   `name:"encryption-key" help:"Use the age secret key in this file instead of generating one (e.g. from 'wk sync export-key' on another machine)"`
 }
 
 func (c *SyncInitCmd) Run(ctx context.Context, flags *RootFlags) error {
@@ -66,6 +88,44 @@ func (c *SyncInitCmd) Run(ctx context.Context, flags *RootFlags) error {
 	}
 	driveFolder = resolved
 
+	ignorePatterns := syncIgnorePatterns(c.Exclude, c.Include)
+	if _, err := sync.NewIgnoreRules("", ignorePatterns); err != nil {
+		return usage(err.Error())
+	}
+
+	nativeDocs, err := sync.ParseNativeDocsMode(c.NativeDocs)
+	if err != nil {
+		return usage(err.Error())
+	}
+
+	if c.MaxDeletes < 0 || c.TrashDays < 0 {
+		return usage("--max-deletes and --trash-days must not be negative")
+	}
+	if c.MaxDeletePercent < 0 || c.MaxDeletePercent > 100 {
+		return usage("--max-delete-percent must be between 0 and 100")
+	}
+
+	if c.Workers < 1 {
+		return usage("--workers must be at least 1")
+	}
+	uploadLimit, err := sync.ParseRate(c.UploadLimit)
+	if err != nil {
+		return usage("--upload-limit: " + err.Error())
+	}
+	downloadLimit, err := sync.ParseRate(c.DownloadLimit)
+	if err != nil {
+		return usage("--download-limit: " + err.Error())
+	}
+
+	encrypt := c.Encrypt || c.EncryptNames
+	keyFile := strings.TrimSpace(c.EncryptionKey)
+	if keyFile != "" && !encrypt {
+		return usage("--encryption-key needs --encrypt")
+	}
+	if encrypt && nativeDocs != sync.NativeSkip {
+		return usage("Google Docs/Sheets/Slides cannot be encrypted; --encrypt needs --native-docs=skip")
+	}
+
 	db, err := sync.OpenDB()
 	if err != nil {
 		return fmt.Errorf("open sync database: %w", err)
@@ -81,16 +141,78 @@ func (c *SyncInitCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return fmt.Errorf("sync config already exists for path: %s", existing.LocalPath)
 	}
 
-	cfg, err := db.CreateConfig(localPath, driveFolder, driveID)
+	// The key is stored before the config, which cannot sync without it.
+	var cipher *sync.Cipher
+	var keyGenerated bool
+	if encrypt {
+		cipher, keyGenerated, err = syncEncryptionKey(driveFolder, keyFile, c.EncryptNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	var cfg *sync.SyncConfig
+	if encrypt {
+		cfg, err = db.CreateEncryptedConfig(localPath, driveFolder, driveID, c.EncryptNames)
+	} else {
+		cfg, err = db.CreateConfig(localPath, driveFolder, driveID)
+	}
 	if err != nil {
 		return fmt.Errorf("create sync config: %w", err)
 	}
+	// A config left half-configured would sync with the wrong settings.
+	saved := false
+	defer func() {
+		if !saved {
+			_ = db.RemoveConfig(cfg.LocalPath)
+		}
+	}()
+	if len(ignorePatterns) > 0 {
+		if err := db.SetIgnorePatterns(cfg.ID, ignorePatterns); err != nil {
+			return fmt.Errorf("save ignore patterns: %w", err)
+		}
+		cfg.IgnorePatterns = ignorePatterns
+	}
+	if account := strings.TrimSpace(flags.Account); account != "" {
+		if err := db.SetConfigAccount(cfg.ID, account); err != nil {
+			return fmt.Errorf("save account: %w", err)
+		}
+		cfg.Account = account
+	}
+	if nativeDocs != sync.NativeSkip {
+		if err := db.SetNativeDocs(cfg.ID, nativeDocs); err != nil {
+			return fmt.Errorf("save native docs mode: %w", err)
+		}
+		cfg.NativeDocs = nativeDocs
+	}
+	if c.MaxDeletes != cfg.MaxDeletes || c.MaxDeletePercent != cfg.MaxDeletePercent || c.TrashDays != cfg.TrashDays {
+		if err := db.SetDeleteGuard(cfg.ID, c.MaxDeletes, c.MaxDeletePercent, c.TrashDays); err != nil {
+			return fmt.Errorf("save delete limits: %w", err)
+		}
+		cfg.MaxDeletes, cfg.MaxDeletePercent, cfg.TrashDays = c.MaxDeletes, c.MaxDeletePercent, c.TrashDays
+	}
+	if c.Workers != cfg.TransferWorkers || uploadLimit != 0 || downloadLimit != 0 {
+		if err := db.SetTransferLimits(cfg.ID, c.Workers, uploadLimit, downloadLimit); err != nil {
+			return fmt.Errorf("save transfer limits: %w", err)
+		}
+		cfg.TransferWorkers, cfg.UploadLimit, cfg.DownloadLimit = c.Workers, uploadLimit, downloadLimit
+	}
+	saved = true
+
+	if keyGenerated {
+		u.Err().Printf("Generated an encryption key. Back it up with 'wk sync export-key %s': files on Drive cannot be decrypted without it.", cfg.LocalPath)
+	}
 
 	if outfmt.IsJSON(ctx) {
-		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+		result := map[string]any{
 			"config":  cfg,
 			"created": true,
-		})
+		}
+		if cipher != nil {
+			result["recipient"] = cipher.Recipient()
+			result["key_generated"] = keyGenerated
+		}
+		return outfmt.WriteJSON(ctx, os.Stdout, result)
 	}
 
 	u.Out().Printf("created\ttrue")
@@ -100,9 +222,147 @@ func (c *SyncInitCmd) Run(ctx context.Context, flags *RootFlags) error {
 	if cfg.DriveID != "" {
 		u.Out().Printf("drive_id\t%s", cfg.DriveID)
 	}
+	if cfg.Account != "" {
+		u.Out().Printf("account\t%s", cfg.Account)
+	}
+	for _, p := range cfg.IgnorePatterns {
+		u.Out().Printf("ignore\t%s", p)
+	}
+	if cfg.NativeDocs != "" {
+		u.Out().Printf("native_docs\t%s", cfg.NativeDocs)
+	}
+	u.Out().Printf("max_deletes\t%d", cfg.MaxDeletes)
+	if cfg.MaxDeletePercent > 0 {
+		u.Out().Printf("max_delete_percent\t%d", cfg.MaxDeletePercent)
+	}
+	u.Out().Printf("trash_days\t%d", cfg.TrashDays)
+	u.Out().Printf("workers\t%d", cfg.TransferWorkers)
+	if cfg.UploadLimit > 0 {
+		u.Out().Printf("upload_limit\t%d", cfg.UploadLimit)
+	}
+	if cfg.DownloadLimit > 0 {
+		u.Out().Printf("download_limit\t%d", cfg.DownloadLimit)
+	}
+	if cipher != nil {
+		u.Out().Printf("encrypted\ttrue")
+		u.Out().Printf("encrypt_names\t%t", cfg.EncryptNames)
+		u.Out().Printf("recipient\t%s", cipher.Recipient())
+	}
 	return nil
 }
 
+// syncEncryptionKey returns the cipher for a new encrypted sync of
+// driveFolderID. The key is read from keyFile if given, else the one already
+// stored for the folder is reused, else a new one is generated; it is saved
+// to the secrets store, and generated reports whether it is new.
+func syncEncryptionKey(driveFolderID, keyFile string, encryptNames bool) (cipher *sync.Cipher, generated bool, err error) {
+	stored, err := sync.LoadEncryptionKey(driveFolderID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := stored
+	switch {
+	case keyFile != "":
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("open encryption key: %w", err)
+		}
+		defer f.Close()
+
+		if key, err = sync.ParseEncryptionKey(f); err != nil {
+			return nil, false, err
+		}
+		if stored != "" && stored != key {
+			return nil, false, fmt.Errorf("a different encryption key is already stored for Drive folder %s (%s)", driveFolderID, sync.EncryptionKeySecret(driveFolderID))
+		}
+	case key == "":
+		if key, err = sync.GenerateEncryptionKey(); err != nil {
+			return nil, false, err
+		}
+		generated = true
+	}
+
+	if key != stored {
+		if err := sync.SaveEncryptionKey(driveFolderID, key); err != nil {
+			return nil, false, err
+		}
+	}
+
+	cipher, err = sync.NewCipher(key, encryptNames)
+	if err != nil {
+		return nil, false, err
+	}
+	return cipher, generated, nil
+}
+
+// SyncExportKeyCmd prints the encryption key of an encrypted sync.
+type SyncExportKeyCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local directory path of an encrypted sync"`
+}
+
+func (c *SyncExportKeyCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	localPath := strings.TrimSpace(c.LocalPath)
+	if localPath == "" {
+		return usage("empty local-path")
+	}
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	cfg, err := db.GetConfig(localPath)
+	if err != nil {
+		return fmt.Errorf("get sync config: %w", err)
+	}
+	if cfg == nil {
+		return fmt.Errorf("sync config not found: %s", localPath)
+	}
+	if !cfg.Encrypted {
+		return fmt.Errorf("sync for %s is not encrypted", cfg.LocalPath)
+	}
+
+	cipher, err := sync.LoadCipher(cfg)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"local_path": cfg.LocalPath,
+			"recipient":  cipher.Recipient(),
+			"key":        cipher.Key(),
+		})
+	}
+
+	// The age identity file format, readable by --encryption-key and age -d.
+	u.Out().Printf("# sync: %s", cfg.LocalPath)
+	u.Out().Printf("# public key: %s", cipher.Recipient())
+	u.Out().Println(cipher.Key())
+	return nil
+}
+
+// syncIgnorePatterns turns --exclude/--include flags into .wkignore lines.
+// Includes come last so they override the excludes and the defaults.
+func syncIgnorePatterns(exclude, include []string) []string {
+	var patterns []string
+	for _, p := range exclude {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	for _, p := range include {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, "!"+strings.TrimPrefix(p, "!"))
+		}
+	}
+	return patterns
+}
+
 // SyncListCmd lists all sync configurations.
 type SyncListCmd struct{}
 
@@ -219,6 +479,20 @@ func (c *SyncStatusCmd) Run(ctx context.Context, flags *RootFlags) error {
 	// Get daemon status
 	daemonStatus, _ := sync.GetDaemonStatus()
 
+	// Engine health is only meaningful while a supervisor is running.
+	daemonRunning := daemonStatus != nil && daemonStatus.Running
+	now := time.Now()
+	for i := range statuses {
+		statuses[i].DaemonRunning = daemonRunning
+		if !daemonRunning {
+			statuses[i].Engine = nil
+		}
+		// Engines record transfer stats themselves, also in the foreground.
+		if t := statuses[i].Transfers; t != nil && t.Stale(now) {
+			statuses[i].Transfers = nil
+		}
+	}
+
 	if outfmt.IsJSON(ctx) {
 		result := map[string]any{
 			"statuses": statuses,
@@ -252,7 +526,7 @@ func (c *SyncStatusCmd) Run(ctx context.Context, flags *RootFlags) error {
 
 	w, flush := tableWriter(ctx)
 	defer flush()
-	fmt.Fprintln(w, "ID\tLOCAL PATH\tTOTAL\tSYNCED\tPENDING\tCONFLICT\tERROR\tLAST SYNC")
+	fmt.Fprintln(w, "ID\tLOCAL PATH\tTOTAL\tSYNCED\tPENDING\tCONFLICT\tERROR\tQUEUE\tUP\tDOWN\tLAST SYNC\tENGINE")
 
 	for _, s := range statuses {
 		lastSync := "-"
@@ -260,7 +534,14 @@ func (c *SyncStatusCmd) Run(ctx context.Context, flags *RootFlags) error {
 			lastSync = s.Config.LastSyncAt.Format(time.RFC3339)
 		}
 
-		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+		engine := engineHealthSummary(s.Engine)
+		if s.Config.PausedReason != "" {
+			engine = "paused"
+		}
+
+		queue, up, down := transferSummary(s.Transfers)
+
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
 			s.Config.ID,
 			s.Config.LocalPath,
 			s.TotalItems,
@@ -268,27 +549,75 @@ func (c *SyncStatusCmd) Run(ctx context.Context, flags *RootFlags) error {
 			s.PendingItems,
 			s.ConflictItems,
 			s.ErrorItems,
+			queue,
+			up,
+			down,
 			lastSync,
+			engine,
 		)
 	}
 
+	for _, s := range statuses {
+		if s.Config.PausedReason != "" {
+			u.Err().Printf("%s: paused: %s (see 'wk sync plan', then 'wk sync resume')", s.Config.LocalPath, s.Config.PausedReason)
+			continue
+		}
+		if s.Engine != nil && s.Engine.State != sync.EngineRunning && s.Engine.LastError != "" {
+			u.Err().Printf("%s: %s", s.Config.LocalPath, s.Engine.LastError)
+		}
+	}
+
 	return nil
 }
 
+// engineHealthSummary renders the ENGINE column of `sync status`.
+func engineHealthSummary(h *sync.EngineHealth) string {
+	if h == nil {
+		return "-"
+	}
+
+	summary := string(h.State)
+	if h.Restarts > 0 {
+		summary += fmt.Sprintf(" (%d restarts)", h.Restarts)
+	}
+	return summary
+}
+
+// transferSummary renders the QUEUE, UP and DOWN columns of `sync status`:
+// transfers queued and running, and the current throughput.
+func transferSummary(t *sync.TransferStats) (queue, up, down string) {
+	if t == nil {
+		return "-", "-", "-"
+	}
+
+	queue = strconv.Itoa(t.Queued)
+	if t.Active > 0 {
+		queue += fmt.Sprintf(" (+%d active)", t.Active)
+	}
+	return queue, formatBytes(t.UploadRate) + "/s", formatBytes(t.DownloadRate) + "/s"
+}
+
 // SyncStartCmd starts the sync daemon.
 type SyncStartCmd struct {
-	LocalPath      string `arg:"" name:"local-path" help:"Local directory path to sync"`
+	LocalPath      string `arg:"" optional:"" name:"local-path" help:"Local directory path to sync (omit with --all)"`
+	All            bool   `name:"all" help:"Sync every configuration from one supervisor process, each with its own account"`
 	Daemon         bool   `name:"daemon" short:"d" help:"Run as background daemon"`
 	InternalDaemon bool   `name:"internal-daemon" hidden:""`
-	Conflict       string `name:"conflict" help:"Conflict resolution strategy: rename (default), local-wins, remote-wins" default:"rename" enum:"rename,local-wins,remote-wins"`
+	Conflict       string `name:"conflict" help:"Conflict resolution strategy: rename (default), local-wins, remote-wins, merge" default:"rename" enum:"rename,local-wins,remote-wins,merge"`
 }
 
 func (c *SyncStartCmd) Run(ctx context.Context, flags *RootFlags) error {
 	u := ui.FromContext(ctx)
 
 	localPath := strings.TrimSpace(c.LocalPath)
+	if c.All {
+		if localPath != "" {
+			return usage("--all cannot be combined with a local path")
+		}
+		return c.runAll(ctx, flags)
+	}
 	if localPath == "" {
-		return usage("empty local-path")
+		return usage("empty local-path (or use --all)")
 	}
 
 	// Handle daemon mode
@@ -317,13 +646,11 @@ func (c *SyncStartCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return nil
 	}
 
-	// If running as internal daemon, write PID file
-	if c.InternalDaemon {
-		if err := sync.WritePIDFile(); err != nil {
-			return fmt.Errorf("write PID file: %w", err)
-		}
-		defer func() { _ = sync.RemovePIDFile() }()
+	release, err := claimSyncPIDFile(c.InternalDaemon)
+	if err != nil {
+		return err
 	}
+	defer release()
 
 	db, err := sync.OpenDB()
 	if err != nil {
@@ -340,17 +667,21 @@ func (c *SyncStartCmd) Run(ctx context.Context, flags *RootFlags) error {
 		return fmt.Errorf("sync config not found: %s (use 'wk sync init' first)", localPath)
 	}
 
-	// Get authenticated Drive service
-	// For now, require the user to specify account via --account flag
-	driveService, err := getDriveService(ctx, flags)
+	driveService, err := syncDriveService(ctx, flags, db, cfg)
 	if err != nil {
-		return fmt.Errorf("get Drive service: %w", err)
+		return err
+	}
+
+	strategy, err := sync.ParseConflictStrategy(c.Conflict)
+	if err != nil {
+		return usage(err.Error())
 	}
 
 	engine, err := sync.NewEngine(sync.EngineOptions{
-		DB:           db,
-		Config:       cfg,
-		DriveService: driveService,
+		DB:               db,
+		Config:           cfg,
+		DriveService:     driveService,
+		ConflictStrategy: strategy,
 	})
 	if err != nil {
 		return fmt.Errorf("create sync engine: %w", err)
@@ -368,6 +699,117 @@ func (c *SyncStartCmd) Run(ctx context.Context, flags *RootFlags) error {
 	return nil
 }
 
+// claimSyncPIDFile writes the sync PID file and returns a func removing it.
+// Engines own the PID file in the foreground too (e.g. under systemd), so
+// `sync status` and `sync stop` find them, and a foreground start refuses to
+// run next to a daemon or supervisor that is already syncing the same
+// configurations.
+func claimSyncPIDFile(internalDaemon bool) (func(), error) {
+	if internalDaemon {
+		if err := sync.WritePIDFile(); err != nil {
+			return nil, fmt.Errorf("write PID file: %w", err)
+		}
+		return func() { _ = sync.RemovePIDFile() }, nil
+	}
+
+	if status, err := sync.GetDaemonStatus(); err == nil && status.Running {
+		return nil, fmt.Errorf("daemon already running with PID %d (stop it with 'wk sync stop' first)", status.PID)
+	}
+	if err := sync.WritePIDFile(); err != nil {
+		return func() {}, nil
+	}
+	return func() { _ = sync.RemovePIDFile() }, nil
+}
+
+// runAll runs one supervisor for every sync configuration.
+func (c *SyncStartCmd) runAll(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	strategy, err := sync.ParseConflictStrategy(c.Conflict)
+	if err != nil {
+		return usage(err.Error())
+	}
+
+	if c.Daemon && !c.InternalDaemon {
+		pid, err := sync.StartSupervisorDaemon(strings.TrimSpace(flags.Account), c.Conflict)
+		if err != nil {
+			return fmt.Errorf("start daemon: %w", err)
+		}
+
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+				"started": true,
+				"pid":     pid,
+			})
+		}
+
+		u.Out().Printf("started\ttrue")
+		u.Out().Printf("pid\t%d", pid)
+
+		return nil
+	}
+
+	release, err := claimSyncPIDFile(c.InternalDaemon)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	configs, err := db.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("list configs: %w", err)
+	}
+
+	fallbackAccount := strings.TrimSpace(flags.Account)
+	supervisor := sync.NewSupervisor(sync.SupervisorOptions{
+		DB:      db,
+		Configs: configs,
+		Run: func(ctx context.Context, cfg *sync.SyncConfig) error {
+			account := cfg.Account
+			if account == "" {
+				account = fallbackAccount
+			}
+			if account == "" {
+				return sync.Permanent(fmt.Errorf("no account recorded for %s (pass --account, or start it once with 'wk sync start %s --account <email>')", cfg.LocalPath, cfg.LocalPath))
+			}
+
+			driveService, err := googleapi.NewDrive(ctx, account)
+			if err != nil {
+				return fmt.Errorf("get Drive service for %s: %w", account, err)
+			}
+
+			engine, err := sync.NewEngine(sync.EngineOptions{
+				DB:               db,
+				Config:           cfg,
+				DriveService:     driveService,
+				ConflictStrategy: strategy,
+			})
+			if err != nil {
+				return fmt.Errorf("create sync engine: %w", err)
+			}
+
+			return engine.Start(ctx)
+		},
+	})
+
+	u.Err().Printf("Starting sync for %d configurations", len(configs))
+	u.Err().Println("Press Ctrl+C to stop")
+
+	if err := supervisor.Start(ctx); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("sync supervisor error: %w", err)
+	}
+
+	u.Err().Println("Sync stopped")
+
+	return nil
+}
+
 // SyncStopCmd stops the sync daemon.
 type SyncStopCmd struct{}
 
@@ -420,3 +862,29 @@ func getDriveService(ctx context.Context, flags *RootFlags) (*drive.Service, err
 
 	return googleapi.NewDrive(ctx, account)
 }
+
+// syncDriveService returns the Drive service for a sync configuration. The
+// account recorded with the config is used when --account is not given,
+// and --account is recorded for `sync start --all` when the config has none.
+func syncDriveService(ctx context.Context, flags *RootFlags, db *sync.DB, cfg *sync.SyncConfig) (*drive.Service, error) {
+	account := strings.TrimSpace(flags.Account)
+	if account == "" {
+		account = cfg.Account
+	}
+	if account == "" {
+		return nil, fmt.Errorf("--account flag is required for sync operations")
+	}
+	if cfg.Account == "" {
+		if err := db.SetConfigAccount(cfg.ID, account); err != nil {
+			return nil, fmt.Errorf("save account: %w", err)
+		}
+		cfg.Account = account
+	}
+
+	driveService, err := googleapi.NewDrive(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("get Drive service: %w", err)
+	}
+
+	return driveService, nil
+}
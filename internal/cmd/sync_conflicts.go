@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/sync"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// SyncConflictsCmd lists and resolves files changed on both sides.
+type SyncConflictsCmd struct {
+	List    SyncConflictsListCmd    `cmd:"" name:"ls" aliases:"list" default:"withargs" help:"List unresolved sync conflicts"`
+	Resolve SyncConflictsResolveCmd `cmd:"" name:"resolve" help:"Resolve a sync conflict by keeping the local, remote or both versions"`
+}
+
+// syncConflict is one unresolved conflict in `sync conflicts ls` output.
+type syncConflict struct {
+	ConfigID     int64  `json:"config_id"`
+	Path         string `json:"path"`
+	ConflictPath string `json:"conflict_path,omitempty"`
+	DriveID      string `json:"drive_id,omitempty"`
+}
+
+// SyncConflictsListCmd lists unresolved conflicts across sync configurations.
+type SyncConflictsListCmd struct {
+	FailEmpty bool `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *SyncConflictsListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	configs, err := db.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("list configs: %w", err)
+	}
+
+	conflicts := []syncConflict{}
+	for _, cfg := range configs {
+		items, err := db.ListConflicts(cfg.ID)
+		if err != nil {
+			return fmt.Errorf("list conflicts: %w", err)
+		}
+		for _, item := range items {
+			conflict := syncConflict{
+				ConfigID: cfg.ID,
+				Path:     filepath.Join(cfg.LocalPath, item.LocalPath),
+				DriveID:  item.DriveID,
+			}
+			if item.ConflictPath != "" {
+				conflict.ConflictPath = filepath.Join(cfg.LocalPath, item.ConflictPath)
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"conflicts": conflicts,
+			"count":     len(conflicts),
+		}); err != nil {
+			return err
+		}
+		if len(conflicts) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(conflicts) == 0 {
+		u.Err().Println("No sync conflicts")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "CONFIG\tPATH (REMOTE)\tCONFLICT COPY (LOCAL)")
+	for _, conflict := range conflicts {
+		fmt.Fprintf(w, "%d\t%s\t%s\n",
+			conflict.ConfigID,
+			sanitizeTab(conflict.Path),
+			sanitizeTab(conflict.ConflictPath),
+		)
+	}
+	return nil
+}
+
+// SyncConflictsResolveCmd resolves one recorded conflict.
+type SyncConflictsResolveCmd struct {
+	Path string `arg:"" name:"path" help:"Conflicted file (absolute, or relative to the current directory)"`
+	Take string `name:"take" required:"" help:"Version to keep: local (the conflict copy), remote (the Drive version) or both"`
+}
+
+func (c *SyncConflictsResolveCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	path := strings.TrimSpace(c.Path)
+	if path == "" {
+		return usage("empty path")
+	}
+
+	take, err := sync.ParseConflictTake(c.Take)
+	if err != nil {
+		return usage(err.Error())
+	}
+
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return fmt.Errorf("expand path: %w", err)
+	}
+	absPath, err := filepath.Abs(expanded)
+	if err != nil {
+		return fmt.Errorf("absolute path: %w", err)
+	}
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	configs, err := db.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("list configs: %w", err)
+	}
+
+	cfg, relPath := syncConfigForPath(configs, absPath)
+	if cfg == nil {
+		return fmt.Errorf("%s is not inside a sync folder (see: wk sync list)", absPath)
+	}
+
+	var driveSvc *drive.Service
+	if take != sync.TakeBoth {
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("resolve conflict on %s by keeping the %s version", absPath, take)); err != nil {
+			return err
+		}
+
+		driveSvc, err = getDriveService(ctx, flags)
+		if err != nil {
+			return fmt.Errorf("get Drive service: %w", err)
+		}
+	}
+
+	item, err := sync.ResolveRecordedConflict(ctx, db, cfg, driveSvc, relPath, take)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"resolved": true,
+			"path":     absPath,
+			"take":     string(take),
+			"item":     item,
+		})
+	}
+
+	u.Out().Printf("resolved\ttrue")
+	u.Out().Printf("path\t%s", absPath)
+	u.Out().Printf("take\t%s", take)
+	return nil
+}
+
+// syncConfigForPath returns the sync configuration whose folder contains
+// absPath (the innermost one if folders nest) and absPath relative to it.
+func syncConfigForPath(configs []sync.SyncConfig, absPath string) (*sync.SyncConfig, string) {
+	var (
+		best    *sync.SyncConfig
+		bestRel string
+	)
+	for i := range configs {
+		cfg := &configs[i]
+		rel, err := filepath.Rel(cfg.LocalPath, absPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if best == nil || len(cfg.LocalPath) > len(best.LocalPath) {
+			best, bestRel = cfg, rel
+		}
+	}
+	return best, bestRel
+}
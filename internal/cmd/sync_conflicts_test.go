@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/sync"
+)
+
+func TestSyncConflictsList_JSON(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	local := filepath.Join(home, "Drive")
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	cfg, err := db.CreateConfig(local, "folder-1", "")
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	if err := db.CreateSyncItem(cfg.ID, "notes.txt", "file-1", "a", "b", time.Now(), time.Now()); err != nil {
+		t.Fatalf("CreateSyncItem: %v", err)
+	}
+	item, _ := db.GetSyncItem(cfg.ID, "notes.txt")
+	if err := db.SetSyncItemState(item.ID, sync.StateConflict, "notes.conflict-20260101-000000.txt"); err != nil {
+		t.Fatalf("SetSyncItemState: %v", err)
+	}
+	db.Close()
+
+	out := captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			if err := Execute([]string{"--json", "sync", "conflicts", "ls"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+
+	var resp struct {
+		Conflicts []syncConflict `json:"conflicts"`
+		Count     int            `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	if resp.Count != 1 || resp.Conflicts[0].Path != filepath.Join(local, "notes.txt") ||
+		resp.Conflicts[0].ConflictPath != filepath.Join(local, "notes.conflict-20260101-000000.txt") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSyncConflictsResolve_InvalidTake(t *testing.T) {
+	err := Execute([]string{"sync", "conflicts", "resolve", "notes.txt", "--take", "newest"})
+	if err == nil || !strings.Contains(err.Error(), "unknown --take") {
+		t.Fatalf("expected --take usage error, got %v", err)
+	}
+	if ExitCode(err) != 2 {
+		t.Fatalf("exit code = %d, want 2", ExitCode(err))
+	}
+}
+
+func TestSyncConfigForPath(t *testing.T) {
+	configs := []sync.SyncConfig{
+		{ID: 1, LocalPath: "/home/u/Drive"},
+		{ID: 2, LocalPath: "/home/u/Drive/Work"},
+	}
+
+	tests := []struct {
+		path   string
+		wantID int64
+		rel    string
+	}{
+		{"/home/u/Drive/a.txt", 1, "a.txt"},
+		{"/home/u/Drive/Work/b.txt", 2, "b.txt"},
+		{"/home/u/Drive", 0, ""},
+		{"/home/u/Other/c.txt", 0, ""},
+	}
+
+	for _, tt := range tests {
+		cfg, rel := syncConfigForPath(configs, tt.path)
+		var gotID int64
+		if cfg != nil {
+			gotID = cfg.ID
+		}
+		if gotID != tt.wantID || rel != tt.rel {
+			t.Errorf("syncConfigForPath(%q) = %d, %q; want %d, %q", tt.path, gotID, rel, tt.wantID, tt.rel)
+		}
+	}
+}
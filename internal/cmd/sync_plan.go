@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/sync"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// SyncPlanCmd prints what a one-shot sync of a folder would do.
+type SyncPlanCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local sync folder"`
+}
+
+func (c *SyncPlanCmd) Run(ctx context.Context, flags *RootFlags) error {
+	engine, db, err := openOneShotSync(ctx, flags, c.LocalPath, sync.ConflictRename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	plan, err := engine.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The plan is meant for scripts, so it is always JSON.
+	return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+		"config_id":  plan.ConfigID,
+		"local_path": plan.LocalPath,
+		"actions":    plan.Actions,
+		"count":      len(plan.Actions),
+	})
+}
+
+// SyncPushCmd applies the local-to-remote actions of a plan.
+type SyncPushCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local sync folder"`
+}
+
+func (c *SyncPushCmd) Run(ctx context.Context, flags *RootFlags) error {
+	return applySyncPlan(ctx, flags, c.LocalPath, sync.ApplyPush, sync.ConflictRename)
+}
+
+// SyncPullCmd applies the remote-to-local actions of a plan.
+type SyncPullCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local sync folder"`
+}
+
+func (c *SyncPullCmd) Run(ctx context.Context, flags *RootFlags) error {
+	return applySyncPlan(ctx, flags, c.LocalPath, sync.ApplyPull, sync.ConflictRename)
+}
+
+// SyncRunCmd applies a whole plan, conflicts included.
+type SyncRunCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local sync folder"`
+	Once      bool   `name:"once" help:"Sync once and exit (required; use 'sync start' for continuous sync)"`
+	Conflict  string `name:"conflict" help:"Conflict resolution strategy: rename (default), local-wins, remote-wins, merge" default:"rename" enum:"rename,local-wins,remote-wins,merge"`
+}
+
+func (c *SyncRunCmd) Run(ctx context.Context, flags *RootFlags) error {
+	if !c.Once {
+		return usage("sync run requires --once (use 'wk sync start' for continuous sync)")
+	}
+
+	strategy, err := sync.ParseConflictStrategy(c.Conflict)
+	if err != nil {
+		return usage(err.Error())
+	}
+
+	return applySyncPlan(ctx, flags, c.LocalPath, sync.ApplyAll, strategy)
+}
+
+// SyncResumeCmd resumes a sync paused by the delete guard.
+type SyncResumeCmd struct {
+	LocalPath string `arg:"" name:"local-path" help:"Local sync folder"`
+	Restore   bool   `name:"restore" help:"Undo the held deletions by copying each file back from the side that still has it"`
+}
+
+func (c *SyncResumeCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	engine, db, err := openOneShotSync(ctx, flags, c.LocalPath, sync.ConflictRename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	plan, err := engine.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := db.GetConfigByID(plan.ConfigID)
+	if err != nil {
+		return fmt.Errorf("get sync config: %w", err)
+	}
+	if cfg == nil || cfg.PausedReason == "" {
+		u.Err().Printf("Sync for %s is not paused", plan.LocalPath)
+		return nil
+	}
+
+	var deletes []sync.PlanAction
+	for _, action := range plan.Actions {
+		if action.Kind.IsDelete() {
+			deletes = append(deletes, action)
+		}
+	}
+
+	op := fmt.Sprintf("resume sync of %s", plan.LocalPath)
+	if c.Restore {
+		op += " restoring held deletions"
+	}
+	if err := dryRunExit(ctx, flags, op, map[string]any{
+		"config_id":     plan.ConfigID,
+		"paused_reason": cfg.PausedReason,
+		"restore":       c.Restore,
+		"actions":       deletes,
+	}); err != nil {
+		return err
+	}
+
+	if !c.Restore && len(deletes) > 0 {
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete %d file(s) held while sync of %s was paused", len(deletes), plan.LocalPath)); err != nil {
+			return err
+		}
+	}
+
+	mode := "resume"
+	if c.Restore {
+		mode = "restore"
+	}
+
+	results, err := engine.Resume(ctx, plan, c.Restore)
+	if err != nil {
+		return err
+	}
+
+	return writeSyncResults(ctx, plan, mode, results)
+}
+
+// openOneShotSync opens the sync database and a one-shot engine for the
+// configuration at localPath. The caller closes the database.
+func openOneShotSync(ctx context.Context, flags *RootFlags, localPath string, strategy sync.ConflictStrategy) (*sync.Engine, *sync.DB, error) {
+	localPath = strings.TrimSpace(localPath)
+	if localPath == "" {
+		return nil, nil, usage("empty local-path")
+	}
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open sync database: %w", err)
+	}
+
+	cfg, err := db.GetConfig(localPath)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("get sync config: %w", err)
+	}
+	if cfg == nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("sync config not found: %s (use 'wk sync init' first)", localPath)
+	}
+
+	driveService, err := syncDriveService(ctx, flags, db, cfg)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	engine, err := sync.NewOneShotEngine(sync.EngineOptions{
+		DB:               db,
+		Config:           cfg,
+		DriveService:     driveService,
+		ConflictStrategy: strategy,
+	})
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("create sync engine: %w", err)
+	}
+
+	return engine, db, nil
+}
+
+// applySyncPlan plans a sync of localPath and applies the actions mode
+// includes. Deletions need confirmation (or --force).
+func applySyncPlan(ctx context.Context, flags *RootFlags, localPath string, mode sync.ApplyMode, strategy sync.ConflictStrategy) error {
+	engine, db, err := openOneShotSync(ctx, flags, localPath, strategy)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	plan, err := engine.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		planned []sync.PlanAction
+		deletes int
+	)
+	for _, action := range plan.Actions {
+		if !mode.Includes(action.Kind) {
+			continue
+		}
+		planned = append(planned, action)
+		if action.Kind.IsDelete() {
+			deletes++
+		}
+	}
+
+	op := fmt.Sprintf("sync %s %s", mode, plan.LocalPath)
+	if err := dryRunExit(ctx, flags, op, map[string]any{
+		"config_id": plan.ConfigID,
+		"actions":   planned,
+	}); err != nil {
+		return err
+	}
+
+	if deletes > 0 {
+		if err := confirmDestructive(ctx, flags, fmt.Sprintf("delete %d file(s) while syncing %s", deletes, plan.LocalPath)); err != nil {
+			return err
+		}
+	}
+
+	results, err := engine.Apply(ctx, plan, mode)
+	if err != nil {
+		return err
+	}
+
+	return writeSyncResults(ctx, plan, string(mode), results)
+}
+
+// writeSyncResults prints the outcome of applying a plan and fails if any
+// action failed.
+func writeSyncResults(ctx context.Context, plan *sync.Plan, mode string, results []sync.ApplyResult) error {
+	u := ui.FromContext(ctx)
+
+	var applied, skipped, failed int
+	for _, r := range results {
+		switch r.Status {
+		case sync.StatusApplied:
+			applied++
+		case sync.StatusSkipped:
+			skipped++
+		case sync.StatusFailed:
+			failed++
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"config_id":  plan.ConfigID,
+			"local_path": plan.LocalPath,
+			"mode":       mode,
+			"results":    results,
+			"applied":    applied,
+			"skipped":    skipped,
+			"failed":     failed,
+		}); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		u.Err().Println("Already in sync")
+	} else {
+		w, flush := tableWriter(ctx)
+		fmt.Fprintln(w, "STATUS\tACTION\tPATH\tDETAIL")
+		for _, r := range results {
+			detail := r.Reason
+			if r.Error != "" {
+				detail = r.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Status, r.Kind, sanitizeTab(r.Path), sanitizeTab(detail))
+		}
+		flush()
+		u.Err().Printf("applied %d, skipped %d, failed %d", applied, skipped, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sync actions failed", failed, applied+failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/automagik-dev/workit/internal/sync"
+)
+
+func TestSyncRun_RequiresOnce(t *testing.T) {
+	err := Execute([]string{"sync", "run", "/tmp/Drive"})
+	if err == nil || !strings.Contains(err.Error(), "--once") {
+		t.Fatalf("expected --once usage error, got %v", err)
+	}
+	if ExitCode(err) != 2 {
+		t.Fatalf("exit code = %d, want 2", ExitCode(err))
+	}
+}
+
+func TestSyncPlan_UnknownConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	for _, sub := range []string{"plan", "push", "pull"} {
+		err := Execute([]string{"sync", sub, filepath.Join(home, "Drive")})
+		if err == nil || !strings.Contains(err.Error(), "sync config not found") {
+			t.Fatalf("sync %s: expected missing config error, got %v", sub, err)
+		}
+	}
+}
+
+func TestSyncPlan_RequiresAccount(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	local := filepath.Join(home, "Drive")
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	if _, err := db.CreateConfig(local, "folder-1", ""); err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	db.Close()
+
+	err = Execute([]string{"sync", "run", "--once", local})
+	if err == nil || !strings.Contains(err.Error(), "--account") {
+		t.Fatalf("expected --account error, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// SyncServiceCmd prints or installs a systemd user unit that runs the sync
+// supervisor for every configuration.
+type SyncServiceCmd struct {
+	Write    bool   `name:"write" help:"Write the unit to the systemd user directory instead of printing it"`
+	Name     string `name:"name" help:"Unit name (without .service)" default:"wk-sync"`
+	Conflict string `name:"conflict" help:"Conflict resolution strategy: rename (default), local-wins, remote-wins, merge" default:"rename" enum:"rename,local-wins,remote-wins,merge"`
+}
+
+func (c *SyncServiceCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	name := strings.TrimSuffix(strings.TrimSpace(c.Name), ".service")
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return usagef("invalid --name %q", c.Name)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("get executable: %w", err)
+	}
+
+	unit := syncServiceUnit(executable, strings.TrimSpace(flags.Account), c.Conflict)
+
+	if !c.Write {
+		if outfmt.IsJSON(ctx) {
+			return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+				"name":    name + ".service",
+				"unit":    unit,
+				"written": false,
+			})
+		}
+
+		_, err := fmt.Fprint(os.Stdout, unit)
+		return err
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("resolve user config dir: %w", err)
+	}
+
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	if err := os.MkdirAll(unitDir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", unitDir, err)
+	}
+
+	unitPath := filepath.Join(unitDir, name+".service")
+	if err := os.WriteFile(unitPath, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("write unit: %w", err)
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"name":    name + ".service",
+			"path":    unitPath,
+			"unit":    unit,
+			"written": true,
+		})
+	}
+
+	u.Out().Printf("written\ttrue")
+	u.Out().Printf("path\t%s", unitPath)
+	u.Err().Printf("Enable it with: systemctl --user daemon-reload && systemctl --user enable --now %s.service", name)
+	return nil
+}
+
+// syncServiceUnit renders a systemd user unit running `sync start --all`.
+func syncServiceUnit(executable, account, conflict string) string {
+	args := []string{systemdQuote(executable), "sync", "start", "--all", "--conflict", conflict}
+	if account != "" {
+		args = append(args, "--account", systemdQuote(account))
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=workit Drive sync\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString("ExecStart=" + strings.Join(args, " ") + "\n")
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=10\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote quotes a word for an ExecStart= line when needed.
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;$%") {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return `"` + r.Replace(s) + `"`
+}
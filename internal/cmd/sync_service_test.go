@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncServiceUnit(t *testing.T) {
+	unit := syncServiceUnit("/opt/my tools/wk", "a@b.com", "local-wins")
+
+	if !strings.Contains(unit, `ExecStart="/opt/my tools/wk" sync start --all --conflict local-wins --account a@b.com`+"\n") {
+		t.Fatalf("unexpected ExecStart:\n%s", unit)
+	}
+	for _, want := range []string{"[Unit]", "[Service]", "Restart=on-failure", "[Install]", "WantedBy=default.target"} {
+		if !strings.Contains(unit, want) {
+			t.Fatalf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := map[string]string{
+		"/usr/bin/wk":   "/usr/bin/wk",
+		"/a b/wk":       `"/a b/wk"`,
+		`/a"b`:          `"/a\"b"`,
+		"/home/$USER/x": `"/home/$$USER/x"`,
+	}
+	for in, want := range tests {
+		if got := systemdQuote(in); got != want {
+			t.Errorf("systemdQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSyncInstallService_Write(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	_ = captureStderr(t, func() {
+		_ = captureStdout(t, func() {
+			if err := Execute([]string{"sync", "install-service", "--write", "--name", "wk-sync-test"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+
+	b, err := os.ReadFile(filepath.Join(home, "xdg-config", "systemd", "user", "wk-sync-test.service"))
+	if err != nil {
+		t.Fatalf("read unit: %v", err)
+	}
+	if !strings.Contains(string(b), "sync start --all --conflict rename") {
+		t.Fatalf("unexpected unit:\n%s", b)
+	}
+}
+
+func TestSyncStart_AllRejectsPath(t *testing.T) {
+	err := Execute([]string{"sync", "start", "--all", "/tmp/x"})
+	if err == nil || ExitCode(err) != 2 {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
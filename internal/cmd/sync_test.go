@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/automagik-dev/workit/internal/sync"
+)
+
+func TestSyncInit_EncryptAndExportKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("WK_KEYRING_BACKEND", "file")
+	t.Setenv("WK_KEYRING_PASSWORD", "test")
+
+	const folder = "1AbCdEfGhIjKlMnOpQrStUv"
+	local := filepath.Join(home, "Clients")
+
+	var initResp struct {
+		Config       sync.SyncConfig `json:"config"`
+		Recipient    string          `json:"recipient"`
+		KeyGenerated bool            `json:"key_generated"`
+	}
+	stderr := captureStderr(t, func() {
+		out := captureStdout(t, func() {
+			if err := Execute([]string{"--json", "sync", "init", local, "--drive-folder", folder, "--encrypt-names"}); err != nil {
+				t.Fatalf("Execute init: %v", err)
+			}
+		})
+		if err := json.Unmarshal([]byte(out), &initResp); err != nil {
+			t.Fatalf("json parse: %v\nout=%q", err, out)
+		}
+	})
+	if !initResp.Config.Encrypted || !initResp.Config.EncryptNames || !initResp.KeyGenerated ||
+		!strings.HasPrefix(initResp.Recipient, "age1") {
+		t.Fatalf("unexpected init response: %+v", initResp)
+	}
+	if !strings.Contains(stderr, "wk sync export-key") {
+		t.Fatalf("no backup hint on stderr: %q", stderr)
+	}
+
+	out := captureStdout(t, func() {
+		if err := Execute([]string{"sync", "export-key", local}); err != nil {
+			t.Fatalf("Execute export-key: %v", err)
+		}
+	})
+	key, err := sync.ParseEncryptionKey(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("exported key: %v\nout=%q", err, out)
+	}
+	if !strings.Contains(out, initResp.Recipient) {
+		t.Fatalf("export does not name the public key: %q", out)
+	}
+
+	// Another sync of the same folder reuses the stored key; a different
+	// key for it is refused.
+	keyFile := filepath.Join(home, "key.txt")
+	if err := os.WriteFile(keyFile, []byte(out), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = captureStdout(t, func() {
+		if err := Execute([]string{"sync", "init", filepath.Join(home, "Copy"), "--drive-folder", folder, "--encrypt", "--encryption-key", keyFile}); err != nil {
+			t.Fatalf("Execute init with key: %v", err)
+		}
+	})
+	otherKey, err := sync.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, []byte(otherKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err = Execute([]string{"sync", "init", filepath.Join(home, "Other"), "--drive-folder", folder, "--encrypt", "--encryption-key", keyFile})
+	if err == nil || !strings.Contains(err.Error(), "different encryption key") {
+		t.Fatalf("expected key mismatch error, got %v", err)
+	}
+
+	stored, err := sync.LoadEncryptionKey(folder)
+	if err != nil || stored != key {
+		t.Fatalf("stored key = %q, %v; want the exported key", stored, err)
+	}
+}
+
+func TestSyncInit_EncryptRejectsNativeDocs(t *testing.T) {
+	err := Execute([]string{"sync", "init", t.TempDir(), "--drive-folder", "1AbCdEfGhIjKlMnOpQrStUv", "--encrypt", "--native-docs", "office"})
+	if err == nil || !strings.Contains(err.Error(), "cannot be encrypted") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+	if ExitCode(err) != 2 {
+		t.Fatalf("exit code = %d, want 2", ExitCode(err))
+	}
+}
+
+func TestSyncStart_RefusesWhileDaemonRunning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sync daemon is not supported on windows")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	// The test process stands in for a running `sync start --all`.
+	if err := sync.WritePIDFile(); err != nil {
+		t.Fatalf("WritePIDFile: %v", err)
+	}
+
+	err := Execute([]string{"sync", "start", filepath.Join(home, "Clients")})
+	if err == nil || !strings.Contains(err.Error(), "daemon already running") {
+		t.Fatalf("Execute start = %v, want daemon already running", err)
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/outfmt"
+	"github.com/automagik-dev/workit/internal/sync"
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// SyncTrashCmd lists and restores files deleted on Drive.
+type SyncTrashCmd struct {
+	List    SyncTrashListCmd    `cmd:"" name:"ls" aliases:"list" default:"withargs" help:"List files deleted on Drive and kept in .wk-trash"`
+	Restore SyncTrashRestoreCmd `cmd:"" name:"restore" help:"Move a trashed file or folder back to its original path"`
+}
+
+// syncTrashEntry is one file in `sync trash ls` output.
+type syncTrashEntry struct {
+	ConfigID  int64     `json:"config_id"`
+	Path      string    `json:"path"`
+	TrashPath string    `json:"trash_path"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Size      int64     `json:"size"`
+}
+
+// SyncTrashListCmd lists the local trash of every sync configuration.
+type SyncTrashListCmd struct {
+	FailEmpty bool `name:"fail-empty" aliases:"non-empty,require-results" help:"Exit with code 3 if no results"`
+}
+
+func (c *SyncTrashListCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	configs, err := db.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("list configs: %w", err)
+	}
+
+	entries := []syncTrashEntry{}
+	for _, cfg := range configs {
+		trashed, err := sync.ListTrash(cfg.LocalPath)
+		if err != nil {
+			return err
+		}
+		for _, t := range trashed {
+			entries = append(entries, syncTrashEntry{
+				ConfigID:  cfg.ID,
+				Path:      filepath.Join(cfg.LocalPath, t.Path),
+				TrashPath: filepath.Join(cfg.LocalPath, sync.TrashDirName, t.ID),
+				DeletedAt: t.DeletedAt,
+				Size:      t.Size,
+			})
+		}
+	}
+
+	if outfmt.IsJSON(ctx) {
+		if err := outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"files": entries,
+			"count": len(entries),
+		}); err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return failEmptyExit(c.FailEmpty)
+		}
+		return nil
+	}
+
+	if len(entries) == 0 {
+		u.Err().Println("Sync trash is empty")
+		return failEmptyExit(c.FailEmpty)
+	}
+
+	w, flush := tableWriter(ctx)
+	defer flush()
+	fmt.Fprintln(w, "CONFIG\tPATH\tDELETED\tSIZE")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\n",
+			e.ConfigID,
+			sanitizeTab(e.Path),
+			e.DeletedAt.Local().Format(time.RFC3339),
+			e.Size,
+		)
+	}
+	return nil
+}
+
+// SyncTrashRestoreCmd restores trashed files to their original paths.
+type SyncTrashRestoreCmd struct {
+	Path string `arg:"" name:"path" help:"Original path of the file or folder (newest copy wins), or its path inside .wk-trash"`
+}
+
+func (c *SyncTrashRestoreCmd) Run(ctx context.Context, flags *RootFlags) error {
+	u := ui.FromContext(ctx)
+
+	path := strings.TrimSpace(c.Path)
+	if path == "" {
+		return usage("empty path")
+	}
+
+	expanded, err := config.ExpandPath(path)
+	if err != nil {
+		return fmt.Errorf("expand path: %w", err)
+	}
+	absPath, err := filepath.Abs(expanded)
+	if err != nil {
+		return fmt.Errorf("absolute path: %w", err)
+	}
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		return fmt.Errorf("open sync database: %w", err)
+	}
+	defer db.Close()
+
+	configs, err := db.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("list configs: %w", err)
+	}
+
+	cfg, relPath := syncConfigForPath(configs, absPath)
+	if cfg == nil {
+		return fmt.Errorf("%s is not inside a sync folder (see: wk sync list)", absPath)
+	}
+
+	// A path inside the trash names one trashed copy.
+	relPath = strings.TrimPrefix(relPath, sync.TrashDirName+string(filepath.Separator))
+
+	if err := dryRunExit(ctx, flags, fmt.Sprintf("restore %s from %s", relPath, sync.TrashDirName), map[string]any{
+		"config_id": cfg.ID,
+		"path":      relPath,
+	}); err != nil {
+		return err
+	}
+
+	restored, err := sync.RestoreTrash(cfg.LocalPath, relPath)
+	if err != nil {
+		return err
+	}
+
+	if outfmt.IsJSON(ctx) {
+		return outfmt.WriteJSON(ctx, os.Stdout, map[string]any{
+			"restored": restored,
+			"count":    len(restored),
+		})
+	}
+
+	for _, t := range restored {
+		u.Out().Printf("restored\t%s", filepath.Join(cfg.LocalPath, t.Path))
+	}
+	u.Err().Println("Restored files upload on the next sync")
+	return nil
+}
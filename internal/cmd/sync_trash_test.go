@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automagik-dev/workit/internal/sync"
+)
+
+func TestSyncTrashListAndRestore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+
+	local := filepath.Join(home, "Drive")
+
+	db, err := sync.OpenDB()
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	if _, err := db.CreateConfig(local, "folder-1", ""); err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	db.Close()
+
+	trashed := filepath.Join(local, sync.TrashDirName, "20260101-000000.000", "docs", "notes.txt")
+	if err := os.MkdirAll(filepath.Dir(trashed), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(trashed, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			if err := Execute([]string{"--json", "sync", "trash", "ls"}); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+		})
+	})
+
+	var resp struct {
+		Files []syncTrashEntry `json:"files"`
+		Count int              `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("json parse: %v\nout=%q", err, out)
+	}
+	original := filepath.Join(local, "docs", "notes.txt")
+	if resp.Count != 1 || resp.Files[0].Path != original || resp.Files[0].TrashPath != trashed {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	_ = captureStdout(t, func() {
+		_ = captureStderr(t, func() {
+			if err := Execute([]string{"sync", "trash", "restore", original}); err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+		})
+	})
+
+	if b, err := os.ReadFile(original); err != nil || string(b) != "notes" {
+		t.Fatalf("restored file = %q, %v", b, err)
+	}
+	if _, err := os.Stat(trashed); !os.IsNotExist(err) {
+		t.Fatalf("trashed copy still exists: %v", err)
+	}
+}
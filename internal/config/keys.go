@@ -10,10 +10,11 @@ import (
 type Key string
 
 const (
-	KeyTimezone       Key = "timezone"
-	KeyKeyringBackend Key = "keyring_backend"
-	KeyCallbackServer Key = "callback_server"
-	KeyAuthMode       Key = "auth_mode"
+	KeyTimezone          Key = "timezone"
+	KeyKeyringBackend    Key = "keyring_backend"
+	KeyCallbackServer    Key = "callback_server"
+	KeyAuthMode          Key = "auth_mode"
+	KeyHandoffEncryption Key = "handoff_encryption"
 )
 
 type KeySpec struct {
@@ -29,6 +30,7 @@ var keyOrder = []Key{
 	KeyKeyringBackend,
 	KeyCallbackServer,
 	KeyAuthMode,
+	KeyHandoffEncryption,
 }
 
 var validAuthModes = map[string]bool{
@@ -36,6 +38,17 @@ var validAuthModes = map[string]bool{
 	"browser":  true,
 	"headless": true,
 	"manual":   true,
+	"device":   true,
+}
+
+// HandoffEncryptionRequired is the handoff_encryption value that refuses
+// relays without end-to-end encrypted handoffs instead of falling back to
+// the plaintext handoff.
+const HandoffEncryptionRequired = "required"
+
+var validHandoffEncryption = map[string]bool{
+	"preferred":               true,
+	HandoffEncryptionRequired: true,
 }
 
 var keySpecs = map[Key]KeySpec{
@@ -100,7 +113,7 @@ var keySpecs = map[Key]KeySpec{
 		},
 		Set: func(cfg *File, value string) error {
 			if !validAuthModes[value] {
-				return fmt.Errorf("%w: %q must be one of auto, browser, headless, manual", errInvalidAuthMode, value)
+				return fmt.Errorf("%w: %q must be one of auto, browser, headless, manual, device", errInvalidAuthMode, value)
 			}
 			cfg.AuthMode = value
 			return nil
@@ -112,14 +125,34 @@ var keySpecs = map[Key]KeySpec{
 			return "(not set, using auto)"
 		},
 	},
+	KeyHandoffEncryption: {
+		Key: KeyHandoffEncryption,
+		Get: func(cfg File) string {
+			return cfg.HandoffEncryption
+		},
+		Set: func(cfg *File, value string) error {
+			if !validHandoffEncryption[value] {
+				return fmt.Errorf("%w: %q must be one of preferred, required", errInvalidHandoffEncrypt, value)
+			}
+			cfg.HandoffEncryption = value
+			return nil
+		},
+		Unset: func(cfg *File) {
+			cfg.HandoffEncryption = ""
+		},
+		EmptyHint: func() string {
+			return "(not set, using preferred)"
+		},
+	},
 }
 
 var (
-	errUnknownConfigKey     = errors.New("unknown config key")
-	errConfigKeyCannotSet   = errors.New("config key cannot be set")
-	errConfigKeyCannotUnset = errors.New("config key cannot be unset")
-	errInvalidCallbackURL   = errors.New("invalid callback server URL")
-	errInvalidAuthMode      = errors.New("invalid auth_mode")
+	errUnknownConfigKey      = errors.New("unknown config key")
+	errConfigKeyCannotSet    = errors.New("config key cannot be set")
+	errConfigKeyCannotUnset  = errors.New("config key cannot be unset")
+	errInvalidCallbackURL    = errors.New("invalid callback server URL")
+	errInvalidAuthMode       = errors.New("invalid auth_mode")
+	errInvalidHandoffEncrypt = errors.New("invalid handoff_encryption")
 )
 
 func (k Key) String() string {
@@ -67,7 +67,7 @@ func TestCallbackServerKey_EmptyHint(t *testing.T) {
 }
 
 func TestAuthModeKey_ValidValues(t *testing.T) {
-	for _, mode := range []string{"auto", "browser", "headless", "manual"} {
+	for _, mode := range []string{"auto", "browser", "headless", "manual", "device"} {
 		var cfg File
 		if err := SetValue(&cfg, KeyAuthMode, mode); err != nil {
 			t.Errorf("SetValue(%q) unexpected error: %v", mode, err)
@@ -114,6 +114,26 @@ func TestAuthModeKey_EmptyHint(t *testing.T) {
 	}
 }
 
+func TestHandoffEncryptionKey(t *testing.T) {
+	for _, value := range []string{"preferred", "required"} {
+		var cfg File
+		if err := SetValue(&cfg, KeyHandoffEncryption, value); err != nil {
+			t.Errorf("SetValue(%q) unexpected error: %v", value, err)
+		}
+
+		if got := GetValue(cfg, KeyHandoffEncryption); got != value {
+			t.Errorf("GetValue after Set(%q) = %q", value, got)
+		}
+	}
+
+	for _, value := range []string{"", "always", "Required"} {
+		var cfg File
+		if err := SetValue(&cfg, KeyHandoffEncryption, value); err == nil {
+			t.Errorf("SetValue(%q) expected error, got nil", value)
+		}
+	}
+}
+
 func TestKeyOrder_IncludesNewKeys(t *testing.T) {
 	keys := KeyList()
 
@@ -123,7 +143,7 @@ func TestKeyOrder_IncludesNewKeys(t *testing.T) {
 		found[k] = true
 	}
 
-	for _, want := range []Key{KeyCallbackServer, KeyAuthMode} {
+	for _, want := range []Key{KeyCallbackServer, KeyAuthMode, KeyHandoffEncryption} {
 		if !found[want] {
 			t.Errorf("KeyList missing %s", want)
 		}
@@ -131,7 +151,7 @@ func TestKeyOrder_IncludesNewKeys(t *testing.T) {
 }
 
 func TestParseKey_NewKeys(t *testing.T) {
-	for _, raw := range []string{"callback_server", "auth_mode"} {
+	for _, raw := range []string{"callback_server", "auth_mode", "handoff_encryption"} {
 		if _, err := ParseKey(raw); err != nil {
 			t.Errorf("ParseKey(%q) unexpected error: %v", raw, err)
 		}
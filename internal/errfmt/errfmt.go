@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/99designs/keyring"
@@ -37,6 +38,16 @@ func Format(err error) string {
 		)
 	}
 
+	var scopeErr *gogapi.ScopeUpgradeRequiredError
+	if errors.As(err, &scopeErr) {
+		return fmt.Sprintf(
+			"%s has not granted %s access (token is missing the required OAuth scopes).\n\nGrant it incrementally (keeps existing access):\n  rerun with --auto-upgrade-scopes\n\nOr re-authorize with the full service list:\n  %s",
+			scopeErr.Email,
+			scopeErr.Service,
+			scopeUpgradeCommand(scopeErr),
+		)
+	}
+
 	var credErr *config.CredentialsMissingError
 	if errors.As(err, &credErr) {
 		return fmt.Sprintf(
@@ -122,3 +133,26 @@ func formatParseError(err *kong.ParseError) string {
 
 	return msg
 }
+
+// scopeUpgradeCommand renders the non-incremental fallback: re-running
+// `auth add` with the union of the already granted and the missing service.
+func scopeUpgradeCommand(e *gogapi.ScopeUpgradeRequiredError) string {
+	set := map[string]struct{}{e.Service: {}}
+	for _, s := range e.Services {
+		set[s] = struct{}{}
+	}
+
+	services := make([]string, 0, len(set))
+	for s := range set {
+		services = append(services, s)
+	}
+
+	sort.Strings(services)
+
+	prefix := "wk "
+	if e.Client != "" && e.Client != config.DefaultClientName {
+		prefix = "wk --client " + e.Client + " "
+	}
+
+	return fmt.Sprintf("%sauth add %s --services %s", prefix, e.Email, strings.Join(services, ","))
+}
@@ -34,6 +34,21 @@ func TestFormat_AuthRequired(t *testing.T) {
 	}
 }
 
+func TestFormat_ScopeUpgradeRequired(t *testing.T) {
+	err := &gogapi.ScopeUpgradeRequiredError{
+		Service:  "drive",
+		Email:    "a@b.com",
+		Client:   "work",
+		Services: []string{"gmail"},
+		Cause:    errNope,
+	}
+	got := Format(err)
+
+	if !containsAll(got, "--auto-upgrade-scopes", "wk --client work auth add a@b.com --services drive,gmail") {
+		t.Fatalf("unexpected: %q", got)
+	}
+}
+
 func TestFormat_CredentialsMissing(t *testing.T) {
 	err := &config.CredentialsMissingError{Path: "/tmp/creds.json", Cause: errNope}
 	got := Format(err)
@@ -0,0 +1,34 @@
+package googleapi
+
+import (
+	"context"
+	"fmt"
+
+	admin "google.golang.org/api/admin/directory/v1"
+
+	"github.com/automagik-dev/workit/internal/googleauth"
+)
+
+const scopeAdminDirectoryUserRO = "https://www.googleapis.com/auth/admin.directory.user.readonly"
+
+func NewAdminDirectory(ctx context.Context, email string) (*admin.Service, error) {
+	if opts, err := optionsForAccount(ctx, googleauth.ServiceAdmin, email); err != nil {
+		return nil, fmt.Errorf("admin options: %w", err)
+	} else if svc, err := admin.NewService(ctx, opts...); err != nil {
+		return nil, fmt.Errorf("create admin service: %w", err)
+	} else {
+		return svc, nil
+	}
+}
+
+// NewAdminDirectoryUsersReadonly requests only the read-only user scope, so
+// listing users works with a narrow domain-wide delegation allowlist.
+func NewAdminDirectoryUsersReadonly(ctx context.Context, email string) (*admin.Service, error) {
+	if opts, err := optionsForAccountScopes(ctx, string(googleauth.ServiceAdmin), email, []string{scopeAdminDirectoryUserRO}); err != nil {
+		return nil, fmt.Errorf("admin options: %w", err)
+	} else if svc, err := admin.NewService(ctx, opts...); err != nil {
+		return nil, fmt.Errorf("create admin service: %w", err)
+	} else {
+		return svc, nil
+	}
+}
@@ -9,12 +9,23 @@ import (
 
 const (
 	scopeCloudIdentityGroupsRO = "https://www.googleapis.com/auth/cloud-identity.groups.readonly"
+	scopeCloudIdentityGroups   = "https://www.googleapis.com/auth/cloud-identity.groups"
 )
 
 // NewCloudIdentityGroups creates a Cloud Identity service for reading groups.
 // This API allows non-admin users to list groups they belong to and view group members.
 func NewCloudIdentityGroups(ctx context.Context, email string) (*cloudidentity.Service, error) {
-	if opts, err := optionsForAccountScopes(ctx, "cloudidentity", email, []string{scopeCloudIdentityGroupsRO}); err != nil {
+	return newCloudIdentity(ctx, email, scopeCloudIdentityGroupsRO)
+}
+
+// NewCloudIdentityGroupsManage creates a Cloud Identity service that can
+// create, update and delete groups and change their memberships.
+func NewCloudIdentityGroupsManage(ctx context.Context, email string) (*cloudidentity.Service, error) {
+	return newCloudIdentity(ctx, email, scopeCloudIdentityGroups)
+}
+
+func newCloudIdentity(ctx context.Context, email string, scope string) (*cloudidentity.Service, error) {
+	if opts, err := optionsForAccountScopes(ctx, "cloudidentity", email, []string{scope}); err != nil {
 		return nil, fmt.Errorf("cloudidentity options: %w", err)
 	} else if svc, err := cloudidentity.NewService(ctx, opts...); err != nil {
 		return nil, fmt.Errorf("create cloudidentity service: %w", err)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	gapi "google.golang.org/api/googleapi"
 )
 
 type AuthRequiredError struct {
@@ -26,6 +28,28 @@ func (e *AuthRequiredError) Unwrap() error {
 	return e.Cause
 }
 
+// ScopeUpgradeRequiredError indicates the stored OAuth token is valid but was
+// not granted the scopes the requested service needs.
+type ScopeUpgradeRequiredError struct {
+	Service  string
+	Email    string
+	Client   string
+	Services []string // services already granted to the stored token
+	Cause    error
+}
+
+func (e *ScopeUpgradeRequiredError) Error() string {
+	if e.Client != "" {
+		return fmt.Sprintf("insufficient OAuth scopes for %s %s (client %s)", e.Service, e.Email, e.Client)
+	}
+
+	return fmt.Sprintf("insufficient OAuth scopes for %s %s", e.Service, e.Email)
+}
+
+func (e *ScopeUpgradeRequiredError) Unwrap() error {
+	return e.Cause
+}
+
 // RateLimitError indicates rate limit was exceeded
 type RateLimitError struct {
 	RetryAfter time.Duration
@@ -94,6 +118,27 @@ func IsAuthRequiredError(err error) bool {
 	return errors.As(err, &e)
 }
 
+// IsInsufficientScopeError reports whether err is a Google API 403 caused by
+// the access token lacking a required OAuth scope (as opposed to the account
+// lacking permission on the resource).
+func IsInsufficientScopeError(err error) bool {
+	var gerr *gapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != 403 {
+		return false
+	}
+
+	for _, item := range gerr.Errors {
+		if strings.EqualFold(item.Reason, "insufficientPermissions") {
+			return true
+		}
+	}
+
+	msg := gerr.Message + " " + gerr.Body
+
+	return strings.Contains(msg, "ACCESS_TOKEN_SCOPE_INSUFFICIENT") ||
+		strings.Contains(msg, "insufficient authentication scopes")
+}
+
 // IsRateLimitError checks if the error is a rate limit error
 func IsRateLimitError(err error) bool {
 	var e *RateLimitError
@@ -138,6 +183,7 @@ var APIEnablementLinks = map[string]string{
 	"people":        "https://console.developers.google.com/apis/api/people.googleapis.com/overview",
 	"classroom":     "https://console.developers.google.com/apis/api/classroom.googleapis.com/overview",
 	"cloudidentity": "https://console.developers.google.com/apis/api/cloudidentity.googleapis.com/overview",
+	"admin":         "https://console.developers.google.com/apis/api/admin.googleapis.com/overview",
 }
 
 // IsAPINotEnabledError checks whether an error message indicates an API that
@@ -2,9 +2,12 @@ package googleapi
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
+
+	gapi "google.golang.org/api/googleapi"
 )
 
 var (
@@ -195,3 +198,33 @@ func TestIsTransientStatusCode(t *testing.T) {
 		}
 	}
 }
+
+func TestIsInsufficientScopeError(t *testing.T) {
+	scopeErr := &gapi.Error{
+		Code:    403,
+		Message: "Request had insufficient authentication scopes.",
+		Errors:  []gapi.ErrorItem{{Reason: "insufficientPermissions"}},
+	}
+	if !IsInsufficientScopeError(fmt.Errorf("list: %w", scopeErr)) {
+		t.Fatalf("expected insufficient scope error")
+	}
+
+	detailsOnly := &gapi.Error{Code: 403, Body: `{"error":{"details":[{"reason":"ACCESS_TOKEN_SCOPE_INSUFFICIENT"}]}}`}
+	if !IsInsufficientScopeError(detailsOnly) {
+		t.Fatalf("expected insufficient scope error from details")
+	}
+
+	forbidden := &gapi.Error{Code: 403, Message: "The caller does not have permission", Errors: []gapi.ErrorItem{{Reason: "forbidden"}}}
+	if IsInsufficientScopeError(forbidden) || IsInsufficientScopeError(errBase) {
+		t.Fatalf("unexpected insufficient scope match")
+	}
+
+	upgradeErr := &ScopeUpgradeRequiredError{Service: "tasks", Email: "a@b.com", Cause: scopeErr}
+	if got := upgradeErr.Error(); got != "insufficient OAuth scopes for tasks a@b.com" {
+		t.Fatalf("unexpected: %q", got)
+	}
+
+	if !errors.Is(upgradeErr, scopeErr) {
+		t.Fatalf("expected unwrap to match cause")
+	}
+}
@@ -26,6 +26,15 @@ var newServiceAccountTokenSource = func(ctx context.Context, keyJSON []byte, sub
 }
 
 func tokenSourceForServiceAccountScopes(ctx context.Context, email string, scopes []string) (oauth2.TokenSource, string, bool, error) {
+	if key := serviceAccountKeyFromContext(ctx); key != nil {
+		ts, err := newServiceAccountTokenSource(ctx, key, email, scopes)
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		return ts, "(domain-wide delegation)", true, nil
+	}
+
 	saPath, err := config.ServiceAccountPath(email)
 	if err != nil {
 		return nil, "", false, fmt.Errorf("service account path: %w", err)
@@ -82,3 +91,20 @@ func tokenSourceForServiceAccountScopes(ctx context.Context, email string, scope
 
 	return nil, "", false, nil
 }
+
+type serviceAccountKeyCtxKey struct{}
+
+// WithServiceAccountKey makes every client built from ctx impersonate its
+// account with keyJSON (a key with domain-wide delegation) instead of the
+// per-account key or OAuth token stored for it.
+func WithServiceAccountKey(ctx context.Context, keyJSON []byte) context.Context {
+	return context.WithValue(ctx, serviceAccountKeyCtxKey{}, keyJSON)
+}
+
+func serviceAccountKeyFromContext(ctx context.Context) []byte {
+	if v, ok := ctx.Value(serviceAccountKeyCtxKey{}).([]byte); ok && len(v) > 0 {
+		return v
+	}
+
+	return nil
+}
@@ -17,11 +17,12 @@ const (
 	AuthModeBrowser  = "browser"
 	AuthModeHeadless = "headless"
 	AuthModeManual   = "manual"
+	AuthModeDevice   = "device"
 )
 
 // AuthModeResult holds the resolved auth mode and how it was determined.
 type AuthModeResult struct {
-	Mode           string // browser, headless, or manual
+	Mode           string // browser, headless, manual, or device
 	Source         string // flag, config, or auto
 	CallbackServer string // resolved callback server URL (empty if not applicable)
 }
@@ -39,7 +40,8 @@ var isTerminal = func() bool {
 //  3. Config auth_mode=browser → browser
 //  4. Config auth_mode=headless → headless (if callback server resolvable)
 //  5. Config auth_mode=manual → manual
-//  6. Auto: no TTY + callback server reachable → headless; otherwise browser
+//  6. Config auth_mode=device → device (RFC 8628 device authorization grant)
+//  7. Auto: no TTY + callback server reachable → headless; otherwise browser
 func ResolveAuthMode(ctx context.Context, explicitHeadless, explicitManual bool, callbackServerFlag string) AuthModeResult {
 	// 1. Explicit flags always win
 	if explicitHeadless {
@@ -68,6 +70,8 @@ func ResolveAuthMode(ctx context.Context, explicitHeadless, explicitManual bool,
 			return AuthModeResult{Mode: AuthModeBrowser, Source: "config"}
 		case AuthModeManual:
 			return AuthModeResult{Mode: AuthModeManual, Source: "config"}
+		case AuthModeDevice:
+			return AuthModeResult{Mode: AuthModeDevice, Source: "config"}
 		}
 	}
 
@@ -126,6 +126,15 @@ func TestResolveAuthMode_ConfigManual(t *testing.T) {
 	}
 }
 
+func TestResolveAuthMode_ConfigDevice(t *testing.T) {
+	setupTestConfig(t, `{"auth_mode":"device"}`)
+
+	result := ResolveAuthMode(context.Background(), false, false, "")
+	if result.Mode != AuthModeDevice || result.Source != "config" {
+		t.Fatalf("expected device from config, got %+v", result)
+	}
+}
+
 func TestResolveAuthMode_AutoDetect_NoTTY_ReachableServer(t *testing.T) {
 	clearTestConfig(t)
 	t.Setenv("WK_CALLBACK_SERVER", "")
@@ -0,0 +1,123 @@
+package googleauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	errDeviceAccessDenied = errors.New("device authorization denied by user")
+	errDeviceCodeExpired  = errors.New("device code expired before authorization completed")
+	errDeviceUnsupported  = errors.New("oauth endpoint does not support device authorization")
+)
+
+// DeviceCodeInfo is what the user needs to finish a device authorization on
+// another device: open VerificationURL and enter UserCode.
+type DeviceCodeInfo struct {
+	UserCode                string `json:"user_code"`
+	VerificationURL         string `json:"verification_url"`
+	VerificationURLComplete string `json:"verification_url_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceOptions configures the RFC 8628 device authorization flow.
+type DeviceOptions struct {
+	Scopes  []string
+	Client  string
+	Timeout time.Duration
+	// OnCode is called once the device code is issued, before polling starts.
+	OnCode func(DeviceCodeInfo)
+}
+
+// DeviceAuthorize runs the OAuth 2.0 device authorization grant (RFC 8628):
+// it requests a device/user code pair, reports it through opts.OnCode, then
+// polls the token endpoint until the user approves, denies, or the code
+// expires. It returns the refresh token.
+//
+// Google only allows this flow for OAuth clients of type "TVs and Limited
+// Input devices", and only for a subset of scopes.
+func DeviceAuthorize(ctx context.Context, opts DeviceOptions) (string, error) {
+	if len(opts.Scopes) == 0 {
+		return "", errMissingScopes
+	}
+
+	if strings.TrimSpace(oauthEndpoint.DeviceAuthURL) == "" {
+		return "", errDeviceUnsupported
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+
+	creds, err := readClientCredentials(opts.Client)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+
+	cfg := oauth2.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		Endpoint:     oauthEndpoint,
+		Scopes:       opts.Scopes,
+	}
+
+	da, err := cfg.DeviceAuth(ctx)
+	if err != nil {
+		if IsInvalidClient(err) {
+			return "", fmt.Errorf("request device code: %w (hint: the device flow needs an OAuth client of type \"TVs and Limited Input devices\")", err)
+		}
+
+		return "", fmt.Errorf("request device code: %w", err)
+	}
+
+	if opts.OnCode != nil {
+		info := DeviceCodeInfo{
+			UserCode:                da.UserCode,
+			VerificationURL:         da.VerificationURI,
+			VerificationURLComplete: da.VerificationURIComplete,
+			Interval:                int(da.Interval),
+		}
+		if !da.Expiry.IsZero() {
+			info.ExpiresIn = int(time.Until(da.Expiry).Round(time.Second).Seconds())
+		}
+
+		opts.OnCode(info)
+	}
+
+	tok, err := cfg.DeviceAccessToken(ctx, da)
+	if err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) {
+			switch re.ErrorCode {
+			case "access_denied":
+				return "", errDeviceAccessDenied
+			case "expired_token":
+				return "", errDeviceCodeExpired
+			}
+		}
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			return "", errDeviceCodeExpired
+		}
+
+		return "", fmt.Errorf("poll device token: %w", err)
+	}
+
+	if strings.TrimSpace(tok.RefreshToken) == "" {
+		return "", errNoRefreshToken
+	}
+
+	return tok.RefreshToken, nil
+}
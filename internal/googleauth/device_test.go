@@ -0,0 +1,135 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+func newDeviceStandIn(t *testing.T, final map[string]any) *httptest.Server {
+	t.Helper()
+
+	var polls atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_id") != "id" || r.Form.Get("scope") != "openid email" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "dev",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": "https://www.google.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || r.Form.Get("device_code") != "dev" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_request"})
+			return
+		}
+		if polls.Add(1) == 1 {
+			w.WriteHeader(http.StatusPreconditionRequired)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "authorization_pending"})
+			return
+		}
+		if _, ok := final["error"]; ok {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		_ = json.NewEncoder(w).Encode(final)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func stubDeviceEndpoints(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+
+	origRead := readClientCredentials
+	origEndpoint := oauthEndpoint
+
+	t.Cleanup(func() {
+		readClientCredentials = origRead
+		oauthEndpoint = origEndpoint
+	})
+
+	readClientCredentials = func(string) (config.ClientCredentials, error) {
+		return config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil
+	}
+	oauthEndpoint = oauth2.Endpoint{
+		DeviceAuthURL: srv.URL + "/device/code",
+		TokenURL:      srv.URL + "/token",
+	}
+}
+
+func TestDeviceAuthorize_Success(t *testing.T) {
+	srv := newDeviceStandIn(t, map[string]any{
+		"access_token":  "access",
+		"refresh_token": "refresh",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+	stubDeviceEndpoints(t, srv)
+
+	var got DeviceCodeInfo
+
+	rt, err := DeviceAuthorize(context.Background(), DeviceOptions{
+		Scopes:  []string{"openid", "email"},
+		Timeout: 10 * time.Second,
+		OnCode:  func(info DeviceCodeInfo) { got = info },
+	})
+	if err != nil {
+		t.Fatalf("DeviceAuthorize: %v", err)
+	}
+
+	if rt != "refresh" {
+		t.Fatalf("refresh token = %q", rt)
+	}
+
+	if got.UserCode != "ABCD-EFGH" || got.VerificationURL != "https://www.google.com/device" || got.Interval != 1 {
+		t.Fatalf("unexpected code info: %#v", got)
+	}
+}
+
+func TestDeviceAuthorize_Denied(t *testing.T) {
+	srv := newDeviceStandIn(t, map[string]any{"error": "access_denied"})
+	stubDeviceEndpoints(t, srv)
+
+	_, err := DeviceAuthorize(context.Background(), DeviceOptions{
+		Scopes:  []string{"openid", "email"},
+		Timeout: 10 * time.Second,
+	})
+	if !errors.Is(err, errDeviceAccessDenied) {
+		t.Fatalf("expected access denied, got %v", err)
+	}
+}
+
+func TestDeviceAuthorize_Unsupported(t *testing.T) {
+	origEndpoint := oauthEndpoint
+	t.Cleanup(func() { oauthEndpoint = origEndpoint })
+
+	oauthEndpoint = oauth2.Endpoint{TokenURL: "http://127.0.0.1/token"}
+
+	if _, err := DeviceAuthorize(context.Background(), DeviceOptions{Scopes: []string{"openid"}}); !errors.Is(err, errDeviceUnsupported) {
+		t.Fatalf("expected unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package googleauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+// End-to-end encrypted relay handoff.
+//
+// HeadlessAuthorize generates an ephemeral age X25519 keypair and a PKCE
+// verifier. The public key travels to the relay inside the OAuth state
+// ("<nonce>.<age1...>") and the auth URL carries the S256 code challenge. The
+// relay can only exchange the code when the polling CLI presents the
+// verifier, and it returns the token encrypted to the public key, so neither
+// the relay's storage nor its responses ever hold a plaintext refresh token.
+//
+// The private half is kept in a 0600 file in the config dir so a later
+// `wk auth poll <state>` (after --no-poll) can finish the handoff.
+
+const (
+	handoffFilePrefix = "oauth-handoff-"
+	handoffFileSuffix = ".json"
+)
+
+// handoffTTL outlives the relay's token TTL so a late poll still finds its key.
+const handoffTTL = 30 * time.Minute
+
+var (
+	errHandoffKeyMissing = errors.New("no local key for this encrypted handoff; run the poll on the machine that started `wk auth add --headless`")
+	errHandoffDecrypt    = errors.New("decrypt relay token")
+)
+
+type handoffSecret struct {
+	State     string    `json:"state"`
+	Identity  string    `json:"identity"`
+	Verifier  string    `json:"verifier"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var handoffDirFn = manualStateDir
+
+// newHandoff creates the per-authorization keypair and PKCE verifier and
+// returns the relay state that embeds the public key.
+func newHandoff() (handoffSecret, error) {
+	nonce, err := randomStateFn()
+	if err != nil {
+		return handoffSecret{}, err
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return handoffSecret{}, fmt.Errorf("generate handoff key: %w", err)
+	}
+
+	return handoffSecret{
+		State:     relayproto.NewState(nonce, identity.Recipient()),
+		Identity:  identity.String(),
+		Verifier:  oauth2.GenerateVerifier(),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// isHandoffState reports whether state was produced by newHandoff.
+func isHandoffState(state string) bool {
+	_, ok, err := relayproto.ParseState(state)
+
+	return ok && err == nil
+}
+
+func handoffPathFor(state string) (string, error) {
+	dir, err := handoffDirFn()
+	if err != nil {
+		return "", err
+	}
+
+	state = strings.TrimSpace(state)
+	if state == "" || strings.ContainsAny(state, `/\`) {
+		return "", relayproto.ErrInvalidState
+	}
+
+	return filepath.Join(dir, handoffFilePrefix+state+handoffFileSuffix), nil
+}
+
+func saveHandoff(secret handoffSecret) error {
+	path, err := handoffPathFor(secret.State)
+	if err != nil {
+		return err
+	}
+
+	pruneHandoffs(filepath.Dir(path))
+
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("encode handoff key: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write handoff key: %w", err)
+	}
+
+	return nil
+}
+
+func loadHandoff(state string) (handoffSecret, bool, error) {
+	path, err := handoffPathFor(state)
+	if err != nil {
+		return handoffSecret{}, false, err
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // config path
+	if err != nil {
+		if os.IsNotExist(err) {
+			return handoffSecret{}, false, nil
+		}
+
+		return handoffSecret{}, false, fmt.Errorf("read handoff key: %w", err)
+	}
+
+	var secret handoffSecret
+	if err := json.Unmarshal(data, &secret); err != nil || secret.State != state {
+		_ = os.Remove(path)
+		return handoffSecret{}, false, nil //nolint:nilerr // corrupt key file is treated as missing
+	}
+
+	return secret, true, nil
+}
+
+func removeHandoff(state string) {
+	if path, err := handoffPathFor(state); err == nil {
+		_ = os.Remove(path)
+	}
+}
+
+// pruneHandoffs removes key files for handoffs that can no longer complete.
+func pruneHandoffs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasPrefix(name, handoffFilePrefix) || !strings.HasSuffix(name, handoffFileSuffix) {
+			continue
+		}
+
+		if info, infoErr := ent.Info(); infoErr == nil && time.Since(info.ModTime()) > handoffTTL {
+			_ = os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+// decryptHandoffToken decrypts the relay's base64 age payload into the
+// plaintext token fields.
+func decryptHandoffToken(secret handoffSecret, encrypted string) (PollResponse, error) {
+	identity, err := age.ParseX25519Identity(secret.Identity)
+	if err != nil {
+		return PollResponse{}, fmt.Errorf("%w: %w", errHandoffDecrypt, err)
+	}
+
+	tok, err := relayproto.DecryptToken(identity, encrypted)
+	if err != nil {
+		return PollResponse{}, fmt.Errorf("%w: %w", errHandoffDecrypt, err)
+	}
+
+	return PollResponse{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenType:    tok.TokenType,
+		Expiry:       tok.Expiry,
+	}, nil
+}
@@ -0,0 +1,243 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+func TestHeadlessEncryptedHandoff(t *testing.T) {
+	dir := t.TempDir()
+
+	origRead := readClientCredentials
+	origDir := handoffDirFn
+
+	t.Cleanup(func() {
+		readClientCredentials = origRead
+		handoffDirFn = origDir
+	})
+
+	readClientCredentials = func(string) (config.ClientCredentials, error) {
+		return config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil
+	}
+	handoffDirFn = func() (string, error) { return dir, nil }
+
+	var challenge string
+
+	// Stand-in relay: checks the PKCE verifier against the challenge from the
+	// auth URL and encrypts the token to the recipient embedded in the state.
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "ok", "features": []string{relayproto.FeatureHandoff}})
+			return
+		}
+
+		state := strings.TrimPrefix(r.URL.Path, "/token/")
+
+		sum := sha256.Sum256([]byte(r.Header.Get(relayproto.CodeVerifierHeader)))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "exchange_failed"})
+			return
+		}
+
+		recipient, _, err := relayproto.ParseState(state)
+		if err != nil {
+			t.Errorf("parse state: %v", err)
+			return
+		}
+
+		resp, err := relayproto.EncryptToken(recipient, relayproto.TokenResponse{RefreshToken: "secret-refresh"})
+		if err != nil {
+			t.Errorf("encrypt token: %v", err)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer relay.Close()
+
+	info, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+		Scopes:         []string{"openid"},
+		CallbackServer: relay.URL,
+	})
+	if err != nil {
+		t.Fatalf("HeadlessAuthorize: %v", err)
+	}
+
+	if !isHandoffState(info.State) || !info.Encrypted {
+		t.Fatalf("state does not carry a public key: %q", info.State)
+	}
+
+	u, err := url.Parse(info.AuthURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("state") != info.State {
+		t.Fatalf("auth url missing PKCE/state: %s", info.AuthURL)
+	}
+
+	challenge = q.Get("code_challenge")
+
+	rt, err := PollForToken(context.Background(), relay.URL, info.State, 10*time.Second)
+	if err != nil {
+		t.Fatalf("PollForToken: %v", err)
+	}
+
+	if rt != "secret-refresh" {
+		t.Fatalf("refresh token = %q", rt)
+	}
+
+	if _, ok, _ := loadHandoff(info.State); ok {
+		t.Fatalf("handoff key should be removed after a successful poll")
+	}
+}
+
+func TestHeadlessAuthorize_LegacyRelayFallback(t *testing.T) {
+	t.Setenv("WK_HANDOFF_ENCRYPTION", "preferred")
+
+	dir := t.TempDir()
+
+	origRead := readClientCredentials
+	origDir := handoffDirFn
+
+	t.Cleanup(func() {
+		readClientCredentials = origRead
+		handoffDirFn = origDir
+	})
+
+	readClientCredentials = func(string) (config.ClientCredentials, error) {
+		return config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil
+	}
+	handoffDirFn = func() (string, error) { return dir, nil }
+
+	// A relay from before encrypted handoffs: /health without features, and
+	// no /health at all under a tenant prefix.
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}))
+	defer relay.Close()
+
+	for _, server := range []string{relay.URL, relay.URL + "/c/acme"} {
+		info, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+			Scopes:         []string{"openid"},
+			CallbackServer: server,
+		})
+		if err != nil {
+			t.Fatalf("HeadlessAuthorize(%s): %v", server, err)
+		}
+
+		if info.Encrypted || isHandoffState(info.State) || strings.Contains(info.AuthURL, "code_challenge") {
+			t.Fatalf("%s: expected a legacy handoff, got %+v", server, info)
+		}
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("legacy handoff saved %d key files", len(entries))
+	}
+
+	// Requiring encryption refuses the downgrade.
+	if _, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+		Scopes:           []string{"openid"},
+		CallbackServer:   relay.URL,
+		RequireEncrypted: true,
+	}); !errors.Is(err, errHandoffUnsupported) {
+		t.Fatalf("HeadlessAuthorize(require) = %v, want errHandoffUnsupported", err)
+	}
+
+	t.Setenv("WK_HANDOFF_ENCRYPTION", "required")
+
+	if _, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+		Scopes:         []string{"openid"},
+		CallbackServer: relay.URL,
+	}); !errors.Is(err, errHandoffUnsupported) {
+		t.Fatalf("HeadlessAuthorize(WK_HANDOFF_ENCRYPTION=required) = %v, want errHandoffUnsupported", err)
+	}
+
+	if _, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+		Scopes:         []string{"openid"},
+		CallbackServer: "http://127.0.0.1:1",
+	}); err == nil {
+		t.Fatal("expected an error for an unreachable callback server")
+	}
+}
+
+func TestPollForToken_HandoffKeyMissing(t *testing.T) {
+	origDir := handoffDirFn
+	t.Cleanup(func() { handoffDirFn = origDir })
+
+	handoffDirFn = func() (string, error) { return t.TempDir(), nil }
+
+	identity, _ := age.GenerateX25519Identity()
+
+	_, err := PollForToken(context.Background(), "http://127.0.0.1:1", "nonce."+identity.Recipient().String(), time.Second)
+	if !errors.Is(err, errHandoffKeyMissing) {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
+
+func TestPollOnce_DomainRejected(t *testing.T) {
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"error":   "domain_not_allowed",
+			"message": "This account's domain is not allowed for this application",
+		})
+	}))
+	defer relay.Close()
+
+	_, done, err := pollOnce(context.Background(), relay.Client(), relay.URL+"/token/s", &handoffSecret{Verifier: "v"})
+	if done || !errors.Is(err, errRelayRejected) || !strings.Contains(err.Error(), "domain is not allowed") {
+		t.Fatalf("expected relay rejection, got done=%v err=%v", done, err)
+	}
+}
+
+func TestSaveHandoff_Permissions(t *testing.T) {
+	dir := t.TempDir()
+
+	origDir := handoffDirFn
+	t.Cleanup(func() { handoffDirFn = origDir })
+
+	handoffDirFn = func() (string, error) { return dir, nil }
+
+	secret, err := newHandoff()
+	if err != nil {
+		t.Fatalf("newHandoff: %v", err)
+	}
+
+	if err := saveHandoff(secret); err != nil {
+		t.Fatalf("saveHandoff: %v", err)
+	}
+
+	path, _ := handoffPathFor(secret.State)
+
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if st.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v", st.Mode().Perm())
+	}
+}
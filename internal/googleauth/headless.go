@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 
 	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/relayproto"
 )
 
 var (
@@ -20,8 +22,12 @@ var (
 	errPollTimeout           = errors.New("timeout waiting for token")
 	errTokenConsumed         = errors.New("token has already been retrieved")
 	errTokenNotFound         = errors.New("token not found or expired")
+	errRelayRejected         = errors.New("callback server rejected the poll")
+	errHandoffUnsupported    = errors.New("callback server does not support end-to-end encrypted handoffs (upgrade it, or drop --require-e2e / handoff_encryption=required to allow the plaintext handoff)")
 )
 
+var relayHealthClient = &http.Client{Timeout: 10 * time.Second}
+
 // IsPollTimeout reports whether err is a poll timeout error.
 // This is needed because errPollTimeout is unexported.
 func IsPollTimeout(err error) bool {
@@ -39,6 +45,9 @@ type HeadlessAuthInfo struct {
 	State     string `json:"state"`
 	PollURL   string `json:"poll_url"`
 	ExpiresIn int    `json:"expires_in"`
+	// Encrypted is false when the callback server predates end-to-end
+	// encrypted handoffs and the token passes through it in the clear.
+	Encrypted bool `json:"encrypted"`
 }
 
 // HeadlessOptions configures the headless OAuth flow.
@@ -48,6 +57,11 @@ type HeadlessOptions struct {
 	ForceConsent   bool
 	Client         string
 	CallbackServer string
+	// RequireEncrypted refuses callback servers without end-to-end
+	// encrypted handoffs instead of falling back to the plaintext handoff.
+	// WK_HANDOFF_ENCRYPTION=required and the handoff_encryption config key
+	// turn it on too.
+	RequireEncrypted bool
 }
 
 // CallbackServerURL returns the callback server URL from the provided sources,
@@ -73,6 +87,24 @@ func CallbackServerURL(override string) (string, error) {
 	return "", errMissingCallbackServer
 }
 
+// RequireEncryptedHandoff reports whether headless logins must refuse
+// callback servers without end-to-end encrypted handoffs, from the provided
+// sources in order of precedence: override > env var > config file. The
+// default still allows the plaintext handoff for relays that predate it.
+func RequireEncryptedHandoff(override bool) bool {
+	if override {
+		return true
+	}
+
+	if env := strings.TrimSpace(os.Getenv("WK_HANDOFF_ENCRYPTION")); env != "" {
+		return env == config.HandoffEncryptionRequired
+	}
+
+	cfg, err := config.ReadConfig()
+
+	return err == nil && cfg.HandoffEncryption == config.HandoffEncryptionRequired
+}
+
 // HeadlessAuthorize generates an OAuth URL for headless authentication.
 // The user must visit the URL and complete authentication in a browser.
 // The callback server will receive the token which can be polled for.
@@ -91,11 +123,42 @@ func HeadlessAuthorize(ctx context.Context, opts HeadlessOptions) (HeadlessAuthI
 		return HeadlessAuthInfo{}, err
 	}
 
-	state, err := randomStateFn()
+	encrypted, err := relaySupportsHandoff(ctx, callbackServer)
 	if err != nil {
 		return HeadlessAuthInfo{}, err
 	}
 
+	// The feature list comes from an unauthenticated /health, so only an
+	// explicit requirement stops a relay from forcing the downgrade.
+	if !encrypted && RequireEncryptedHandoff(opts.RequireEncrypted) {
+		return HeadlessAuthInfo{}, errHandoffUnsupported
+	}
+
+	var (
+		state  string
+		params = authURLParams(opts.ForceConsent)
+	)
+
+	if encrypted {
+		handoff, handoffErr := newHandoff()
+		if handoffErr != nil {
+			return HeadlessAuthInfo{}, handoffErr
+		}
+
+		if saveErr := saveHandoff(handoff); saveErr != nil {
+			return HeadlessAuthInfo{}, saveErr
+		}
+
+		state = handoff.State
+		params = append(params, oauth2.S256ChallengeOption(handoff.Verifier))
+	} else {
+		// An older relay exchanges the code on callback without a verifier,
+		// which Google rejects for a PKCE code: use a legacy opaque state.
+		if state, err = randomStateFn(); err != nil {
+			return HeadlessAuthInfo{}, err
+		}
+	}
+
 	// Build redirect URL pointing to the callback server
 	redirectURL := strings.TrimSuffix(callbackServer, "/") + "/callback"
 
@@ -107,7 +170,7 @@ func HeadlessAuthorize(ctx context.Context, opts HeadlessOptions) (HeadlessAuthI
 		Scopes:       opts.Scopes,
 	}
 
-	authURL := cfg.AuthCodeURL(state, authURLParams(opts.ForceConsent)...)
+	authURL := cfg.AuthCodeURL(state, params...)
 
 	pollURL := strings.TrimSuffix(callbackServer, "/") + "/token/" + state
 
@@ -116,9 +179,42 @@ func HeadlessAuthorize(ctx context.Context, opts HeadlessOptions) (HeadlessAuthI
 		State:     state,
 		PollURL:   pollURL,
 		ExpiresIn: 300, // 5 minutes default TTL on callback server
+		Encrypted: encrypted,
 	}, nil
 }
 
+// relaySupportsHandoff reports whether the callback server advertises
+// end-to-end encrypted handoffs in its /health features. Relays that predate
+// them (no features, or no /health under a tenant prefix) report false.
+func relaySupportsHandoff(ctx context.Context, callbackServer string) (bool, error) {
+	healthURL := strings.TrimSuffix(callbackServer, "/") + "/health"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("create health request: %w", err)
+	}
+
+	resp, err := relayHealthClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("reach callback server: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	var health struct {
+		Features []string `json:"features"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return false, nil //nolint:nilerr // an unparseable health body is an old relay
+	}
+
+	return slices.Contains(health.Features, relayproto.FeatureHandoff), nil
+}
+
 // PollResponse represents the response from polling the callback server.
 type PollResponse struct {
 	// Token fields (when ready)
@@ -127,6 +223,9 @@ type PollResponse struct {
 	TokenType    string `json:"token_type,omitempty"`
 	Expiry       string `json:"expiry,omitempty"`
 
+	// Encrypted holds the age-encrypted token for end-to-end encrypted handoffs.
+	Encrypted string `json:"encrypted,omitempty"`
+
 	// Status fields (when pending or error)
 	Status  string `json:"status,omitempty"`
 	Error   string `json:"error,omitempty"`
@@ -141,6 +240,21 @@ func PollForToken(ctx context.Context, callbackServer, state string, timeout tim
 
 	pollURL := strings.TrimSuffix(callbackServer, "/") + "/token/" + state
 
+	var handoff *handoffSecret
+
+	if isHandoffState(state) {
+		secret, ok, err := loadHandoff(state)
+		if err != nil {
+			return "", err
+		}
+
+		if !ok {
+			return "", errHandoffKeyMissing
+		}
+
+		handoff = &secret
+	}
+
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
@@ -156,24 +270,29 @@ func PollForToken(ctx context.Context, callbackServer, state string, timeout tim
 		case <-ctx.Done():
 			return "", errPollTimeout
 		case <-ticker.C:
-			refreshToken, done, err := pollOnce(ctx, client, pollURL)
+			refreshToken, done, err := pollOnce(ctx, client, pollURL, handoff)
 			if err != nil {
 				return "", err
 			}
 
 			if done {
+				removeHandoff(state)
 				return refreshToken, nil
 			}
 		}
 	}
 }
 
-func pollOnce(ctx context.Context, client *http.Client, pollURL string) (refreshToken string, done bool, err error) {
+func pollOnce(ctx context.Context, client *http.Client, pollURL string, handoff *handoffSecret) (refreshToken string, done bool, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pollURL, nil)
 	if err != nil {
 		return "", false, fmt.Errorf("create poll request: %w", err)
 	}
 
+	if handoff != nil {
+		req.Header.Set(relayproto.CodeVerifierHeader, handoff.Verifier)
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
 		// Network error, keep trying
@@ -191,6 +310,16 @@ func pollOnce(ctx context.Context, client *http.Client, pollURL string) (refresh
 	switch resp.StatusCode {
 	case http.StatusOK:
 		// Token is ready
+		if pollResp.Encrypted != "" {
+			if handoff == nil {
+				return "", false, errHandoffKeyMissing
+			}
+
+			if pollResp, err = decryptHandoffToken(*handoff, pollResp.Encrypted); err != nil {
+				return "", false, err
+			}
+		}
+
 		if pollResp.RefreshToken == "" {
 			return "", false, errNoRefreshToken
 		}
@@ -201,6 +330,16 @@ func pollOnce(ctx context.Context, client *http.Client, pollURL string) (refresh
 		// Token pending, continue polling
 		return "", false, nil
 
+	case http.StatusBadRequest, http.StatusForbidden:
+		// Encrypted handoff refused (missing/wrong PKCE verifier, expired code)
+		// or the account's domain is not allowed for the relay's client
+		msg := pollResp.Message
+		if msg == "" {
+			msg = pollResp.Error
+		}
+
+		return "", false, fmt.Errorf("%w: %s", errRelayRejected, msg)
+
 	case http.StatusGone:
 		// Token already consumed
 		return "", false, errTokenConsumed
@@ -0,0 +1,329 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+// Embedded auth relay.
+//
+// Relay serves the same /callback, /token/{state} and /status/{state}
+// protocol as the standalone auth-server (both build on relayproto), so
+// `wk auth add --headless` works against `wk auth relay serve` unchanged. It covers a single OAuth client with
+// in-memory storage; multi-tenant, durable or rate-limited deployments should
+// run the auth-server module instead.
+
+// DefaultRelayTTL is how long the relay keeps an unclaimed token or code.
+const DefaultRelayTTL = 15 * time.Minute
+
+var errRelayMissingClient = errors.New("relay requires an OAuth client ID and secret")
+
+// RelayOptions configures the embedded auth relay.
+type RelayOptions struct {
+	ClientID     string
+	ClientSecret string
+	// PublicURL is the base URL clients reach the relay at; it is the
+	// callback_server value and the redirect URL is PublicURL + "/callback".
+	PublicURL string
+	TTL       time.Duration
+}
+
+// Relay is an http.Handler implementing the auth relay protocol.
+type Relay struct {
+	config *oauth2.Config
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*relayEntry
+
+	// exchange swaps an authorization code for a token; verifier is empty
+	// for legacy (non-PKCE) states.
+	exchange func(ctx context.Context, code string, verifier string) (*oauth2.Token, error)
+}
+
+// relayEntry is a handoff's state: pending (neither token nor code yet,
+// e.g. while a legacy callback exchanges the code), ready, or consumed.
+type relayEntry struct {
+	token     *oauth2.Token
+	code      string
+	createdAt time.Time
+	consumed  bool
+}
+
+func (e *relayEntry) status() relayproto.Status {
+	return relayproto.EntryStatus(e.consumed, e.token != nil || e.code != "")
+}
+
+// NewRelay creates an embedded auth relay.
+func NewRelay(opts RelayOptions) (*Relay, error) {
+	if strings.TrimSpace(opts.ClientID) == "" || strings.TrimSpace(opts.ClientSecret) == "" {
+		return nil, errRelayMissingClient
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultRelayTTL
+	}
+
+	cfg := &oauth2.Config{
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		Endpoint:     oauthEndpoint,
+		RedirectURL:  RelayRedirectURL(opts.PublicURL),
+	}
+
+	r := &Relay{
+		config:  cfg,
+		ttl:     ttl,
+		entries: make(map[string]*relayEntry),
+	}
+	r.exchange = func(ctx context.Context, code string, verifier string) (*oauth2.Token, error) {
+		authOpts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+		if verifier != "" {
+			authOpts = append(authOpts, oauth2.VerifierOption(verifier))
+		}
+
+		return cfg.Exchange(ctx, code, authOpts...)
+	}
+
+	return r, nil
+}
+
+// RelayRedirectURL returns the OAuth redirect URL for a relay public URL.
+func RelayRedirectURL(publicURL string) string {
+	return strings.TrimSuffix(publicURL, "/") + "/callback"
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case req.URL.Path == "/health":
+		writeRelayJSON(w, http.StatusOK, map[string]any{
+			"status":    "ok",
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"features":  []string{relayproto.FeatureHandoff},
+		})
+	case req.URL.Path == "/callback":
+		r.handleCallback(w, req)
+	case strings.HasPrefix(req.URL.Path, "/token/"):
+		r.handleToken(w, req, strings.TrimPrefix(req.URL.Path, "/token/"))
+	case strings.HasPrefix(req.URL.Path, "/status/"):
+		r.handleStatus(w, strings.TrimPrefix(req.URL.Path, "/status/"))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *Relay) handleCallback(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+
+	if errParam := q.Get("error"); errParam != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErrorPage(w, "OAuth error: "+errParam)
+
+		return
+	}
+
+	code, state := q.Get("code"), q.Get("state")
+	if code == "" || state == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErrorPage(w, "Missing authorization code or state")
+
+		return
+	}
+
+	_, e2e, err := relayproto.ParseState(state)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		renderErrorPage(w, "Invalid state parameter")
+
+		return
+	}
+
+	if e2e {
+		// PKCE-bound code: exchanged when the polling CLI presents its verifier.
+		r.put(state, &relayEntry{code: code})
+		renderSuccessPage(w)
+
+		return
+	}
+
+	// Pollers see the state as pending while the code is exchanged.
+	r.put(state, &relayEntry{})
+
+	ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+	defer cancel()
+
+	tok, err := r.exchange(ctx, code, "")
+	if err != nil {
+		r.remove(state)
+		w.WriteHeader(http.StatusInternalServerError)
+		renderErrorPage(w, "Failed to exchange authorization code for token")
+
+		return
+	}
+
+	r.put(state, &relayEntry{token: tok})
+	renderSuccessPage(w)
+}
+
+func (r *Relay) handleToken(w http.ResponseWriter, req *http.Request, state string) {
+	if state == "" {
+		writeRelayJSON(w, http.StatusBadRequest, map[string]string{"error": "missing_state"})
+		return
+	}
+
+	recipient, e2e, err := relayproto.ParseState(state)
+	if err != nil {
+		writeRelayJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_state", "message": "Malformed state parameter"})
+		return
+	}
+
+	verifier := strings.TrimSpace(req.Header.Get(relayproto.CodeVerifierHeader))
+	if e2e && verifier == "" {
+		writeRelayJSON(w, http.StatusBadRequest, map[string]string{
+			"error":   "missing_verifier",
+			"message": "Encrypted handoff requires the " + relayproto.CodeVerifierHeader + " header",
+		})
+
+		return
+	}
+
+	// An encrypted handoff's code is only consumed once the exchange
+	// succeeds, so a poll with a wrong verifier does not destroy the login.
+	entry, status := r.lookup(state, !e2e)
+	if status != relayproto.StatusReady {
+		writeRelayStatus(w, status)
+		return
+	}
+
+	tok := entry.token
+	if e2e {
+		ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+		defer cancel()
+
+		tok, err = r.exchange(ctx, entry.code, verifier)
+		if err != nil {
+			writeRelayJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "exchange_failed",
+				"message": "Failed to exchange authorization code (PKCE verification failed or code expired)",
+			})
+
+			return
+		}
+
+		if _, status := r.lookup(state, true); status != relayproto.StatusReady {
+			writeRelayStatus(w, status)
+			return
+		}
+	}
+
+	resp := relayproto.NewTokenResponse(tok)
+	if !e2e {
+		writeRelayJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	encrypted, err := relayproto.EncryptToken(recipient, resp)
+	if err != nil {
+		writeRelayJSON(w, http.StatusInternalServerError, map[string]string{"error": "encryption_failed"})
+		return
+	}
+
+	writeRelayJSON(w, http.StatusOK, encrypted)
+}
+
+func (r *Relay) handleStatus(w http.ResponseWriter, state string) {
+	if state == "" {
+		writeRelayJSON(w, http.StatusBadRequest, map[string]string{"error": "missing_state"})
+		return
+	}
+
+	writeRelayJSON(w, http.StatusOK, map[string]relayproto.Status{"status": r.status(state)})
+}
+
+func (r *Relay) put(state string, entry *relayEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for s, e := range r.entries {
+		if now.Sub(e.createdAt) > r.ttl {
+			delete(r.entries, s)
+		}
+	}
+
+	entry.createdAt = now
+	r.entries[state] = entry
+}
+
+func (r *Relay) remove(state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.entries, state)
+}
+
+// lookup returns the entry for state if it is ready, consuming it if
+// consume is set, and the entry's status.
+func (r *Relay) lookup(state string, consume bool) (relayEntry, relayproto.Status) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[state]
+	if !ok || time.Since(entry.createdAt) > r.ttl {
+		delete(r.entries, state)
+		return relayEntry{}, relayproto.StatusNotFound
+	}
+
+	status := entry.status()
+	if status != relayproto.StatusReady {
+		return relayEntry{}, status
+	}
+
+	out := *entry
+	if consume {
+		entry.consumed = true
+		entry.token = nil
+		entry.code = ""
+	}
+
+	return out, status
+}
+
+func (r *Relay) status(state string) relayproto.Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[state]
+	if !ok || time.Since(entry.createdAt) > r.ttl {
+		return relayproto.StatusNotFound
+	}
+
+	return entry.status()
+}
+
+func writeRelayStatus(w http.ResponseWriter, status relayproto.Status) {
+	code, body := relayproto.StatusResponse(status)
+	writeRelayJSON(w, code, body)
+}
+
+func writeRelayJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
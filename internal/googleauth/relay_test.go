@@ -0,0 +1,291 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/config"
+	"github.com/automagik-dev/workit/internal/relayproto"
+)
+
+func TestRelay_HeadlessRoundTrip(t *testing.T) {
+	origRead := readClientCredentials
+	origDir := handoffDirFn
+
+	t.Cleanup(func() {
+		readClientCredentials = origRead
+		handoffDirFn = origDir
+	})
+
+	dir := t.TempDir()
+	readClientCredentials = func(string) (config.ClientCredentials, error) {
+		return config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil
+	}
+	handoffDirFn = func() (string, error) { return dir, nil }
+
+	relay, err := NewRelay(RelayOptions{ClientID: "id", ClientSecret: "secret", PublicURL: "http://relay.test/"})
+	if err != nil {
+		t.Fatalf("NewRelay: %v", err)
+	}
+
+	if relay.config.RedirectURL != "http://relay.test/callback" {
+		t.Fatalf("redirect url = %q", relay.config.RedirectURL)
+	}
+
+	var challenge string
+
+	relay.exchange = func(_ context.Context, code string, verifier string) (*oauth2.Token, error) {
+		sum := sha256.Sum256([]byte(verifier))
+		if code != "auth-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			return nil, errors.New("invalid_grant")
+		}
+
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "relay-refresh"}, nil
+	}
+
+	srv := httptest.NewServer(relay)
+	defer srv.Close()
+
+	info, err := HeadlessAuthorize(context.Background(), HeadlessOptions{
+		Scopes:         []string{"openid"},
+		CallbackServer: srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("HeadlessAuthorize: %v", err)
+	}
+
+	u, err := url.Parse(info.AuthURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+
+	challenge = u.Query().Get("code_challenge")
+
+	// The browser returns from Google to the relay's callback.
+	resp, err := http.Get(srv.URL + "/callback?code=auth-code&state=" + url.QueryEscape(info.State))
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("callback status = %d", resp.StatusCode)
+	}
+
+	if !info.Encrypted {
+		t.Fatalf("relay did not advertise encrypted handoffs")
+	}
+
+	// A poll with a wrong verifier fails without consuming the code.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/token/"+info.State, nil)
+	req.Header.Set(relayproto.CodeVerifierHeader, "wrong")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("wrong verifier poll: %v", err)
+	}
+
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("wrong verifier status = %d", resp.StatusCode)
+	}
+
+	rt, err := PollForToken(context.Background(), srv.URL, info.State, 10*time.Second)
+	if err != nil {
+		t.Fatalf("PollForToken: %v", err)
+	}
+
+	if rt != "relay-refresh" {
+		t.Fatalf("refresh token = %q", rt)
+	}
+}
+
+func TestRelay_LegacyStateAndStatus(t *testing.T) {
+	relay, err := NewRelay(RelayOptions{ClientID: "id", ClientSecret: "secret", PublicURL: "http://relay.test"})
+	if err != nil {
+		t.Fatalf("NewRelay: %v", err)
+	}
+
+	relay.exchange = func(_ context.Context, _ string, verifier string) (*oauth2.Token, error) {
+		if verifier != "" {
+			t.Errorf("legacy exchange got verifier %q", verifier)
+		}
+
+		return &oauth2.Token{AccessToken: "access", RefreshToken: "legacy-refresh", TokenType: "Bearer"}, nil
+	}
+
+	get := func(path string) (int, map[string]string) {
+		rec := httptest.NewRecorder()
+		relay.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var body map[string]string
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+
+		return rec.Code, body
+	}
+
+	if code, body := get("/status/legacy"); code != http.StatusOK || body["status"] != "not_found" {
+		t.Fatalf("status before callback = %d %v", code, body)
+	}
+
+	if code, _ := get("/callback?code=c&state=legacy"); code != http.StatusOK {
+		t.Fatalf("callback = %d", code)
+	}
+
+	if _, body := get("/status/legacy"); body["status"] != "ready" {
+		t.Fatalf("status after callback = %v", body)
+	}
+
+	if code, body := get("/token/legacy"); code != http.StatusOK || body["refresh_token"] != "legacy-refresh" {
+		t.Fatalf("token = %d %v", code, body)
+	}
+
+	if code, body := get("/token/legacy"); code != http.StatusGone || body["error"] != "consumed" {
+		t.Fatalf("second token = %d %v", code, body)
+	}
+
+	if code, body := get("/token/nonce.notakey"); code != http.StatusBadRequest || body["error"] != "invalid_state" {
+		t.Fatalf("malformed state = %d %v", code, body)
+	}
+
+	if code, _ := get("/health"); code != http.StatusOK {
+		t.Fatalf("health = %d", code)
+	}
+}
+
+// TestRelay_HeadlessPollerContract runs the CLI's poller against the
+// embedded relay through every status a poll can see.
+func TestRelay_HeadlessPollerContract(t *testing.T) {
+	origDir := handoffDirFn
+	t.Cleanup(func() { handoffDirFn = origDir })
+
+	dir := t.TempDir()
+	handoffDirFn = func() (string, error) { return dir, nil }
+
+	relay, err := NewRelay(RelayOptions{ClientID: "id", ClientSecret: "secret", PublicURL: "http://relay.test"})
+	if err != nil {
+		t.Fatalf("NewRelay: %v", err)
+	}
+
+	exchanging := make(chan struct{})
+	release := make(chan struct{})
+	relay.exchange = func(_ context.Context, code string, _ string) (*oauth2.Token, error) {
+		if code == "slow-code" {
+			close(exchanging)
+			<-release
+		}
+
+		return &oauth2.Token{AccessToken: "access", RefreshToken: code + "-refresh"}, nil
+	}
+
+	srv := httptest.NewServer(relay)
+	defer srv.Close()
+
+	callback := func(code, state string) {
+		resp, err := http.Get(srv.URL + "/callback?code=" + code + "&state=" + url.QueryEscape(state))
+		if err != nil {
+			t.Errorf("callback: %v", err)
+			return
+		}
+
+		_ = resp.Body.Close()
+	}
+
+	status := func(state string) relayproto.Status {
+		resp, err := http.Get(srv.URL + "/status/" + url.PathEscape(state))
+		if err != nil {
+			t.Fatalf("status: %v", err)
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		var body struct {
+			Status relayproto.Status `json:"status"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+
+		return body.Status
+	}
+
+	poll := func(state string, handoff *handoffSecret) (string, bool, error) {
+		return pollOnce(context.Background(), srv.Client(), srv.URL+"/token/"+state, handoff)
+	}
+
+	t.Run("encrypted", func(t *testing.T) {
+		handoff, err := newHandoff()
+		if err != nil {
+			t.Fatalf("newHandoff: %v", err)
+		}
+
+		if err := saveHandoff(handoff); err != nil {
+			t.Fatalf("saveHandoff: %v", err)
+		}
+
+		if _, done, err := poll(handoff.State, &handoff); done || err != nil {
+			t.Fatalf("poll before callback = %v, %v; want keep polling", done, err)
+		}
+
+		callback("e2e-code", handoff.State)
+
+		if got := status(handoff.State); got != relayproto.StatusReady {
+			t.Fatalf("status after callback = %q", got)
+		}
+
+		rt, err := PollForToken(context.Background(), srv.URL, handoff.State, 10*time.Second)
+		if err != nil || rt != "e2e-code-refresh" {
+			t.Fatalf("PollForToken = %q, %v", rt, err)
+		}
+
+		if got := status(handoff.State); got != relayproto.StatusConsumed {
+			t.Fatalf("status after poll = %q", got)
+		}
+
+		if _, _, err := poll(handoff.State, &handoff); !errors.Is(err, errTokenConsumed) {
+			t.Fatalf("second poll = %v, want consumed", err)
+		}
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		const state = "legacy-state"
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			callback("slow-code", state)
+		}()
+
+		<-exchanging
+
+		if got := status(state); got != relayproto.StatusPending {
+			t.Fatalf("status while exchanging = %q", got)
+		}
+
+		if _, finished, err := poll(state, nil); finished || err != nil {
+			t.Fatalf("poll while exchanging = %v, %v; want keep polling", finished, err)
+		}
+
+		close(release)
+		<-done
+
+		rt, err := PollForToken(context.Background(), srv.URL, state, 10*time.Second)
+		if err != nil || rt != "slow-code-refresh" {
+			t.Fatalf("PollForToken = %q, %v", rt, err)
+		}
+	})
+}
+
+func TestNewRelay_RequiresClient(t *testing.T) {
+	if _, err := NewRelay(RelayOptions{ClientID: "id"}); !errors.Is(err, errRelayMissingClient) {
+		t.Fatalf("expected missing client error, got %v", err)
+	}
+}
@@ -27,6 +27,7 @@ var CommandServiceMap = map[string]Service{
 	"keep":      ServiceKeep,
 	"appscript": ServiceAppScript,
 	"groups":    ServiceGroups,
+	"admin":     ServiceAdmin,
 }
 
 // ScopesForCommands returns the union of OAuth scopes needed for the given CLI
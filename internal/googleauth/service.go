@@ -25,6 +25,7 @@ const (
 	ServiceAppScript Service = "appscript"
 	ServiceGroups    Service = "groups"
 	ServiceKeep      Service = "keep"
+	ServiceAdmin     Service = "admin"
 )
 
 const (
@@ -74,6 +75,7 @@ var serviceOrder = []Service{
 	ServiceAppScript,
 	ServiceGroups,
 	ServiceKeep,
+	ServiceAdmin,
 }
 
 var serviceInfoByService = map[Service]serviceInfo{
@@ -192,7 +194,7 @@ var serviceInfoByService = map[Service]serviceInfo{
 		apis: []string{"Apps Script API"},
 	},
 	ServiceGroups: {
-		scopes: []string{"https://www.googleapis.com/auth/cloud-identity.groups.readonly"},
+		scopes: []string{"https://www.googleapis.com/auth/cloud-identity.groups"},
 		user:   false,
 		apis:   []string{"Cloud Identity API"},
 		note:   "Workspace only",
@@ -203,6 +205,17 @@ var serviceInfoByService = map[Service]serviceInfo{
 		apis:   []string{"Keep API"},
 		note:   "Workspace only; service account (domain-wide delegation)",
 	},
+	ServiceAdmin: {
+		scopes: []string{
+			"https://www.googleapis.com/auth/admin.directory.user",
+			"https://www.googleapis.com/auth/admin.directory.orgunit",
+			"https://www.googleapis.com/auth/admin.directory.group",
+			"https://www.googleapis.com/auth/admin.directory.rolemanagement",
+		},
+		user: false,
+		apis: []string{"Admin SDK API"},
+		note: "Workspace admins only",
+	},
 }
 
 func ParseService(s string) (Service, error) {
@@ -519,8 +532,23 @@ func scopesForServiceWithOptions(service Service, opts ScopeOptions) ([]string,
 
 		return Scopes(service)
 	case ServiceGroups:
+		if opts.Readonly {
+			return []string{"https://www.googleapis.com/auth/cloud-identity.groups.readonly"}, nil
+		}
+
 		return Scopes(service)
 	case ServiceKeep:
+		return Scopes(service)
+	case ServiceAdmin:
+		if opts.Readonly {
+			return []string{
+				"https://www.googleapis.com/auth/admin.directory.user.readonly",
+				"https://www.googleapis.com/auth/admin.directory.orgunit.readonly",
+				"https://www.googleapis.com/auth/admin.directory.group.readonly",
+				"https://www.googleapis.com/auth/admin.directory.rolemanagement.readonly",
+			}, nil
+		}
+
 		return Scopes(service)
 	default:
 		return nil, errUnknownService
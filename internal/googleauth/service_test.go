@@ -23,6 +23,7 @@ func TestParseService(t *testing.T) {
 		{"appscript", ServiceAppScript},
 		{"groups", ServiceGroups},
 		{"keep", ServiceKeep},
+		{"admin", ServiceAdmin},
 	}
 	for _, tt := range tests {
 		got, err := ParseService(tt.in)
@@ -65,7 +66,7 @@ func TestExtractCodeAndState_Errors(t *testing.T) {
 
 func TestAllServices(t *testing.T) {
 	svcs := AllServices()
-	if len(svcs) != 15 {
+	if len(svcs) != 16 {
 		t.Fatalf("unexpected: %v", svcs)
 	}
 	seen := make(map[Service]bool)
@@ -74,7 +75,7 @@ func TestAllServices(t *testing.T) {
 		seen[s] = true
 	}
 
-	for _, want := range []Service{ServiceGmail, ServiceCalendar, ServiceChat, ServiceClassroom, ServiceDrive, ServiceDocs, ServiceSlides, ServiceContacts, ServiceTasks, ServicePeople, ServiceSheets, ServiceForms, ServiceAppScript, ServiceGroups, ServiceKeep} {
+	for _, want := range []Service{ServiceGmail, ServiceCalendar, ServiceChat, ServiceClassroom, ServiceDrive, ServiceDocs, ServiceSlides, ServiceContacts, ServiceTasks, ServicePeople, ServiceSheets, ServiceForms, ServiceAppScript, ServiceGroups, ServiceKeep, ServiceAdmin} {
 		if !seen[want] {
 			t.Fatalf("missing %q", want)
 		}
@@ -475,3 +476,38 @@ func TestScopes_UnknownService(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestScopesForServiceWithOptions_ServiceAdmin_Readonly(t *testing.T) {
+	scopes, err := scopesForServiceWithOptions(ServiceAdmin, ScopeOptions{Readonly: true})
+	if err != nil {
+		t.Fatalf("scopesForServiceWithOptions: %v", err)
+	}
+
+	if len(scopes) != 4 || !containsScope(scopes, "https://www.googleapis.com/auth/admin.directory.user.readonly") {
+		t.Fatalf("unexpected admin readonly scopes: %#v", scopes)
+	}
+
+	if containsScope(scopes, "https://www.googleapis.com/auth/admin.directory.user") {
+		t.Fatalf("readonly admin scopes include write scope: %#v", scopes)
+	}
+}
+
+func TestScopesForServiceWithOptions_ServiceGroups_Readonly(t *testing.T) {
+	scopes, err := scopesForServiceWithOptions(ServiceGroups, ScopeOptions{Readonly: true})
+	if err != nil {
+		t.Fatalf("scopesForServiceWithOptions: %v", err)
+	}
+
+	if len(scopes) != 1 || scopes[0] != "https://www.googleapis.com/auth/cloud-identity.groups.readonly" {
+		t.Fatalf("unexpected groups readonly scopes: %#v", scopes)
+	}
+
+	scopes, err = scopesForServiceWithOptions(ServiceGroups, ScopeOptions{})
+	if err != nil {
+		t.Fatalf("scopesForServiceWithOptions: %v", err)
+	}
+
+	if len(scopes) != 1 || scopes[0] != "https://www.googleapis.com/auth/cloud-identity.groups" {
+		t.Fatalf("unexpected groups scopes: %#v", scopes)
+	}
+}
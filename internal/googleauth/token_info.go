@@ -0,0 +1,188 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+var errTokenInfoRequestFailed = errors.New("tokeninfo request failed")
+
+// TokenInfo describes what Google actually granted for a refresh token.
+type TokenInfo struct {
+	Email     string   `json:"email,omitempty"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in,omitempty"`
+}
+
+// InspectRefreshToken exchanges a refresh token for an access token and asks
+// the tokeninfo endpoint which scopes were granted to it.
+func InspectRefreshToken(ctx context.Context, client string, refreshToken string, timeout time.Duration) (TokenInfo, error) {
+	if strings.TrimSpace(refreshToken) == "" {
+		return TokenInfo{}, errMissingToken
+	}
+
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	creds, err := readClientCredentials(client)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("read credentials: %w", err)
+	}
+
+	cfg := oauth2.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		Endpoint:     oauthEndpoint,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	httpClient := &http.Client{Timeout: timeout}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	tok, err := cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("refresh access token: %w", err)
+	}
+
+	if strings.TrimSpace(tok.AccessToken) == "" {
+		return TokenInfo{}, errMissingAccessToken
+	}
+
+	return fetchTokenInfo(ctx, httpClient, tok.AccessToken)
+}
+
+func fetchTokenInfo(ctx context.Context, httpClient *http.Client, accessToken string) (TokenInfo, error) {
+	u := tokenInfoURL + "?" + url.Values{"access_token": {accessToken}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("create tokeninfo request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("fetch tokeninfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg := readHTTPBodySnippet(resp.Body, 512)
+		if msg != "" {
+			return TokenInfo{}, fmt.Errorf("%w: status %d: %s", errTokenInfoRequestFailed, resp.StatusCode, msg)
+		}
+
+		return TokenInfo{}, fmt.Errorf("%w: status %d", errTokenInfoRequestFailed, resp.StatusCode)
+	}
+
+	var raw struct {
+		Email     string `json:"email"`
+		Scope     string `json:"scope"`
+		ExpiresIn string `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return TokenInfo{}, fmt.Errorf("decode tokeninfo response: %w", err)
+	}
+
+	info := TokenInfo{Email: raw.Email, Scopes: strings.Fields(raw.Scope)}
+	if n, err := strconv.Atoi(raw.ExpiresIn); err == nil {
+		info.ExpiresIn = n
+	}
+
+	sort.Strings(info.Scopes)
+
+	return info, nil
+}
+
+// normalizeScope maps OIDC shorthand scopes to the URL form that tokeninfo
+// reports, so requested and granted scopes can be compared directly.
+func normalizeScope(scope string) string {
+	switch scope = strings.TrimSpace(scope); scope {
+	case scopeEmail:
+		return scopeUserinfoEmail
+	case "profile":
+		return "https://www.googleapis.com/auth/userinfo.profile"
+	default:
+		return scope
+	}
+}
+
+// MissingScopes returns the required scopes that are not present in granted,
+// treating OIDC shorthand ("email", "profile") and their URL forms as equal.
+func MissingScopes(required []string, granted []string) []string {
+	have := make(map[string]struct{}, len(granted))
+	for _, s := range granted {
+		have[normalizeScope(s)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{}, len(required))
+	out := make([]string, 0)
+
+	for _, s := range required {
+		n := normalizeScope(s)
+		if n == "" {
+			continue
+		}
+
+		if _, ok := seen[n]; ok {
+			continue
+		}
+
+		seen[n] = struct{}{}
+
+		if _, ok := have[n]; !ok {
+			out = append(out, s)
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// IsInvalidGrant reports whether err is an OAuth invalid_grant error, which
+// Google returns for revoked, expired or otherwise unusable refresh tokens.
+func IsInvalidGrant(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) && re.ErrorCode != "" {
+		return re.ErrorCode == "invalid_grant"
+	}
+
+	return strings.Contains(err.Error(), "invalid_grant")
+}
+
+// IsInvalidClient reports whether err indicates the OAuth client credentials
+// were rejected (deleted client, rotated secret).
+func IsInvalidClient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) && re.ErrorCode != "" {
+		return re.ErrorCode == "invalid_client" || re.ErrorCode == "unauthorized_client"
+	}
+
+	msg := err.Error()
+
+	return strings.Contains(msg, "invalid_client") || strings.Contains(msg, "unauthorized_client")
+}
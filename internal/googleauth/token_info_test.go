@@ -0,0 +1,99 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+func TestInspectRefreshToken(t *testing.T) {
+	origRead := readClientCredentials
+	origEndpoint := oauthEndpoint
+	origInfo := tokenInfoURL
+
+	t.Cleanup(func() {
+		readClientCredentials = origRead
+		oauthEndpoint = origEndpoint
+		tokenInfoURL = origInfo
+	})
+
+	readClientCredentials = func(string) (config.ClientCredentials, error) {
+		return config.ClientCredentials{ClientID: "id", ClientSecret: "secret"}, nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("refresh_token") != "good" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant", "error_description": "Token has been expired or revoked."})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 3600})
+	})
+	mux.HandleFunc("/tokeninfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "access" {
+			http.Error(w, "bad", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"email":      "a@b.com",
+			"scope":      "https://www.googleapis.com/auth/gmail.modify openid",
+			"expires_in": "3599",
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	oauthEndpoint = oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"}
+	tokenInfoURL = srv.URL + "/tokeninfo"
+
+	info, err := InspectRefreshToken(context.Background(), "default", "good", time.Second)
+	if err != nil {
+		t.Fatalf("InspectRefreshToken: %v", err)
+	}
+
+	if info.Email != "a@b.com" || info.ExpiresIn != 3599 {
+		t.Fatalf("unexpected info: %#v", info)
+	}
+
+	if want := []string{"https://www.googleapis.com/auth/gmail.modify", "openid"}; !reflect.DeepEqual(info.Scopes, want) {
+		t.Fatalf("scopes = %v, want %v", info.Scopes, want)
+	}
+
+	_, err = InspectRefreshToken(context.Background(), "default", "revoked", time.Second)
+	if err == nil || !IsInvalidGrant(err) {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	got := MissingScopes(
+		[]string{"email", "openid", "https://www.googleapis.com/auth/drive", "https://www.googleapis.com/auth/tasks"},
+		[]string{"https://www.googleapis.com/auth/userinfo.email", "openid", "https://www.googleapis.com/auth/drive"},
+	)
+	if want := []string{"https://www.googleapis.com/auth/tasks"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("MissingScopes = %v, want %v", got, want)
+	}
+}
+
+func TestIsInvalidClient(t *testing.T) {
+	if !IsInvalidClient(&oauth2.RetrieveError{ErrorCode: "invalid_client"}) {
+		t.Fatalf("expected invalid_client")
+	}
+
+	if IsInvalidClient(errors.New("timeout")) || IsInvalidGrant(nil) {
+		t.Fatalf("unexpected match")
+	}
+}
@@ -0,0 +1,46 @@
+package outfmt
+
+import (
+	"context"
+	"sync"
+)
+
+// Collector captures the JSON values a command writes instead of encoding
+// them to its writer. It is used to run one command several times (for
+// example once per user) and emit a single merged document afterwards.
+type Collector struct {
+	mu     sync.Mutex
+	values []any
+}
+
+type collectorKey struct{}
+
+// WithCollector makes WriteJSON append to c instead of writing. Results-only
+// and select transforms are applied before collecting; jq is not, so it can
+// run once over the merged document.
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, c)
+}
+
+func collectorFromContext(ctx context.Context) *Collector {
+	if c, ok := ctx.Value(collectorKey{}).(*Collector); ok {
+		return c
+	}
+
+	return nil
+}
+
+// Values returns the collected values in write order.
+func (c *Collector) Values() []any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]any(nil), c.values...)
+}
+
+func (c *Collector) add(v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = append(c.values, v)
+}
@@ -117,6 +117,11 @@ func WriteJSON(ctx context.Context, w io.Writer, v any) error {
 		}
 	}
 
+	if c := collectorFromContext(ctx); c != nil {
+		c.add(v)
+		return nil
+	}
+
 	// Apply JQ filter if set.
 	if t, ok := JSONTransformFromContext(ctx); ok && t.JQ != "" {
 		// First marshal to JSON bytes.
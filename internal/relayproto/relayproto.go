@@ -0,0 +1,188 @@
+// Package relayproto is the auth relay protocol shared by the standalone
+// auth-server, the embedded relay (`wk auth relay serve`) and the headless
+// CLI that polls them: handoff states, token payloads and their end-to-end
+// encryption, and the poll statuses.
+package relayproto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// CodeVerifierHeader carries the PKCE code_verifier on /token/ polls for
+	// end-to-end encrypted handoffs. Only the CLI that built the auth URL
+	// knows it.
+	CodeVerifierHeader = "X-Code-Verifier"
+
+	// FeatureHandoff is listed in /health features by relays that support
+	// end-to-end encrypted handoffs.
+	FeatureHandoff = "e2e-handoff"
+)
+
+// ErrInvalidState reports a malformed end-to-end encrypted handoff state.
+var ErrInvalidState = errors.New("invalid handoff state")
+
+// NewState returns the end-to-end encrypted handoff state carrying the CLI's
+// ephemeral age recipient: "<nonce>.<age1...>".
+func NewState(nonce string, recipient *age.X25519Recipient) string {
+	return nonce + "." + recipient.String()
+}
+
+// ParseState extracts the age recipient from an end-to-end encrypted state.
+// ok is false for legacy opaque states, which the relay exchanges on
+// callback and returns in the clear.
+func ParseState(state string) (recipient *age.X25519Recipient, ok bool, err error) {
+	nonce, pub, found := strings.Cut(state, ".")
+	if !found {
+		return nil, false, nil
+	}
+
+	if nonce == "" || !strings.HasPrefix(pub, "age1") {
+		return nil, false, ErrInvalidState
+	}
+
+	recipient, err = age.ParseX25519Recipient(pub)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	return recipient, true, nil
+}
+
+// TokenResponse is the token a /token/ poll returns: the response body for
+// legacy states, and the encrypted plaintext for end-to-end encrypted ones.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	Expiry       string `json:"expiry"`
+}
+
+// NewTokenResponse returns the response for an exchanged token.
+func NewTokenResponse(tok *oauth2.Token) TokenResponse {
+	return TokenResponse{
+		AccessToken:  tok.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		TokenType:    tok.TokenType,
+		Expiry:       tok.Expiry.Format(time.RFC3339),
+	}
+}
+
+// EncryptedTokenResponse is returned by /token/ for end-to-end encrypted
+// handoffs. Encrypted is a base64-encoded age file whose plaintext is a
+// TokenResponse; the relay never stores or returns the token in the clear.
+type EncryptedTokenResponse struct {
+	Encrypted string `json:"encrypted"`
+}
+
+// EncryptToken encrypts resp to the recipient from the handoff state.
+func EncryptToken(recipient age.Recipient, resp TokenResponse) (EncryptedTokenResponse, error) {
+	plaintext, err := json.Marshal(resp)
+	if err != nil {
+		return EncryptedTokenResponse{}, fmt.Errorf("encode token: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return EncryptedTokenResponse{}, fmt.Errorf("encrypt token: %w", err)
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return EncryptedTokenResponse{}, fmt.Errorf("encrypt token: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return EncryptedTokenResponse{}, fmt.Errorf("encrypt token: %w", err)
+	}
+
+	return EncryptedTokenResponse{Encrypted: base64.StdEncoding.EncodeToString(buf.Bytes())}, nil
+}
+
+// DecryptToken decrypts an EncryptedTokenResponse payload with the CLI's
+// ephemeral identity.
+func DecryptToken(identity age.Identity, encrypted string) (TokenResponse, error) {
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(raw), identity)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	var tok TokenResponse
+	if err := json.Unmarshal(plaintext, &tok); err != nil {
+		return TokenResponse{}, err
+	}
+
+	return tok, nil
+}
+
+// Status is the state of a handoff as reported by /status/.
+type Status string
+
+const (
+	// StatusPending means the state is known but its token or code has not
+	// arrived yet.
+	StatusPending Status = "pending"
+	// StatusReady means a token or code is waiting to be retrieved.
+	StatusReady Status = "ready"
+	// StatusConsumed means the token was already retrieved.
+	StatusConsumed Status = "consumed"
+	// StatusNotFound means the state is unknown or has expired.
+	StatusNotFound Status = "not_found"
+)
+
+// EntryStatus returns the status of a stored handoff entry.
+func EntryStatus(consumed, hasTokenOrCode bool) Status {
+	switch {
+	case consumed:
+		return StatusConsumed
+	case hasTokenOrCode:
+		return StatusReady
+	default:
+		return StatusPending
+	}
+}
+
+// StatusResponse returns the HTTP status code and JSON body of a /token/
+// poll that did not yield a token. Pollers keep polling on pending and
+// not_found, and stop on consumed.
+func StatusResponse(status Status) (int, map[string]string) {
+	switch status {
+	case StatusPending:
+		return http.StatusAccepted, map[string]string{
+			"status":  string(StatusPending),
+			"message": "Token not yet available, please try again",
+		}
+	case StatusConsumed:
+		return http.StatusGone, map[string]string{
+			"error":   string(StatusConsumed),
+			"message": "Token has already been retrieved",
+		}
+	default:
+		return http.StatusNotFound, map[string]string{
+			"error":   string(StatusNotFound),
+			"message": "Token not found or expired",
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package relayproto
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestParseState(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+
+	state := NewState("nonce", identity.Recipient())
+
+	recipient, ok, err := ParseState(state)
+	if err != nil || !ok || recipient.String() != identity.Recipient().String() {
+		t.Fatalf("ParseState(%q) = %v, %v, %v", state, recipient, ok, err)
+	}
+
+	if _, ok, err := ParseState("legacy-state"); ok || err != nil {
+		t.Fatalf("legacy state: ok=%v err=%v", ok, err)
+	}
+
+	for _, bad := range []string{"nonce.age1notakey", ".age1abc", "nonce.key"} {
+		if _, _, err := ParseState(bad); !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("ParseState(%q) = %v, want invalid state", bad, err)
+		}
+	}
+}
+
+func TestEncryptToken_RoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+
+	want := TokenResponse{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: "2026-01-02T03:04:05Z"}
+
+	enc, err := EncryptToken(identity.Recipient(), want)
+	if err != nil {
+		t.Fatalf("EncryptToken: %v", err)
+	}
+
+	got, err := DecryptToken(identity, enc.Encrypted)
+	if err != nil || got != want {
+		t.Fatalf("DecryptToken = %+v, %v; want %+v", got, err, want)
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	if _, err := DecryptToken(other, enc.Encrypted); err == nil {
+		t.Fatal("decrypted with the wrong identity")
+	}
+}
+
+func TestEntryStatusAndResponse(t *testing.T) {
+	tests := []struct {
+		consumed, hasTokenOrCode bool
+		want                     Status
+		code                     int
+	}{
+		{false, false, StatusPending, http.StatusAccepted},
+		{false, true, StatusReady, 0},
+		{true, false, StatusConsumed, http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		status := EntryStatus(tt.consumed, tt.hasTokenOrCode)
+		if status != tt.want {
+			t.Errorf("EntryStatus(%v, %v) = %q, want %q", tt.consumed, tt.hasTokenOrCode, status, tt.want)
+		}
+
+		if tt.code == 0 {
+			continue
+		}
+
+		if code, _ := StatusResponse(status); code != tt.code {
+			t.Errorf("StatusResponse(%q) = %d, want %d", status, code, tt.code)
+		}
+	}
+
+	if code, body := StatusResponse(StatusNotFound); code != http.StatusNotFound || body["error"] != "not_found" {
+		t.Errorf("StatusResponse(not_found) = %d %v", code, body)
+	}
+}
@@ -0,0 +1,190 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/99designs/keyring"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+// execBackendPrefix selects an external credential helper as the keyring
+// backend: keyring_backend=exec:<cmd> [args...]. The command is split on
+// whitespace without shell quoting, so a helper path or argument containing
+// spaces needs a wrapper script.
+const execBackendPrefix = "exec:"
+
+// execHelperTimeout bounds a single helper invocation so a hung helper
+// (e.g. waiting on an unreachable Vault agent) cannot block the CLI.
+const execHelperTimeout = 30 * time.Second
+
+const execHelperNotFound = "not_found"
+
+var (
+	errEmptyExecHelper  = errors.New("exec keyring backend requires a command (exec:<cmd>)")
+	errQuotedExecHelper = errors.New("exec keyring backend splits the command on whitespace and does not support quotes (wrap a helper path or arguments with spaces in a script)")
+	errExecHelperFailed = errors.New("credential helper failed")
+)
+
+// execHelperRequest is written as JSON to the helper's stdin. The action is
+// also passed as the helper's last argument, like git credential helpers.
+type execHelperRequest struct {
+	Action  string `json:"action"`
+	Service string `json:"service"`
+	Key     string `json:"key,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// execHelperResponse is read as JSON from the helper's stdout. Empty stdout
+// is treated as success with no data (convenient for set/delete).
+type execHelperResponse struct {
+	Value *string  `json:"value,omitempty"`
+	Keys  []string `json:"keys,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// IsExecBackend reports whether a keyring backend value selects an external
+// credential helper.
+func IsExecBackend(value string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), execBackendPrefix)
+}
+
+// execKeyring implements keyring.Keyring by delegating every operation to an
+// external helper process over a stdin/stdout JSON protocol, so tokens can
+// live in pass, a Vault agent, or a company secret broker.
+type execKeyring struct {
+	argv    []string
+	service string
+	run     func(ctx context.Context, argv []string, stdin []byte) ([]byte, error)
+}
+
+// ExecHelperArgv returns the helper command and arguments of an
+// exec:<cmd> [args...] backend. Quotes are rejected rather than passed on
+// literally, since they would not group words the way a shell does.
+func ExecHelperArgv(backend string) ([]string, error) {
+	raw := strings.TrimSpace(backend)
+	if IsExecBackend(raw) {
+		raw = raw[len(execBackendPrefix):]
+	}
+
+	if strings.ContainsAny(raw, `"'`) {
+		return nil, errQuotedExecHelper
+	}
+
+	argv := strings.Fields(raw)
+	if len(argv) == 0 {
+		return nil, errEmptyExecHelper
+	}
+
+	return argv, nil
+}
+
+func newExecKeyring(backend string) (*execKeyring, error) {
+	argv, err := ExecHelperArgv(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &execKeyring{argv: argv, service: config.AppName, run: runExecHelper}, nil
+}
+
+func runExecHelper(ctx context.Context, argv []string, stdin []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...) //nolint:gosec // helper command is user-configured
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s: %w: %s", errExecHelperFailed, argv[0], err, msg)
+		}
+
+		return nil, fmt.Errorf("%w: %s: %w", errExecHelperFailed, argv[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (k *execKeyring) call(req execHelperRequest) (execHelperResponse, error) {
+	req.Service = k.service
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return execHelperResponse{}, fmt.Errorf("encode helper request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execHelperTimeout)
+	defer cancel()
+
+	argv := append(append([]string{}, k.argv...), req.Action)
+
+	out, err := k.run(ctx, argv, payload)
+	if err != nil {
+		return execHelperResponse{}, err
+	}
+
+	var resp execHelperResponse
+	if len(bytes.TrimSpace(out)) == 0 {
+		return resp, nil
+	}
+
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return execHelperResponse{}, fmt.Errorf("decode helper %s response: %w", req.Action, err)
+	}
+
+	switch resp.Error {
+	case "":
+		return resp, nil
+	case execHelperNotFound:
+		return resp, keyring.ErrKeyNotFound
+	default:
+		return resp, fmt.Errorf("%w: %s: %s", errExecHelperFailed, req.Action, resp.Error)
+	}
+}
+
+func (k *execKeyring) Get(key string) (keyring.Item, error) {
+	resp, err := k.call(execHelperRequest{Action: "get", Key: key})
+	if err != nil {
+		return keyring.Item{}, err
+	}
+
+	if resp.Value == nil {
+		return keyring.Item{}, keyring.ErrKeyNotFound
+	}
+
+	return keyringItem(key, []byte(*resp.Value)), nil
+}
+
+func (k *execKeyring) GetMetadata(string) (keyring.Metadata, error) {
+	return keyring.Metadata{}, keyring.ErrMetadataNotSupported
+}
+
+func (k *execKeyring) Set(item keyring.Item) error {
+	_, err := k.call(execHelperRequest{Action: "set", Key: item.Key, Value: string(item.Data)})
+
+	return err
+}
+
+func (k *execKeyring) Remove(key string) error {
+	_, err := k.call(execHelperRequest{Action: "delete", Key: key})
+
+	return err
+}
+
+func (k *execKeyring) Keys() ([]string, error) {
+	resp, err := k.call(execHelperRequest{Action: "list"})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Keys, nil
+}
@@ -0,0 +1,180 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/99designs/keyring"
+
+	"github.com/automagik-dev/workit/internal/config"
+)
+
+// fakeExecHelper is an in-memory credential helper speaking the exec protocol.
+func fakeExecHelper(t *testing.T, data map[string]string) func(context.Context, []string, []byte) ([]byte, error) {
+	t.Helper()
+
+	return func(_ context.Context, argv []string, stdin []byte) ([]byte, error) {
+		var req execHelperRequest
+		if err := json.Unmarshal(stdin, &req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+
+		if argv[len(argv)-1] != req.Action || req.Service != config.AppName {
+			t.Fatalf("unexpected invocation %v %#v", argv, req)
+		}
+
+		switch req.Action {
+		case "get":
+			v, ok := data[req.Key]
+			if !ok {
+				return []byte(`{"error":"not_found"}`), nil
+			}
+
+			return json.Marshal(map[string]string{"value": v})
+		case "set":
+			data[req.Key] = req.Value
+			return nil, nil
+		case "delete":
+			if _, ok := data[req.Key]; !ok {
+				return []byte(`{"error":"not_found"}`), nil
+			}
+
+			delete(data, req.Key)
+
+			return nil, nil
+		case "list":
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+
+			sort.Strings(keys)
+
+			return json.Marshal(map[string][]string{"keys": keys})
+		default:
+			return []byte(`{"error":"unsupported action"}`), nil
+		}
+	}
+}
+
+func TestExecKeyring_StoreRoundTrip(t *testing.T) {
+	ring, err := newExecKeyring("exec:/usr/local/bin/wk-helper --vault")
+	if err != nil {
+		t.Fatalf("newExecKeyring: %v", err)
+	}
+
+	if len(ring.argv) != 2 || ring.argv[0] != "/usr/local/bin/wk-helper" {
+		t.Fatalf("unexpected argv: %v", ring.argv)
+	}
+
+	data := map[string]string{}
+	ring.run = fakeExecHelper(t, data)
+	store := &KeyringStore{ring: ring}
+
+	if err := store.SetToken("work", "A@B.com", Token{Email: "a@b.com", RefreshToken: "rt", Services: []string{"gmail"}}); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+
+	if err := store.SetDefaultAccount("work", "a@b.com"); err != nil {
+		t.Fatalf("SetDefaultAccount: %v", err)
+	}
+
+	tok, err := store.GetToken("work", "a@b.com")
+	if err != nil || tok.RefreshToken != "rt" || tok.Services[0] != "gmail" {
+		t.Fatalf("GetToken: %#v %v", tok, err)
+	}
+
+	tokens, err := store.ListTokens()
+	if err != nil || len(tokens) != 1 {
+		t.Fatalf("ListTokens: %#v %v", tokens, err)
+	}
+
+	if email, err := store.GetDefaultAccount("work"); err != nil || email != "a@b.com" {
+		t.Fatalf("GetDefaultAccount: %q %v", email, err)
+	}
+
+	if err := store.DeleteToken("work", "a@b.com"); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+
+	if _, err := store.GetToken("work", "a@b.com"); !errors.Is(err, keyring.ErrKeyNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestExecKeyring_HelperError(t *testing.T) {
+	ring, err := newExecKeyring("exec:helper")
+	if err != nil {
+		t.Fatalf("newExecKeyring: %v", err)
+	}
+
+	ring.run = func(context.Context, []string, []byte) ([]byte, error) {
+		return []byte(`{"error":"vault sealed"}`), nil
+	}
+
+	if _, err := ring.Get("k"); !errors.Is(err, errExecHelperFailed) {
+		t.Fatalf("expected helper error, got %v", err)
+	}
+
+	if _, err := newExecKeyring("exec:  "); !errors.Is(err, errEmptyExecHelper) {
+		t.Fatalf("expected empty helper error, got %v", err)
+	}
+}
+
+func TestExecHelperArgv(t *testing.T) {
+	argv, err := ExecHelperArgv("exec:/opt/helper  --mount secret/wk")
+	if err != nil || strings.Join(argv, "|") != "/opt/helper|--mount|secret/wk" {
+		t.Fatalf("ExecHelperArgv = %q, %v", argv, err)
+	}
+
+	for _, backend := range []string{`exec:"/opt/my helper"`, `exec:/opt/helper --name 'a b'`} {
+		if _, err := ExecHelperArgv(backend); !errors.Is(err, errQuotedExecHelper) {
+			t.Errorf("ExecHelperArgv(%s) = %v, want quoted command error", backend, err)
+		}
+	}
+}
+
+func TestExecKeyring_RunsProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell helper")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "helper.sh")
+	body := "#!/bin/sh\ncat >/dev/null\nif [ \"$1\" = get ]; then echo '{\"value\":\"secret\"}'; else echo boom >&2; exit 3; fi\n"
+
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+
+	ring, err := newExecKeyring("exec:" + script)
+	if err != nil {
+		t.Fatalf("newExecKeyring: %v", err)
+	}
+
+	item, err := ring.Get("k")
+	if err != nil || string(item.Data) != "secret" {
+		t.Fatalf("Get: %q %v", item.Data, err)
+	}
+
+	if err := ring.Remove("k"); !errors.Is(err, errExecHelperFailed) {
+		t.Fatalf("expected failure, got %v", err)
+	}
+}
+
+func TestNormalizeKeyringBackend_ExecKeepsCase(t *testing.T) {
+	if got := normalizeKeyringBackend("  EXEC: /opt/Helper --Flag "); got != "exec:/opt/Helper --Flag" {
+		t.Fatalf("normalizeKeyringBackend = %q", got)
+	}
+
+	if got := normalizeKeyringBackend(" File "); got != "file" {
+		t.Fatalf("normalizeKeyringBackend = %q", got)
+	}
+}
@@ -105,7 +105,7 @@ func allowedBackends(info KeyringBackendInfo) ([]keyring.BackendType, error) {
 	case "file":
 		return []keyring.BackendType{keyring.FileBackend}, nil
 	default:
-		return nil, fmt.Errorf("%w: %q (expected %s, keychain, or file)", errInvalidKeyringBackend, info.Value, keyringBackendAuto)
+		return nil, fmt.Errorf("%w: %q (expected %s, keychain, file, or exec:<cmd>)", errInvalidKeyringBackend, info.Value, keyringBackendAuto)
 	}
 }
 
@@ -159,7 +159,14 @@ func fileKeyringPasswordFunc() keyring.PromptFunc {
 }
 
 func normalizeKeyringBackend(value string) string {
-	return strings.ToLower(strings.TrimSpace(value))
+	value = strings.TrimSpace(value)
+
+	// exec:<cmd> keeps the helper command verbatim (paths and args are case-sensitive).
+	if IsExecBackend(value) {
+		return execBackendPrefix + strings.TrimSpace(value[len(execBackendPrefix):])
+	}
+
+	return strings.ToLower(value)
 }
 
 // keyringOpenTimeout is the maximum time to wait for keyring.Open() to complete.
@@ -176,6 +183,15 @@ func shouldUseKeyringTimeout(goos string, backendInfo KeyringBackendInfo, dbusAd
 }
 
 func openKeyring() (keyring.Keyring, error) {
+	backendInfo, err := ResolveKeyringBackendInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if IsExecBackend(backendInfo.Value) {
+		return newExecKeyring(backendInfo.Value)
+	}
+
 	// On Linux/WSL/containers, OS keychains (secret-service/kwallet) may be unavailable.
 	// In that case github.com/99designs/keyring falls back to the "file" backend,
 	// which *requires* both a directory and a password prompt function.
@@ -184,11 +200,6 @@ func openKeyring() (keyring.Keyring, error) {
 		return nil, fmt.Errorf("ensure keyring dir: %w", err)
 	}
 
-	backendInfo, err := ResolveKeyringBackendInfo()
-	if err != nil {
-		return nil, err
-	}
-
 	backends, err := allowedBackends(backendInfo)
 	if err != nil {
 		return nil, err
@@ -15,6 +15,27 @@ const (
 	ConflictLocalWins ConflictStrategy = "local-wins"
 	// ConflictRemoteWins overwrites the local with the remote version.
 	ConflictRemoteWins ConflictStrategy = "remote-wins"
+	// ConflictMerge merges non-overlapping edits to text files line by line
+	// against the last-synced content, and renames like ConflictRename
+	// when the edits overlap or the file cannot be merged.
+	ConflictMerge ConflictStrategy = "merge"
+)
+
+// NativeDocsMode defines how Google-native files (Docs, Sheets, Slides)
+// are represented locally.
+type NativeDocsMode string
+
+const (
+	// NativeSkip leaves Google-native files out of sync.
+	NativeSkip NativeDocsMode = "skip"
+	// NativeOffice exports Docs, Sheets and Slides as .docx, .xlsx and
+	// .pptx and re-imports local edits into the same file.
+	NativeOffice NativeDocsMode = "office"
+	// NativeText exports Docs as .md and Sheets as .csv (first sheet only);
+	// Slides fall back to .pptx. Local edits are re-imported.
+	NativeText NativeDocsMode = "text"
+	// NativeLink writes read-only .gdoc/.gsheet/.gslides link stubs.
+	NativeLink NativeDocsMode = "link"
 )
 
 // SyncState represents the state of a synced item.
@@ -42,6 +63,35 @@ type SyncConfig struct {
 	CreatedAt     time.Time `json:"created_at"`
 	LastSyncAt    time.Time `json:"last_sync_at,omitempty"`
 	ChangeToken   string    `json:"change_token,omitempty"` // Drive changes page token
+	// IgnorePatterns are .wkignore-style rules stored with the config.
+	// They are applied after the sync root's .wkignore file.
+	IgnorePatterns []string `json:"ignore_patterns,omitempty"`
+	// NativeDocs selects how Google-native files sync. Empty means NativeSkip.
+	NativeDocs NativeDocsMode `json:"native_docs,omitempty"`
+	// Account is the Google account the config syncs as, when recorded.
+	Account string `json:"account,omitempty"`
+	// MaxDeletes and MaxDeletePercent limit how many tracked files may be
+	// deleted (in either direction) within DeleteWindow before sync pauses.
+	// Zero disables a limit.
+	MaxDeletes       int `json:"max_deletes"`
+	MaxDeletePercent int `json:"max_delete_percent"`
+	// TrashDays is how long files deleted on Drive are kept in the local
+	// .wk-trash folder. Zero keeps them until removed by hand.
+	TrashDays int `json:"trash_days"`
+	// TransferWorkers is the number of files transferred at once.
+	// UploadLimit and DownloadLimit cap the bytes per second sent to and
+	// received from Drive; zero is unlimited.
+	TransferWorkers int   `json:"transfer_workers"`
+	UploadLimit     int64 `json:"upload_limit,omitempty"`
+	DownloadLimit   int64 `json:"download_limit,omitempty"`
+	// Encrypted configs encrypt file contents on Drive with the key held
+	// in the secrets store (see Cipher); EncryptNames encrypts file and
+	// folder names too.
+	Encrypted    bool `json:"encrypted,omitempty"`
+	EncryptNames bool `json:"encrypt_names,omitempty"`
+	// PausedReason is set while sync is paused by the delete guard.
+	PausedReason string    `json:"paused_reason,omitempty"`
+	PausedAt     time.Time `json:"paused_at,omitempty"`
 }
 
 // SyncItem represents a tracked file/folder in a sync configuration.
@@ -51,10 +101,22 @@ type SyncItem struct {
 	LocalPath   string    `json:"local_path"`  // Relative to config's local_path
 	DriveID     string    `json:"drive_id"`    // Drive file ID
 	LocalMD5    string    `json:"local_md5"`   // MD5 hash of local file
-	RemoteMD5   string    `json:"remote_md5"`  // MD5 hash from Drive
+	RemoteMD5   string    `json:"remote_md5"`  // MD5 hash from Drive (see remoteFingerprint)
 	LocalMtime  time.Time `json:"local_mtime"` // Local modification time
 	RemoteMtime time.Time `json:"remote_mtime"`
 	SyncState   SyncState `json:"sync_state"`
+	// ConflictPath is the conflict copy kept next to LocalPath while the
+	// item is in StateConflict.
+	ConflictPath string `json:"conflict_path,omitempty"`
+	// Size and Inode describe the local file as last synced. They help
+	// tell a moved file from a new copy; zero means unknown.
+	Size  int64  `json:"size,omitempty"`
+	Inode uint64 `json:"inode,omitempty"`
+	// Attempts, NextRetryAt and LastError describe failed transfers of an
+	// item still queued in a pending state.
+	Attempts    int       `json:"attempts,omitempty"`
+	NextRetryAt time.Time `json:"next_retry_at,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
 }
 
 // SyncLogEntry represents an entry in the sync log.
@@ -76,4 +138,9 @@ type SyncStatus struct {
 	ConflictItems int64      `json:"conflict_items"`
 	ErrorItems    int64      `json:"error_items"`
 	DaemonRunning bool       `json:"daemon_running"`
+	// Engine is the supervisor's view of this config's engine, when a
+	// `sync start --all` supervisor runs it.
+	Engine *EngineHealth `json:"engine,omitempty"`
+	// Transfers is the transfer queue and throughput of a running engine.
+	Transfers *TransferStats `json:"transfers,omitempty"`
 }
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"google.golang.org/api/drive/v3"
 )
 
 // ConflictResolver handles sync conflict detection and resolution.
@@ -41,6 +43,7 @@ type ConflictInfo struct {
 	LastSyncMtime  time.Time
 	LocalModified  bool
 	RemoteModified bool
+	MimeType       string // Drive MIME type, when known
 }
 
 // DetectConflict checks if a file has a conflict.
@@ -103,8 +106,10 @@ func (r *ConflictResolver) Resolve(ctx context.Context, conflict *ConflictInfo,
 	}
 
 	switch r.strategy {
-	case ConflictRename:
-		// Keep both versions by renaming the local file
+	case ConflictRename, ConflictMerge:
+		// Keep both versions by renaming the local file. Conflicts reach
+		// here under ConflictMerge only when the edits could not be merged.
+		result.Action = string(ConflictRename)
 		absPath := filepath.Join(localRoot, conflict.LocalPath)
 		renamedPath, err := r.renameWithTimestamp(absPath)
 		if err != nil {
@@ -178,7 +183,132 @@ func ParseConflictStrategy(s string) (ConflictStrategy, error) {
 		return ConflictLocalWins, nil
 	case "remote-wins", "remote_wins", "remotewins":
 		return ConflictRemoteWins, nil
+	case "merge":
+		return ConflictMerge, nil
 	default:
-		return "", fmt.Errorf("unknown conflict strategy: %s (valid: rename, local-wins, remote-wins)", s)
+		return "", fmt.Errorf("unknown conflict strategy: %s (valid: rename, local-wins, remote-wins, merge)", s)
+	}
+}
+
+// ConflictTake selects which version `wk sync conflicts resolve` keeps.
+type ConflictTake string
+
+const (
+	// TakeLocal keeps the local version (the conflict copy) and uploads it.
+	TakeLocal ConflictTake = "local"
+	// TakeRemote keeps the Drive version and discards the conflict copy.
+	TakeRemote ConflictTake = "remote"
+	// TakeBoth keeps both files; the conflict copy syncs as its own file.
+	TakeBoth ConflictTake = "both"
+)
+
+// ParseConflictTake parses a --take value.
+func ParseConflictTake(s string) (ConflictTake, error) {
+	switch take := ConflictTake(strings.ToLower(strings.TrimSpace(s))); take {
+	case TakeLocal, TakeRemote, TakeBoth:
+		return take, nil
+	default:
+		return "", fmt.Errorf("unknown --take %q (valid: local, remote, both)", s)
+	}
+}
+
+// ResolveRecordedConflict settles a conflict recorded by the rename
+// strategy, where the Drive version sits at relPath and the local edit at
+// the item's conflict copy. service may be nil for TakeBoth, which touches
+// nothing on Drive.
+func ResolveRecordedConflict(ctx context.Context, db *DB, cfg *SyncConfig, service *drive.Service, relPath string, take ConflictTake) (*SyncItem, error) {
+	item, err := db.GetSyncItem(cfg.ID, relPath)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || item.SyncState != StateConflict {
+		return nil, fmt.Errorf("no conflict recorded for %s", relPath)
+	}
+	if take != TakeBoth && service == nil {
+		return nil, fmt.Errorf("resolving with --take %s needs Drive access", take)
+	}
+
+	absPath, err := localJoin(cfg.LocalPath, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var copyAbs string
+	if item.ConflictPath != "" {
+		if copyAbs, err = localJoin(cfg.LocalPath, item.ConflictPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var uploader *Uploader
+	if service != nil {
+		uploader = NewUploader(service, cfg.DriveFolderID, cfg.DriveID)
+		uploader.SetNativeDocs(cfg.NativeDocs)
+
+		cipher, err := LoadCipher(cfg)
+		if err != nil {
+			return nil, err
+		}
+		uploader.SetCipher(cipher)
 	}
+
+	// discardCopy removes the conflict copy locally and from Drive.
+	discardCopy := func() error {
+		if item.ConflictPath == "" {
+			return nil
+		}
+		if err := os.Remove(copyAbs); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove conflict copy: %w", err)
+		}
+		if err := uploader.Delete(ctx, item.ConflictPath); err != nil {
+			return fmt.Errorf("remove conflict copy from Drive: %w", err)
+		}
+		return db.RemoveSyncItem(cfg.ID, item.ConflictPath)
+	}
+
+	switch take {
+	case TakeLocal:
+		if copyAbs == "" {
+			return nil, fmt.Errorf("no conflict copy recorded for %s", relPath)
+		}
+		if err := os.Rename(copyAbs, absPath); err != nil {
+			return nil, fmt.Errorf("restore local version: %w", err)
+		}
+
+		remote, err := uploader.RemoteFile(ctx, relPath, item.DriveID)
+		if err != nil {
+			return nil, err
+		}
+		result, err := uploader.UploadTo(ctx, relPath, absPath, remote)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.UpdateSyncItem(item.ID, result.DriveID, result.MD5, result.RemoteMD5, StateSynced); err != nil {
+			return nil, err
+		}
+
+		// The copy's content now lives at relPath; drop its Drive twin.
+		if err := discardCopy(); err != nil {
+			return nil, err
+		}
+
+	case TakeRemote:
+		if err := discardCopy(); err != nil {
+			return nil, err
+		}
+
+	case TakeBoth:
+		// Nothing to move; the conflict copy is an ordinary file now.
+	}
+
+	if err := db.SetSyncItemState(item.ID, StateSynced, ""); err != nil {
+		return nil, err
+	}
+
+	_ = db.AddLogEntry(cfg.ID, "conflict_resolved", relPath, map[string]any{
+		"take":          string(take),
+		"conflict_path": item.ConflictPath,
+	})
+
+	return db.GetSyncItem(cfg.ID, relPath)
 }
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,6 +24,7 @@ func TestParseConflictStrategy(t *testing.T) {
 		{"remote-wins", ConflictRemoteWins, false},
 		{"remote_wins", ConflictRemoteWins, false},
 		{"remotewins", ConflictRemoteWins, false},
+		{"merge", ConflictMerge, false},
 		{"invalid", "", true},
 	}
 
@@ -204,3 +206,51 @@ func TestConflictResolver_NoConflict_OnlyRemoteChanged(t *testing.T) {
 		t.Error("expected no conflict when only remote changed")
 	}
 }
+
+func TestResolveRecordedConflict_TakeBoth(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+	cfg := &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root"}
+
+	insertTestSyncItem(t, d, configID, "notes.txt", StateSynced)
+	item, _ := d.GetSyncItem(configID, "notes.txt")
+	if err := d.SetSyncItemState(item.ID, StateConflict, "notes.conflict-20260101-000000.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := ResolveRecordedConflict(context.Background(), d, cfg, nil, "notes.txt", TakeBoth)
+	if err != nil {
+		t.Fatalf("ResolveRecordedConflict: %v", err)
+	}
+	if resolved.SyncState != StateSynced || resolved.ConflictPath != "" {
+		t.Fatalf("resolved = %+v", resolved)
+	}
+
+	if _, err := ResolveRecordedConflict(context.Background(), d, cfg, nil, "notes.txt", TakeBoth); err == nil {
+		t.Fatal("expected error resolving a path with no recorded conflict")
+	}
+}
+
+func TestResolveRecordedConflict_TakeLocalNeedsDrive(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	cfg := &SyncConfig{ID: configID, LocalPath: t.TempDir(), DriveFolderID: "root"}
+
+	insertTestSyncItem(t, d, configID, "notes.txt", StateConflict)
+
+	if _, err := ResolveRecordedConflict(context.Background(), d, cfg, nil, "notes.txt", TakeLocal); err == nil {
+		t.Fatal("expected error without a Drive service")
+	}
+}
+
+func TestParseConflictTake(t *testing.T) {
+	for _, in := range []string{"local", "REMOTE", " both "} {
+		if _, err := ParseConflictTake(in); err != nil {
+			t.Errorf("ParseConflictTake(%q): %v", in, err)
+		}
+	}
+	if _, err := ParseConflictTake("newest"); err == nil {
+		t.Error("expected error for unknown value")
+	}
+}
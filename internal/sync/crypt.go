@@ -0,0 +1,218 @@
+package sync
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/99designs/keyring"
+
+	"github.com/automagik-dev/workit/internal/secrets"
+)
+
+// encryptedMimeType is the content type of encrypted files on Drive, so
+// Drive neither previews nor converts them.
+const encryptedMimeType = "application/octet-stream"
+
+// nameEncoding encodes encrypted names: lowercase letters and digits only.
+var nameEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Secret store access, replaced in tests.
+var (
+	getSecret = secrets.GetSecret
+	setSecret = secrets.SetSecret
+)
+
+// Cipher encrypts the files of a sync configuration with client-side
+// encryption: contents with age, and names, if enabled, with AES-GCM under
+// a nonce derived from the name, so the same name always encrypts the same
+// way and can be looked up on Drive. A nil Cipher leaves everything in the
+// clear.
+type Cipher struct {
+	identity *age.X25519Identity
+
+	names   cipher.AEAD // nil unless names are encrypted
+	nameMAC []byte      // derives the nonce of an encrypted name
+}
+
+// NewCipher creates a cipher from an age X25519 secret key
+// ("AGE-SECRET-KEY-1...").
+func NewCipher(key string, encryptNames bool) (*Cipher, error) {
+	identity, err := age.ParseX25519Identity(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("parse encryption key: %w", err)
+	}
+
+	c := &Cipher{identity: identity}
+	if !encryptNames {
+		return c, nil
+	}
+
+	keys, err := hkdf.Key(sha256.New, []byte(identity.String()), nil, "workit sync file names", 64)
+	if err != nil {
+		return nil, fmt.Errorf("derive name keys: %w", err)
+	}
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		return nil, fmt.Errorf("create name cipher: %w", err)
+	}
+	if c.names, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("create name cipher: %w", err)
+	}
+	c.nameMAC = keys[32:]
+
+	return c, nil
+}
+
+// GenerateEncryptionKey returns a new age X25519 secret key.
+func GenerateEncryptionKey() (string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", fmt.Errorf("generate encryption key: %w", err)
+	}
+	return identity.String(), nil
+}
+
+// ParseEncryptionKey reads the first age X25519 secret key from r, e.g.
+// an age-keygen file or the output of 'wk sync export-key'.
+func ParseEncryptionKey(r io.Reader) (string, error) {
+	identities, err := age.ParseIdentities(r)
+	if err != nil {
+		return "", fmt.Errorf("parse encryption key: %w", err)
+	}
+	for _, identity := range identities {
+		if x, ok := identity.(*age.X25519Identity); ok {
+			return x.String(), nil
+		}
+	}
+	return "", errors.New("parse encryption key: no X25519 secret key found")
+}
+
+// EncryptionKeySecret is the secrets store key holding the encryption key
+// of configurations syncing driveFolderID. It is keyed by Drive folder, so
+// initializing the same folder again, or on another machine with the same
+// keyring, uses the same key.
+func EncryptionKeySecret(driveFolderID string) string {
+	return "sync/" + driveFolderID + "/encryption_key"
+}
+
+// LoadEncryptionKey returns the stored encryption key for driveFolderID,
+// or "" if there is none.
+func LoadEncryptionKey(driveFolderID string) (string, error) {
+	key, err := getSecret(EncryptionKeySecret(driveFolderID))
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read encryption key: %w", err)
+	}
+	return string(key), nil
+}
+
+// SaveEncryptionKey stores the encryption key for driveFolderID.
+func SaveEncryptionKey(driveFolderID, key string) error {
+	if _, err := age.ParseX25519Identity(strings.TrimSpace(key)); err != nil {
+		return fmt.Errorf("parse encryption key: %w", err)
+	}
+	if err := setSecret(EncryptionKeySecret(driveFolderID), []byte(strings.TrimSpace(key))); err != nil {
+		return fmt.Errorf("store encryption key: %w", err)
+	}
+	return nil
+}
+
+// LoadCipher returns the cipher of an encrypted configuration, with its
+// key from the secrets store, or nil if the configuration is not
+// encrypted.
+func LoadCipher(cfg *SyncConfig) (*Cipher, error) {
+	if !cfg.Encrypted {
+		return nil, nil
+	}
+
+	key, err := LoadEncryptionKey(cfg.DriveFolderID)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no encryption key for %s in the secrets store (%s)", cfg.LocalPath, EncryptionKeySecret(cfg.DriveFolderID))
+	}
+
+	return NewCipher(key, cfg.EncryptNames)
+}
+
+// Key returns the age secret key.
+func (c *Cipher) Key() string {
+	return c.identity.String()
+}
+
+// Recipient returns the age public key files are encrypted to.
+func (c *Cipher) Recipient() string {
+	return c.identity.Recipient().String()
+}
+
+// Encrypt returns a reader of the encrypted content of r. Close it to stop
+// encrypting early.
+func (c *Cipher) Encrypt(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := age.Encrypt(pw, c.identity.Recipient())
+		if err == nil {
+			_, err = io.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// Decrypt returns a reader of the plaintext of encrypted content r.
+func (c *Cipher) Decrypt(r io.Reader) (io.Reader, error) {
+	plain, err := age.Decrypt(r, c.identity)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt file: %w", err)
+	}
+	return plain, nil
+}
+
+// DriveName returns the Drive name of a local file or folder name.
+func (c *Cipher) DriveName(name string) string {
+	if c == nil || c.names == nil {
+		return name
+	}
+
+	mac := hmac.New(sha256.New, c.nameMAC)
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:c.names.NonceSize()]
+
+	sealed := c.names.Seal(nonce, nonce, []byte(name), nil)
+	return strings.ToLower(nameEncoding.EncodeToString(sealed))
+}
+
+// LocalName maps a Drive name to a single local path component. With
+// encrypted names it reports false for names it cannot decrypt, which are
+// not synced.
+func (c *Cipher) LocalName(driveName string) (string, bool) {
+	if c == nil || c.names == nil {
+		return driveLocalName(driveName), true
+	}
+
+	sealed, err := nameEncoding.DecodeString(strings.ToUpper(driveName))
+	if err != nil || len(sealed) < c.names.NonceSize() {
+		return "", false
+	}
+	nonce, ciphertext := sealed[:c.names.NonceSize()], sealed[c.names.NonceSize():]
+	name, err := c.names.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", false
+	}
+	return driveLocalName(string(name)), true
+}
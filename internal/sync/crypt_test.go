@@ -0,0 +1,339 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/keyring"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func testCipher(t *testing.T, encryptNames bool) *Cipher {
+	t.Helper()
+
+	key, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCipher(key, encryptNames)
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	return c
+}
+
+func TestCipherNames(t *testing.T) {
+	var plain *Cipher
+	if plain.DriveName("a.txt") != "a.txt" {
+		t.Fatal("nil cipher changed a name")
+	}
+	if name, ok := plain.LocalName("a/b.txt"); !ok || name != "a_b.txt" {
+		t.Fatalf("nil cipher LocalName = %q, %v", name, ok)
+	}
+
+	if c := testCipher(t, false); c.DriveName("a.txt") != "a.txt" {
+		t.Fatal("content-only cipher encrypted a name")
+	}
+
+	c := testCipher(t, true)
+	enc := c.DriveName("Client Report.pdf")
+	if enc == "Client Report.pdf" || strings.Contains(enc, "Report") {
+		t.Fatalf("name not encrypted: %q", enc)
+	}
+	if enc != strings.ToLower(enc) {
+		t.Fatalf("encrypted name %q is not lowercase", enc)
+	}
+	if c.DriveName("Client Report.pdf") != enc {
+		t.Fatal("name encryption is not deterministic")
+	}
+	if c.DriveName("Client Report.pdx") == enc {
+		t.Fatal("different names encrypt the same")
+	}
+	if name, ok := c.LocalName(enc); !ok || name != "Client Report.pdf" {
+		t.Fatalf("LocalName = %q, %v", name, ok)
+	}
+
+	// Names written by someone else, or with another key, are not synced.
+	other := testCipher(t, true)
+	for _, foreign := range []string{"Meeting notes", other.DriveName("Client Report.pdf"), enc[:len(enc)-2]} {
+		if name, ok := c.LocalName(foreign); ok {
+			t.Errorf("LocalName(%q) = %q, want undecryptable", foreign, name)
+		}
+	}
+}
+
+func TestCipherContent(t *testing.T) {
+	c := testCipher(t, false)
+	plain := bytes.Repeat([]byte("confidential\n"), 10000)
+
+	encrypted, err := io.ReadAll(c.Encrypt(bytes.NewReader(plain)))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Contains(encrypted, []byte("confidential")) {
+		t.Fatal("encrypted content contains the plaintext")
+	}
+
+	r, err := c.Decrypt(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("round trip = %d bytes, %v", len(got), err)
+	}
+
+	if _, err := testCipher(t, false).Decrypt(bytes.NewReader(encrypted)); err == nil {
+		t.Fatal("decrypted with the wrong key")
+	}
+	if _, err := c.Decrypt(strings.NewReader("plain text")); err == nil {
+		t.Fatal("decrypted content that was never encrypted")
+	}
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	c := testCipher(t, false)
+	file := "# created by age-keygen\n# public key: " + c.Recipient() + "\n" + c.Key() + "\n"
+
+	key, err := ParseEncryptionKey(strings.NewReader(file))
+	if err != nil || key != c.Key() {
+		t.Fatalf("ParseEncryptionKey = %q, %v", key, err)
+	}
+	if _, err := ParseEncryptionKey(strings.NewReader("# nothing here\n")); err == nil {
+		t.Fatal("parsed a file without a key")
+	}
+}
+
+func TestLoadCipher(t *testing.T) {
+	stored := map[string][]byte{}
+	origGet, origSet := getSecret, setSecret
+	getSecret = func(key string) ([]byte, error) {
+		if v, ok := stored[key]; ok {
+			return v, nil
+		}
+		return nil, keyring.ErrKeyNotFound
+	}
+	setSecret = func(key string, value []byte) error {
+		stored[key] = value
+		return nil
+	}
+	t.Cleanup(func() { getSecret, setSecret = origGet, origSet })
+
+	cfg := &SyncConfig{LocalPath: "/data/clients", DriveFolderID: "folder-1"}
+	if c, err := LoadCipher(cfg); c != nil || err != nil {
+		t.Fatalf("unencrypted config: cipher = %v, %v", c, err)
+	}
+
+	cfg.Encrypted = true
+	if _, err := LoadCipher(cfg); err == nil || !strings.Contains(err.Error(), "no encryption key") {
+		t.Fatalf("missing key error = %v", err)
+	}
+
+	key, _ := GenerateEncryptionKey()
+	if err := SaveEncryptionKey("folder-1", key); err != nil {
+		t.Fatalf("SaveEncryptionKey: %v", err)
+	}
+	if _, ok := stored["sync/folder-1/encryption_key"]; !ok {
+		t.Fatalf("key stored under %v", stored)
+	}
+	c, err := LoadCipher(cfg)
+	if err != nil || c.Key() != key {
+		t.Fatalf("LoadCipher = %v, %v", c, err)
+	}
+
+	if err := SaveEncryptionKey("folder-1", "not a key"); err == nil {
+		t.Fatal("saved an invalid key")
+	}
+	getSecret = func(string) ([]byte, error) { return nil, errors.New("keyring locked") }
+	if _, err := LoadCipher(cfg); err == nil || !strings.Contains(err.Error(), "keyring locked") {
+		t.Fatalf("keyring error = %v", err)
+	}
+}
+
+// encryptedDrive is a fake Drive holding uploaded files in memory, by ID.
+type encryptedDrive struct {
+	names    map[string]string // file ID -> Drive name
+	contents map[string][]byte // file ID -> content as uploaded
+	types    map[string]string // file ID -> media content type
+
+	downloads int
+}
+
+func (fd *encryptedDrive) serve(t *testing.T) *drive.Service {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			meta, content, contentType, err := readMultipartUpload(r)
+			if err != nil {
+				t.Errorf("read upload: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			if id == "files" {
+				id = "file-" + meta.Name
+				fd.names[id] = meta.Name
+			}
+			fd.contents[id] = content
+			fd.types[id] = contentType
+			sum := md5.Sum(content)
+			json.NewEncoder(w).Encode(map[string]string{"id": id, "md5Checksum": hex.EncodeToString(sum[:])})
+			return
+		}
+
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if id == "files" {
+			json.NewEncoder(w).Encode(map[string]any{"files": []any{}})
+			return
+		}
+		content, ok := fd.contents[id]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case r.URL.Query().Get("alt") == "media":
+			fd.downloads++
+			_, _ = w.Write(content)
+		default:
+			sum := md5.Sum(content)
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": id, "name": fd.names[id], "mimeType": "application/octet-stream",
+				"md5Checksum": hex.EncodeToString(sum[:]),
+			})
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	svc, err := drive.NewService(context.Background(),
+		option.WithEndpoint(ts.URL),
+		option.WithHTTPClient(ts.Client()),
+	)
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+	return svc
+}
+
+// readMultipartUpload splits a multipart upload into its metadata and
+// media parts.
+func readMultipartUpload(r *http.Request) (meta drive.File, content []byte, contentType string, err error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return meta, nil, "", err
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return meta, content, contentType, nil
+		}
+		if err != nil {
+			return meta, nil, "", err
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "application/json") {
+			if err := json.NewDecoder(part).Decode(&meta); err != nil {
+				return meta, nil, "", err
+			}
+			continue
+		}
+		contentType = part.Header.Get("Content-Type")
+		if content, err = io.ReadAll(part); err != nil {
+			return meta, nil, "", err
+		}
+	}
+}
+
+func TestEncryptedSyncRoundTrip(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+	c := testCipher(t, true)
+
+	fd := &encryptedDrive{names: map[string]string{}, contents: map[string][]byte{}, types: map[string]string{}}
+	svc := fd.serve(t)
+
+	folders := NewFolderTree("root")
+	folders.SetCipher(c)
+	uploader := NewUploader(svc, "root", "")
+	uploader.SetFolderTree(folders)
+	uploader.SetCipher(c)
+	dloader := NewDownloader(svc, tmpDir)
+	dloader.SetCipher(c)
+
+	engine := &Engine{
+		db:       d,
+		config:   &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root", Encrypted: true, EncryptNames: true},
+		service:  svc,
+		folders:  folders,
+		uploader: uploader,
+		dloader:  dloader,
+		resolver: NewConflictResolver(d, configID, ConflictRename),
+		cipher:   c,
+	}
+	ctx := context.Background()
+
+	writeLocal(t, tmpDir, "contract.txt", "the secret terms")
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "contract.txt", Op: OpCreate})
+
+	item, _ := d.GetSyncItem(configID, "contract.txt")
+	if item == nil || item.SyncState != StateSynced {
+		t.Fatalf("uploaded item = %+v", item)
+	}
+	driveName := fd.names[item.DriveID]
+	uploaded := fd.contents[item.DriveID]
+	if driveName != c.DriveName("contract.txt") {
+		t.Fatalf("Drive name = %q, want the encrypted name", driveName)
+	}
+	if bytes.Contains(uploaded, []byte("secret")) || fd.types[item.DriveID] != encryptedMimeType {
+		t.Fatalf("uploaded %q as %q, want ciphertext", uploaded, fd.types[item.DriveID])
+	}
+
+	// Local changes are detected against the plaintext, remote ones
+	// against what Drive stores.
+	sum := md5.Sum(uploaded)
+	if item.LocalMD5 != md5Hex("the secret terms") || item.RemoteMD5 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("item MD5s = %s / %s", item.LocalMD5, item.RemoteMD5)
+	}
+
+	// The echo of our own upload is recognized, without a download.
+	engine.handleRemoteChange(ctx, DriveChange{FileID: item.DriveID, Op: DriveOpModify, RelPath: "contract.txt", MD5: item.RemoteMD5})
+	if fd.downloads != 0 {
+		t.Fatalf("downloads = %d, want the echo ignored", fd.downloads)
+	}
+
+	// A new version from another machine is decrypted locally.
+	update, err := io.ReadAll(c.Encrypt(strings.NewReader("the amended terms")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.contents[item.DriveID] = update
+	sum = md5.Sum(update)
+	engine.handleRemoteChange(ctx, DriveChange{FileID: item.DriveID, Op: DriveOpModify, RelPath: "contract.txt", MD5: hex.EncodeToString(sum[:])})
+
+	if b, err := os.ReadFile(filepath.Join(tmpDir, "contract.txt")); err != nil || string(b) != "the amended terms" {
+		t.Fatalf("downloaded = %q, %v", b, err)
+	}
+	if item, _ = d.GetSyncItem(configID, "contract.txt"); item.LocalMD5 != md5Hex("the amended terms") || item.RemoteMD5 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("item after download = %+v", item)
+	}
+
+	// Content read for merges is decrypted too.
+	if b, err := dloader.Content(ctx, item.DriveID, maxMergeSize); err != nil || string(b) != "the amended terms" {
+		t.Fatalf("Content = %q, %v", b, err)
+	}
+}
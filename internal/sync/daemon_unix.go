@@ -154,6 +154,27 @@ func CheckNotAlreadyRunning() error {
 // StartDaemon starts the sync daemon in the background.
 // It re-executes the current binary with --internal-daemon flag.
 func StartDaemon(localPath, account, conflict string) (int, error) {
+	return startDaemon([]string{
+		"sync", "start", localPath,
+		"--internal-daemon",
+		"--account", account,
+		"--conflict", conflict,
+	})
+}
+
+// StartSupervisorDaemon starts a background supervisor that syncs every
+// configuration. account is the fallback for configs without one.
+func StartSupervisorDaemon(account, conflict string) (int, error) {
+	args := []string{"sync", "start", "--all", "--internal-daemon", "--conflict", conflict}
+	if account != "" {
+		args = append(args, "--account", account)
+	}
+
+	return startDaemon(args)
+}
+
+// startDaemon re-executes the current binary with args in the background.
+func startDaemon(args []string) (int, error) {
 	if err := CheckNotAlreadyRunning(); err != nil {
 		return 0, err
 	}
@@ -177,14 +198,6 @@ func StartDaemon(localPath, account, conflict string) (int, error) {
 	}
 	defer logFile.Close()
 
-	// Build command arguments
-	args := []string{
-		"sync", "start", localPath,
-		"--internal-daemon",
-		"--account", account,
-		"--conflict", conflict,
-	}
-
 	// Create command
 	cmd := exec.Command(executable, args...)
 	cmd.Stdout = logFile
@@ -39,3 +39,9 @@ func CheckNotAlreadyRunning() error {
 func StartDaemon(localPath, account, conflict string) (int, error) {
 	return 0, fmt.Errorf("sync daemon is not supported on Windows")
 }
+
+// StartSupervisorDaemon starts a background supervisor for every config.
+// Not supported on Windows.
+func StartSupervisorDaemon(account, conflict string) (int, error) {
+	return 0, fmt.Errorf("sync daemon is not supported on Windows")
+}
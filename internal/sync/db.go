@@ -1,12 +1,17 @@
 package sync
 
 import (
+	"bytes"
+	"compress/gzip"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	_ "modernc.org/sqlite"
 
@@ -106,14 +111,147 @@ func (d *DB) migrate() error {
 
 	CREATE INDEX IF NOT EXISTS idx_sync_log_config_id ON sync_log(config_id);
 	CREATE INDEX IF NOT EXISTS idx_sync_log_timestamp ON sync_log(timestamp);
+
+	CREATE TABLE IF NOT EXISTS sync_health (
+		config_id INTEGER PRIMARY KEY,
+		state TEXT NOT NULL,
+		account TEXT DEFAULT '',
+		restarts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT DEFAULT '',
+		started_at DATETIME,
+		next_retry_at DATETIME,
+		updated_at DATETIME NOT NULL,
+		FOREIGN KEY (config_id) REFERENCES sync_configs(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_transfers (
+		config_id INTEGER PRIMARY KEY,
+		workers INTEGER NOT NULL DEFAULT 0,
+		queued INTEGER NOT NULL DEFAULT 0,
+		active INTEGER NOT NULL DEFAULT 0,
+		upload_rate INTEGER NOT NULL DEFAULT 0,
+		download_rate INTEGER NOT NULL DEFAULT 0,
+		uploaded_bytes INTEGER NOT NULL DEFAULT 0,
+		downloaded_bytes INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL,
+		FOREIGN KEY (config_id) REFERENCES sync_configs(id) ON DELETE CASCADE
+	);
 	`
 
-	_, err := d.db.Exec(schema)
-	return err
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS
+	// leaves existing databases untouched, so add them explicitly.
+	if err := d.addColumnIfMissing("sync_configs", "ignore_patterns", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "native_docs", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "account", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "max_deletes", fmt.Sprintf("INTEGER DEFAULT %d", DefaultMaxDeletes)); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "max_delete_percent", fmt.Sprintf("INTEGER DEFAULT %d", DefaultMaxDeletePercent)); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "trash_days", fmt.Sprintf("INTEGER DEFAULT %d", DefaultTrashDays)); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "paused_reason", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "paused_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "conflict_path", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "size", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "inode", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "base_content", "BLOB"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "transfer_workers", fmt.Sprintf("INTEGER DEFAULT %d", DefaultTransferWorkers)); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "upload_limit", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "download_limit", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "attempts", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "next_retry_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_items", "last_error", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("sync_configs", "encrypted", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	return d.addColumnIfMissing("sync_configs", "encrypt_names", "INTEGER DEFAULT 0")
+}
+
+// addColumnIfMissing adds a column to table unless it already exists.
+func (d *DB) addColumnIfMissing(table, column, definition string) error {
+	rows, err := d.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("scan table info %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if _, err := d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
 }
 
 // CreateConfig creates a new sync configuration.
 func (d *DB) CreateConfig(localPath, driveFolderID, driveID string) (*SyncConfig, error) {
+	return d.createConfig(localPath, driveFolderID, driveID, false, false)
+}
+
+// CreateEncryptedConfig creates a new sync configuration that encrypts file
+// contents, and names if encryptNames is set. Encryption is part of the
+// same insert, so the config is never visible unencrypted.
+func (d *DB) CreateEncryptedConfig(localPath, driveFolderID, driveID string, encryptNames bool) (*SyncConfig, error) {
+	return d.createConfig(localPath, driveFolderID, driveID, true, encryptNames)
+}
+
+func (d *DB) createConfig(localPath, driveFolderID, driveID string, encrypted, encryptNames bool) (*SyncConfig, error) {
 	// Expand and clean the path
 	expandedPath, err := config.ExpandPath(localPath)
 	if err != nil {
@@ -141,9 +279,9 @@ func (d *DB) CreateConfig(localPath, driveFolderID, driveID string) (*SyncConfig
 
 	now := time.Now()
 	result, err := d.db.Exec(
-		`INSERT INTO sync_configs (local_path, drive_folder_id, drive_id, created_at)
-		 VALUES (?, ?, ?, ?)`,
-		absPath, driveFolderID, driveID, now,
+		`INSERT INTO sync_configs (local_path, drive_folder_id, drive_id, created_at, encrypted, encrypt_names)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		absPath, driveFolderID, driveID, now, encrypted, encryptNames,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert config: %w", err)
@@ -155,11 +293,17 @@ func (d *DB) CreateConfig(localPath, driveFolderID, driveID string) (*SyncConfig
 	}
 
 	return &SyncConfig{
-		ID:            id,
-		LocalPath:     absPath,
-		DriveFolderID: driveFolderID,
-		DriveID:       driveID,
-		CreatedAt:     now,
+		ID:               id,
+		LocalPath:        absPath,
+		DriveFolderID:    driveFolderID,
+		DriveID:          driveID,
+		CreatedAt:        now,
+		MaxDeletes:       DefaultMaxDeletes,
+		MaxDeletePercent: DefaultMaxDeletePercent,
+		TrashDays:        DefaultTrashDays,
+		TransferWorkers:  DefaultTransferWorkers,
+		Encrypted:        encrypted,
+		EncryptNames:     encryptNames,
 	}, nil
 }
 
@@ -174,53 +318,38 @@ func (d *DB) GetConfig(localPath string) (*SyncConfig, error) {
 		return nil, fmt.Errorf("absolute path: %w", err)
 	}
 
-	var cfg SyncConfig
-	var lastSyncAt sql.NullTime
-	err = d.db.QueryRow(
-		`SELECT id, local_path, drive_folder_id, drive_id, created_at, last_sync_at, change_token
-		 FROM sync_configs WHERE local_path = ?`,
+	cfg, err := scanConfig(d.db.QueryRow(
+		`SELECT `+configColumns+` FROM sync_configs WHERE local_path = ?`,
 		absPath,
-	).Scan(&cfg.ID, &cfg.LocalPath, &cfg.DriveFolderID, &cfg.DriveID,
-		&cfg.CreatedAt, &lastSyncAt, &cfg.ChangeToken)
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query config: %w", err)
 	}
-	if lastSyncAt.Valid {
-		cfg.LastSyncAt = lastSyncAt.Time
-	}
-	return &cfg, nil
+	return cfg, nil
 }
 
 // GetConfigByID retrieves a sync configuration by ID.
 func (d *DB) GetConfigByID(id int64) (*SyncConfig, error) {
-	var cfg SyncConfig
-	var lastSyncAt sql.NullTime
-	err := d.db.QueryRow(
-		`SELECT id, local_path, drive_folder_id, drive_id, created_at, last_sync_at, change_token
-		 FROM sync_configs WHERE id = ?`,
+	cfg, err := scanConfig(d.db.QueryRow(
+		`SELECT `+configColumns+` FROM sync_configs WHERE id = ?`,
 		id,
-	).Scan(&cfg.ID, &cfg.LocalPath, &cfg.DriveFolderID, &cfg.DriveID,
-		&cfg.CreatedAt, &lastSyncAt, &cfg.ChangeToken)
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query config: %w", err)
 	}
-	if lastSyncAt.Valid {
-		cfg.LastSyncAt = lastSyncAt.Time
-	}
-	return &cfg, nil
+	return cfg, nil
 }
 
 // ListConfigs returns all sync configurations.
 func (d *DB) ListConfigs() ([]SyncConfig, error) {
 	rows, err := d.db.Query(
-		`SELECT id, local_path, drive_folder_id, drive_id, created_at, last_sync_at, change_token
-		 FROM sync_configs ORDER BY created_at DESC`,
+		`SELECT ` + configColumns + ` FROM sync_configs ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query configs: %w", err)
@@ -229,20 +358,56 @@ func (d *DB) ListConfigs() ([]SyncConfig, error) {
 
 	var configs []SyncConfig
 	for rows.Next() {
-		var cfg SyncConfig
-		var lastSyncAt sql.NullTime
-		if err := rows.Scan(&cfg.ID, &cfg.LocalPath, &cfg.DriveFolderID, &cfg.DriveID,
-			&cfg.CreatedAt, &lastSyncAt, &cfg.ChangeToken); err != nil {
+		cfg, err := scanConfig(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan config: %w", err)
 		}
-		if lastSyncAt.Valid {
-			cfg.LastSyncAt = lastSyncAt.Time
-		}
-		configs = append(configs, cfg)
+		configs = append(configs, *cfg)
 	}
 	return configs, rows.Err()
 }
 
+// configColumns lists the sync_configs columns read by scanConfig.
+const configColumns = `id, local_path, drive_folder_id, drive_id, created_at, last_sync_at,
+	change_token, ignore_patterns, native_docs, account, max_deletes, max_delete_percent,
+	trash_days, paused_reason, paused_at, transfer_workers, upload_limit, download_limit,
+	encrypted, encrypt_names`
+
+// scanConfig scans a row selected with configColumns.
+func scanConfig(row rowScanner) (*SyncConfig, error) {
+	var cfg SyncConfig
+	var lastSyncAt, pausedAt sql.NullTime
+	var ignorePatterns, nativeDocs, account, pausedReason sql.NullString
+	var maxDeletes, maxDeletePercent, trashDays sql.NullInt64
+	var transferWorkers, uploadLimit, downloadLimit sql.NullInt64
+	var encrypted, encryptNames sql.NullBool
+	if err := row.Scan(&cfg.ID, &cfg.LocalPath, &cfg.DriveFolderID, &cfg.DriveID,
+		&cfg.CreatedAt, &lastSyncAt, &cfg.ChangeToken, &ignorePatterns, &nativeDocs, &account,
+		&maxDeletes, &maxDeletePercent, &trashDays, &pausedReason, &pausedAt,
+		&transferWorkers, &uploadLimit, &downloadLimit, &encrypted, &encryptNames); err != nil {
+		return nil, err
+	}
+	if lastSyncAt.Valid {
+		cfg.LastSyncAt = lastSyncAt.Time
+	}
+	if pausedAt.Valid {
+		cfg.PausedAt = pausedAt.Time
+	}
+	cfg.MaxDeletes = int(maxDeletes.Int64)
+	cfg.MaxDeletePercent = int(maxDeletePercent.Int64)
+	cfg.TrashDays = int(trashDays.Int64)
+	cfg.TransferWorkers = int(transferWorkers.Int64)
+	cfg.UploadLimit = uploadLimit.Int64
+	cfg.DownloadLimit = downloadLimit.Int64
+	cfg.Encrypted = encrypted.Bool
+	cfg.EncryptNames = encryptNames.Bool
+	cfg.PausedReason = pausedReason.String
+	cfg.IgnorePatterns = splitIgnorePatterns(ignorePatterns.String)
+	cfg.NativeDocs = NativeDocsMode(nativeDocs.String)
+	cfg.Account = account.String
+	return &cfg, nil
+}
+
 // RemoveConfig removes a sync configuration by local path.
 func (d *DB) RemoveConfig(localPath string) error {
 	expandedPath, err := config.ExpandPath(localPath)
@@ -278,6 +443,127 @@ func (d *DB) UpdateChangeToken(configID int64, token string) error {
 	return err
 }
 
+// SetIgnorePatterns replaces the per-config ignore patterns.
+// Patterns use .wkignore syntax; a leading "!" re-includes a path.
+func (d *DB) SetIgnorePatterns(configID int64, patterns []string) error {
+	result, err := d.db.Exec(
+		`UPDATE sync_configs SET ignore_patterns = ? WHERE id = ?`,
+		strings.Join(patterns, "\n"), configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update ignore patterns: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("config not found: %d", configID)
+	}
+	return nil
+}
+
+// SetNativeDocs sets how Google-native files sync for a config.
+func (d *DB) SetNativeDocs(configID int64, mode NativeDocsMode) error {
+	result, err := d.db.Exec(
+		`UPDATE sync_configs SET native_docs = ? WHERE id = ?`,
+		string(mode), configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update native docs mode: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("config not found: %d", configID)
+	}
+	return nil
+}
+
+// SetConfigAccount sets the account a config syncs as.
+func (d *DB) SetConfigAccount(configID int64, account string) error {
+	result, err := d.db.Exec(
+		`UPDATE sync_configs SET account = ? WHERE id = ?`,
+		account, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update account: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("config not found: %d", configID)
+	}
+	return nil
+}
+
+// SetDeleteGuard sets a config's delete limits and trash retention.
+func (d *DB) SetDeleteGuard(configID int64, maxDeletes, maxDeletePercent, trashDays int) error {
+	result, err := d.db.Exec(
+		`UPDATE sync_configs SET max_deletes = ?, max_delete_percent = ?, trash_days = ? WHERE id = ?`,
+		maxDeletes, maxDeletePercent, trashDays, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update delete guard: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("config not found: %d", configID)
+	}
+	return nil
+}
+
+// SetTransferLimits sets a config's number of transfer workers and its
+// upload and download limits in bytes per second (zero is unlimited).
+func (d *DB) SetTransferLimits(configID int64, workers int, uploadLimit, downloadLimit int64) error {
+	result, err := d.db.Exec(
+		`UPDATE sync_configs SET transfer_workers = ?, upload_limit = ?, download_limit = ? WHERE id = ?`,
+		workers, uploadLimit, downloadLimit, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update transfer limits: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("config not found: %d", configID)
+	}
+	return nil
+}
+
+// PauseConfig marks a config as paused by the delete guard.
+func (d *DB) PauseConfig(configID int64, reason string) error {
+	_, err := d.db.Exec(
+		`UPDATE sync_configs SET paused_reason = ?, paused_at = ? WHERE id = ?`,
+		reason, time.Now(), configID,
+	)
+	if err != nil {
+		return fmt.Errorf("pause config: %w", err)
+	}
+	return nil
+}
+
+// ResumeConfig clears a config's paused state.
+func (d *DB) ResumeConfig(configID int64) error {
+	_, err := d.db.Exec(
+		`UPDATE sync_configs SET paused_reason = '', paused_at = NULL WHERE id = ?`,
+		configID,
+	)
+	if err != nil {
+		return fmt.Errorf("resume config: %w", err)
+	}
+	return nil
+}
+
 // GetStatus returns the sync status for a configuration.
 func (d *DB) GetStatus(configID int64) (*SyncStatus, error) {
 	cfg, err := d.GetConfigByID(configID)
@@ -319,7 +605,19 @@ func (d *DB) GetStatus(configID int64) (*SyncStatus, error) {
 		}
 	}
 
-	return status, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if status.Engine, err = d.GetEngineHealth(configID); err != nil {
+		return nil, err
+	}
+
+	if status.Transfers, err = d.GetTransferStats(configID); err != nil {
+		return nil, err
+	}
+
+	return status, nil
 }
 
 // ListStatuses returns the sync status for all configurations.
@@ -393,24 +691,27 @@ func (d *DB) CreateSyncItem(configID int64, localPath, driveID, localMD5, remote
 	return err
 }
 
-// GetSyncItem retrieves a sync item by local path.
-func (d *DB) GetSyncItem(configID int64, localPath string) (*SyncItem, error) {
-	var item SyncItem
-	var localMtime, remoteMtime sql.NullTime
+// syncItemColumns lists the sync_items columns read by scanSyncItem.
+const syncItemColumns = `id, config_id, local_path, drive_id, local_md5, remote_md5,
+		        local_mtime, remote_mtime, sync_state, conflict_path, size, inode,
+		        attempts, next_retry_at, last_error`
 
-	err := d.db.QueryRow(
-		`SELECT id, config_id, local_path, drive_id, local_md5, remote_md5, local_mtime, remote_mtime, sync_state
-		 FROM sync_items WHERE config_id = ? AND local_path = ?`,
-		configID, localPath,
-	).Scan(&item.ID, &item.ConfigID, &item.LocalPath, &item.DriveID,
-		&item.LocalMD5, &item.RemoteMD5, &localMtime, &remoteMtime, &item.SyncState)
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// scanSyncItem scans a row selected with syncItemColumns.
+func scanSyncItem(row rowScanner) (*SyncItem, error) {
+	var item SyncItem
+	var localMtime, remoteMtime, nextRetryAt sql.NullTime
+	var conflictPath, lastError sql.NullString
+	var size, inode, attempts sql.NullInt64
 
-	if err != nil {
-		return nil, fmt.Errorf("query sync item: %w", err)
+	if err := row.Scan(&item.ID, &item.ConfigID, &item.LocalPath, &item.DriveID,
+		&item.LocalMD5, &item.RemoteMD5, &localMtime, &remoteMtime, &item.SyncState, &conflictPath,
+		&size, &inode, &attempts, &nextRetryAt, &lastError); err != nil {
+		return nil, err
 	}
 
 	if localMtime.Valid {
@@ -421,20 +722,44 @@ func (d *DB) GetSyncItem(configID int64, localPath string) (*SyncItem, error) {
 		item.RemoteMtime = remoteMtime.Time
 	}
 
+	item.ConflictPath = conflictPath.String
+	item.Size = size.Int64
+	item.Inode = uint64(inode.Int64)
+	item.Attempts = int(attempts.Int64)
+	if nextRetryAt.Valid {
+		item.NextRetryAt = nextRetryAt.Time
+	}
+	item.LastError = lastError.String
+
 	return &item, nil
 }
 
+// GetSyncItem retrieves a sync item by local path.
+func (d *DB) GetSyncItem(configID int64, localPath string) (*SyncItem, error) {
+	item, err := scanSyncItem(d.db.QueryRow(
+		`SELECT `+syncItemColumns+`
+		 FROM sync_items WHERE config_id = ? AND local_path = ?`,
+		configID, localPath,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("query sync item: %w", err)
+	}
+
+	return item, nil
+}
+
 // GetSyncItemByDriveID retrieves a sync item by Drive file ID.
 func (d *DB) GetSyncItemByDriveID(configID int64, driveID string) (*SyncItem, error) {
-	var item SyncItem
-	var localMtime, remoteMtime sql.NullTime
-
-	err := d.db.QueryRow(
-		`SELECT id, config_id, local_path, drive_id, local_md5, remote_md5, local_mtime, remote_mtime, sync_state
+	item, err := scanSyncItem(d.db.QueryRow(
+		`SELECT `+syncItemColumns+`
 		 FROM sync_items WHERE config_id = ? AND drive_id = ?`,
 		configID, driveID,
-	).Scan(&item.ID, &item.ConfigID, &item.LocalPath, &item.DriveID,
-		&item.LocalMD5, &item.RemoteMD5, &localMtime, &remoteMtime, &item.SyncState)
+	))
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -444,21 +769,15 @@ func (d *DB) GetSyncItemByDriveID(configID int64, driveID string) (*SyncItem, er
 		return nil, fmt.Errorf("query sync item by drive id: %w", err)
 	}
 
-	if localMtime.Valid {
-		item.LocalMtime = localMtime.Time
-	}
-
-	if remoteMtime.Valid {
-		item.RemoteMtime = remoteMtime.Time
-	}
-
-	return &item, nil
+	return item, nil
 }
 
-// UpdateSyncItem updates a sync item.
+// UpdateSyncItem updates a sync item after a completed transfer, clearing
+// any queued retry.
 func (d *DB) UpdateSyncItem(itemID int64, driveID, localMD5, remoteMD5 string, state SyncState) error {
 	_, err := d.db.Exec(
-		`UPDATE sync_items SET drive_id = ?, local_md5 = ?, remote_md5 = ?, sync_state = ?
+		`UPDATE sync_items SET drive_id = ?, local_md5 = ?, remote_md5 = ?, sync_state = ?,
+		        attempts = 0, next_retry_at = NULL, last_error = ''
 		 WHERE id = ?`,
 		driveID, localMD5, remoteMD5, state, itemID,
 	)
@@ -466,6 +785,80 @@ func (d *DB) UpdateSyncItem(itemID int64, driveID, localMD5, remoteMD5 string, s
 	return err
 }
 
+// SetSyncItemFileInfo records the size and inode of an item's local file.
+func (d *DB) SetSyncItemFileInfo(itemID, size int64, inode uint64) error {
+	_, err := d.db.Exec(
+		`UPDATE sync_items SET size = ?, inode = ? WHERE id = ?`,
+		size, int64(inode), itemID,
+	)
+
+	return err
+}
+
+// SetSyncBase stores content, gzip-compressed, as the last-synced content of
+// an item: the base of three-way merges. nil clears it.
+func (d *DB) SetSyncBase(itemID int64, content []byte) error {
+	var blob []byte
+	if content != nil {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(content); err != nil {
+			return fmt.Errorf("compress sync base: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compress sync base: %w", err)
+		}
+		blob = buf.Bytes()
+	}
+
+	_, err := d.db.Exec(`UPDATE sync_items SET base_content = ? WHERE id = ?`, blob, itemID)
+
+	return err
+}
+
+// GetSyncBase returns the last-synced content of an item, or nil if none
+// is stored.
+func (d *DB) GetSyncBase(itemID int64) ([]byte, error) {
+	var blob []byte
+	err := d.db.QueryRow(`SELECT base_content FROM sync_items WHERE id = ?`, itemID).Scan(&blob)
+	if err == sql.ErrNoRows || (err == nil && len(blob) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query sync base: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		return nil, fmt.Errorf("decompress sync base: %w", err)
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompress sync base: %w", err)
+	}
+
+	return content, nil
+}
+
+// FindSyncItemsByMD5 lists the items whose local file last synced with the
+// given MD5.
+func (d *DB) FindSyncItemsByMD5(configID int64, md5 string) ([]SyncItem, error) {
+	rows, err := d.db.Query(
+		`SELECT `+syncItemColumns+`
+		 FROM sync_items WHERE config_id = ? AND local_md5 = ?
+		 ORDER BY local_path`,
+		configID, md5,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query sync items by md5: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSyncItems(rows)
+}
+
 // RemoveSyncItem removes a sync item by local path.
 func (d *DB) RemoveSyncItem(configID int64, localPath string) error {
 	_, err := d.db.Exec(
@@ -476,39 +869,281 @@ func (d *DB) RemoveSyncItem(configID int64, localPath string) error {
 	return err
 }
 
+// RemoveSyncItemsUnder removes the sync item at localPath and every item
+// below it, for when a whole directory goes away.
+func (d *DB) RemoveSyncItemsUnder(configID int64, localPath string) error {
+	prefix := localPath + string(filepath.Separator)
+	_, err := d.db.Exec(
+		`DELETE FROM sync_items
+		 WHERE config_id = ? AND (local_path = ? OR substr(local_path, 1, ?) = ?)`,
+		configID, localPath, utf8.RuneCountInString(prefix), prefix,
+	)
+
+	return err
+}
+
+// RenameSyncItems moves the sync item at oldPath, and every item below it,
+// to newPath.
+func (d *DB) RenameSyncItems(configID int64, oldPath, newPath string) error {
+	prefix := oldPath + string(filepath.Separator)
+	_, err := d.db.Exec(
+		`UPDATE sync_items SET local_path = ? || substr(local_path, ?)
+		 WHERE config_id = ? AND (local_path = ? OR substr(local_path, 1, ?) = ?)`,
+		newPath, utf8.RuneCountInString(oldPath)+1,
+		configID, oldPath, utf8.RuneCountInString(prefix), prefix,
+	)
+
+	return err
+}
+
+// SetSyncItemState changes an item's state without touching its last-synced
+// checksums. conflictPath records the conflict copy for StateConflict and
+// is cleared for every other state.
+func (d *DB) SetSyncItemState(itemID int64, state SyncState, conflictPath string) error {
+	if state != StateConflict {
+		conflictPath = ""
+	}
+
+	_, err := d.db.Exec(
+		`UPDATE sync_items SET sync_state = ?, conflict_path = ? WHERE id = ?`,
+		state, conflictPath, itemID,
+	)
+
+	return err
+}
+
+// SetSyncItemRetry records a failed transfer of an item: its state, the
+// number of attempts so far, when to try again and why it failed.
+func (d *DB) SetSyncItemRetry(itemID int64, state SyncState, attempts int, nextRetryAt time.Time, lastError string) error {
+	_, err := d.db.Exec(
+		`UPDATE sync_items SET sync_state = ?, attempts = ?, next_retry_at = ?, last_error = ? WHERE id = ?`,
+		state, attempts, nullTime(nextRetryAt), lastError, itemID,
+	)
+
+	return err
+}
+
+// ListDueTransfers returns the items of a config waiting to be uploaded or
+// downloaded whose retry time, if any, has passed.
+func (d *DB) ListDueTransfers(configID int64, now time.Time) ([]SyncItem, error) {
+	rows, err := d.db.Query(
+		`SELECT `+syncItemColumns+`
+		 FROM sync_items WHERE config_id = ? AND sync_state IN (?, ?)
+		   AND (next_retry_at IS NULL OR next_retry_at <= ?)
+		 ORDER BY local_path`,
+		configID, StatePendingUpload, StatePendingDownload, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query due transfers: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSyncItems(rows)
+}
+
 // ListPendingUploads returns all sync items with pending_upload state for a config.
 func (d *DB) ListPendingUploads(configID int64) ([]SyncItem, error) {
+	items, err := d.listSyncItemsByState(configID, StatePendingUpload)
+	if err != nil {
+		return nil, fmt.Errorf("query pending uploads: %w", err)
+	}
+
+	return items, nil
+}
+
+// ListConflicts returns all sync items in the conflict state for a config.
+func (d *DB) ListConflicts(configID int64) ([]SyncItem, error) {
+	items, err := d.listSyncItemsByState(configID, StateConflict)
+	if err != nil {
+		return nil, fmt.Errorf("query conflicts: %w", err)
+	}
+
+	return items, nil
+}
+
+// CountSyncItems counts the sync items at or under localPath; an empty
+// localPath counts every item of the config.
+func (d *DB) CountSyncItems(configID int64, localPath string) (int, error) {
+	var count int
+	var err error
+	if localPath == "" {
+		err = d.db.QueryRow(
+			`SELECT COUNT(*) FROM sync_items WHERE config_id = ?`,
+			configID,
+		).Scan(&count)
+	} else {
+		prefix := localPath + string(filepath.Separator)
+		err = d.db.QueryRow(
+			`SELECT COUNT(*) FROM sync_items
+			 WHERE config_id = ? AND (local_path = ? OR substr(local_path, 1, ?) = ?)`,
+			configID, localPath, utf8.RuneCountInString(prefix), prefix,
+		).Scan(&count)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("count sync items: %w", err)
+	}
+	return count, nil
+}
+
+// ListSyncItems returns every sync item for a config.
+func (d *DB) ListSyncItems(configID int64) ([]SyncItem, error) {
 	rows, err := d.db.Query(
-		`SELECT id, config_id, local_path, drive_id, local_md5, remote_md5,
-		        local_mtime, remote_mtime, sync_state
-		 FROM sync_items WHERE config_id = ? AND sync_state = ?`,
-		configID, StatePendingUpload,
+		`SELECT `+syncItemColumns+`
+		 FROM sync_items WHERE config_id = ?
+		 ORDER BY local_path`,
+		configID,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("query pending uploads: %w", err)
+		return nil, fmt.Errorf("query sync items: %w", err)
 	}
 	defer rows.Close()
 
-	var items []SyncItem
-	for rows.Next() {
-		var item SyncItem
-		var localMtime, remoteMtime sql.NullTime
+	return scanSyncItems(rows)
+}
 
-		if err := rows.Scan(&item.ID, &item.ConfigID, &item.LocalPath, &item.DriveID,
-			&item.LocalMD5, &item.RemoteMD5, &localMtime, &remoteMtime, &item.SyncState); err != nil {
-			return nil, fmt.Errorf("scan pending upload: %w", err)
-		}
+func (d *DB) listSyncItemsByState(configID int64, state SyncState) ([]SyncItem, error) {
+	rows, err := d.db.Query(
+		`SELECT `+syncItemColumns+`
+		 FROM sync_items WHERE config_id = ? AND sync_state = ?
+		 ORDER BY local_path`,
+		configID, state,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		if localMtime.Valid {
-			item.LocalMtime = localMtime.Time
-		}
+	return scanSyncItems(rows)
+}
 
-		if remoteMtime.Valid {
-			item.RemoteMtime = remoteMtime.Time
+func scanSyncItems(rows *sql.Rows) ([]SyncItem, error) {
+
+	var items []SyncItem
+	for rows.Next() {
+		item, err := scanSyncItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan sync item: %w", err)
 		}
 
-		items = append(items, item)
+		items = append(items, *item)
 	}
 
 	return items, rows.Err()
 }
+
+// SetEngineHealth records the supervisor's view of one engine.
+func (d *DB) SetEngineHealth(h EngineHealth) error {
+	_, err := d.db.Exec(
+		`INSERT INTO sync_health (config_id, state, account, restarts, last_error, started_at, next_retry_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(config_id) DO UPDATE SET
+		   state = excluded.state,
+		   account = excluded.account,
+		   restarts = excluded.restarts,
+		   last_error = excluded.last_error,
+		   started_at = excluded.started_at,
+		   next_retry_at = excluded.next_retry_at,
+		   updated_at = excluded.updated_at`,
+		h.ConfigID, string(h.State), h.Account, h.Restarts, h.LastError,
+		nullTime(h.StartedAt), nullTime(h.NextRetryAt), h.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("update engine health: %w", err)
+	}
+	return nil
+}
+
+// GetEngineHealth returns the recorded health of a config's engine, or nil
+// if no supervisor is running it.
+func (d *DB) GetEngineHealth(configID int64) (*EngineHealth, error) {
+	var h EngineHealth
+	var state string
+	var account, lastError sql.NullString
+	var startedAt, nextRetryAt sql.NullTime
+	err := d.db.QueryRow(
+		`SELECT config_id, state, account, restarts, last_error, started_at, next_retry_at, updated_at
+		 FROM sync_health WHERE config_id = ?`,
+		configID,
+	).Scan(&h.ConfigID, &state, &account, &h.Restarts, &lastError, &startedAt, &nextRetryAt, &h.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query engine health: %w", err)
+	}
+	h.State = EngineState(state)
+	h.Account = account.String
+	h.LastError = lastError.String
+	if startedAt.Valid {
+		h.StartedAt = startedAt.Time
+	}
+	if nextRetryAt.Valid {
+		h.NextRetryAt = nextRetryAt.Time
+	}
+	return &h, nil
+}
+
+// ClearEngineHealth forgets all engine health, e.g. when the supervisor exits.
+func (d *DB) ClearEngineHealth() error {
+	if _, err := d.db.Exec(`DELETE FROM sync_health`); err != nil {
+		return fmt.Errorf("clear engine health: %w", err)
+	}
+	return nil
+}
+
+// SetTransferStats records a running engine's transfer queue and
+// throughput.
+func (d *DB) SetTransferStats(configID int64, t TransferStats) error {
+	_, err := d.db.Exec(
+		`INSERT INTO sync_transfers (config_id, workers, queued, active, upload_rate, download_rate,
+		                             uploaded_bytes, downloaded_bytes, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(config_id) DO UPDATE SET
+		   workers = excluded.workers,
+		   queued = excluded.queued,
+		   active = excluded.active,
+		   upload_rate = excluded.upload_rate,
+		   download_rate = excluded.download_rate,
+		   uploaded_bytes = excluded.uploaded_bytes,
+		   downloaded_bytes = excluded.downloaded_bytes,
+		   updated_at = excluded.updated_at`,
+		configID, t.Workers, t.Queued, t.Active, t.UploadRate, t.DownloadRate,
+		t.UploadedBytes, t.DownloadedBytes, t.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("update transfer stats: %w", err)
+	}
+	return nil
+}
+
+// GetTransferStats returns the last recorded transfer stats of a config's
+// engine, or nil if no engine is running it.
+func (d *DB) GetTransferStats(configID int64) (*TransferStats, error) {
+	var t TransferStats
+	err := d.db.QueryRow(
+		`SELECT workers, queued, active, upload_rate, download_rate, uploaded_bytes, downloaded_bytes, updated_at
+		 FROM sync_transfers WHERE config_id = ?`,
+		configID,
+	).Scan(&t.Workers, &t.Queued, &t.Active, &t.UploadRate, &t.DownloadRate,
+		&t.UploadedBytes, &t.DownloadedBytes, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query transfer stats: %w", err)
+	}
+	return &t, nil
+}
+
+// ClearTransferStats forgets the transfer stats of a config, e.g. when its
+// engine stops.
+func (d *DB) ClearTransferStats(configID int64) error {
+	if _, err := d.db.Exec(`DELETE FROM sync_transfers WHERE config_id = ?`, configID); err != nil {
+		return fmt.Errorf("clear transfer stats: %w", err)
+	}
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
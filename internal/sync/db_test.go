@@ -2,6 +2,8 @@ package sync
 
 import (
 	"database/sql"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -160,3 +162,411 @@ func TestListPendingUploads_FiltersByConfigID(t *testing.T) {
 		t.Errorf("expected config1-file.txt, got %s", items[0].LocalPath)
 	}
 }
+
+func TestSetIgnorePatterns(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	cfg, err := d.GetConfigByID(configID)
+	if err != nil {
+		t.Fatalf("GetConfigByID() error = %v", err)
+	}
+	if len(cfg.IgnorePatterns) != 0 {
+		t.Fatalf("IgnorePatterns = %q, want none", cfg.IgnorePatterns)
+	}
+
+	if err := d.SetIgnorePatterns(configID, []string{"*.log", "!keep.log"}); err != nil {
+		t.Fatalf("SetIgnorePatterns() error = %v", err)
+	}
+
+	configs, err := d.ListConfigs()
+	if err != nil {
+		t.Fatalf("ListConfigs() error = %v", err)
+	}
+	if len(configs) != 1 || len(configs[0].IgnorePatterns) != 2 || configs[0].IgnorePatterns[1] != "!keep.log" {
+		t.Fatalf("unexpected configs: %+v", configs)
+	}
+
+	if err := d.SetIgnorePatterns(configID+100, nil); err == nil {
+		t.Fatal("expected error for unknown config")
+	}
+}
+
+func TestMigrate_AddsIgnorePatternsColumn(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("open in-memory db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	d := &DB{db: sqlDB}
+	t.Cleanup(func() { d.Close() })
+
+	// A database created before ignore_patterns existed.
+	if _, err := sqlDB.Exec(`CREATE TABLE sync_configs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		local_path TEXT NOT NULL UNIQUE,
+		drive_folder_id TEXT NOT NULL,
+		drive_id TEXT DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_sync_at DATETIME,
+		change_token TEXT DEFAULT ''
+	)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	configID := insertTestConfig(t, d)
+
+	for i := 0; i < 2; i++ {
+		if err := d.migrate(); err != nil {
+			t.Fatalf("migrate() run %d error = %v", i+1, err)
+		}
+	}
+
+	cfg, err := d.GetConfigByID(configID)
+	if err != nil {
+		t.Fatalf("GetConfigByID() error = %v", err)
+	}
+	if cfg == nil || len(cfg.IgnorePatterns) != 0 {
+		t.Fatalf("unexpected config after migration: %+v", cfg)
+	}
+}
+
+func TestRenameAndRemoveSyncItemsUnder(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	for _, p := range []string{"src", "src/a.go", "src/pkg/b.go", "srcs/c.go", "other.txt"} {
+		insertTestSyncItem(t, d, configID, p, StateSynced)
+	}
+
+	if err := d.RenameSyncItems(configID, "src", "lib/src"); err != nil {
+		t.Fatalf("RenameSyncItems() error = %v", err)
+	}
+	for _, p := range []string{"lib/src", "lib/src/a.go", "lib/src/pkg/b.go", "srcs/c.go"} {
+		if item, err := d.GetSyncItem(configID, p); err != nil || item == nil {
+			t.Errorf("GetSyncItem(%q) = %v, %v after rename", p, item, err)
+		}
+	}
+	if item, _ := d.GetSyncItem(configID, "src/a.go"); item != nil {
+		t.Error("old path still present after rename")
+	}
+
+	if err := d.RemoveSyncItemsUnder(configID, "lib"); err != nil {
+		t.Fatalf("RemoveSyncItemsUnder() error = %v", err)
+	}
+	for p, want := range map[string]bool{"lib/src/a.go": false, "srcs/c.go": true, "other.txt": true} {
+		item, err := d.GetSyncItem(configID, p)
+		if err != nil {
+			t.Fatalf("GetSyncItem(%q) error = %v", p, err)
+		}
+		if (item != nil) != want {
+			t.Errorf("GetSyncItem(%q) present = %v, want %v", p, item != nil, want)
+		}
+	}
+}
+
+func TestSetSyncItemStateAndListConflicts(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	insertTestSyncItem(t, d, configID, "b.txt", StateSynced)
+	insertTestSyncItem(t, d, configID, "a.txt", StateSynced)
+
+	item, err := d.GetSyncItem(configID, "b.txt")
+	if err != nil {
+		t.Fatalf("GetSyncItem: %v", err)
+	}
+	if err := d.SetSyncItemState(item.ID, StateConflict, "b.conflict-20260101-000000.txt"); err != nil {
+		t.Fatalf("SetSyncItemState: %v", err)
+	}
+
+	conflicts, err := d.ListConflicts(configID)
+	if err != nil {
+		t.Fatalf("ListConflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].LocalPath != "b.txt" || conflicts[0].ConflictPath != "b.conflict-20260101-000000.txt" {
+		t.Fatalf("ListConflicts() = %+v", conflicts)
+	}
+
+	// Leaving the conflict state clears the recorded copy.
+	if err := d.SetSyncItemState(item.ID, StateSynced, "ignored"); err != nil {
+		t.Fatalf("SetSyncItemState: %v", err)
+	}
+	item, _ = d.GetSyncItem(configID, "b.txt")
+	if item.SyncState != StateSynced || item.ConflictPath != "" {
+		t.Fatalf("item = %+v, want synced without conflict path", item)
+	}
+	if conflicts, _ := d.ListConflicts(configID); len(conflicts) != 0 {
+		t.Fatalf("ListConflicts() after resolve = %+v", conflicts)
+	}
+}
+
+func TestSetNativeDocs(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	if err := d.SetNativeDocs(configID, NativeOffice); err != nil {
+		t.Fatalf("SetNativeDocs() error = %v", err)
+	}
+
+	cfg, err := d.GetConfigByID(configID)
+	if err != nil {
+		t.Fatalf("GetConfigByID() error = %v", err)
+	}
+	if cfg.NativeDocs != NativeOffice {
+		t.Fatalf("NativeDocs = %q, want %q", cfg.NativeDocs, NativeOffice)
+	}
+
+	if err := d.SetNativeDocs(configID+100, NativeLink); err == nil {
+		t.Fatal("expected error for unknown config")
+	}
+}
+
+func TestCreateEncryptedConfig(t *testing.T) {
+	d := openTestDB(t)
+
+	created, err := d.CreateEncryptedConfig(t.TempDir(), "folder-1", "", true)
+	if err != nil {
+		t.Fatalf("CreateEncryptedConfig() error = %v", err)
+	}
+	if !created.Encrypted || !created.EncryptNames {
+		t.Fatalf("created config = %+v, want encrypted names", created)
+	}
+
+	cfg, err := d.GetConfigByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID() error = %v", err)
+	}
+	if !cfg.Encrypted || !cfg.EncryptNames {
+		t.Fatalf("stored config = %+v, want encrypted names", cfg)
+	}
+
+	plain, err := d.CreateConfig(t.TempDir(), "folder-2", "")
+	if err != nil {
+		t.Fatalf("CreateConfig() error = %v", err)
+	}
+	if cfg, _ = d.GetConfigByID(plain.ID); cfg.Encrypted || cfg.EncryptNames {
+		t.Fatalf("plain config = %+v, want unencrypted", cfg)
+	}
+}
+
+func TestEngineHealthAndConfigAccount(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	if err := d.SetConfigAccount(configID, "a@b.com"); err != nil {
+		t.Fatalf("SetConfigAccount() error = %v", err)
+	}
+
+	started := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	if err := d.SetEngineHealth(EngineHealth{
+		ConfigID: configID, State: EngineBackoff, Account: "a@b.com", Restarts: 3,
+		LastError: "boom", StartedAt: started, UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("SetEngineHealth() error = %v", err)
+	}
+
+	status, err := d.GetStatus(configID)
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if status.Config.Account != "a@b.com" {
+		t.Fatalf("Account = %q", status.Config.Account)
+	}
+	h := status.Engine
+	if h == nil || h.State != EngineBackoff || h.Restarts != 3 || h.LastError != "boom" || !h.StartedAt.Equal(started) || !h.NextRetryAt.IsZero() {
+		t.Fatalf("Engine = %+v", h)
+	}
+
+	if err := d.ClearEngineHealth(); err != nil {
+		t.Fatalf("ClearEngineHealth() error = %v", err)
+	}
+	if h, _ := d.GetEngineHealth(configID); h != nil {
+		t.Fatalf("health after clear = %+v", h)
+	}
+}
+
+func TestDeleteGuardSettingsAndPause(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+
+	cfg, err := d.GetConfigByID(cfgID)
+	if err != nil {
+		t.Fatalf("GetConfigByID: %v", err)
+	}
+	if cfg.MaxDeletes != DefaultMaxDeletes || cfg.MaxDeletePercent != DefaultMaxDeletePercent || cfg.TrashDays != DefaultTrashDays {
+		t.Fatalf("defaults = %d, %d, %d", cfg.MaxDeletes, cfg.MaxDeletePercent, cfg.TrashDays)
+	}
+
+	if err := d.SetDeleteGuard(cfgID, 5, 10, 0); err != nil {
+		t.Fatalf("SetDeleteGuard: %v", err)
+	}
+	if err := d.PauseConfig(cfgID, "too many deletions"); err != nil {
+		t.Fatalf("PauseConfig: %v", err)
+	}
+
+	cfg, _ = d.GetConfigByID(cfgID)
+	if cfg.MaxDeletes != 5 || cfg.MaxDeletePercent != 10 || cfg.TrashDays != 0 {
+		t.Fatalf("limits = %d, %d, %d", cfg.MaxDeletes, cfg.MaxDeletePercent, cfg.TrashDays)
+	}
+	if cfg.PausedReason != "too many deletions" || cfg.PausedAt.IsZero() {
+		t.Fatalf("pause = %q at %v", cfg.PausedReason, cfg.PausedAt)
+	}
+
+	if err := d.ResumeConfig(cfgID); err != nil {
+		t.Fatalf("ResumeConfig: %v", err)
+	}
+	if cfg, _ = d.GetConfigByID(cfgID); cfg.PausedReason != "" || !cfg.PausedAt.IsZero() {
+		t.Fatalf("still paused: %+v", cfg)
+	}
+
+	if err := d.SetDeleteGuard(999, 1, 1, 1); err == nil {
+		t.Fatal("SetDeleteGuard on a missing config succeeded")
+	}
+}
+
+func TestCountSyncItems(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+
+	for _, p := range []string{"a.txt", filepath.Join("dir", "b.txt"), filepath.Join("dir", "sub", "c.txt"), "dir2.txt"} {
+		insertTestSyncItem(t, d, cfgID, p, StateSynced)
+	}
+
+	for under, want := range map[string]int{"": 4, "dir": 2, "a.txt": 1, "missing": 0} {
+		if got, err := d.CountSyncItems(cfgID, under); err != nil || got != want {
+			t.Errorf("CountSyncItems(%q) = %d, %v; want %d", under, got, err, want)
+		}
+	}
+}
+
+func TestSyncItemFileInfoAndFindByMD5(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+
+	insertTestSyncItem(t, d, cfgID, "a.txt", StateSynced)
+	insertTestSyncItem(t, d, cfgID, "b.txt", StateSynced)
+	if _, err := d.db.Exec(`UPDATE sync_items SET local_md5 = 'other' WHERE local_path = 'b.txt'`); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := d.GetSyncItem(cfgID, "a.txt")
+	if err != nil || item == nil {
+		t.Fatalf("GetSyncItem: %v, %v", item, err)
+	}
+	if err := d.SetSyncItemFileInfo(item.ID, 42, 1<<63+7); err != nil {
+		t.Fatalf("SetSyncItemFileInfo: %v", err)
+	}
+
+	items, err := d.FindSyncItemsByMD5(cfgID, "abc123")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("FindSyncItemsByMD5 = %+v, %v; want a.txt only", items, err)
+	}
+	if items[0].LocalPath != "a.txt" || items[0].Size != 42 || items[0].Inode != 1<<63+7 {
+		t.Fatalf("item = %+v, want size 42 and the large inode back", items[0])
+	}
+}
+
+func TestSyncBaseRoundTrip(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+	insertTestSyncItem(t, d, cfgID, "notes.md", StateSynced)
+	item, _ := d.GetSyncItem(cfgID, "notes.md")
+
+	if got, err := d.GetSyncBase(item.ID); err != nil || got != nil {
+		t.Fatalf("GetSyncBase before set = %q, %v; want nil", got, err)
+	}
+
+	content := []byte(strings.Repeat("# heading\nsome notes\n", 100))
+	if err := d.SetSyncBase(item.ID, content); err != nil {
+		t.Fatalf("SetSyncBase: %v", err)
+	}
+	if got, err := d.GetSyncBase(item.ID); err != nil || string(got) != string(content) {
+		t.Fatalf("GetSyncBase = %d bytes, %v; want the stored content", len(got), err)
+	}
+
+	// Moving the item keeps its base.
+	if err := d.RenameSyncItems(cfgID, "notes.md", "renamed.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.GetSyncBase(item.ID); string(got) != string(content) {
+		t.Fatal("base lost after rename")
+	}
+
+	if err := d.SetSyncBase(item.ID, nil); err != nil {
+		t.Fatalf("SetSyncBase(nil): %v", err)
+	}
+	if got, _ := d.GetSyncBase(item.ID); got != nil {
+		t.Fatalf("GetSyncBase after clear = %q, want nil", got)
+	}
+}
+
+func TestTransferRetryAndStats(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+	insertTestSyncItem(t, d, cfgID, "a.txt", StatePendingUpload)
+	insertTestSyncItem(t, d, cfgID, "b.txt", StatePendingDownload)
+	insertTestSyncItem(t, d, cfgID, "c.txt", StateSynced)
+
+	cfg, _ := d.GetConfigByID(cfgID)
+	if cfg.TransferWorkers != DefaultTransferWorkers || cfg.UploadLimit != 0 || cfg.DownloadLimit != 0 {
+		t.Fatalf("transfer defaults = %d, %d, %d", cfg.TransferWorkers, cfg.UploadLimit, cfg.DownloadLimit)
+	}
+	if err := d.SetTransferLimits(cfgID, 2, 1<<20, 0); err != nil {
+		t.Fatalf("SetTransferLimits: %v", err)
+	}
+	if cfg, _ = d.GetConfigByID(cfgID); cfg.TransferWorkers != 2 || cfg.UploadLimit != 1<<20 {
+		t.Fatalf("transfer limits = %d, %d", cfg.TransferWorkers, cfg.UploadLimit)
+	}
+
+	now := time.Now()
+	item, _ := d.GetSyncItem(cfgID, "b.txt")
+	if err := d.SetSyncItemRetry(item.ID, StatePendingDownload, 2, now.Add(time.Minute), "boom"); err != nil {
+		t.Fatalf("SetSyncItemRetry: %v", err)
+	}
+	if item, _ = d.GetSyncItem(cfgID, "b.txt"); item.Attempts != 2 || item.LastError != "boom" || item.NextRetryAt.IsZero() {
+		t.Fatalf("retry not recorded: %+v", item)
+	}
+
+	paths := func(at time.Time) []string {
+		items, err := d.ListDueTransfers(cfgID, at)
+		if err != nil {
+			t.Fatalf("ListDueTransfers: %v", err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, item.LocalPath)
+		}
+		return got
+	}
+	if got := paths(now); len(got) != 1 || got[0] != "a.txt" {
+		t.Fatalf("due now = %v, want [a.txt]", got)
+	}
+	if got := paths(now.Add(2 * time.Minute)); len(got) != 2 {
+		t.Fatalf("due later = %v, want both pending items", got)
+	}
+
+	// A completed transfer clears the retry.
+	if err := d.UpdateSyncItem(item.ID, "drive-b", "md5", "md5", StateSynced); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ = d.GetSyncItem(cfgID, "b.txt"); item.Attempts != 0 || item.LastError != "" || !item.NextRetryAt.IsZero() {
+		t.Fatalf("retry not cleared: %+v", item)
+	}
+
+	if err := d.SetTransferStats(cfgID, TransferStats{Workers: 2, Queued: 5, Active: 2, UploadRate: 1024, UpdatedAt: now}); err != nil {
+		t.Fatalf("SetTransferStats: %v", err)
+	}
+	status, err := d.GetStatus(cfgID)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if s := status.Transfers; s == nil || s.Queued != 5 || s.Active != 2 || s.UploadRate != 1024 {
+		t.Fatalf("Transfers = %+v", s)
+	}
+	if err := d.ClearTransferStats(cfgID); err != nil {
+		t.Fatalf("ClearTransferStats: %v", err)
+	}
+	if s, _ := d.GetTransferStats(cfgID); s != nil {
+		t.Fatalf("stats after clear = %+v", s)
+	}
+}
@@ -2,8 +2,11 @@ package sync
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,14 +16,18 @@ import (
 
 // Downloader handles downloading files from Google Drive.
 type Downloader struct {
-	service   *drive.Service
-	localRoot string
+	service    *drive.Service
+	localRoot  string
+	nativeDocs NativeDocsMode
+	throttle   *Throttle
+	cipher     *Cipher // nil unless the config is encrypted
 }
 
 // DownloadResult contains the result of a download operation.
 type DownloadResult struct {
 	LocalPath string
 	MD5       string
+	RemoteMD5 string // remoteFingerprint of the Drive file
 }
 
 // NewDownloader creates a new downloader.
@@ -31,32 +38,53 @@ func NewDownloader(service *drive.Service, localRoot string) *Downloader {
 	}
 }
 
-// DownloadFile downloads a file from Drive to the local filesystem.
-func (d *Downloader) DownloadFile(ctx context.Context, fileID, fileName string) (*DownloadResult, error) {
+// SetNativeDocs sets how Google-native files are written locally.
+func (d *Downloader) SetNativeDocs(mode NativeDocsMode) {
+	d.nativeDocs = mode
+}
+
+// SetThrottle limits and counts the bytes downloaded.
+func (d *Downloader) SetThrottle(t *Throttle) {
+	d.throttle = t
+}
+
+// SetCipher decrypts downloaded contents, and names if the cipher encrypts
+// them, with c.
+func (d *Downloader) SetCipher(c *Cipher) {
+	d.cipher = c
+}
+
+// DownloadFile downloads a file from Drive to relPath under the local root.
+// Google-native files are exported or written as link stubs according to
+// the native docs mode.
+func (d *Downloader) DownloadFile(ctx context.Context, fileID, relPath string) (*DownloadResult, error) {
 	// Get file metadata to determine the path
 	file, err := d.service.Files.Get(fileID).
 		Context(ctx).
-		Fields("id,name,mimeType,md5Checksum,parents").
+		Fields("id,name,mimeType,md5Checksum,modifiedTime,webViewLink,parents").
 		SupportsAllDrives(true).
 		Do()
 	if err != nil {
 		return nil, fmt.Errorf("get file metadata: %w", err)
 	}
 
-	// Skip Google Docs, Sheets, etc. (they don't have binary content)
+	// Google Docs, Sheets, etc. have no binary content of their own
 	if isGoogleDocsType(file.MimeType) {
-		return nil, fmt.Errorf("cannot download Google Docs type: %s", file.MimeType)
+		return d.downloadNative(ctx, file, relPath)
 	}
 
-	// Build local path (for now, just use the filename in root)
-	// TODO: Reconstruct full path from Drive folder hierarchy
-	localPath := file.Name
-
-	absPath := filepath.Join(d.localRoot, localPath)
+	localPath := relPath
+	if localPath == "" {
+		name, ok := d.cipher.LocalName(file.Name)
+		if !ok {
+			return nil, fmt.Errorf("cannot decrypt file name %q", file.Name)
+		}
+		localPath = name
+	}
 
-	// Verify the resolved path stays within localRoot (prevent path traversal).
-	if !strings.HasPrefix(filepath.Clean(absPath)+string(os.PathSeparator), filepath.Clean(d.localRoot)+string(os.PathSeparator)) {
-		return nil, fmt.Errorf("path traversal detected: %q", localPath)
+	absPath, err := localJoin(d.localRoot, localPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure parent directory exists
@@ -81,8 +109,12 @@ func (d *Downloader) DownloadFile(ctx context.Context, fileID, fileName string)
 	}
 	defer f.Close()
 
+	if d.cipher != nil {
+		return d.decryptTo(ctx, f, resp.Body, file, localPath, absPath)
+	}
+
 	// Copy content
-	if _, err := io.Copy(f, resp.Body); err != nil {
+	if _, err := io.Copy(f, d.throttle.Reader(ctx, resp.Body)); err != nil {
 		return nil, fmt.Errorf("write file content: %w", err)
 	}
 
@@ -100,16 +132,163 @@ func (d *Downloader) DownloadFile(ctx context.Context, fileID, fileName string)
 	return &DownloadResult{
 		LocalPath: localPath,
 		MD5:       md5Hash,
+		RemoteMD5: md5Hash,
+	}, nil
+}
+
+// decryptTo writes the plaintext of the encrypted Drive file body to f.
+// The MD5 Drive reports is checked against the ciphertext, and the local
+// MD5 is that of the plaintext.
+func (d *Downloader) decryptTo(ctx context.Context, f *os.File, body io.Reader, file *drive.File, localPath, absPath string) (*DownloadResult, error) {
+	remoteHash := md5.New()
+	encrypted := io.TeeReader(d.throttle.Reader(ctx, body), remoteHash)
+
+	plain, err := d.cipher.Decrypt(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, plain); err != nil {
+		return nil, fmt.Errorf("write file content: %w", err)
+	}
+	// Hash anything after the end of the age stream too.
+	if _, err := io.Copy(io.Discard, encrypted); err != nil {
+		return nil, fmt.Errorf("read file content: %w", err)
+	}
+
+	remoteMD5 := hex.EncodeToString(remoteHash.Sum(nil))
+	if file.Md5Checksum != "" && remoteMD5 != file.Md5Checksum {
+		return nil, fmt.Errorf("md5 mismatch: downloaded=%s, remote=%s", remoteMD5, file.Md5Checksum)
+	}
+
+	md5Hash, err := computeMD5(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("compute md5: %w", err)
+	}
+
+	return &DownloadResult{
+		LocalPath: localPath,
+		MD5:       md5Hash,
+		RemoteMD5: remoteMD5,
+	}, nil
+}
+
+// downloadNative exports a Google-native file, or writes its link stub.
+func (d *Downloader) downloadNative(ctx context.Context, file *drive.File, relPath string) (*DownloadResult, error) {
+	format, ok := d.nativeDocs.format(file.MimeType)
+	if !ok {
+		return nil, fmt.Errorf("cannot download Google Docs type: %s", file.MimeType)
+	}
+
+	localPath := relPath
+	if localPath == "" {
+		localPath, _ = d.nativeDocs.localName(file.Name, file.MimeType)
+	}
+
+	absPath, err := localJoin(d.localRoot, localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create parent directory: %w", err)
+	}
+
+	if format.mimeType == "" {
+		content, err := linkStubContent(file)
+		if err != nil {
+			return nil, fmt.Errorf("render link stub: %w", err)
+		}
+		if err := os.WriteFile(absPath, content, 0o644); err != nil {
+			return nil, fmt.Errorf("write link stub: %w", err)
+		}
+	} else {
+		resp, err := d.service.Files.Export(file.Id, format.mimeType).
+			Context(ctx).
+			Download()
+		if err != nil {
+			return nil, fmt.Errorf("export file: %w", err)
+		}
+		defer resp.Body.Close()
+
+		f, err := os.Create(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("create local file: %w", err)
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(f, d.throttle.Reader(ctx, resp.Body)); err != nil {
+			return nil, fmt.Errorf("write file content: %w", err)
+		}
+	}
+
+	md5Hash, err := computeMD5(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("compute md5: %w", err)
+	}
+
+	return &DownloadResult{
+		LocalPath: localPath,
+		MD5:       md5Hash,
+		RemoteMD5: remoteFingerprint(file),
 	}, nil
 }
 
-// DownloadFolder creates a local folder.
-func (d *Downloader) DownloadFolder(ctx context.Context, folderID, folderName string) error {
-	absPath := filepath.Join(d.localRoot, folderName)
+// Content returns what DownloadFile would write for a Drive file, without
+// touching the local tree. It fails for link stubs and for content larger
+// than limit bytes.
+func (d *Downloader) Content(ctx context.Context, fileID string, limit int64) ([]byte, error) {
+	file, err := d.service.Files.Get(fileID).
+		Context(ctx).
+		Fields("id,mimeType").
+		SupportsAllDrives(true).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("get file metadata: %w", err)
+	}
+
+	var resp *http.Response
+	if isGoogleDocsType(file.MimeType) {
+		format, ok := d.nativeDocs.format(file.MimeType)
+		if !ok || format.mimeType == "" {
+			return nil, fmt.Errorf("cannot read Google Docs type: %s", file.MimeType)
+		}
+		resp, err = d.service.Files.Export(fileID, format.mimeType).
+			Context(ctx).
+			Download()
+	} else {
+		resp, err = d.service.Files.Get(fileID).
+			Context(ctx).
+			SupportsAllDrives(true).
+			Download()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
 
-	// Verify the resolved path stays within localRoot (prevent path traversal).
-	if !strings.HasPrefix(filepath.Clean(absPath)+string(os.PathSeparator), filepath.Clean(d.localRoot)+string(os.PathSeparator)) {
-		return fmt.Errorf("path traversal detected: %q", folderName)
+	body := d.throttle.Reader(ctx, resp.Body)
+	if d.cipher != nil && !isGoogleDocsType(file.MimeType) {
+		if body, err = d.cipher.Decrypt(body); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file content: %w", err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("file is larger than %d bytes", limit)
+	}
+
+	return content, nil
+}
+
+// DownloadFolder creates a local folder at relPath under the local root.
+func (d *Downloader) DownloadFolder(ctx context.Context, folderID, relPath string) error {
+	absPath, err := localJoin(d.localRoot, relPath)
+	if err != nil {
+		return err
 	}
 
 	if err := os.MkdirAll(absPath, 0o755); err != nil {
@@ -119,6 +298,18 @@ func (d *Downloader) DownloadFolder(ctx context.Context, folderID, folderName st
 	return nil
 }
 
+// localJoin resolves relPath under root, rejecting paths that escape it.
+func localJoin(root, relPath string) (string, error) {
+	absPath := filepath.Join(root, relPath)
+
+	// Verify the resolved path stays within root (prevent path traversal).
+	if !strings.HasPrefix(filepath.Clean(absPath)+string(os.PathSeparator), filepath.Clean(root)+string(os.PathSeparator)) ||
+		filepath.Clean(absPath) == filepath.Clean(root) {
+		return "", fmt.Errorf("path traversal detected: %q", relPath)
+	}
+	return absPath, nil
+}
+
 // isGoogleDocsType returns true if the MIME type is a Google Docs type.
 func isGoogleDocsType(mimeType string) bool {
 	googleDocTypes := []string{
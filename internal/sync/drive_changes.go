@@ -3,6 +3,8 @@ package sync
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"google.golang.org/api/drive/v3"
@@ -11,14 +13,22 @@ import (
 // DefaultPollIntervalDuration is the default poll interval for Drive changes.
 const DefaultPollIntervalDuration = 5 * time.Second
 
+// driveFolderMimeType is the MIME type Drive uses for folders.
+const driveFolderMimeType = "application/vnd.google-apps.folder"
+
 // DriveChange represents a change detected on Google Drive.
 type DriveChange struct {
-	FileID    string
-	FileName  string
-	MimeType  string
-	Op        DriveChangeOp
-	Removed   bool // File was deleted/trashed
-	Timestamp time.Time
+	FileID     string
+	FileName   string
+	MimeType   string
+	Op         DriveChangeOp
+	Removed    bool // File was deleted/trashed
+	Timestamp  time.Time
+	RelPath    string // Path relative to the sync root, when resolvable
+	OldRelPath string // Previous path of a moved file or folder (DriveOpMove)
+	IsFolder   bool
+	MD5        string // remoteFingerprint of the file, empty for folders
+	Size       int64  // Content size in bytes, zero for folders and Google-native files
 }
 
 // DriveChangeOp represents the type of Drive change.
@@ -31,6 +41,9 @@ const (
 	DriveOpModify
 	// DriveOpDelete indicates a file was deleted.
 	DriveOpDelete
+	// DriveOpMove indicates a file or folder was renamed or moved within the
+	// sync root.
+	DriveOpMove
 )
 
 // String returns the string representation of the change operation.
@@ -42,6 +55,8 @@ func (op DriveChangeOp) String() string {
 		return "modify"
 	case DriveOpDelete:
 		return "delete"
+	case DriveOpMove:
+		return "move"
 	default:
 		return "unknown"
 	}
@@ -54,6 +69,10 @@ type DrivePoller struct {
 	pollInterval time.Duration // Default 5s
 	events       chan DriveChange
 	errors       chan error
+	rules        *IgnoreRules // Optional; filters changes by path
+	tree         *FolderTree  // Folder ID -> relative path for the whole sync tree
+	nativeDocs   NativeDocsMode
+	cipher       *Cipher // decrypts names, for configs with encrypted names
 
 	// Page token management
 	db       *DB
@@ -78,11 +97,38 @@ func NewDrivePoller(service *drive.Service, db *DB, configID int64, folderID str
 		configID:     configID,
 		folderID:     folderID,
 		pollInterval: pollInterval,
+		tree:         NewFolderTree(folderID),
 		events:       make(chan DriveChange, 100),
 		errors:       make(chan error, 10),
 	}
 }
 
+// SetIgnoreRules makes the poller drop changes to ignored paths, so remote
+// files are filtered by the same rules as local ones.
+func (p *DrivePoller) SetIgnoreRules(rules *IgnoreRules) {
+	p.rules = rules
+}
+
+// SetFolderTree shares a folder tree with the poller. The tree should be
+// rooted at the poller's folder and is updated as folder changes arrive.
+func (p *DrivePoller) SetFolderTree(tree *FolderTree) {
+	if tree != nil {
+		p.tree = tree
+	}
+}
+
+// SetNativeDocs sets which Google-native files the poller reports and the
+// local names they get. By default they are dropped.
+func (p *DrivePoller) SetNativeDocs(mode NativeDocsMode) {
+	p.nativeDocs = mode
+}
+
+// SetCipher decrypts Drive names with c. Items whose names it cannot
+// decrypt are dropped.
+func (p *DrivePoller) SetCipher(c *Cipher) {
+	p.cipher = c
+}
+
 // Events returns the channel of drive changes.
 func (p *DrivePoller) Events() <-chan DriveChange {
 	return p.events
@@ -203,21 +249,22 @@ func (p *DrivePoller) pollChanges(ctx context.Context, pageToken string) ([]Driv
 			Context(ctx).
 			PageSize(1000).
 			IncludeRemoved(true).
-			Fields("nextPageToken,newStartPageToken,changes(fileId,file(id,name,mimeType,parents,trashed),removed,time)")
+			Fields("nextPageToken,newStartPageToken,changes(fileId,file(id,name,mimeType,md5Checksum,modifiedTime,parents,trashed,size),removed,time)")
 
 		resp, err := req.Do()
 		if err != nil {
 			return nil, pageToken, err
 		}
 
-		// Process changes
-		for _, change := range resp.Changes {
-			// Filter to only files in our folder
-			if change.File != nil && !p.isInFolderByParents(change.File.Parents) {
-				continue
-			}
+		// Resolve folder changes first so files in new or moved folders in
+		// the same page land at their current path.
+		changes := append([]*drive.Change(nil), resp.Changes...)
+		slices.SortStableFunc(changes, func(a, b *drive.Change) int {
+			return boolRank(isFolderChange(b)) - boolRank(isFolderChange(a))
+		})
 
-			driveChange := p.convertChange(change)
+		for _, change := range changes {
+			driveChange := p.resolveChange(change)
 			if driveChange != nil {
 				allChanges = append(allChanges, *driveChange)
 			}
@@ -265,6 +312,7 @@ func (p *DrivePoller) convertChange(change *drive.Change) *DriveChange {
 	if change.File != nil {
 		driveChange.FileName = change.File.Name
 		driveChange.MimeType = change.File.MimeType
+		driveChange.Size = change.File.Size
 
 		if change.File.Trashed {
 			driveChange.Op = DriveOpDelete
@@ -279,15 +327,134 @@ func (p *DrivePoller) convertChange(change *drive.Change) *DriveChange {
 	return driveChange
 }
 
-// isInFolderByParents checks if a file is within our synced folder by checking parents.
-func (p *DrivePoller) isInFolderByParents(parents []string) bool {
-	if parents == nil {
-		return false
+// resolveChange places a Drive change in the sync tree and keeps the folder
+// tree current. It returns nil for changes outside the tree, to the root
+// folder itself, or to ignored paths.
+func (p *DrivePoller) resolveChange(change *drive.Change) *DriveChange {
+	if change == nil || change.FileId == p.folderID {
+		return nil
+	}
+
+	file := change.File
+	if change.Removed || file == nil {
+		// Permanently deleted or no longer visible. Known folders resolve
+		// here; files are looked up by Drive ID in the sync database.
+		driveChange := p.convertChange(change)
+		driveChange.Op = DriveOpDelete
+		driveChange.Removed = true
+		if relPath, ok := p.tree.Remove(change.FileId); ok {
+			driveChange.RelPath = relPath
+			driveChange.IsFolder = true
+		}
+		return driveChange
+	}
+
+	isFolder := file.MimeType == driveFolderMimeType
+
+	parentDir, inTree := p.tree.Dir(file.Parents)
+	if !inTree {
+		// Moved out of the sync tree: drop what we had locally.
+		if isFolder {
+			if relPath, ok := p.tree.Remove(file.Id); ok {
+				return p.deleteChange(change, relPath, true)
+			}
+			return nil
+		}
+		if p.isTracked(change.FileId) {
+			return p.deleteChange(change, "", false)
+		}
+		return nil
+	}
+
+	name, ok := p.cipher.LocalName(file.Name)
+	if !ok {
+		return nil
+	}
+	if !isFolder && isGoogleDocsType(file.MimeType) {
+		if name, ok = p.nativeDocs.localName(file.Name, file.MimeType); !ok {
+			return nil
+		}
+	}
+
+	driveChange := p.convertChange(change)
+	driveChange.RelPath = filepath.Join(parentDir, name)
+	driveChange.IsFolder = isFolder
+	driveChange.MD5 = remoteFingerprint(file)
+
+	if isFolder {
+		if driveChange.Removed {
+			p.tree.Remove(file.Id)
+		} else {
+			oldPath, known := p.tree.Set(file.Id, driveChange.RelPath)
+			switch {
+			case !known:
+				driveChange.Op = DriveOpCreate
+			case oldPath != driveChange.RelPath:
+				driveChange.Op = DriveOpMove
+				driveChange.OldRelPath = oldPath
+			}
+		}
 	}
-	for _, parent := range parents {
-		if parent == p.folderID {
-			return true
+
+	// A tracked file at a new path was renamed or moved on Drive.
+	if !isFolder {
+		if oldPath, ok := p.trackedPath(change.FileId); ok && oldPath != driveChange.RelPath {
+			driveChange.Op = DriveOpMove
+			driveChange.OldRelPath = oldPath
 		}
 	}
-	return false
+
+	// Ignored folders stay in the tree so their contents resolve (and are
+	// ignored) too.
+	if p.rules.Match(driveChange.RelPath, isFolder) {
+		return nil
+	}
+
+	return driveChange
+}
+
+// deleteChange builds a delete for an item that left the sync tree.
+func (p *DrivePoller) deleteChange(change *drive.Change, relPath string, isFolder bool) *DriveChange {
+	driveChange := p.convertChange(change)
+	driveChange.Op = DriveOpDelete
+	driveChange.Removed = true
+	driveChange.RelPath = relPath
+	driveChange.IsFolder = isFolder
+	return driveChange
+}
+
+// isTracked reports whether a Drive file is a known sync item.
+func (p *DrivePoller) isTracked(fileID string) bool {
+	_, ok := p.trackedPath(fileID)
+	return ok
+}
+
+// trackedPath returns the local path of a Drive file known as a sync item.
+func (p *DrivePoller) trackedPath(fileID string) (string, bool) {
+	if p.db == nil {
+		return "", false
+	}
+	item, err := p.db.GetSyncItemByDriveID(p.configID, fileID)
+	if err != nil || item == nil {
+		return "", false
+	}
+	return item.LocalPath, true
+}
+
+// isInFolderByParents checks if a file is within the synced tree by
+// checking its parents against every known folder.
+func (p *DrivePoller) isInFolderByParents(parents []string) bool {
+	_, ok := p.tree.Dir(parents)
+	return ok
+}
+
+func isFolderChange(change *drive.Change) bool {
+	return change != nil && change.File != nil && change.File.MimeType == driveFolderMimeType
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
 }
@@ -3,8 +3,11 @@ package sync
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"google.golang.org/api/drive/v3"
 )
 
 func TestDriveChangeOpString(t *testing.T) {
@@ -199,3 +202,123 @@ func TestIsInFolderWithParents(t *testing.T) {
 		})
 	}
 }
+
+func TestDrivePollerResolveChange_NestedPaths(t *testing.T) {
+	poller := NewDrivePoller(nil, nil, 1, "root", 5*time.Second)
+	rules, err := NewIgnoreRules("", []string{"*.log", "cache/"})
+	if err != nil {
+		t.Fatalf("NewIgnoreRules() error = %v", err)
+	}
+	poller.SetIgnoreRules(rules)
+
+	folder := func(id, name, parent string) *drive.Change {
+		return &drive.Change{FileId: id, File: &drive.File{Id: id, Name: name, MimeType: driveFolderMimeType, Parents: []string{parent}}}
+	}
+	file := func(id, name, parent string) *drive.Change {
+		return &drive.Change{FileId: id, File: &drive.File{Id: id, Name: name, Md5Checksum: "md5-" + id, Parents: []string{parent}}}
+	}
+
+	// New folders are created and recorded in the tree.
+	got := poller.resolveChange(folder("f-src", "src", "root"))
+	if got == nil || got.Op != DriveOpCreate || !got.IsFolder || got.RelPath != "src" {
+		t.Fatalf("folder create = %+v", got)
+	}
+	got = poller.resolveChange(folder("f-pkg", "pkg", "f-src"))
+	if got == nil || got.RelPath != filepath.Join("src", "pkg") {
+		t.Fatalf("nested folder = %+v", got)
+	}
+
+	// Files land at their nested path.
+	got = poller.resolveChange(file("x", "main.go", "f-pkg"))
+	if got == nil || got.Op != DriveOpModify || got.RelPath != filepath.Join("src", "pkg", "main.go") || got.MD5 != "md5-x" {
+		t.Fatalf("nested file = %+v", got)
+	}
+	if !poller.isInFolderByParents([]string{"f-pkg"}) {
+		t.Fatal("isInFolderByParents() = false for nested folder")
+	}
+
+	// Renaming a folder is a move, and descendants follow it.
+	got = poller.resolveChange(folder("f-src", "lib", "root"))
+	if got == nil || got.Op != DriveOpMove || got.OldRelPath != "src" || got.RelPath != "lib" {
+		t.Fatalf("folder rename = %+v", got)
+	}
+	got = poller.resolveChange(file("x", "main.go", "f-pkg"))
+	if got == nil || got.RelPath != filepath.Join("lib", "pkg", "main.go") {
+		t.Fatalf("file after parent rename = %+v", got)
+	}
+
+	// Ignore rules see the full relative path.
+	if got := poller.resolveChange(file("y", "debug.log", "f-pkg")); got != nil {
+		t.Fatalf("ignored file = %+v", got)
+	}
+	if got := poller.resolveChange(folder("f-cache", "cache", "f-src")); got != nil {
+		t.Fatalf("ignored folder = %+v", got)
+	}
+	if got := poller.resolveChange(file("z", "data.bin", "f-cache")); got != nil {
+		t.Fatalf("file in ignored folder = %+v", got)
+	}
+
+	// Moving a folder out of the tree deletes it locally.
+	got = poller.resolveChange(folder("f-pkg", "pkg", "elsewhere"))
+	if got == nil || got.Op != DriveOpDelete || !got.IsFolder || got.RelPath != filepath.Join("lib", "pkg") {
+		t.Fatalf("folder moved out = %+v", got)
+	}
+	if got := poller.resolveChange(file("x", "main.go", "f-pkg")); got != nil {
+		t.Fatalf("untracked file outside tree = %+v", got)
+	}
+
+	// Removed folders resolve to their last path; the root is never removed.
+	got = poller.resolveChange(&drive.Change{FileId: "f-src", Removed: true})
+	if got == nil || got.Op != DriveOpDelete || got.RelPath != "lib" {
+		t.Fatalf("folder removed = %+v", got)
+	}
+	if got := poller.resolveChange(&drive.Change{FileId: "root", Removed: true}); got != nil {
+		t.Fatalf("root change = %+v", got)
+	}
+}
+
+func TestDrivePollerResolveChange_NativeDocs(t *testing.T) {
+	doc := &drive.Change{FileId: "doc-1", File: &drive.File{
+		Id: "doc-1", Name: "Plan", MimeType: mimeGoogleDoc, ModifiedTime: "2026-01-02T03:04:05.000Z", Parents: []string{"root"},
+	}}
+
+	poller := NewDrivePoller(nil, nil, 1, "root", 5*time.Second)
+	if got := poller.resolveChange(doc); got != nil {
+		t.Fatalf("native doc with default mode = %+v, want dropped", got)
+	}
+
+	poller.SetNativeDocs(NativeOffice)
+	got := poller.resolveChange(doc)
+	if got == nil || got.RelPath != "Plan.docx" || got.MD5 != "modified:2026-01-02T03:04:05.000Z" {
+		t.Fatalf("office mode = %+v", got)
+	}
+
+	poller.SetNativeDocs(NativeLink)
+	if got := poller.resolveChange(doc); got == nil || got.RelPath != "Plan.gdoc" {
+		t.Fatalf("link mode = %+v", got)
+	}
+}
+
+func TestDrivePollerResolveChange_TrackedFileMove(t *testing.T) {
+	d := openTestDB(t)
+	cfgID := insertTestConfig(t, d)
+	if err := d.CreateSyncItem(cfgID, "notes.txt", "file-1", "md5", "md5", time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	poller := NewDrivePoller(nil, d, cfgID, "root", 5*time.Second)
+	poller.tree.Set("f-docs", "docs")
+
+	file := func(name, parent string) *drive.Change {
+		return &drive.Change{FileId: "file-1", File: &drive.File{Id: "file-1", Name: name, Md5Checksum: "md5", Parents: []string{parent}}}
+	}
+
+	if got := poller.resolveChange(file("notes.txt", "root")); got == nil || got.Op != DriveOpModify {
+		t.Fatalf("unmoved file = %+v", got)
+	}
+
+	got := poller.resolveChange(file("renamed.txt", "f-docs"))
+	if got == nil || got.Op != DriveOpMove || got.OldRelPath != "notes.txt" || got.RelPath != filepath.Join("docs", "renamed.txt") {
+		t.Fatalf("moved file = %+v", got)
+	}
+}
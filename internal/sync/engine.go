@@ -2,6 +2,8 @@ package sync
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,13 +20,31 @@ type Engine struct {
 	db       *DB
 	config   *SyncConfig
 	service  *drive.Service
+	rules    *IgnoreRules
+	folders  *FolderTree
 	watcher  *Watcher
 	poller   *DrivePoller
 	uploader *Uploader
 	dloader  *Downloader
+	resolver *ConflictResolver
+	guard    *DeleteGuard
+	cipher   *Cipher // nil unless the config is encrypted
 
 	mu      sync.Mutex
 	running bool
+	paused  chan error // receives ErrPaused when the delete guard trips
+
+	// Local move detection (see move.go). Only the event loop uses these.
+	moveWindow time.Duration
+	held       map[string]time.Time // deleted path -> end of its move window
+	movedFrom  map[string]time.Time // old paths of applied moves
+
+	// Transfers (see transfer.go). One-shot engines have no scheduler and
+	// transfer inline. stats is only used by the event loop.
+	transfers    *Scheduler
+	upThrottle   *Throttle
+	downThrottle *Throttle
+	stats        TransferStats
 }
 
 // EngineOptions configures the sync engine.
@@ -34,6 +54,9 @@ type EngineOptions struct {
 	DriveService *drive.Service
 	Debounce     time.Duration
 	PollInterval time.Duration
+	// ConflictStrategy decides how files changed on both sides since the
+	// last sync are resolved. Defaults to ConflictRename.
+	ConflictStrategy ConflictStrategy
 }
 
 // NewEngine creates a new sync engine.
@@ -46,40 +69,103 @@ func NewEngine(opts EngineOptions) (*Engine, error) {
 		opts.PollInterval = DefaultPollInterval()
 	}
 
-	watcher, err := NewWatcher(opts.Config.LocalPath, opts.Debounce)
+	e, err := newEngine(opts)
+	if err != nil {
+		return nil, err
+	}
+	e.moveWindow = opts.Debounce
+	e.transfers = NewScheduler(opts.Config.TransferWorkers)
+
+	e.watcher, err = NewWatcherWithRules(opts.Config.LocalPath, opts.Debounce, e.rules)
 	if err != nil {
 		return nil, fmt.Errorf("create watcher: %w", err)
 	}
 
-	poller := NewDrivePoller(
+	e.poller = NewDrivePoller(
 		opts.DriveService,
 		opts.DB,
 		opts.Config.ID,
 		opts.Config.DriveFolderID,
 		opts.PollInterval,
 	)
+	e.poller.SetIgnoreRules(e.rules)
+	e.poller.SetNativeDocs(opts.Config.NativeDocs)
+	e.poller.SetCipher(e.cipher)
+	// The poller and uploader share one view of the Drive folder tree.
+	e.poller.SetFolderTree(e.folders)
+
+	return e, nil
+}
+
+// NewOneShotEngine creates an engine without a watcher or poller, for
+// Plan and Apply. Start cannot be used on it.
+func NewOneShotEngine(opts EngineOptions) (*Engine, error) {
+	return newEngine(opts)
+}
+
+// newEngine builds the parts shared by continuous and one-shot engines.
+func newEngine(opts EngineOptions) (*Engine, error) {
+	rules, err := NewIgnoreRules(opts.Config.LocalPath, opts.Config.IgnorePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("load ignore rules: %w", err)
+	}
+
+	cipher, err := LoadCipher(opts.Config)
+	if err != nil {
+		return nil, err
+	}
 
 	uploader := NewUploader(opts.DriveService, opts.Config.DriveFolderID, opts.Config.DriveID)
+	uploader.SetNativeDocs(opts.Config.NativeDocs)
 	dloader := NewDownloader(opts.DriveService, opts.Config.LocalPath)
+	dloader.SetNativeDocs(opts.Config.NativeDocs)
+
+	folders := NewFolderTree(opts.Config.DriveFolderID)
+	uploader.SetFolderTree(folders)
+
+	folders.SetCipher(cipher)
+	uploader.SetCipher(cipher)
+	dloader.SetCipher(cipher)
+
+	upThrottle := NewThrottle(opts.Config.UploadLimit)
+	downThrottle := NewThrottle(opts.Config.DownloadLimit)
+	uploader.SetThrottle(upThrottle)
+	dloader.SetThrottle(downThrottle)
 
 	return &Engine{
 		db:       opts.DB,
 		config:   opts.Config,
 		service:  opts.DriveService,
-		watcher:  watcher,
-		poller:   poller,
+		rules:    rules,
+		folders:  folders,
 		uploader: uploader,
 		dloader:  dloader,
+		resolver: NewConflictResolver(opts.DB, opts.Config.ID, opts.ConflictStrategy),
+		guard:    NewDeleteGuard(opts.Config.MaxDeletes, opts.Config.MaxDeletePercent),
+		cipher:   cipher,
+		paused:   make(chan error, 1),
+
+		upThrottle:   upThrottle,
+		downThrottle: downThrottle,
 	}, nil
 }
 
-// Start begins the sync loop. Blocks until context is cancelled.
+// Start begins the sync loop. Blocks until context is cancelled or the
+// engine stops with an error; its goroutines have all returned by then.
 func (e *Engine) Start(ctx context.Context) error {
 	e.mu.Lock()
 	if e.running {
 		e.mu.Unlock()
 		return fmt.Errorf("engine already running")
 	}
+	if err := e.pausedErr(); err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	if e.watcher == nil || e.poller == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("engine has no watcher (one-shot engines only Plan and Apply)")
+	}
 	e.running = true
 	e.mu.Unlock()
 
@@ -89,6 +175,30 @@ func (e *Engine) Start(ctx context.Context) error {
 		e.mu.Unlock()
 	}()
 
+	// Whatever ends Start shuts the engine down before returning, so an
+	// engine restarted after an error never runs next to this one.
+	defer func() { _ = e.db.ClearTransferStats(e.config.ID) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		if err := e.watcher.Stop(); err != nil {
+			// Log but don't fail on cleanup errors
+			_ = e.db.AddLogEntry(e.config.ID, "error", "", map[string]any{"error": err.Error()})
+		}
+		wg.Wait()
+	}()
+
+	e.purgeTrash()
+
+	// Map the Drive folder hierarchy so remote changes resolve to nested paths
+	if e.service != nil && e.folders != nil {
+		if err := e.folders.Seed(ctx, e.service, e.config.DriveID); err != nil {
+			return fmt.Errorf("load Drive folder tree: %w", err)
+		}
+	}
+
 	// Start initial scan to populate sync_items
 	if err := e.initialScan(ctx); err != nil {
 		return fmt.Errorf("initial scan: %w", err)
@@ -96,7 +206,13 @@ func (e *Engine) Start(ctx context.Context) error {
 
 	// Create error channel to collect errors from goroutines
 	errChan := make(chan error, 4)
-	var wg sync.WaitGroup
+
+	// Start the transfer workers
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e.transfers.Run(ctx)
+	}()
 
 	// Start the filesystem watcher
 	wg.Add(1)
@@ -137,28 +253,38 @@ func (e *Engine) Start(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		// Normal shutdown
+		return ctx.Err()
 	case err := <-errChan:
 		return err
+	case err := <-e.paused:
+		return err
 	}
-
-	// Stop watcher
-	if err := e.watcher.Stop(); err != nil {
-		// Log but don't fail on cleanup errors
-		_ = e.db.AddLogEntry(e.config.ID, "error", "", map[string]any{"error": err.Error()})
-	}
-
-	wg.Wait()
-
-	return ctx.Err()
 }
 
 // eventLoop processes events from watcher and poller.
 func (e *Engine) eventLoop(ctx context.Context) {
+	var flush <-chan time.Time
+	if e.moveWindow > 0 {
+		ticker := time.NewTicker(e.moveWindow)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	tick := time.NewTicker(transferTick)
+	defer tick.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
+		case now := <-flush:
+			e.flushHeldDeletes(ctx, now)
+
+		case now := <-tick.C:
+			e.retryTransfers(ctx, now)
+			e.recordTransferStats(now)
+
 		case event := <-e.watcher.Events():
 			e.handleLocalEvent(ctx, event)
 
@@ -200,6 +326,11 @@ func (e *Engine) handleLocalEvent(ctx context.Context, event WatchEvent) {
 			return
 		}
 
+		// A new path may be the second half of a move.
+		if e.detectMove(ctx, relPath, absPath, info) {
+			return
+		}
+
 		if info.IsDir() {
 			// Create folder in Drive
 			if err := e.uploader.CreateFolder(ctx, relPath); err != nil {
@@ -213,133 +344,434 @@ func (e *Engine) handleLocalEvent(ctx context.Context, event WatchEvent) {
 
 			_ = e.db.AddLogEntry(e.config.ID, "upload", relPath, map[string]any{"type": "folder"})
 		} else {
-			// Upload file
-			result, err := e.uploader.UploadFile(ctx, relPath, absPath)
-			if err != nil {
-				_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
-					"action": "upload",
-					"error":  err.Error(),
-				})
-
-				return
-			}
-
-			// Update sync item
-			if err := e.updateSyncItem(relPath, result); err != nil {
-				_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
-					"action": "update_sync_item",
-					"error":  err.Error(),
-				})
-			}
-
-			_ = e.db.AddLogEntry(e.config.ID, "upload", relPath, map[string]any{
-				"drive_id": result.DriveID,
-				"md5":      result.MD5,
+			e.transfer(ctx, TransferUpload, relPath, "", info.Size(), func(ctx context.Context) error {
+				return e.uploadLocal(ctx, relPath)
 			})
 		}
 
-	case OpDelete:
-		// Delete from Drive
-		if err := e.uploader.Delete(ctx, relPath); err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
-				"action": "delete",
-				"error":  err.Error(),
-			})
+	case OpDelete, OpRename:
+		// Editors that save by delete+create, and conflict renames, leave
+		// the path in place by the time the event is handled.
+		if e.localExists(relPath) {
+			return
+		}
 
+		// The create of a move was seen first and already moved the file.
+		if e.wasMoved(relPath) {
 			return
 		}
 
-		// Remove sync item
-		if err := e.removeSyncItem(relPath); err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
-				"action": "remove_sync_item",
-				"error":  err.Error(),
-			})
+		if e.holdDelete(relPath) {
+			return
 		}
 
-		_ = e.db.AddLogEntry(e.config.ID, "delete", relPath, nil)
+		e.queueDelete(ctx, relPath)
+	}
+}
 
-	case OpRename:
-		// Handle rename as delete + create
-		// The create event will follow separately
-		if err := e.uploader.Delete(ctx, relPath); err != nil {
-			// Ignore not found errors for renames
-			_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
-				"action": "rename_delete",
-				"error":  err.Error(),
-			})
-		}
+// uploadLocal uploads relPath unless it is unchanged or Drive changed it
+// too, and records the result. Errors are logged as well as returned.
+func (e *Engine) uploadLocal(ctx context.Context, relPath string) error {
+	absPath := filepath.Join(e.config.LocalPath, relPath)
+
+	// Upload file, unless Drive changed it too
+	result, err := e.uploadChecked(ctx, relPath, absPath)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "upload",
+			"error":  err.Error(),
+		})
+
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+
+	// Update sync item
+	if err := e.updateSyncItem(relPath, result); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "update_sync_item",
+			"error":  err.Error(),
+		})
+	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "upload", relPath, map[string]any{
+		"drive_id": result.DriveID,
+		"md5":      result.MD5,
+	})
+
+	return nil
+}
+
+// propagateDelete trashes the Drive side of a path deleted locally.
+func (e *Engine) propagateDelete(ctx context.Context, relPath string) {
+	if !e.allowDeletePath(relPath) {
+		return
+	}
+
+	// Delete from Drive
+	if err := e.deleteRemote(ctx, relPath); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "delete",
+			"error":  err.Error(),
+		})
+
+		return
+	}
+
+	// Remove sync item
+	if err := e.removeSyncItem(relPath); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "remove_sync_item",
+			"error":  err.Error(),
+		})
 	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "delete", relPath, nil)
 }
 
 // handleRemoteChange processes a Drive change.
 func (e *Engine) handleRemoteChange(ctx context.Context, change DriveChange) {
 	switch change.Op {
 	case DriveOpDelete:
+		if change.IsFolder && change.RelPath != "" {
+			if e.allowDeletePath(change.RelPath) {
+				e.removeLocal(change.RelPath)
+			}
+			return
+		}
+
 		// Find the local path for this file
 		item, err := e.db.GetSyncItemByDriveID(e.config.ID, change.FileID)
 		if err != nil || item == nil {
 			return // File not tracked
 		}
 
-		// Delete locally
-		absPath := filepath.Join(e.config.LocalPath, item.LocalPath)
-		if err := os.RemoveAll(absPath); err != nil && !os.IsNotExist(err) {
-			_ = e.db.AddLogEntry(e.config.ID, "error", item.LocalPath, map[string]any{
-				"action": "local_delete",
-				"error":  err.Error(),
-			})
+		e.queueRemoveLocal(ctx, item.LocalPath)
 
+	case DriveOpMove:
+		if change.IsFolder {
+			e.moveLocal(change.OldRelPath, change.RelPath, change.FileID, true)
 			return
 		}
 
-		// Remove sync item
-		if err := e.removeSyncItem(item.LocalPath); err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", item.LocalPath, map[string]any{
-				"action": "remove_sync_item",
-				"error":  err.Error(),
-			})
+		// A moved file may have changed too: the file is moved below,
+		// then compared like any other change.
+		fallthrough
+
+	case DriveOpCreate, DriveOpModify:
+		relPath := change.RelPath
+		if relPath == "" {
+			relPath = driveLocalName(change.FileName)
 		}
 
-		_ = e.db.AddLogEntry(e.config.ID, "download_delete", item.LocalPath, nil)
+		if change.IsFolder {
+			if err := e.dloader.DownloadFolder(ctx, change.FileID, relPath); err != nil {
+				_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+					"action":   "create_local_folder",
+					"error":    err.Error(),
+					"drive_id": change.FileID,
+				})
+			}
+			return
+		}
 
-	case DriveOpCreate, DriveOpModify:
 		// Check if we already have this file
 		item, err := e.db.GetSyncItemByDriveID(e.config.ID, change.FileID)
 		if err != nil {
 			return
 		}
 
-		if item != nil && item.RemoteMD5 == change.FileID {
-			// No change needed
+		// A tracked file renamed or moved on Drive is moved locally first.
+		if item != nil && item.LocalPath != relPath {
+			if !e.moveLocal(item.LocalPath, relPath, change.FileID, false) {
+				return
+			}
+		}
+
+		if item != nil && change.MD5 != "" && item.RemoteMD5 == change.MD5 && e.localExists(relPath) {
+			// Content unchanged
 			return
 		}
 
-		// Download the file
-		result, err := e.dloader.DownloadFile(ctx, change.FileID, change.FileName)
+		change.RelPath = relPath
+		e.transfer(ctx, TransferDownload, relPath, change.FileID, change.Size, func(ctx context.Context) error {
+			return e.downloadRemote(ctx, change)
+		})
+	}
+}
+
+// downloadRemote brings a changed Drive file to change.RelPath, unless the
+// local file changed too, and records the result. Errors are logged as well
+// as returned.
+func (e *Engine) downloadRemote(ctx context.Context, change DriveChange) error {
+	relPath := change.RelPath
+
+	// Looked up again, since the item may have changed while queued.
+	item, err := e.db.GetSyncItemByDriveID(e.config.ID, change.FileID)
+	if err != nil {
+		return err
+	}
+
+	if item != nil && change.MD5 != "" && item.RemoteMD5 == change.MD5 && e.localExists(relPath) {
+		// Content unchanged
+		return nil
+	}
+
+	// Three-state check: only overwrite a local file that is unchanged
+	// since the last sync.
+	if e.localExists(relPath) {
+		absPath := filepath.Join(e.config.LocalPath, relPath)
+		conflict, err := e.resolver.DetectConflict(ctx, syncBase(item, relPath, change.FileID), absPath, change.MD5, change.Timestamp)
 		if err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", change.FileName, map[string]any{
-				"action":   "download",
+			_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+				"action":   "detect_conflict",
 				"error":    err.Error(),
 				"drive_id": change.FileID,
 			})
 
-			return
+			return err
 		}
 
-		// Update sync item
-		if err := e.updateSyncItemFromDownload(result.LocalPath, change.FileID, result.MD5); err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", result.LocalPath, map[string]any{
-				"action": "update_sync_item",
-				"error":  err.Error(),
-			})
+		if conflict != nil {
+			if conflict.LocalMD5 == change.MD5 {
+				// Both sides made the same change
+				if err := e.updateSyncItemFromDownload(relPath, change.FileID, change.MD5, change.MD5); err != nil {
+					_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+						"action": "update_sync_item",
+						"error":  err.Error(),
+					})
+				}
+
+				return nil
+			}
+
+			conflict.DriveID = change.FileID
+			conflict.MimeType = change.MimeType
+			_ = e.resolveConflict(ctx, conflict)
+
+			return nil
 		}
+	}
 
-		_ = e.db.AddLogEntry(e.config.ID, "download", result.LocalPath, map[string]any{
+	// Download the file
+	result, err := e.dloader.DownloadFile(ctx, change.FileID, relPath)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action":   "download",
+			"error":    err.Error(),
 			"drive_id": change.FileID,
-			"md5":      result.MD5,
 		})
+
+		return err
+	}
+
+	// Update sync item
+	if err := e.updateSyncItemFromDownload(result.LocalPath, change.FileID, result.MD5, result.RemoteMD5); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", result.LocalPath, map[string]any{
+			"action": "update_sync_item",
+			"error":  err.Error(),
+		})
+	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "download", result.LocalPath, map[string]any{
+		"drive_id": change.FileID,
+		"md5":      result.MD5,
+	})
+
+	return nil
+}
+
+// deleteRemote trashes the Drive file behind a locally deleted path. Tracked
+// files are trashed by ID, since exported Google-native files have a
+// different name on Drive. Link stubs are read-only and never touch Drive.
+func (e *Engine) deleteRemote(ctx context.Context, relPath string) error {
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	if err != nil {
+		return err
+	}
+
+	if item == nil || item.DriveID == "" {
+		return e.uploader.Delete(ctx, relPath)
+	}
+
+	if e.config.NativeDocs == NativeLink && isLinkStub(relPath) {
+		return nil
+	}
+
+	return e.uploader.Trash(ctx, item.DriveID)
+}
+
+// removeLocal moves a local file or directory removed on Drive into the
+// local trash, and drops the sync items under it.
+func (e *Engine) removeLocal(relPath string) {
+	trashID, err := moveToTrash(e.config.LocalPath, relPath, time.Now())
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "local_delete",
+			"error":  err.Error(),
+		})
+
+		return
+	}
+
+	if err := e.db.RemoveSyncItemsUnder(e.config.ID, relPath); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "remove_sync_item",
+			"error":  err.Error(),
+		})
+	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "download_delete", relPath, map[string]any{"trash": trashID})
+}
+
+// allowDeletePath checks a deletion of relPath, counting every tracked file
+// under it, against the delete guard.
+func (e *Engine) allowDeletePath(relPath string) bool {
+	n, err := e.db.CountSyncItems(e.config.ID, relPath)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "delete_guard",
+			"error":  err.Error(),
+		})
+	}
+
+	return e.allowDeletes(max(n, 1))
+}
+
+// allowDeletes reports whether n more deletions stay within the delete
+// guard's limits. When they do not, sync is paused: the pause is recorded
+// so other processes see it, and a running engine stops with ErrPaused.
+func (e *Engine) allowDeletes(n int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.config.PausedReason != "" {
+		return false
+	}
+
+	total, err := e.db.CountSyncItems(e.config.ID, "")
+	if err != nil {
+		total = 0
+	}
+
+	count, ok := e.guard.Allow(n, total)
+	if ok {
+		return true
+	}
+
+	reason := e.guard.describe(count, total)
+	if err := e.db.PauseConfig(e.config.ID, reason); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", "", map[string]any{
+			"action": "pause",
+			"error":  err.Error(),
+		})
+	}
+	e.config.PausedReason = reason
+	e.config.PausedAt = time.Now()
+	_ = e.db.AddLogEntry(e.config.ID, "paused", "", map[string]any{"reason": reason})
+
+	select {
+	case e.paused <- e.pausedErr():
+	default:
+	}
+
+	return false
+}
+
+// isPaused reports whether the delete guard has paused sync.
+func (e *Engine) isPaused() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.config.PausedReason != ""
+}
+
+// pausedErr returns the ErrPaused error for a paused config, or nil.
+// Callers hold e.mu.
+func (e *Engine) pausedErr() error {
+	if e.config.PausedReason == "" {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s (review with 'wk sync plan', then run 'wk sync resume')", ErrPaused, e.config.PausedReason)
+}
+
+// purgeTrash removes local trash batches older than the retention period.
+func (e *Engine) purgeTrash() {
+	retention := time.Duration(e.config.TrashDays) * 24 * time.Hour
+	if _, err := PurgeTrash(e.config.LocalPath, retention, time.Now()); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", TrashDirName, map[string]any{
+			"action": "purge_trash",
+			"error":  err.Error(),
+		})
+	}
+}
+
+// moveLocal mirrors a Drive rename or move of a file or folder. It reports
+// whether the local tree now matches newPath.
+func (e *Engine) moveLocal(oldPath, newPath, driveID string, isFolder bool) bool {
+	logErr := func(err error) bool {
+		_ = e.db.AddLogEntry(e.config.ID, "error", newPath, map[string]any{
+			"action":   "local_move",
+			"from":     oldPath,
+			"error":    err.Error(),
+			"drive_id": driveID,
+		})
+		return false
+	}
+
+	oldAbs, err := localJoin(e.config.LocalPath, oldPath)
+	if err != nil {
+		return logErr(err)
+	}
+	newAbs, err := localJoin(e.config.LocalPath, newPath)
+	if err != nil {
+		return logErr(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newAbs), 0o755); err != nil {
+		return logErr(err)
+	}
+
+	switch _, err := os.Lstat(oldAbs); {
+	case err == nil:
+		if err := os.Rename(oldAbs, newAbs); err != nil {
+			return logErr(err)
+		}
+	case os.IsNotExist(err):
+		// Nothing local to move. Folders are created so their contents
+		// have somewhere to land; files are downloaded by the caller.
+		if isFolder {
+			if err := os.MkdirAll(newAbs, 0o755); err != nil {
+				return logErr(err)
+			}
+		}
+	default:
+		return logErr(err)
+	}
+
+	if err := e.db.RenameSyncItems(e.config.ID, oldPath, newPath); err != nil {
+		return logErr(err)
 	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "download_move", newPath, map[string]any{
+		"from":     oldPath,
+		"drive_id": driveID,
+	})
+
+	return true
+}
+
+// localExists reports whether relPath exists under the sync root.
+func (e *Engine) localExists(relPath string) bool {
+	absPath, err := localJoin(e.config.LocalPath, relPath)
+	if err != nil {
+		return false
+	}
+	_, err = os.Lstat(absPath)
+	return err == nil
 }
 
 // initialScan scans the local directory and populates sync_items.
@@ -364,7 +796,7 @@ func (e *Engine) initialScan(ctx context.Context) error {
 		}
 
 		// Skip ignored paths
-		if e.watcher.shouldIgnore(path) {
+		if e.rules.Match(relPath, d.IsDir()) {
 			if d.IsDir() {
 				return filepath.SkipDir
 			}
@@ -384,7 +816,20 @@ func (e *Engine) initialScan(ctx context.Context) error {
 		}
 
 		if item != nil {
-			// Already tracked
+			// Already tracked; queue it if it changed while sync was stopped.
+			if item.SyncState != StateSynced || !hasSyncBase(item) {
+				return nil
+			}
+
+			md5, err := computeMD5(path)
+			if err != nil {
+				return err
+			}
+
+			if md5 != item.LocalMD5 {
+				return e.db.SetSyncItemState(item.ID, StatePendingUpload, "")
+			}
+
 			return nil
 		}
 
@@ -412,40 +857,319 @@ func (e *Engine) updateSyncItem(relPath string, result *UploadResult) error {
 	}
 
 	if item == nil {
-		return e.db.CreateSyncItem(
+		if err := e.db.CreateSyncItem(
 			e.config.ID,
 			relPath,
 			result.DriveID,
 			result.MD5,
-			result.MD5,
+			result.RemoteMD5,
 			result.ModTime,
 			time.Now(),
-		)
+		); err != nil {
+			return err
+		}
+
+		if item, err = e.db.GetSyncItem(e.config.ID, relPath); err != nil || item == nil {
+			return err
+		}
+	}
+
+	if err := e.db.UpdateSyncItem(item.ID, result.DriveID, result.MD5, result.RemoteMD5, syncedState(item)); err != nil {
+		return err
 	}
 
-	return e.db.UpdateSyncItem(item.ID, result.DriveID, result.MD5, result.MD5, StateSynced)
+	return e.recordLocalFile(item.ID, relPath)
 }
 
 // updateSyncItemFromDownload updates or creates a sync item after download.
-func (e *Engine) updateSyncItemFromDownload(relPath, driveID, md5 string) error {
+// remoteMD5 is the Drive file's remoteFingerprint; it equals localMD5 except
+// for exported Google-native files.
+func (e *Engine) updateSyncItemFromDownload(relPath, driveID, localMD5, remoteMD5 string) error {
 	item, err := e.db.GetSyncItem(e.config.ID, relPath)
 	if err != nil {
 		return err
 	}
 
 	if item == nil {
-		return e.db.CreateSyncItem(
+		if err := e.db.CreateSyncItem(
 			e.config.ID,
 			relPath,
 			driveID,
-			md5,
-			md5,
+			localMD5,
+			remoteMD5,
 			time.Now(),
 			time.Now(),
-		)
+		); err != nil {
+			return err
+		}
+
+		if item, err = e.db.GetSyncItem(e.config.ID, relPath); err != nil || item == nil {
+			return err
+		}
+	}
+
+	if err := e.db.UpdateSyncItem(item.ID, driveID, localMD5, remoteMD5, syncedState(item)); err != nil {
+		return err
+	}
+
+	return e.recordLocalFile(item.ID, relPath)
+}
+
+// recordLocalFile stores what later syncs compare the local file against:
+// its size and inode, which move detection matches, and for text files its
+// content as the base of three-way merges.
+func (e *Engine) recordLocalFile(itemID int64, relPath string) error {
+	absPath := filepath.Join(e.config.LocalPath, relPath)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		// Gone again already; the next sync records it.
+		return nil
+	}
+
+	if err := e.db.SetSyncItemFileInfo(itemID, info.Size(), fileInode(info)); err != nil {
+		return err
+	}
+
+	var base []byte
+	if info.Size() <= maxMergeSize {
+		if content, err := os.ReadFile(absPath); err == nil && isMergeable(content) {
+			base = content
+		}
+	}
+
+	return e.db.SetSyncBase(itemID, base)
+}
+
+// syncedState is the state an item takes after a successful transfer.
+// Recorded conflicts stay listed until `wk sync conflicts resolve`.
+func syncedState(item *SyncItem) SyncState {
+	if item.SyncState == StateConflict {
+		return StateConflict
+	}
+
+	return StateSynced
+}
+
+// hasSyncBase reports whether item records a completed sync, i.e. whether
+// its MD5s describe the last content both sides agreed on.
+func hasSyncBase(item *SyncItem) bool {
+	return item != nil && item.DriveID != "" && item.RemoteMD5 != ""
+}
+
+// syncBase returns the last-synced state to compare both sides against.
+// Items that never completed a sync get an empty base, so any existing
+// content on both sides counts as a change.
+func syncBase(item *SyncItem, relPath, driveID string) *SyncItem {
+	if hasSyncBase(item) {
+		return item
+	}
+
+	return &SyncItem{LocalPath: relPath, DriveID: driveID}
+}
+
+// uploadChecked uploads a local file unless it is unchanged since the last
+// sync or Drive changed it too. Conflicts go to the resolver. A nil result
+// with a nil error means nothing was uploaded.
+func (e *Engine) uploadChecked(ctx context.Context, relPath, absPath string) (*UploadResult, error) {
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	localMD5, err := computeMD5(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("compute md5: %w", err)
+	}
+
+	if hasSyncBase(item) && localMD5 == item.LocalMD5 {
+		// Unchanged since the last sync (e.g. a file we just downloaded)
+		return nil, nil
+	}
+
+	base := syncBase(item, relPath, "")
+
+	remote, err := e.uploader.RemoteFile(ctx, relPath, base.DriveID)
+	if err != nil {
+		return nil, err
+	}
+
+	if remote != nil && isGoogleDocsType(remote.MimeType) && !e.config.NativeDocs.imports(remote.MimeType) {
+		// Link stubs and export-only formats are read-only
+		return nil, nil
+	}
+
+	if fingerprint := remoteFingerprint(remote); fingerprint != "" && fingerprint != base.RemoteMD5 {
+		if fingerprint == localMD5 {
+			// Both sides already agree; record them as synced.
+			if err := e.updateSyncItemFromDownload(relPath, remote.Id, localMD5, localMD5); err != nil {
+				return nil, err
+			}
+
+			return nil, nil
+		}
+
+		// Drive changed since the last sync as well.
+		base.DriveID = remote.Id
+
+		conflict, err := e.resolver.DetectConflict(ctx, base, absPath, fingerprint, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		if conflict != nil {
+			conflict.MimeType = remote.MimeType
+			_ = e.resolveConflict(ctx, conflict)
+
+			return nil, nil
+		}
+	}
+
+	return e.uploader.UploadTo(ctx, relPath, absPath, remote)
+}
+
+// resolveConflict applies the configured strategy to a file changed on both
+// sides. With the rename strategy both versions are kept and the item is
+// recorded in StateConflict until resolved by hand. Errors are logged as
+// well as returned.
+func (e *Engine) resolveConflict(ctx context.Context, conflict *ConflictInfo) error {
+	relPath := conflict.LocalPath
+	absPath := filepath.Join(e.config.LocalPath, relPath)
+
+	logErr := func(action string, err error) error {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action":   action,
+			"error":    err.Error(),
+			"drive_id": conflict.DriveID,
+		})
+		return fmt.Errorf("%s: %w", action, err)
+	}
+
+	if e.resolver.strategy == ConflictMerge {
+		merged, err := e.mergeConflict(ctx, conflict)
+		if merged {
+			if err != nil {
+				return logErr("merge", err)
+			}
+			return nil
+		}
+		if err != nil {
+			// Fall back to keeping both versions.
+			_ = logErr("merge", err)
+		}
+	}
+
+	result, err := e.resolver.Resolve(ctx, conflict, e.config.LocalPath)
+	if err != nil {
+		return logErr("resolve_conflict", err)
+	}
+
+	switch {
+	case result.UploadLocal:
+		var remote *drive.File
+		if conflict.DriveID != "" {
+			remote = &drive.File{Id: conflict.DriveID, MimeType: conflict.MimeType}
+		}
+
+		uploaded, err := e.uploader.UploadTo(ctx, relPath, absPath, remote)
+		if err != nil {
+			return logErr("upload", err)
+		}
+
+		if err := e.updateSyncItem(relPath, uploaded); err != nil {
+			return logErr("update_sync_item", err)
+		}
+
+	case result.DownloadRemote:
+		downloaded, err := e.dloader.DownloadFile(ctx, conflict.DriveID, relPath)
+		if err != nil {
+			return logErr("download", err)
+		}
+
+		if err := e.updateSyncItemFromDownload(relPath, conflict.DriveID, downloaded.MD5, downloaded.RemoteMD5); err != nil {
+			return logErr("update_sync_item", err)
+		}
+	}
+
+	if result.RenamedPath == "" {
+		return nil
+	}
+
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	if err != nil || item == nil {
+		return err
 	}
 
-	return e.db.UpdateSyncItem(item.ID, driveID, md5, md5, StateSynced)
+	conflictPath, err := filepath.Rel(e.config.LocalPath, result.RenamedPath)
+	if err != nil {
+		conflictPath = result.RenamedPath
+	}
+
+	if err := e.db.SetSyncItemState(item.ID, StateConflict, conflictPath); err != nil {
+		return logErr("record_conflict", err)
+	}
+
+	return nil
+}
+
+// mergeConflict merges both sides of a conflicted text file against the
+// content recorded at the last sync, writes the result locally and uploads
+// it. It reports false, leaving both sides untouched, when there is no
+// usable base, a version is not text, or the edits overlap.
+func (e *Engine) mergeConflict(ctx context.Context, conflict *ConflictInfo) (bool, error) {
+	relPath := conflict.LocalPath
+	absPath := filepath.Join(e.config.LocalPath, relPath)
+
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	if err != nil || !hasSyncBase(item) {
+		return false, err
+	}
+
+	base, err := e.db.GetSyncBase(item.ID)
+	if err != nil || base == nil {
+		return false, err
+	}
+	if sum := md5.Sum(base); hex.EncodeToString(sum[:]) != item.LocalMD5 {
+		// Recorded for an older sync
+		return false, nil
+	}
+
+	local, err := os.ReadFile(absPath)
+	if err != nil || !isMergeable(local) {
+		return false, err
+	}
+
+	remote, err := e.dloader.Content(ctx, conflict.DriveID, maxMergeSize)
+	if err != nil || !isMergeable(remote) {
+		return false, err
+	}
+
+	merged, ok := merge3(base, local, remote)
+	if !ok {
+		return false, nil
+	}
+
+	if err := os.WriteFile(absPath, merged, 0o644); err != nil {
+		return false, fmt.Errorf("write merged file: %w", err)
+	}
+
+	uploaded, err := e.uploader.UploadTo(ctx, relPath, absPath, &drive.File{Id: conflict.DriveID, MimeType: conflict.MimeType})
+	if err != nil {
+		// The merge is kept locally and uploads as a local change.
+		return true, fmt.Errorf("upload merged file: %w", err)
+	}
+
+	if err := e.updateSyncItem(relPath, uploaded); err != nil {
+		return true, fmt.Errorf("update sync item: %w", err)
+	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "conflict", relPath, map[string]any{
+		"strategy":   string(ConflictMerge),
+		"local_md5":  conflict.LocalMD5,
+		"remote_md5": conflict.RemoteMD5,
+		"merged_md5": uploaded.MD5,
+	})
+
+	return true, nil
 }
 
 // removeSyncItem removes a sync item.
@@ -454,7 +1178,8 @@ func (e *Engine) removeSyncItem(relPath string) error {
 }
 
 // processPendingUploads uploads all files that were marked pending_upload by initialScan.
-// It runs sequentially, logging progress and continuing past individual failures.
+// It queues them on the transfer workers (or uploads them in turn without
+// any), logging progress and continuing past individual failures.
 func (e *Engine) processPendingUploads(ctx context.Context) error {
 	items, err := e.db.ListPendingUploads(e.config.ID)
 	if err != nil {
@@ -468,42 +1193,79 @@ func (e *Engine) processPendingUploads(ctx context.Context) error {
 	total := len(items)
 	logPendingUploadProgress(ctx, "Uploading pre-existing files: 0/%d", total)
 
-	for i, item := range items {
+	var (
+		mu   sync.Mutex
+		done int
+	)
+	progress := func(failed string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done++
+		if failed != "" {
+			logPendingUploadProgress(ctx, "Uploading pre-existing files: %d/%d (failed: %s)", done, total, failed)
+			return
+		}
+		logPendingUploadProgress(ctx, "Uploading pre-existing files: %d/%d", done, total)
+	}
+
+	for _, item := range items {
 		// Check for graceful shutdown between items.
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
-		absPath := filepath.Join(e.config.LocalPath, item.LocalPath)
+		relPath := item.LocalPath
+		size := item.Size
+		if info, err := os.Stat(filepath.Join(e.config.LocalPath, relPath)); err == nil {
+			size = info.Size()
+		}
 
-		result, err := e.uploader.UploadFile(ctx, item.LocalPath, absPath)
-		if err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", item.LocalPath, map[string]any{
-				"action": "pending_upload",
-				"error":  err.Error(),
-			})
+		e.transfer(ctx, TransferUpload, relPath, "", size, func(ctx context.Context) error {
+			if err := e.uploadPending(ctx, relPath); err != nil {
+				progress(relPath)
+				return err
+			}
+			progress("")
+			return nil
+		})
+	}
 
-			logPendingUploadProgress(ctx, "Uploading pre-existing files: %d/%d (failed: %s)", i+1, total, item.LocalPath)
+	return nil
+}
 
-			continue
-		}
+// uploadPending uploads a file found by initialScan and records the result.
+func (e *Engine) uploadPending(ctx context.Context, relPath string) error {
+	absPath := filepath.Join(e.config.LocalPath, relPath)
 
-		if err := e.updateSyncItem(item.LocalPath, result); err != nil {
-			_ = e.db.AddLogEntry(e.config.ID, "error", item.LocalPath, map[string]any{
-				"action": "update_sync_item",
-				"error":  err.Error(),
-			})
-		}
-
-		_ = e.db.AddLogEntry(e.config.ID, "upload", item.LocalPath, map[string]any{
-			"drive_id": result.DriveID,
-			"md5":      result.MD5,
-			"source":   "initial_scan",
+	result, err := e.uploadChecked(ctx, relPath, absPath)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "pending_upload",
+			"error":  err.Error(),
 		})
 
-		logPendingUploadProgress(ctx, "Uploading pre-existing files: %d/%d", i+1, total)
+		return err
+	}
+
+	if result == nil {
+		// Unchanged, or a conflict handed to the resolver
+		return nil
+	}
+
+	if err := e.updateSyncItem(relPath, result); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "update_sync_item",
+			"error":  err.Error(),
+		})
 	}
 
+	_ = e.db.AddLogEntry(e.config.ID, "upload", relPath, map[string]any{
+		"drive_id": result.DriveID,
+		"md5":      result.MD5,
+		"source":   "initial_scan",
+	})
+
 	return nil
 }
 
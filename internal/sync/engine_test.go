@@ -2,12 +2,17 @@ package sync
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -319,3 +324,626 @@ func TestProcessPendingUploads_StartsAfterWatcherInStart(t *testing.T) {
 		t.Fatalf("processPendingUploads: %v", err)
 	}
 }
+
+func TestHandleRemoteChange_NestedDownloadAndFolderMove(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/files/file-1") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			fmt.Fprint(w, "package main")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": "file-1", "name": "main.go", "mimeType": "text/plain"})
+	}))
+	defer ts.Close()
+
+	svc, err := drive.NewService(context.Background(),
+		option.WithEndpoint(ts.URL),
+		option.WithHTTPClient(ts.Client()),
+	)
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+
+	engine := &Engine{
+		db:      d,
+		config:  &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root"},
+		service: svc,
+		folders: NewFolderTree("root"),
+		dloader: NewDownloader(svc, tmpDir),
+	}
+	ctx := context.Background()
+
+	nested := filepath.Join("src", "cmd", "main.go")
+	engine.handleRemoteChange(ctx, DriveChange{FileID: "file-1", FileName: "main.go", Op: DriveOpModify, RelPath: nested})
+
+	if b, err := os.ReadFile(filepath.Join(tmpDir, nested)); err != nil || string(b) != "package main" {
+		t.Fatalf("nested download = %q, %v", b, err)
+	}
+
+	engine.handleRemoteChange(ctx, DriveChange{FileID: "folder-src", Op: DriveOpMove, IsFolder: true, OldRelPath: "src", RelPath: filepath.Join("lib", "src")})
+
+	moved := filepath.Join("lib", "src", "cmd", "main.go")
+	if _, err := os.Stat(filepath.Join(tmpDir, moved)); err != nil {
+		t.Fatalf("moved file missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "src")); !os.IsNotExist(err) {
+		t.Fatalf("old folder still present: %v", err)
+	}
+	item, err := d.GetSyncItemByDriveID(configID, "file-1")
+	if err != nil || item == nil || item.LocalPath != moved {
+		t.Fatalf("sync item after move = %+v, %v", item, err)
+	}
+
+	engine.handleRemoteChange(ctx, DriveChange{FileID: "folder-lib", Op: DriveOpDelete, IsFolder: true, RelPath: "lib"})
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "lib")); !os.IsNotExist(err) {
+		t.Fatalf("deleted folder still present: %v", err)
+	}
+	if item, _ := d.GetSyncItemByDriveID(configID, "file-1"); item != nil {
+		t.Fatalf("sync item not removed with folder: %+v", item)
+	}
+}
+
+// stubDrive is an in-memory Drive for engine tests. It serves files by ID
+// (metadata, content, and children listed by parent) and records every
+// write as "PATCH id key=value..." or "UPLOAD METHOD" in writes.
+type stubDrive struct {
+	files   map[string]*drive.File
+	content map[string]string
+	writes  []string
+}
+
+func newStubDrive() *stubDrive {
+	return &stubDrive{files: map[string]*drive.File{}, content: map[string]string{}}
+}
+
+// add serves f as is, e.g. a native Google Doc.
+func (s *stubDrive) add(f *drive.File) {
+	s.files[f.Id] = f
+}
+
+// addFile serves a text file under parent holding content.
+func (s *stubDrive) addFile(id, name, parent, content string) {
+	s.add(&drive.File{Id: id, Name: name, MimeType: "text/plain", Parents: []string{parent}, Md5Checksum: md5Hex(content)})
+	s.content[id] = content
+}
+
+// addFolder serves a folder under parent.
+func (s *stubDrive) addFolder(id, name, parent string) {
+	s.add(&drive.File{Id: id, Name: name, MimeType: driveFolderMimeType, Parents: []string{parent}})
+}
+
+// folderPath returns the path of folder id below the root.
+func (s *stubDrive) folderPath(id string) string {
+	f := s.files[id]
+	if f.Parents[0] == "root" {
+		return f.Name
+	}
+	return filepath.Join(s.folderPath(f.Parents[0]), f.Name)
+}
+
+func (s *stubDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	file, known := s.files[id]
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/upload/"):
+		s.writes = append(s.writes, "UPLOAD "+r.Method)
+		if !known {
+			file = &drive.File{Id: "uploaded"}
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": file.Id})
+	case id == "files" && r.Method == http.MethodGet:
+		list := []*drive.File{}
+		for _, f := range s.files {
+			if stubQueryMatches(r.URL.Query().Get("q"), f) {
+				list = append(list, f)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"files": list})
+	case !known:
+		http.NotFound(w, r)
+	case r.Method == http.MethodGet && r.URL.Query().Get("alt") == "media":
+		fmt.Fprint(w, s.content[id])
+	case r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(file)
+	case r.Method == http.MethodPatch:
+		var update drive.File
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &update)
+
+		entry := "PATCH " + id
+		if update.Trashed {
+			entry += " trashed"
+			file.Trashed = true
+		}
+		if update.Name != "" {
+			entry += " name=" + update.Name
+			file.Name = update.Name
+		}
+		if add := r.URL.Query().Get("addParents"); add != "" {
+			entry += " add=" + add + " remove=" + r.URL.Query().Get("removeParents")
+			file.Parents = []string{add}
+		}
+		s.writes = append(s.writes, entry)
+		json.NewEncoder(w).Encode(file)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var stubQueryClause = regexp.MustCompile(`'([^']*)' in parents|(name|mimeType) (!?=) '([^']*)'`)
+
+// stubQueryMatches reports whether f matches a Drive files.list query of
+// the forms the sync engine sends: a parent, optionally narrowed by name
+// and mimeType.
+func stubQueryMatches(q string, f *drive.File) bool {
+	if f.Trashed {
+		return false
+	}
+	for _, m := range stubQueryClause.FindAllStringSubmatch(q, -1) {
+		switch {
+		case m[1] != "" && m[1] != f.Parents[0]:
+			return false
+		case m[2] == "name" && m[4] != f.Name:
+			return false
+		case m[2] == "mimeType" && (m[4] == f.MimeType) != (m[3] == "="):
+			return false
+		}
+	}
+	return strings.Contains(q, " in parents")
+}
+
+// newStubService returns a Drive service served by stub.
+func newStubService(t *testing.T, stub *stubDrive) *drive.Service {
+	t.Helper()
+
+	ts := httptest.NewServer(stub)
+	t.Cleanup(ts.Close)
+
+	svc, err := drive.NewService(context.Background(),
+		option.WithEndpoint(ts.URL),
+		option.WithHTTPClient(ts.Client()),
+	)
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+
+	return svc
+}
+
+// newStubEngine builds a one-shot engine syncing a temp directory with the
+// root of stub, its folder tree holding stub's folders.
+func newStubEngine(t *testing.T, stub *stubDrive) (*Engine, *DB, string) {
+	t.Helper()
+
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+
+	engine, err := NewOneShotEngine(EngineOptions{
+		DB:           d,
+		Config:       &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root"},
+		DriveService: newStubService(t, stub),
+	})
+	if err != nil {
+		t.Fatalf("NewOneShotEngine: %v", err)
+	}
+	for id, f := range stub.files {
+		if f.MimeType == driveFolderMimeType {
+			engine.folders.Set(id, stub.folderPath(id))
+		}
+	}
+
+	return engine, d, tmpDir
+}
+
+func md5Hex(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+// seedSynced records relPath as synced with Drive file driveID at content
+// base on both sides, with the size and inode of the local file if it
+// exists.
+func seedSynced(t *testing.T, engine *Engine, relPath, driveID, base string) {
+	t.Helper()
+
+	if err := engine.updateSyncItemFromDownload(relPath, driveID, md5Hex(base), md5Hex(base)); err != nil {
+		t.Fatalf("updateSyncItemFromDownload: %v", err)
+	}
+}
+
+// notesEngine is a stub engine whose Drive holds notes.txt (file-1) with
+// content remote.
+func notesEngine(t *testing.T, remote string) (*Engine, *DB, string, *stubDrive) {
+	t.Helper()
+
+	stub := newStubDrive()
+	stub.addFile("file-1", "notes.txt", "root", remote)
+	engine, d, tmpDir := newStubEngine(t, stub)
+	return engine, d, tmpDir, stub
+}
+
+func TestHandleRemoteChange_ConflictKeepsLocalEdit(t *testing.T) {
+	engine, d, tmpDir, _ := notesEngine(t, "remote edit")
+	seedSynced(t, engine, "notes.txt", "file-1", "base")
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleRemoteChange(context.Background(), DriveChange{
+		FileID: "file-1", FileName: "notes.txt", Op: DriveOpModify, RelPath: "notes.txt", MD5: md5Hex("remote edit"),
+	})
+
+	if b, _ := os.ReadFile(localFile); string(b) != "remote edit" {
+		t.Fatalf("original path = %q, want remote version", b)
+	}
+
+	item, err := d.GetSyncItem(engine.config.ID, "notes.txt")
+	if err != nil || item == nil {
+		t.Fatalf("GetSyncItem: %v, %v", item, err)
+	}
+	if item.SyncState != StateConflict || !strings.Contains(item.ConflictPath, ".conflict-") {
+		t.Fatalf("item = %+v, want recorded conflict", item)
+	}
+	if b, _ := os.ReadFile(filepath.Join(tmpDir, item.ConflictPath)); string(b) != "local edit" {
+		t.Fatalf("conflict copy = %q, want local edit", b)
+	}
+
+	conflicts, err := d.ListConflicts(engine.config.ID)
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("ListConflicts() = %v, %v", conflicts, err)
+	}
+}
+
+func TestHandleRemoteChange_MergeCombinesTextEdits(t *testing.T) {
+	base := "title\n\nfirst\nsecond\nthird\n"
+	remote := "title\n\nfirst\nsecond\nthird (remote)\n"
+	engine, d, tmpDir, stub := notesEngine(t, remote)
+	engine.resolver = NewConflictResolver(d, engine.config.ID, ConflictMerge)
+	seedSynced(t, engine, "notes.txt", "file-1", base)
+	item, _ := d.GetSyncItem(engine.config.ID, "notes.txt")
+	if err := d.SetSyncBase(item.ID, []byte(base)); err != nil {
+		t.Fatalf("SetSyncBase: %v", err)
+	}
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("title\n\nfirst (local)\nsecond\nthird\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleRemoteChange(context.Background(), DriveChange{
+		FileID: "file-1", FileName: "notes.txt", Op: DriveOpModify, RelPath: "notes.txt", MD5: md5Hex(remote),
+	})
+
+	want := "title\n\nfirst (local)\nsecond\nthird (remote)\n"
+	if b, _ := os.ReadFile(localFile); string(b) != want {
+		t.Fatalf("merged file = %q, want %q", b, want)
+	}
+	if len(stub.writes) != 1 {
+		t.Fatalf("drive writes = %q, want the merged file uploaded", stub.writes)
+	}
+
+	item, _ = d.GetSyncItem(engine.config.ID, "notes.txt")
+	if item.SyncState != StateSynced || item.LocalMD5 != md5Hex(want) {
+		t.Fatalf("item = %+v, want synced at the merged content", item)
+	}
+	if got, _ := d.GetSyncBase(item.ID); string(got) != want {
+		t.Fatalf("new base = %q, want the merged content", got)
+	}
+}
+
+func TestHandleRemoteChange_MergeOverlapFallsBackToRename(t *testing.T) {
+	base := "one\ntwo\n"
+	engine, d, tmpDir, stub := notesEngine(t, "one\ntwo (remote)\n")
+	engine.resolver = NewConflictResolver(d, engine.config.ID, ConflictMerge)
+	seedSynced(t, engine, "notes.txt", "file-1", base)
+	item, _ := d.GetSyncItem(engine.config.ID, "notes.txt")
+	if err := d.SetSyncBase(item.ID, []byte(base)); err != nil {
+		t.Fatalf("SetSyncBase: %v", err)
+	}
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("one\ntwo (local)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleRemoteChange(context.Background(), DriveChange{
+		FileID: "file-1", FileName: "notes.txt", Op: DriveOpModify, RelPath: "notes.txt", MD5: md5Hex("one\ntwo (remote)\n"),
+	})
+
+	if len(stub.writes) != 0 {
+		t.Fatalf("drive writes = %q, want none", stub.writes)
+	}
+	item, _ = d.GetSyncItem(engine.config.ID, "notes.txt")
+	if item.SyncState != StateConflict || item.ConflictPath == "" {
+		t.Fatalf("item = %+v, want a recorded rename conflict", item)
+	}
+	if b, _ := os.ReadFile(filepath.Join(tmpDir, item.ConflictPath)); string(b) != "one\ntwo (local)\n" {
+		t.Fatalf("conflict copy = %q, want local edit", b)
+	}
+}
+
+func TestHandleRemoteChange_OnlyRemoteChangedDownloads(t *testing.T) {
+	engine, d, tmpDir, _ := notesEngine(t, "remote edit")
+	seedSynced(t, engine, "notes.txt", "file-1", "base")
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleRemoteChange(context.Background(), DriveChange{
+		FileID: "file-1", FileName: "notes.txt", Op: DriveOpModify, RelPath: "notes.txt", MD5: md5Hex("remote edit"),
+	})
+
+	if b, _ := os.ReadFile(localFile); string(b) != "remote edit" {
+		t.Fatalf("local file = %q, want remote version", b)
+	}
+	item, _ := d.GetSyncItem(engine.config.ID, "notes.txt")
+	if item.SyncState != StateSynced || item.LocalMD5 != md5Hex("remote edit") {
+		t.Fatalf("item = %+v", item)
+	}
+}
+
+func TestUploadChecked_RemoteChangedIsConflict(t *testing.T) {
+	engine, d, tmpDir, stub := notesEngine(t, "remote edit")
+	seedSynced(t, engine, "notes.txt", "file-1", "base")
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := engine.uploadChecked(context.Background(), "notes.txt", localFile)
+	if err != nil {
+		t.Fatalf("uploadChecked: %v", err)
+	}
+	if result != nil || len(stub.writes) != 0 {
+		t.Fatalf("uploaded over a remote change: result=%+v writes=%q", result, stub.writes)
+	}
+
+	item, _ := d.GetSyncItem(engine.config.ID, "notes.txt")
+	if item.SyncState != StateConflict {
+		t.Fatalf("item = %+v, want conflict", item)
+	}
+	if b, _ := os.ReadFile(filepath.Join(tmpDir, item.ConflictPath)); string(b) != "local edit" {
+		t.Fatalf("conflict copy = %q", b)
+	}
+}
+
+func TestUploadChecked_SkipsUnchangedAndUploadsLocalOnlyEdits(t *testing.T) {
+	engine, _, tmpDir, stub := notesEngine(t, "base")
+	seedSynced(t, engine, "notes.txt", "file-1", "base")
+
+	localFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(localFile, []byte("base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := engine.uploadChecked(context.Background(), "notes.txt", localFile); err != nil || result != nil {
+		t.Fatalf("unchanged file: result=%+v err=%v", result, err)
+	}
+
+	if err := os.WriteFile(localFile, []byte("local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := engine.uploadChecked(context.Background(), "notes.txt", localFile); err != nil || result == nil {
+		t.Fatalf("local-only edit: result=%+v err=%v", result, err)
+	}
+	if len(stub.writes) != 1 {
+		t.Fatalf("drive writes = %q, want one", stub.writes)
+	}
+}
+
+func TestNativeDocsRoundTrip_Office(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+
+	modified := "2026-01-02T03:04:05.000Z"
+	var imports []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/files/doc-1/export"):
+			if got := r.URL.Query().Get("mimeType"); got != mimeDocx {
+				t.Errorf("export mimeType = %q", got)
+			}
+			fmt.Fprint(w, "exported docx")
+		case strings.HasSuffix(r.URL.Path, "/files/doc-1") && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]string{"id": "doc-1", "name": "Plan", "mimeType": mimeGoogleDoc, "modifiedTime": modified})
+		case strings.HasPrefix(r.URL.Path, "/upload/") && strings.HasSuffix(r.URL.Path, "/files/doc-1") && r.Method == http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			imports = append(imports, string(body))
+			modified = "2026-01-03T00:00:00.000Z"
+			json.NewEncoder(w).Encode(map[string]string{"id": "doc-1", "mimeType": mimeGoogleDoc, "modifiedTime": modified})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	svc, err := drive.NewService(context.Background(), option.WithEndpoint(ts.URL), option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+
+	cfg := &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root", NativeDocs: NativeOffice}
+	uploader := NewUploader(svc, "root", "")
+	uploader.SetNativeDocs(NativeOffice)
+	dloader := NewDownloader(svc, tmpDir)
+	dloader.SetNativeDocs(NativeOffice)
+	engine := &Engine{
+		db:       d,
+		config:   cfg,
+		service:  svc,
+		folders:  NewFolderTree("root"),
+		uploader: uploader,
+		dloader:  dloader,
+		resolver: NewConflictResolver(d, configID, ConflictRename),
+	}
+
+	// A Google Doc changed on Drive is exported next to its siblings.
+	engine.handleRemoteChange(context.Background(), DriveChange{
+		FileID: "doc-1", FileName: "Plan", MimeType: mimeGoogleDoc, Op: DriveOpModify,
+		RelPath: "Plan.docx", MD5: "modified:" + modified,
+	})
+
+	localFile := filepath.Join(tmpDir, "Plan.docx")
+	if b, err := os.ReadFile(localFile); err != nil || string(b) != "exported docx" {
+		t.Fatalf("exported file = %q, %v", b, err)
+	}
+	item, _ := d.GetSyncItem(configID, "Plan.docx")
+	if item == nil || item.DriveID != "doc-1" || item.RemoteMD5 != "modified:2026-01-02T03:04:05.000Z" {
+		t.Fatalf("item after export = %+v", item)
+	}
+
+	// A local edit is imported back into the same file ID.
+	if err := os.WriteFile(localFile, []byte("edited docx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := engine.uploadChecked(context.Background(), "Plan.docx", localFile)
+	if err != nil || result == nil {
+		t.Fatalf("uploadChecked() = %+v, %v", result, err)
+	}
+	if result.DriveID != "doc-1" || result.RemoteMD5 != "modified:2026-01-03T00:00:00.000Z" {
+		t.Fatalf("import result = %+v", result)
+	}
+	if len(imports) != 1 || !strings.Contains(imports[0], "edited docx") || !strings.Contains(imports[0], mimeDocx) {
+		t.Fatalf("imports = %q", imports)
+	}
+}
+
+func TestDeleteRemote_LinkStubIsReadOnly(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+
+	if err := d.CreateSyncItem(configID, "Plan.gdoc", "doc-1", "a", "b", time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The engine has no Drive service: any remote call would panic.
+	engine := &Engine{
+		db:     d,
+		config: &SyncConfig{ID: configID, LocalPath: t.TempDir(), DriveFolderID: "root", NativeDocs: NativeLink},
+	}
+	if err := engine.deleteRemote(context.Background(), "Plan.gdoc"); err != nil {
+		t.Fatalf("deleteRemote() error = %v", err)
+	}
+}
+
+func TestHandleLocalEvent_MassDeletePausesSync(t *testing.T) {
+	engine, d, _, _ := notesEngine(t, "remote")
+	engine.guard = NewDeleteGuard(2, 0)
+	engine.paused = make(chan error, 1)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		seedSynced(t, engine, name, "id-"+name, "content")
+		engine.handleLocalEvent(context.Background(), WatchEvent{RelPath: name, Op: OpDelete})
+	}
+
+	select {
+	case err := <-engine.paused:
+		if !errors.Is(err, ErrPaused) {
+			t.Fatalf("paused error = %v", err)
+		}
+	default:
+		t.Fatal("engine did not pause")
+	}
+
+	cfg, err := d.GetConfigByID(engine.config.ID)
+	if err != nil || cfg.PausedReason == "" || cfg.PausedAt.IsZero() {
+		t.Fatalf("config = %+v, %v; want paused", cfg, err)
+	}
+
+	// The deletion over the limit is held, not propagated.
+	if item, _ := d.GetSyncItem(engine.config.ID, "b.txt"); item != nil {
+		t.Fatalf("b.txt item = %+v, want removed", item)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, "c.txt"); item == nil {
+		t.Fatal("c.txt item removed while paused")
+	}
+
+	if err := engine.Start(context.Background()); !errors.Is(err, ErrPaused) {
+		t.Fatalf("Start while paused = %v, want ErrPaused", err)
+	}
+}
+
+func TestEngineStart_PauseStopsEngine(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	if err := d.UpdateChangeToken(configID, "token-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewEngine(EngineOptions{
+		DB:           d,
+		Config:       &SyncConfig{ID: configID, LocalPath: t.TempDir(), DriveFolderID: "root"},
+		DriveService: newStubService(t, newStubDrive()),
+		PollInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	engine.guard = NewDeleteGuard(2, 0)
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Start(context.Background()) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for engineGoroutines() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if engine.allowDeletes(3) {
+		t.Fatal("delete guard did not trip")
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrPaused) {
+			t.Fatalf("Start() = %v, want ErrPaused", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("engine kept running after the delete guard tripped")
+	}
+
+	// Its watcher, poller and transfer workers are all gone.
+	if n := engineGoroutines(); n != 0 {
+		t.Fatalf("%d engine goroutines still running after the pause", n)
+	}
+}
+
+func TestHandleRemoteChange_DeleteMovesToTrash(t *testing.T) {
+	engine, d, tmpDir, _ := notesEngine(t, "remote")
+	seedSynced(t, engine, "notes.txt", "file-1", "base")
+	writeLocal(t, tmpDir, "notes.txt", "base")
+
+	engine.handleRemoteChange(context.Background(), DriveChange{FileID: "file-1", Op: DriveOpDelete})
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "notes.txt")); !os.IsNotExist(err) {
+		t.Fatalf("notes.txt still exists: %v", err)
+	}
+
+	entries, err := ListTrash(tmpDir)
+	if err != nil || len(entries) != 1 || entries[0].Path != "notes.txt" {
+		t.Fatalf("trash = %+v, %v", entries, err)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, "notes.txt"); item != nil {
+		t.Fatalf("item = %+v, want removed", item)
+	}
+}
@@ -0,0 +1,217 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// FolderTree maps the Drive folders under a sync root to their paths
+// relative to that root. The root folder itself maps to "".
+// It is safe for concurrent use; the poller keeps it current from change
+// events and the uploader records the folders it creates.
+type FolderTree struct {
+	rootID string
+	cipher *Cipher // decrypts folder names read by Seed
+
+	mu    gosync.RWMutex
+	paths map[string]string // folder ID -> relative path
+	ids   map[string]string // relative path -> folder ID
+}
+
+// NewFolderTree creates a tree containing only the root folder.
+func NewFolderTree(rootID string) *FolderTree {
+	return &FolderTree{
+		rootID: rootID,
+		paths:  map[string]string{rootID: ""},
+		ids:    map[string]string{"": rootID},
+	}
+}
+
+// Seed walks the Drive folder hierarchy under the root and records every
+// folder. driveID is the shared drive to search, or "" for My Drive.
+func (t *FolderTree) Seed(ctx context.Context, service *drive.Service, driveID string) error {
+	queue := []string{t.rootID}
+
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+
+		parentPath, ok := t.Path(parentID)
+		if !ok {
+			continue
+		}
+
+		query := fmt.Sprintf("'%s' in parents and mimeType = '%s' and trashed = false",
+			escapeDriveQuery(parentID), driveFolderMimeType)
+
+		call := service.Files.List().
+			Context(ctx).
+			Q(query).
+			Fields("nextPageToken,files(id,name)").
+			PageSize(1000)
+
+		if driveID != "" {
+			call = call.SupportsAllDrives(true).
+				IncludeItemsFromAllDrives(true).
+				Corpora("drive").
+				DriveId(driveID)
+		}
+
+		err := call.Pages(ctx, func(resp *drive.FileList) error {
+			for _, f := range resp.Files {
+				if f == nil || f.Id == "" {
+					continue
+				}
+				name, ok := t.cipher.LocalName(f.Name)
+				if !ok {
+					continue
+				}
+				t.Set(f.Id, filepath.Join(parentPath, name))
+				queue = append(queue, f.Id)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("list folders in %s: %w", parentID, err)
+		}
+	}
+
+	return nil
+}
+
+// SetCipher decrypts the folder names Seed reads with c, for configs with
+// encrypted names.
+func (t *FolderTree) SetCipher(c *Cipher) {
+	t.cipher = c
+}
+
+// RootID returns the Drive ID of the sync root folder.
+func (t *FolderTree) RootID() string {
+	return t.rootID
+}
+
+// Path returns the relative path of a known folder.
+func (t *FolderTree) Path(folderID string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	p, ok := t.paths[folderID]
+	return p, ok
+}
+
+// FolderID returns the Drive ID of the folder at relPath.
+func (t *FolderTree) FolderID(relPath string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	id, ok := t.ids[cleanRelPath(relPath)]
+	return id, ok
+}
+
+// Dir returns the relative path of the first known folder in parents,
+// i.e. the local directory an item with those parents belongs in.
+func (t *FolderTree) Dir(parents []string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, parent := range parents {
+		if p, ok := t.paths[parent]; ok {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Set records folderID at relPath. If the folder was known under another
+// path, its descendants are moved with it and the old path is returned.
+func (t *FolderTree) Set(folderID, relPath string) (oldPath string, known bool) {
+	relPath = cleanRelPath(relPath)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	oldPath, known = t.paths[folderID]
+	if known && oldPath == relPath {
+		return oldPath, true
+	}
+
+	if known {
+		if t.ids[oldPath] == folderID {
+			delete(t.ids, oldPath)
+		}
+		prefix := oldPath + string(filepath.Separator)
+		for id, p := range t.paths {
+			if !strings.HasPrefix(p, prefix) {
+				continue
+			}
+			moved := filepath.Join(relPath, strings.TrimPrefix(p, prefix))
+			if t.ids[p] == id {
+				delete(t.ids, p)
+			}
+			t.paths[id] = moved
+			t.ids[moved] = id
+		}
+	}
+
+	t.paths[folderID] = relPath
+	t.ids[relPath] = folderID
+
+	return oldPath, known
+}
+
+// Remove forgets a folder and everything below it, returning the path it had.
+// The root folder cannot be removed.
+func (t *FolderTree) Remove(folderID string) (string, bool) {
+	if folderID == t.rootID {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	relPath, ok := t.paths[folderID]
+	if !ok {
+		return "", false
+	}
+
+	prefix := relPath + string(filepath.Separator)
+	for id, p := range t.paths {
+		if id != folderID && !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		delete(t.paths, id)
+		if t.ids[p] == id {
+			delete(t.ids, p)
+		}
+	}
+
+	return relPath, true
+}
+
+// cleanRelPath normalizes a relative path; the root is "".
+func cleanRelPath(relPath string) string {
+	relPath = filepath.Clean(relPath)
+	if relPath == "." {
+		return ""
+	}
+	return relPath
+}
+
+// driveLocalName maps a Drive item name to a single local path component.
+// Drive allows "/" in names, which would otherwise nest the item, and names
+// like "." or "..", which would resolve to the folder itself or its parent;
+// their dots become underscores.
+func driveLocalName(name string) string {
+	switch name {
+	case "":
+		return "_"
+	case ".", "..":
+		return strings.Repeat("_", len(name))
+	}
+	return strings.ReplaceAll(name, string(filepath.Separator), "_")
+}
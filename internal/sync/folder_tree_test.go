@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestFolderTree_SetMovesDescendants(t *testing.T) {
+	tree := NewFolderTree("root")
+	tree.Set("a", "a")
+	tree.Set("b", filepath.Join("a", "b"))
+	tree.Set("c", filepath.Join("a", "b", "c"))
+	tree.Set("ab", "ab") // shares a prefix with "a" but is not below it
+
+	if old, known := tree.Set("a", filepath.Join("x", "renamed")); !known || old != "a" {
+		t.Fatalf("Set() = %q, %v; want \"a\", true", old, known)
+	}
+
+	want := map[string]string{
+		"root": "",
+		"a":    filepath.Join("x", "renamed"),
+		"b":    filepath.Join("x", "renamed", "b"),
+		"c":    filepath.Join("x", "renamed", "b", "c"),
+		"ab":   "ab",
+	}
+	for id, path := range want {
+		if got, ok := tree.Path(id); !ok || got != path {
+			t.Errorf("Path(%q) = %q, %v; want %q", id, got, ok, path)
+		}
+		if got, ok := tree.FolderID(path); !ok || got != id {
+			t.Errorf("FolderID(%q) = %q, %v; want %q", path, got, ok, id)
+		}
+	}
+	if _, ok := tree.FolderID(filepath.Join("a", "b")); ok {
+		t.Error("old path still resolves after move")
+	}
+
+	if dir, ok := tree.Dir([]string{"unknown", "b"}); !ok || dir != filepath.Join("x", "renamed", "b") {
+		t.Errorf("Dir() = %q, %v", dir, ok)
+	}
+}
+
+func TestFolderTree_Remove(t *testing.T) {
+	tree := NewFolderTree("root")
+	tree.Set("a", "a")
+	tree.Set("b", filepath.Join("a", "b"))
+	tree.Set("ab", "ab")
+
+	if path, ok := tree.Remove("a"); !ok || path != "a" {
+		t.Fatalf("Remove() = %q, %v", path, ok)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, ok := tree.Path(id); ok {
+			t.Errorf("Path(%q) still known after removing its parent", id)
+		}
+	}
+	if _, ok := tree.Path("ab"); !ok {
+		t.Error("sibling with shared prefix was removed")
+	}
+	if _, ok := tree.Remove("root"); ok {
+		t.Error("Remove(root) succeeded")
+	}
+}
+
+func TestFolderTree_Seed(t *testing.T) {
+	children := map[string][]map[string]string{
+		"root": {{"id": "f1", "name": "docs"}, {"id": "f2", "name": "a/b"}, {"id": "f4", "name": "."}, {"id": "f5", "name": ".."}},
+		"f1":   {{"id": "f3", "name": "api"}},
+		"f4":   {{"id": "f6", "name": "inner"}},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		parent := strings.TrimPrefix(q[:strings.Index(q, "' in parents")], "'")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"files": children[parent]})
+	}))
+	defer ts.Close()
+
+	svc, err := drive.NewService(context.Background(),
+		option.WithEndpoint(ts.URL),
+		option.WithHTTPClient(ts.Client()),
+	)
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+
+	tree := NewFolderTree("root")
+	if err := tree.Seed(context.Background(), svc, ""); err != nil {
+		t.Fatalf("Seed() error = %v", err)
+	}
+
+	want := map[string]string{
+		"f1": "docs",
+		"f2": "a_b",
+		"f3": filepath.Join("docs", "api"),
+		"f4": "_",
+		"f5": "__",
+		"f6": filepath.Join("_", "inner"),
+	}
+	for id, path := range want {
+		if got, ok := tree.Path(id); !ok || got != path {
+			t.Errorf("Path(%q) = %q, %v; want %q", id, got, ok, path)
+		}
+	}
+
+	// A folder named "." must not take over the sync root.
+	if id, ok := tree.FolderID(""); !ok || id != "root" {
+		t.Errorf("FolderID(\"\") = %q, %v; want root", id, ok)
+	}
+}
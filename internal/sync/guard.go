@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	gosync "sync"
+	"time"
+)
+
+// Defaults for new sync configurations.
+const (
+	DefaultMaxDeletes       = 50
+	DefaultMaxDeletePercent = 0
+	DefaultTrashDays        = 30
+)
+
+// DeleteWindow is the period over which the delete guard counts deletions.
+const DeleteWindow = time.Minute
+
+// ErrPaused is returned when sync is paused by the delete guard. Run
+// `wk sync resume` to apply or undo the held deletions.
+var ErrPaused = errors.New("sync paused")
+
+// DeleteGuard stops a burst of deletions, such as an unmounted drive or an
+// accidental rm -rf, from propagating to the other side.
+type DeleteGuard struct {
+	maxDeletes int
+	maxPercent int
+	window     time.Duration
+	now        func() time.Time
+
+	mu     gosync.Mutex
+	recent []time.Time
+}
+
+// NewDeleteGuard creates a guard allowing at most maxDeletes deletions, or
+// maxPercent percent of the tracked files, within DeleteWindow. Zero
+// disables a limit.
+func NewDeleteGuard(maxDeletes, maxPercent int) *DeleteGuard {
+	return &DeleteGuard{
+		maxDeletes: maxDeletes,
+		maxPercent: maxPercent,
+		window:     DeleteWindow,
+		now:        time.Now,
+	}
+}
+
+// Allow records n deletions out of total tracked files and reports whether
+// they stay within the limits, along with the number of deletions in the
+// window including them. Deletions that are not allowed are not recorded.
+func (g *DeleteGuard) Allow(n, total int) (int, bool) {
+	if g == nil || n <= 0 {
+		return 0, true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	cutoff := now.Add(-g.window)
+	kept := g.recent[:0]
+	for _, t := range g.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	g.recent = kept
+
+	count := len(g.recent) + n
+	if g.exceeds(count, total) {
+		return count, false
+	}
+
+	for range n {
+		g.recent = append(g.recent, now)
+	}
+	return count, true
+}
+
+// exceeds reports whether count deletions out of total break a limit.
+func (g *DeleteGuard) exceeds(count, total int) bool {
+	if g.maxDeletes > 0 && count > g.maxDeletes {
+		return true
+	}
+	return g.maxPercent > 0 && total > 0 && count*100 > g.maxPercent*total
+}
+
+// describe explains a tripped limit for the pause reason.
+func (g *DeleteGuard) describe(count, total int) string {
+	return fmt.Sprintf("%d deletions of %d tracked files within %s exceed the limit (max %d files, %d%%)",
+		count, total, g.window, g.maxDeletes, g.maxPercent)
+}
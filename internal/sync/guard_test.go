@@ -0,0 +1,46 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeleteGuard_Limits(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := NewDeleteGuard(3, 0)
+	g.now = func() time.Time { return now }
+
+	if _, ok := g.Allow(2, 100); !ok {
+		t.Fatal("2 deletions rejected")
+	}
+	if count, ok := g.Allow(2, 100); ok || count != 4 {
+		t.Fatalf("Allow = %d, %v; want 4, false", count, ok)
+	}
+	// Rejected deletions are not recorded.
+	if _, ok := g.Allow(1, 100); !ok {
+		t.Fatal("3rd deletion rejected")
+	}
+
+	now = now.Add(DeleteWindow + time.Second)
+	if _, ok := g.Allow(3, 100); !ok {
+		t.Fatal("deletions after the window rejected")
+	}
+}
+
+func TestDeleteGuard_Percent(t *testing.T) {
+	t.Parallel()
+
+	g := NewDeleteGuard(0, 10)
+
+	if _, ok := g.Allow(1, 10); !ok {
+		t.Fatal("10% rejected")
+	}
+	if _, ok := g.Allow(1, 10); ok {
+		t.Fatal("20% allowed")
+	}
+	if _, ok := NewDeleteGuard(0, 0).Allow(1000, 10); !ok {
+		t.Fatal("disabled guard rejected deletions")
+	}
+}
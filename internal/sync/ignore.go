@@ -0,0 +1,272 @@
+package sync
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	gosync "sync"
+)
+
+// IgnoreFileName is the per-folder ignore file read from the sync root.
+const IgnoreFileName = ".wkignore"
+
+// DefaultIgnorePatterns are applied before .wkignore and the config patterns,
+// so either can re-include them with a "!" rule.
+var DefaultIgnorePatterns = []string{
+	".git/",
+	".gog-sync/",
+	"node_modules/",
+	"__pycache__/",
+	".DS_Store",
+	"*~",
+}
+
+// IgnoreRules decides which paths under a sync root are excluded from sync.
+// Rules follow .gitignore semantics: the last matching pattern wins, a
+// leading "!" re-includes a path, a trailing "/" matches directories only,
+// a pattern containing "/" is anchored to the root, and "**" matches any
+// number of directories. A path inside an ignored directory stays ignored.
+type IgnoreRules struct {
+	root     string
+	patterns []string // config patterns, applied after the .wkignore file
+
+	mu    gosync.RWMutex
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewIgnoreRules builds the rules for root from the defaults, root's
+// .wkignore file (if any) and the given config patterns, in that order.
+func NewIgnoreRules(root string, patterns []string) (*IgnoreRules, error) {
+	r := &IgnoreRules{
+		root:     root,
+		patterns: append([]string(nil), patterns...),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the .wkignore file from the sync root.
+func (r *IgnoreRules) Reload() error {
+	lines := append([]string(nil), DefaultIgnorePatterns...)
+
+	if r.root != "" {
+		fileLines, err := readIgnoreFile(filepath.Join(r.root, IgnoreFileName))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fileLines...)
+	}
+	lines = append(lines, r.patterns...)
+
+	rules := make([]ignoreRule, 0, len(lines))
+	for _, line := range lines {
+		rule, ok, err := parseIgnorePattern(line)
+		if err != nil {
+			return err
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	return nil
+}
+
+// Match reports whether relPath (slash- or OS-separated, relative to the
+// sync root) is ignored. isDir tells whether relPath itself is a directory.
+func (r *IgnoreRules) Match(relPath string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+
+	// The local trash is never synced, whatever the rules say.
+	if relPath == TrashDirName || strings.HasPrefix(relPath, TrashDirName+"/") {
+		return true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Excluding a directory excludes everything below it, so check each
+	// ancestor before the path itself.
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.matchLocked(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.matchLocked(relPath, isDir)
+}
+
+// MatchPath is Match for an absolute path under the sync root.
+func (r *IgnoreRules) MatchPath(absPath string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+	relPath, err := filepath.Rel(r.root, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return r.Match(relPath, isDir)
+}
+
+func (r *IgnoreRules) matchLocked(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(relPath) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// splitIgnorePatterns parses the newline-separated patterns stored in
+// sync_configs.
+func splitIgnorePatterns(s string) []string {
+	var patterns []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open %s: %w", IgnoreFileName, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", IgnoreFileName, err)
+	}
+	return lines, nil
+}
+
+// parseIgnorePattern compiles one .wkignore line. ok is false for blank
+// lines and comments.
+func parseIgnorePattern(line string) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimSuffix(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false, nil
+	}
+
+	pattern := line
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		rule.negate = true
+		pattern = pattern[1:]
+	case strings.HasPrefix(pattern, `\!`), strings.HasPrefix(pattern, `\#`):
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return ignoreRule{}, false, nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to the sync root;
+	// otherwise it matches a name at any depth.
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	rule.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	return rule, true, nil
+}
+
+// globToRegexp translates a gitignore glob into a regular expression body.
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				atStart := i == 0 || pattern[i-1] == '/'
+				switch {
+				case atStart && i+2 < len(pattern) && pattern[i+2] == '/':
+					// "**/" matches zero or more leading directories.
+					b.WriteString("(?:.*/)?")
+					i += 2
+				case atStart && i+2 == len(pattern):
+					// Trailing "/**" matches everything inside.
+					b.WriteString(".*")
+					i++
+				default:
+					b.WriteString("[^/]*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreRules_Match(t *testing.T) {
+	tmpDir := t.TempDir()
+	wkignore := "# build output\n" +
+		"build/\n" +
+		"*.log\n" +
+		"!keep.log\n" +
+		"/secrets.txt\n" +
+		"docs/**/draft-*\n" +
+		".env\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte(wkignore), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	rules, err := NewIgnoreRules(tmpDir, []string{"tmp/", "!node_modules/"})
+	if err != nil {
+		t.Fatalf("NewIgnoreRules() error = %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		// Defaults
+		{".git", true, true},
+		{".git/config", false, true},
+		{"a/.DS_Store", false, true},
+		{"notes.txt~", false, true},
+		{"__pycache__/x.pyc", false, true},
+		// Dotfiles are no longer ignored wholesale
+		{".env.example", false, false},
+		{".github", true, false},
+		{".github/workflows/ci.yml", false, false},
+		{IgnoreFileName, false, false},
+		// .wkignore rules
+		{".env", false, true},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build/out.o", false, true},
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"secrets.txt", false, true},
+		{"sub/secrets.txt", false, false},
+		{"docs/draft-a.md", false, true},
+		{"docs/x/y/draft-a.md", false, true},
+		{"docs/final.md", false, false},
+		// Config patterns apply after the file and can re-include defaults
+		{"tmp/scratch", false, true},
+		{"node_modules/pkg/index.js", false, false},
+		// Re-including a file does not rescue it from an ignored directory
+		{"build/keep.log", false, true},
+		{"src/main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRules_Reload(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	rules, err := NewIgnoreRules(tmpDir, nil)
+	if err != nil {
+		t.Fatalf("NewIgnoreRules() error = %v", err)
+	}
+	if rules.MatchPath(filepath.Join(tmpDir, "cache", "x"), false) {
+		t.Fatal("cache/x ignored before .wkignore exists")
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte("cache/\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := rules.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !rules.MatchPath(filepath.Join(tmpDir, "cache", "x"), false) {
+		t.Fatal("cache/x not ignored after reload")
+	}
+}
+
+func TestIgnoreRules_InvalidPattern(t *testing.T) {
+	if _, err := NewIgnoreRules("", []string{"[z-a]"}); err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+}
+
+func TestSplitIgnorePatterns(t *testing.T) {
+	got := splitIgnorePatterns("*.log\n\n  !keep.log \n")
+	if len(got) != 2 || got[0] != "*.log" || got[1] != "!keep.log" {
+		t.Fatalf("splitIgnorePatterns() = %q", got)
+	}
+	if got := splitIgnorePatterns(""); got != nil {
+		t.Fatalf("splitIgnorePatterns(\"\") = %q, want nil", got)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package sync
+
+import "os"
+
+// fileInode returns the inode number of a file, or 0 if unknown.
+// os.FileInfo carries no file index on Windows, so moves are matched by
+// content alone.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
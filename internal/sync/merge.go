@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"bytes"
+	"slices"
+	"unicode/utf8"
+)
+
+// maxMergeSize caps each version of a file merged in memory, and the merge
+// bases kept in the sync database.
+const maxMergeSize = 1 << 20
+
+// maxMergeCells caps the comparison table used to diff the changed middle
+// of two files, so very different files fall back instead of using a lot
+// of memory.
+const maxMergeCells = 1 << 22
+
+// isMergeable reports whether content is text that can be merged line by
+// line: valid UTF-8 without NUL bytes, and at most maxMergeSize bytes.
+func isMergeable(content []byte) bool {
+	return len(content) <= maxMergeSize && utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// merge3 applies both the changes from base to local and from base to
+// remote, line by line. It reports false when both sides changed the same
+// (or adjacent) lines differently, or the files are too different to
+// compare.
+func merge3(base, local, remote []byte) ([]byte, bool) {
+	o, a, b := splitLines(base), splitLines(local), splitLines(remote)
+
+	matchA, ok := matchLines(o, a)
+	if !ok {
+		return nil, false
+	}
+	matchB, ok := matchLines(o, b)
+	if !ok {
+		return nil, false
+	}
+
+	var out bytes.Buffer
+	write := func(lines []string) {
+		for _, line := range lines {
+			out.WriteString(line)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// Base lines kept in place on both sides.
+		n := 0
+		for i+n < len(o) && matchA[i+n] == j+n && matchB[i+n] == k+n {
+			n++
+		}
+		if n > 0 {
+			write(o[i : i+n])
+			i, j, k = i+n, j+n, k+n
+			continue
+		}
+
+		// A changed chunk, up to the next base line both sides kept.
+		next := i
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+		endA, endB := len(a), len(b)
+		if next < len(o) {
+			endA, endB = matchA[next], matchB[next]
+		}
+
+		chunkO, chunkA, chunkB := o[i:next], a[j:endA], b[k:endB]
+		switch {
+		case slices.Equal(chunkA, chunkO):
+			write(chunkB)
+		case slices.Equal(chunkB, chunkO), slices.Equal(chunkA, chunkB):
+			write(chunkA)
+		default:
+			return nil, false
+		}
+
+		i, j, k = next, endA, endB
+	}
+
+	return out.Bytes(), true
+}
+
+// splitLines splits content into lines, keeping their line endings.
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		i := bytes.IndexByte(content, '\n')
+		if i < 0 {
+			lines = append(lines, string(content))
+			break
+		}
+		lines = append(lines, string(content[:i+1]))
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// matchLines pairs the lines of a with lines of b along a longest common
+// subsequence. It returns, for each line of a, the index of its line in b
+// or -1, and false if the changed middle is too large to compare.
+func matchLines(a, b []string) ([]int, bool) {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Most edits are small: pair the common prefix and suffix directly.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		match[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		match[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+
+	midA, midB := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(midA), len(midB)
+	if n == 0 || m == 0 {
+		return match, true
+	}
+	if (n+1)*(m+1) > maxMergeCells {
+		return nil, false
+	}
+
+	// lcs[i*w+j] is the LCS length of midA[i:] and midB[j:].
+	w := m + 1
+	lcs := make([]int32, (n+1)*w)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else {
+				lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case midA[i] == midB[j]:
+			match[pre+i] = pre + j
+			i++
+			j++
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return match, true
+}
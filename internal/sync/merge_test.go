@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "# Notes\n\nalpha\nbeta\ngamma\ndelta\n"
+
+	tests := []struct {
+		name   string
+		local  string
+		remote string
+		want   string
+		ok     bool
+	}{
+		{
+			name:   "non-overlapping edits",
+			local:  "# Notes\n\nALPHA\nbeta\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nbeta\ngamma\nDELTA\n",
+			want:   "# Notes\n\nALPHA\nbeta\ngamma\nDELTA\n",
+			ok:     true,
+		},
+		{
+			name:   "insertions at both ends",
+			local:  "intro\n# Notes\n\nalpha\nbeta\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nbeta\ngamma\ndelta\nouttro\n",
+			want:   "intro\n# Notes\n\nalpha\nbeta\ngamma\ndelta\nouttro\n",
+			ok:     true,
+		},
+		{
+			name:   "deletion and edit",
+			local:  "# Notes\n\nbeta\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nbeta\ngamma\ndelta!\n",
+			want:   "# Notes\n\nbeta\ngamma\ndelta!\n",
+			ok:     true,
+		},
+		{
+			name:   "same change on both sides",
+			local:  "# Notes\n\nalpha\nBETA\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nBETA\ngamma\ndelta\n",
+			want:   "# Notes\n\nalpha\nBETA\ngamma\ndelta\n",
+			ok:     true,
+		},
+		{
+			name:   "overlapping edits",
+			local:  "# Notes\n\nalpha\nbeta (local)\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nbeta (remote)\ngamma\ndelta\n",
+			ok:     false,
+		},
+		{
+			name:   "different insertions at the same place",
+			local:  "# Notes\n\nalpha\nbeta\nlocal\ngamma\ndelta\n",
+			remote: "# Notes\n\nalpha\nbeta\nremote\ngamma\ndelta\n",
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := merge3([]byte(base), []byte(tt.local), []byte(tt.remote))
+			if ok != tt.ok {
+				t.Fatalf("merge3 ok = %v, want %v (got %q)", ok, tt.ok, got)
+			}
+			if ok && string(got) != tt.want {
+				t.Fatalf("merge3 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3TooDifferent(t *testing.T) {
+	// Every line differs, so the whole file is compared line by line,
+	// which exceeds maxMergeCells.
+	var base, local strings.Builder
+	for i := range 2100 {
+		fmt.Fprintf(&base, "base %d\n", i)
+		fmt.Fprintf(&local, "local %d\n", i)
+	}
+	remote := base.String() + "appended\n"
+
+	if _, ok := merge3([]byte(base.String()), []byte(local.String()), []byte(remote)); ok {
+		t.Fatal("merge3 of completely rewritten large file succeeded, want fallback")
+	}
+}
+
+func TestIsMergeable(t *testing.T) {
+	if !isMergeable([]byte("plain text\nwith lines\n")) {
+		t.Error("text not mergeable")
+	}
+	if isMergeable([]byte("PK\x03\x04\x00binary")) {
+		t.Error("binary content mergeable")
+	}
+	if isMergeable([]byte{0xff, 0xfe, 'a'}) {
+		t.Error("invalid UTF-8 mergeable")
+	}
+}
@@ -0,0 +1,271 @@
+package sync
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A local rename or move reaches the engine as a delete (or rename) of the
+// old path and a create of the new one, in either order. Deletions of
+// tracked paths are held for the move window; a create whose content
+// matches a tracked file that has gone missing locally is applied as a
+// move of the Drive file, keeping its ID, sharing, comments and history.
+
+// holdDelete holds a deletion of a tracked file or folder for the move
+// window, in case it is the first half of a move. It reports whether the
+// deletion was held.
+func (e *Engine) holdDelete(relPath string) bool {
+	if e.moveWindow <= 0 {
+		return false
+	}
+
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	tracked := err == nil && item != nil && item.DriveID != ""
+	if !tracked && e.folders != nil && relPath != "" {
+		_, tracked = e.folders.FolderID(relPath)
+	}
+	if !tracked {
+		return false
+	}
+
+	if e.held == nil {
+		e.held = make(map[string]time.Time)
+	}
+	e.held[relPath] = time.Now().Add(e.moveWindow)
+
+	return true
+}
+
+// flushHeldDeletes propagates held deletions whose move window has passed
+// without a matching create.
+func (e *Engine) flushHeldDeletes(ctx context.Context, now time.Time) {
+	for _, relPath := range slices.Sorted(maps.Keys(e.held)) {
+		if now.Before(e.held[relPath]) {
+			continue
+		}
+		delete(e.held, relPath)
+
+		if !e.localExists(relPath) {
+			e.queueDelete(ctx, relPath)
+		}
+	}
+
+	for relPath, at := range e.movedFrom {
+		if now.Sub(at) > 2*e.moveWindow {
+			delete(e.movedFrom, relPath)
+		}
+	}
+}
+
+// wasMoved reports whether relPath was the old path of a move already
+// applied, consuming the record.
+func (e *Engine) wasMoved(relPath string) bool {
+	if _, ok := e.movedFrom[relPath]; !ok {
+		return false
+	}
+	delete(e.movedFrom, relPath)
+
+	return true
+}
+
+// detectMove applies the creation of an untracked relPath as a move when it
+// matches a tracked file or folder missing from its old path. It reports
+// whether the event was handled. On failure the create falls back to an
+// upload, and the old path's deletion trashes the old Drive file.
+func (e *Engine) detectMove(ctx context.Context, relPath, absPath string, info os.FileInfo) bool {
+	if existing, err := e.db.GetSyncItem(e.config.ID, relPath); err != nil || existing != nil {
+		return false
+	}
+
+	var (
+		item    *SyncItem
+		oldPath string
+		driveID string
+	)
+	if info.IsDir() {
+		oldPath, driveID = e.findMovedFolder(relPath, absPath)
+	} else if item = e.findMovedFile(relPath, absPath, info); item != nil {
+		oldPath, driveID = item.LocalPath, item.DriveID
+	}
+	if oldPath == "" {
+		return false
+	}
+
+	moved, err := e.uploader.Move(ctx, driveID, relPath)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action":   "move",
+			"from":     oldPath,
+			"error":    err.Error(),
+			"drive_id": driveID,
+		})
+
+		return false
+	}
+
+	delete(e.held, oldPath)
+	if e.movedFrom == nil {
+		e.movedFrom = make(map[string]time.Time)
+	}
+	e.movedFrom[oldPath] = time.Now()
+
+	// Update the rows in place so they keep their sync base.
+	if err := e.db.RenameSyncItems(e.config.ID, oldPath, relPath); err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action": "update_sync_item",
+			"error":  err.Error(),
+		})
+	}
+
+	if item != nil {
+		// Renaming a Google-native file bumps its modification time, which
+		// is its fingerprint.
+		if fingerprint := remoteFingerprint(moved); fingerprint != "" && fingerprint != item.RemoteMD5 {
+			if err := e.db.UpdateSyncItem(item.ID, driveID, item.LocalMD5, fingerprint, item.SyncState); err != nil {
+				_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+					"action": "update_sync_item",
+					"error":  err.Error(),
+				})
+			}
+		}
+		_ = e.db.SetSyncItemFileInfo(item.ID, info.Size(), fileInode(info))
+	}
+
+	_ = e.db.AddLogEntry(e.config.ID, "move", relPath, map[string]any{
+		"from":     oldPath,
+		"drive_id": driveID,
+	})
+
+	return true
+}
+
+// findMovedFile returns the tracked file that relPath was moved from: one
+// missing locally with the same MD5 and size, and the same inode where both
+// are known. Ambiguous matches return nil, so copies are uploaded.
+func (e *Engine) findMovedFile(relPath, absPath string, info os.FileInfo) *SyncItem {
+	if isLinkStub(relPath) {
+		return nil
+	}
+
+	md5, err := computeMD5(absPath)
+	if err != nil {
+		return nil
+	}
+
+	items, err := e.db.FindSyncItemsByMD5(e.config.ID, md5)
+	if err != nil {
+		return nil
+	}
+
+	inode := fileInode(info)
+
+	var candidates []SyncItem
+	for _, item := range items {
+		if !hasSyncBase(&item) || item.SyncState == StateConflict || isLinkStub(item.LocalPath) {
+			continue
+		}
+		if item.Size != 0 && item.Size != info.Size() {
+			continue
+		}
+		if e.localExists(item.LocalPath) {
+			continue
+		}
+		if inode != 0 && item.Inode != 0 {
+			if item.Inode == inode {
+				return &item
+			}
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+
+	if len(candidates) != 1 {
+		return nil
+	}
+
+	return &candidates[0]
+}
+
+// findMovedFolder returns the old path and Drive ID of the tracked folder
+// that relPath was moved from, matched by the first file inside it. Empty
+// or ambiguous folders return "".
+func (e *Engine) findMovedFolder(relPath, absPath string) (string, string) {
+	if e.folders == nil {
+		return "", ""
+	}
+
+	sub, md5 := e.firstFile(absPath)
+	if sub == "" {
+		return "", ""
+	}
+
+	items, err := e.db.FindSyncItemsByMD5(e.config.ID, md5)
+	if err != nil {
+		return "", ""
+	}
+
+	suffix := string(filepath.Separator) + sub
+	found := map[string]string{}
+	for _, item := range items {
+		oldPath, ok := strings.CutSuffix(item.LocalPath, suffix)
+		if !ok || oldPath == relPath || strings.HasPrefix(relPath, oldPath+string(filepath.Separator)) {
+			continue
+		}
+		if e.localExists(oldPath) {
+			continue
+		}
+		if folderID, ok := e.folders.FolderID(oldPath); ok {
+			found[oldPath] = folderID
+		}
+	}
+
+	if len(found) != 1 {
+		return "", ""
+	}
+
+	for oldPath, folderID := range found {
+		return oldPath, folderID
+	}
+
+	return "", ""
+}
+
+// firstFile returns the path relative to dir, and the MD5, of the first
+// regular file under dir that is not ignored.
+func (e *Engine) firstFile(dir string) (string, string) {
+	var sub, md5 string
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.rules != nil && e.rules.MatchPath(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if md5, err = computeMD5(path); err != nil {
+			return err
+		}
+		sub, err = filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return filepath.SkipAll
+	})
+	if err != nil {
+		return "", ""
+	}
+
+	return sub, md5
+}
@@ -0,0 +1,159 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestDetectMove_CreateBeforeDelete(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFolder("folder-docs", "docs", "root")
+	stub.addFile("file-1", "notes.txt", "root", "notes")
+	engine, d, tmpDir := newStubEngine(t, stub)
+	ctx := context.Background()
+	writeLocal(t, tmpDir, "notes.txt", "notes")
+	seedSynced(t, engine, "notes.txt", "file-1", "notes")
+
+	newPath := filepath.Join("docs", "renamed.txt")
+	if err := os.MkdirAll(filepath.Join(tmpDir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(tmpDir, "notes.txt"), filepath.Join(tmpDir, newPath)); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: newPath, Op: OpCreate})
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "notes.txt", Op: OpRename})
+
+	want := "PATCH file-1 name=renamed.txt add=folder-docs remove=root"
+	if len(stub.writes) != 1 || stub.writes[0] != want {
+		t.Fatalf("drive writes = %q, want [%q]", stub.writes, want)
+	}
+
+	item, err := d.GetSyncItem(engine.config.ID, newPath)
+	if err != nil || item == nil || item.DriveID != "file-1" || item.SyncState != StateSynced {
+		t.Fatalf("moved item = %+v, %v", item, err)
+	}
+	if old, _ := d.GetSyncItem(engine.config.ID, "notes.txt"); old != nil {
+		t.Fatalf("old item still tracked: %+v", old)
+	}
+}
+
+func TestDetectMove_HeldDeleteThenCreate(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFile("file-1", "a.txt", "root", "aaa")
+	stub.addFile("file-2", "b.txt", "root", "bbb")
+	engine, d, tmpDir := newStubEngine(t, stub)
+	engine.moveWindow = time.Minute
+	ctx := context.Background()
+	writeLocal(t, tmpDir, "a.txt", "aaa")
+	writeLocal(t, tmpDir, "b.txt", "bbb")
+	seedSynced(t, engine, "a.txt", "file-1", "aaa")
+	seedSynced(t, engine, "b.txt", "file-2", "bbb")
+
+	// a.txt is renamed; b.txt is deleted for good.
+	if err := os.Rename(filepath.Join(tmpDir, "a.txt"), filepath.Join(tmpDir, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(tmpDir, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "a.txt", Op: OpRename})
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "b.txt", Op: OpDelete})
+	if len(stub.writes) != 0 {
+		t.Fatalf("deletes not held: %q", stub.writes)
+	}
+
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "c.txt", Op: OpCreate})
+	engine.flushHeldDeletes(ctx, time.Now())
+	if len(stub.writes) != 1 || stub.writes[0] != "PATCH file-1 name=c.txt" {
+		t.Fatalf("drive writes = %q, want only the rename", stub.writes)
+	}
+
+	engine.flushHeldDeletes(ctx, time.Now().Add(2*time.Minute))
+	if len(stub.writes) != 2 || stub.writes[1] != "PATCH file-2 trashed" {
+		t.Fatalf("drive writes = %q, want b.txt trashed after the window", stub.writes)
+	}
+
+	if item, _ := d.GetSyncItem(engine.config.ID, "c.txt"); item == nil || item.DriveID != "file-1" {
+		t.Fatalf("moved item = %+v", item)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, "b.txt"); item != nil {
+		t.Fatalf("deleted item still tracked: %+v", item)
+	}
+}
+
+func TestDetectMove_AmbiguousCopyUploads(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFile("file-1", "a.txt", "root", "same")
+	stub.addFile("file-2", "b.txt", "root", "same")
+	engine, _, tmpDir := newStubEngine(t, stub)
+	ctx := context.Background()
+	seedSynced(t, engine, "a.txt", "file-1", "same")
+	seedSynced(t, engine, "b.txt", "file-2", "same")
+
+	// Both tracked copies are missing and neither has an inode on record,
+	// so the new file cannot be paired with either.
+	writeLocal(t, tmpDir, "c.txt", "same")
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "c.txt", Op: OpCreate})
+
+	if len(stub.writes) != 1 || stub.writes[0] != "UPLOAD POST" {
+		t.Fatalf("drive writes = %q, want a fresh upload", stub.writes)
+	}
+}
+
+func TestDetectMove_Folder(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFolder("folder-docs", "docs", "root")
+	stub.addFile("file-1", "a.txt", "folder-docs", "aaa")
+	engine, d, tmpDir := newStubEngine(t, stub)
+	ctx := context.Background()
+	writeLocal(t, tmpDir, filepath.Join("docs", "a.txt"), "aaa")
+	seedSynced(t, engine, filepath.Join("docs", "a.txt"), "file-1", "aaa")
+
+	if err := os.Rename(filepath.Join(tmpDir, "docs"), filepath.Join(tmpDir, "papers")); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "papers", Op: OpCreate})
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "docs", Op: OpRename})
+
+	if len(stub.writes) != 1 || stub.writes[0] != "PATCH folder-docs name=papers" {
+		t.Fatalf("drive writes = %q, want the folder renamed", stub.writes)
+	}
+	if id, ok := engine.folders.FolderID("papers"); !ok || id != "folder-docs" {
+		t.Fatalf("FolderID(papers) = %q, %v", id, ok)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, filepath.Join("papers", "a.txt")); item == nil || item.DriveID != "file-1" {
+		t.Fatalf("item under moved folder = %+v", item)
+	}
+}
+
+func TestUploaderMove_NativeDocKeepsDriveName(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFolder("folder-docs", "docs", "root")
+	stub.add(&drive.File{Id: "doc-1", Name: "Plan", MimeType: mimeGoogleDoc, Parents: []string{"root"}, ModifiedTime: "2026-01-02T03:04:05Z"})
+	engine, _, _ := newStubEngine(t, stub)
+	engine.uploader.SetNativeDocs(NativeOffice)
+	ctx := context.Background()
+
+	// Moving without renaming leaves the name alone.
+	if _, err := engine.uploader.Move(ctx, "doc-1", filepath.Join("docs", "Plan.docx")); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if _, err := engine.uploader.Move(ctx, "doc-1", filepath.Join("docs", "Roadmap.docx")); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	want := []string{"PATCH doc-1 add=folder-docs remove=root", "PATCH doc-1 name=Roadmap"}
+	if strings.Join(stub.writes, "|") != strings.Join(want, "|") {
+		t.Fatalf("drive writes = %q, want %q", stub.writes, want)
+	}
+}
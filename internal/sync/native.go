@@ -0,0 +1,173 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	mimeGoogleDoc     = "application/vnd.google-apps.document"
+	mimeGoogleSheet   = "application/vnd.google-apps.spreadsheet"
+	mimeGoogleSlides  = "application/vnd.google-apps.presentation"
+	mimeGoogleDrawing = "application/vnd.google-apps.drawing"
+
+	mimeDocx     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXlsx     = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimePptx     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	mimeMarkdown = "text/markdown"
+	mimeCSV      = "text/csv"
+)
+
+// nativeFormat is the local representation of a Google-native file.
+type nativeFormat struct {
+	mimeType   string // Export (and re-import) MIME type; empty for link stubs
+	ext        string
+	exportOnly bool // Lossy export, never imported back
+}
+
+var nativeOfficeFormats = map[string]nativeFormat{
+	mimeGoogleDoc:    {mimeDocx, ".docx", false},
+	mimeGoogleSheet:  {mimeXlsx, ".xlsx", false},
+	mimeGoogleSlides: {mimePptx, ".pptx", false},
+}
+
+// Drive exports only the first sheet of a spreadsheet as CSV, so importing
+// it back would delete every other sheet.
+var nativeTextFormats = map[string]nativeFormat{
+	mimeGoogleDoc:    {mimeMarkdown, ".md", false},
+	mimeGoogleSheet:  {mimeCSV, ".csv", true},
+	mimeGoogleSlides: {mimePptx, ".pptx", false},
+}
+
+// nativeLinkExts follow the stub extensions used by Drive for desktop.
+var nativeLinkExts = map[string]string{
+	mimeGoogleDoc:                        ".gdoc",
+	mimeGoogleSheet:                      ".gsheet",
+	mimeGoogleSlides:                     ".gslides",
+	mimeGoogleDrawing:                    ".gdraw",
+	"application/vnd.google-apps.form":   ".gform",
+	"application/vnd.google-apps.script": ".gscript",
+	"application/vnd.google-apps.site":   ".gsite",
+	"application/vnd.google-apps.map":    ".gmap",
+	"application/vnd.google-apps.jam":    ".gjam",
+}
+
+// isLinkStub reports whether relPath has a link stub extension.
+func isLinkStub(relPath string) bool {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	for _, stubExt := range nativeLinkExts {
+		if ext == stubExt {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNativeDocsMode parses a --native-docs value.
+func ParseNativeDocsMode(s string) (NativeDocsMode, error) {
+	switch mode := NativeDocsMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", NativeSkip:
+		return NativeSkip, nil
+	case NativeOffice, NativeText, NativeLink:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown native docs mode %q (valid: skip, office, text, link)", s)
+	}
+}
+
+// imports reports whether local edits are imported back into a native file
+// of mimeType.
+func (m NativeDocsMode) imports(mimeType string) bool {
+	if m != NativeOffice && m != NativeText {
+		return false
+	}
+	f, ok := m.format(mimeType)
+	return ok && !f.exportOnly
+}
+
+// format returns how a Google-native file of mimeType is stored locally
+// in this mode.
+func (m NativeDocsMode) format(mimeType string) (nativeFormat, bool) {
+	switch m {
+	case NativeOffice:
+		f, ok := nativeOfficeFormats[mimeType]
+		return f, ok
+	case NativeText:
+		f, ok := nativeTextFormats[mimeType]
+		return f, ok
+	case NativeLink:
+		ext, ok := nativeLinkExts[mimeType]
+		return nativeFormat{ext: ext}, ok
+	default:
+		return nativeFormat{}, false
+	}
+}
+
+// localName returns the local file name for a Google-native file, or false
+// if files of that type are not synced in this mode.
+func (m NativeDocsMode) localName(name, mimeType string) (string, bool) {
+	f, ok := m.format(mimeType)
+	if !ok {
+		return "", false
+	}
+
+	name = driveLocalName(name)
+	if !strings.EqualFold(filepath.Ext(name), f.ext) {
+		name += f.ext
+	}
+	return name, true
+}
+
+// importMimeType returns the MIME type to upload relPath as when re-importing
+// it into a Google-native file of mimeType.
+func (m NativeDocsMode) importMimeType(relPath, mimeType string) (string, error) {
+	f, ok := m.format(mimeType)
+	if !ok || !m.imports(mimeType) {
+		return "", fmt.Errorf("%s: cannot import into %s in native docs mode %q", relPath, mimeType, m)
+	}
+	if !strings.EqualFold(filepath.Ext(relPath), f.ext) {
+		return "", fmt.Errorf("%s: expected a %s file to import into %s", relPath, f.ext, mimeType)
+	}
+	return f.mimeType, nil
+}
+
+// remoteFingerprint identifies the content version of a Drive file. It is
+// the md5Checksum, or for Google-native files, which have none, the
+// modification time.
+func remoteFingerprint(file *drive.File) string {
+	if file == nil {
+		return ""
+	}
+	if file.Md5Checksum != "" {
+		return file.Md5Checksum
+	}
+	if isGoogleDocsType(file.MimeType) && file.ModifiedTime != "" {
+		return "modified:" + file.ModifiedTime
+	}
+	return ""
+}
+
+// linkStub is the content of a link-mode stub file.
+type linkStub struct {
+	URL      string `json:"url"`
+	DocID    string `json:"doc_id"`
+	MimeType string `json:"mime_type"`
+}
+
+// linkStubContent renders the stub for a Google-native file.
+func linkStubContent(file *drive.File) ([]byte, error) {
+	url := file.WebViewLink
+	if url == "" {
+		url = "https://drive.google.com/open?id=" + file.Id
+	}
+
+	b, err := json.Marshal(linkStub{URL: url, DocID: file.Id, MimeType: file.MimeType})
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestParseNativeDocsMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    NativeDocsMode
+		wantErr bool
+	}{
+		{"", NativeSkip, false},
+		{"skip", NativeSkip, false},
+		{"Office", NativeOffice, false},
+		{" text ", NativeText, false},
+		{"link", NativeLink, false},
+		{"pdf", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNativeDocsMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseNativeDocsMode(%q) = %q, %v; want %q (err %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNativeDocsMode_LocalName(t *testing.T) {
+	tests := []struct {
+		mode     NativeDocsMode
+		name     string
+		mimeType string
+		want     string
+		ok       bool
+	}{
+		{NativeOffice, "Plan", mimeGoogleDoc, "Plan.docx", true},
+		{NativeOffice, "Budget.xlsx", mimeGoogleSheet, "Budget.xlsx", true},
+		{NativeOffice, "Q1/Q2", mimeGoogleSlides, "Q1_Q2.pptx", true},
+		{NativeOffice, "Diagram", mimeGoogleDrawing, "", false},
+		{NativeText, "Plan", mimeGoogleDoc, "Plan.md", true},
+		{NativeText, "Budget", mimeGoogleSheet, "Budget.csv", true},
+		{NativeText, "Deck", mimeGoogleSlides, "Deck.pptx", true},
+		{NativeLink, "Diagram", mimeGoogleDrawing, "Diagram.gdraw", true},
+		{NativeSkip, "Plan", mimeGoogleDoc, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.mode.localName(tt.name, tt.mimeType)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s.localName(%q, %q) = %q, %v; want %q, %v", tt.mode, tt.name, tt.mimeType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNativeDocsMode_ImportMimeType(t *testing.T) {
+	if got, err := NativeOffice.importMimeType("Plan.docx", mimeGoogleDoc); err != nil || got != mimeDocx {
+		t.Fatalf("office import = %q, %v", got, err)
+	}
+	if got, err := NativeText.importMimeType("Notes.MD", mimeGoogleDoc); err != nil || got != mimeMarkdown {
+		t.Fatalf("text import = %q, %v", got, err)
+	}
+	if _, err := NativeText.importMimeType("Budget.csv", mimeGoogleSheet); err == nil {
+		t.Fatal("expected error importing a CSV export over a spreadsheet")
+	}
+	if _, err := NativeOffice.importMimeType("Plan.txt", mimeGoogleDoc); err == nil {
+		t.Fatal("expected error for mismatched extension")
+	}
+	if _, err := NativeLink.importMimeType("Plan.gdoc", mimeGoogleDoc); err == nil {
+		t.Fatal("expected error importing a link stub")
+	}
+}
+
+func TestRemoteFingerprint(t *testing.T) {
+	if got := remoteFingerprint(&drive.File{Md5Checksum: "abc", ModifiedTime: "t"}); got != "abc" {
+		t.Errorf("blob fingerprint = %q", got)
+	}
+	if got := remoteFingerprint(&drive.File{MimeType: mimeGoogleDoc, ModifiedTime: "t"}); got != "modified:t" {
+		t.Errorf("native fingerprint = %q", got)
+	}
+	if got := remoteFingerprint(nil); got != "" {
+		t.Errorf("nil fingerprint = %q", got)
+	}
+}
@@ -0,0 +1,567 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// PlanActionKind is what a one-shot sync would do to one file.
+type PlanActionKind string
+
+const (
+	ActionUpload       PlanActionKind = "upload"
+	ActionDownload     PlanActionKind = "download"
+	ActionDeleteRemote PlanActionKind = "delete_remote"
+	ActionDeleteLocal  PlanActionKind = "delete_local"
+	ActionConflict     PlanActionKind = "conflict"
+	// ActionTrack records a file that is identical on both sides as
+	// synced, so later changes to it are compared with that state.
+	ActionTrack PlanActionKind = "track"
+)
+
+// IsDelete reports whether the action deletes a file on either side.
+func (k PlanActionKind) IsDelete() bool {
+	return k == ActionDeleteLocal || k == ActionDeleteRemote
+}
+
+// PlanAction is one file-level step of a Plan.
+type PlanAction struct {
+	Kind      PlanActionKind `json:"action"`
+	Path      string         `json:"path"`
+	DriveID   string         `json:"drive_id,omitempty"`
+	MimeType  string         `json:"mime_type,omitempty"`
+	LocalMD5  string         `json:"local_md5,omitempty"`
+	RemoteMD5 string         `json:"remote_md5,omitempty"`
+	Reason    string         `json:"reason"`
+}
+
+// Plan is the set of actions that would bring a sync folder and its Drive
+// folder back in step, computed by comparing both trees with the last
+// synced state.
+type Plan struct {
+	ConfigID  int64        `json:"config_id"`
+	LocalPath string       `json:"local_path"`
+	Actions   []PlanAction `json:"actions"`
+}
+
+// ApplyMode selects which plan actions Apply carries out.
+type ApplyMode string
+
+const (
+	// ApplyPush applies local-to-remote actions (uploads, remote deletes).
+	ApplyPush ApplyMode = "push"
+	// ApplyPull applies remote-to-local actions (downloads, local deletes).
+	ApplyPull ApplyMode = "pull"
+	// ApplyAll applies every action, resolving conflicts with the engine's
+	// conflict strategy.
+	ApplyAll ApplyMode = "run"
+)
+
+// Includes reports whether actions of kind are applied in this mode. Every
+// mode records identical files as synced.
+func (m ApplyMode) Includes(kind PlanActionKind) bool {
+	if kind == ActionTrack {
+		return m == ApplyPush || m == ApplyPull || m == ApplyAll
+	}
+
+	switch m {
+	case ApplyPush:
+		return kind == ActionUpload || kind == ActionDeleteRemote
+	case ApplyPull:
+		return kind == ActionDownload || kind == ActionDeleteLocal
+	case ApplyAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// ApplyStatus is the outcome of one applied plan action.
+type ApplyStatus string
+
+const (
+	StatusApplied ApplyStatus = "applied"
+	StatusSkipped ApplyStatus = "skipped"
+	StatusFailed  ApplyStatus = "failed"
+)
+
+// ApplyResult is the outcome of one plan action.
+type ApplyResult struct {
+	PlanAction
+	Status ApplyStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// planRemote is a Drive file found while listing the remote tree.
+type planRemote struct {
+	file        *drive.File
+	fingerprint string
+}
+
+// Plan compares the local folder, the Drive folder and the recorded sync
+// state and returns what a sync would do, without changing anything.
+func (e *Engine) Plan(ctx context.Context) (*Plan, error) {
+	local, err := e.listLocal(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scan local folder: %w", err)
+	}
+
+	remote, err := e.listRemote(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list Drive folder: %w", err)
+	}
+
+	items, err := e.db.ListSyncItems(e.config.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	bases := make(map[string]*SyncItem, len(items))
+	for i := range items {
+		bases[items[i].LocalPath] = &items[i]
+	}
+
+	paths := make(map[string]struct{}, len(local)+len(remote))
+	for p := range local {
+		paths[p] = struct{}{}
+	}
+	for p := range remote {
+		paths[p] = struct{}{}
+	}
+
+	plan := &Plan{ConfigID: e.config.ID, LocalPath: e.config.LocalPath, Actions: []PlanAction{}}
+	for p := range paths {
+		localMD5, hasLocal := local[p]
+		r, hasRemote := remote[p]
+		if action, ok := e.planPath(p, localMD5, hasLocal, r, hasRemote, bases[p]); ok {
+			plan.Actions = append(plan.Actions, action)
+		}
+	}
+
+	sort.Slice(plan.Actions, func(i, j int) bool {
+		return plan.Actions[i].Path < plan.Actions[j].Path
+	})
+
+	return plan, nil
+}
+
+// planPath classifies one path with a three-way comparison of the local
+// MD5, the remote fingerprint and the last synced state.
+func (e *Engine) planPath(relPath, localMD5 string, hasLocal bool, r planRemote, hasRemote bool, item *SyncItem) (PlanAction, bool) {
+	action := PlanAction{Path: relPath, LocalMD5: localMD5}
+	if item != nil {
+		action.DriveID = item.DriveID
+	}
+	if hasRemote {
+		action.DriveID = r.file.Id
+		action.MimeType = r.file.MimeType
+		action.RemoteMD5 = r.fingerprint
+	}
+
+	base := hasSyncBase(item)
+	// Link stubs are read-only, and native exports are only re-imported in
+	// modes and formats that import.
+	readOnly := (e.config.NativeDocs == NativeLink && isLinkStub(relPath)) ||
+		(hasRemote && isGoogleDocsType(r.file.MimeType) && !e.config.NativeDocs.imports(r.file.MimeType))
+
+	plan := func(kind PlanActionKind, reason string) (PlanAction, bool) {
+		action.Kind = kind
+		action.Reason = reason
+		return action, true
+	}
+
+	switch {
+	case hasLocal && hasRemote:
+		if localMD5 == r.fingerprint {
+			if base && item.LocalMD5 == localMD5 && item.RemoteMD5 == r.fingerprint {
+				return action, false
+			}
+			return plan(ActionTrack, "identical on both sides")
+		}
+		if !base {
+			return plan(ActionConflict, "exists on both sides with different content")
+		}
+
+		localChanged := localMD5 != item.LocalMD5
+		remoteChanged := r.fingerprint != item.RemoteMD5
+		switch {
+		case localChanged && remoteChanged:
+			return plan(ActionConflict, "changed on both sides")
+		case remoteChanged:
+			return plan(ActionDownload, "changed on Drive")
+		case localChanged && !readOnly:
+			return plan(ActionUpload, "changed locally")
+		}
+
+	case hasLocal:
+		action.DriveID = ""
+		switch {
+		case base && localMD5 == item.LocalMD5:
+			return plan(ActionDeleteLocal, "deleted on Drive")
+		case readOnly:
+			if base {
+				return plan(ActionDeleteLocal, "deleted on Drive")
+			}
+		case base:
+			return plan(ActionUpload, "changed locally, deleted on Drive")
+		default:
+			return plan(ActionUpload, "new local file")
+		}
+
+	case hasRemote:
+		switch {
+		case base && r.fingerprint == item.RemoteMD5:
+			return plan(ActionDeleteRemote, "deleted locally")
+		case base:
+			return plan(ActionDownload, "changed on Drive, deleted locally")
+		default:
+			return plan(ActionDownload, "new on Drive")
+		}
+	}
+
+	return action, false
+}
+
+// listLocal returns the MD5 of every regular, non-ignored file under the
+// sync root, keyed by relative path.
+func (e *Engine) listLocal(ctx context.Context) (map[string]string, error) {
+	files := map[string]string{}
+
+	err := filepath.WalkDir(e.config.LocalPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if path == e.config.LocalPath {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(e.config.LocalPath, path)
+		if err != nil {
+			return err
+		}
+
+		if e.rules.Match(relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		md5, err := computeMD5(path)
+		if err != nil {
+			return err
+		}
+
+		files[relPath] = md5
+		return nil
+	})
+
+	return files, err
+}
+
+// listRemote walks the Drive folder tree under the sync root, recording
+// folders in the engine's folder tree, and returns every syncable file
+// keyed by local relative path. Google-native files are named according
+// to the native docs mode and left out when the mode skips them.
+func (e *Engine) listRemote(ctx context.Context) (map[string]planRemote, error) {
+	files := map[string]planRemote{}
+	queue := []string{e.config.DriveFolderID}
+
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+
+		parentPath, ok := e.folders.Path(parentID)
+		if !ok {
+			continue
+		}
+
+		query := fmt.Sprintf("'%s' in parents and trashed = false", escapeDriveQuery(parentID))
+
+		call := e.service.Files.List().
+			Context(ctx).
+			Q(query).
+			Fields("nextPageToken,files(id,name,mimeType,md5Checksum,modifiedTime)").
+			PageSize(1000)
+
+		if e.config.DriveID != "" {
+			call = call.SupportsAllDrives(true).
+				IncludeItemsFromAllDrives(true).
+				Corpora("drive").
+				DriveId(e.config.DriveID)
+		}
+
+		err := call.Pages(ctx, func(resp *drive.FileList) error {
+			for _, f := range resp.Files {
+				if f == nil || f.Id == "" {
+					continue
+				}
+
+				name, ok := e.cipher.LocalName(f.Name)
+				if !ok {
+					continue
+				}
+
+				if f.MimeType == driveFolderMimeType {
+					relPath := filepath.Join(parentPath, name)
+					if e.rules.Match(relPath, true) {
+						continue
+					}
+					e.folders.Set(f.Id, relPath)
+					queue = append(queue, f.Id)
+					continue
+				}
+
+				if isGoogleDocsType(f.MimeType) {
+					if name, ok = e.config.NativeDocs.localName(f.Name, f.MimeType); !ok {
+						continue
+					}
+				}
+
+				relPath := filepath.Join(parentPath, name)
+				if e.rules.Match(relPath, false) {
+					continue
+				}
+				if _, dup := files[relPath]; dup {
+					// Drive allows duplicate names; only the first is synced.
+					continue
+				}
+
+				files[relPath] = planRemote{file: f, fingerprint: remoteFingerprint(f)}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list files in %s: %w", parentID, err)
+		}
+	}
+
+	return files, nil
+}
+
+// Apply carries out the actions of plan that mode includes, continuing
+// past individual failures. Actions outside mode are reported as skipped.
+// If the deletions would trip the delete guard, nothing is applied, sync is
+// paused and the error wraps ErrPaused.
+func (e *Engine) Apply(ctx context.Context, plan *Plan, mode ApplyMode) ([]ApplyResult, error) {
+	e.mu.Lock()
+	err := e.pausedErr()
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	deletes := 0
+	for _, action := range plan.Actions {
+		if mode.Includes(action.Kind) && action.Kind.IsDelete() {
+			deletes++
+		}
+	}
+	if !e.allowDeletes(deletes) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return nil, e.pausedErr()
+	}
+
+	e.purgeTrash()
+
+	results := make([]ApplyResult, 0, len(plan.Actions))
+
+	for _, action := range plan.Actions {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		result := ApplyResult{PlanAction: action, Status: StatusSkipped}
+		if mode.Includes(action.Kind) {
+			if err := e.applyAction(ctx, action); err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+			} else {
+				result.Status = StatusApplied
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// applyAction carries out one plan action and records the new sync state.
+func (e *Engine) applyAction(ctx context.Context, action PlanAction) error {
+	relPath := action.Path
+	absPath, err := localJoin(e.config.LocalPath, relPath)
+	if err != nil {
+		return err
+	}
+
+	logErr := func(name string, err error) error {
+		_ = e.db.AddLogEntry(e.config.ID, "error", relPath, map[string]any{
+			"action":   name,
+			"error":    err.Error(),
+			"drive_id": action.DriveID,
+			"source":   "plan",
+		})
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch action.Kind {
+	case ActionUpload:
+		var remote *drive.File
+		if action.DriveID != "" {
+			remote = &drive.File{Id: action.DriveID, MimeType: action.MimeType}
+		}
+
+		result, err := e.uploader.UploadTo(ctx, relPath, absPath, remote)
+		if err != nil {
+			return logErr("upload", err)
+		}
+
+		if err := e.updateSyncItem(relPath, result); err != nil {
+			return logErr("update_sync_item", err)
+		}
+
+		_ = e.db.AddLogEntry(e.config.ID, "upload", relPath, map[string]any{
+			"drive_id": result.DriveID,
+			"md5":      result.MD5,
+			"source":   "plan",
+		})
+
+	case ActionDownload:
+		result, err := e.dloader.DownloadFile(ctx, action.DriveID, relPath)
+		if err != nil {
+			return logErr("download", err)
+		}
+
+		if err := e.updateSyncItemFromDownload(relPath, action.DriveID, result.MD5, result.RemoteMD5); err != nil {
+			return logErr("update_sync_item", err)
+		}
+
+		_ = e.db.AddLogEntry(e.config.ID, "download", relPath, map[string]any{
+			"drive_id": action.DriveID,
+			"md5":      result.MD5,
+			"source":   "plan",
+		})
+
+	case ActionDeleteRemote:
+		if err := e.deleteRemote(ctx, relPath); err != nil {
+			return logErr("delete", err)
+		}
+
+		if err := e.removeSyncItem(relPath); err != nil {
+			return logErr("remove_sync_item", err)
+		}
+
+		_ = e.db.AddLogEntry(e.config.ID, "delete", relPath, map[string]any{"source": "plan"})
+
+	case ActionDeleteLocal:
+		trashID, err := moveToTrash(e.config.LocalPath, relPath, time.Now())
+		if err != nil {
+			return logErr("local_delete", err)
+		}
+
+		if err := e.removeSyncItem(relPath); err != nil {
+			return logErr("remove_sync_item", err)
+		}
+
+		_ = e.db.AddLogEntry(e.config.ID, "download_delete", relPath, map[string]any{
+			"source": "plan",
+			"trash":  trashID,
+		})
+
+	case ActionTrack:
+		if err := e.updateSyncItemFromDownload(relPath, action.DriveID, action.LocalMD5, action.RemoteMD5); err != nil {
+			return logErr("update_sync_item", err)
+		}
+
+	case ActionConflict:
+		return e.resolveConflict(ctx, &ConflictInfo{
+			LocalPath:      relPath,
+			DriveID:        action.DriveID,
+			LocalMD5:       action.LocalMD5,
+			RemoteMD5:      action.RemoteMD5,
+			LocalModified:  true,
+			RemoteModified: true,
+			MimeType:       action.MimeType,
+		})
+
+	default:
+		return fmt.Errorf("unknown plan action %q", action.Kind)
+	}
+
+	return nil
+}
+
+// Resume clears a pause set by the delete guard and settles the held
+// deletions in plan: they are applied, or with restore, undone by copying
+// each file back from the side that still has it. Other actions are
+// reported as skipped and left to the next sync.
+func (e *Engine) Resume(ctx context.Context, plan *Plan, restore bool) ([]ApplyResult, error) {
+	if err := e.db.ResumeConfig(e.config.ID); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.config.PausedReason = ""
+	e.config.PausedAt = time.Time{}
+	e.mu.Unlock()
+
+	_ = e.db.AddLogEntry(e.config.ID, "resumed", "", map[string]any{"restore": restore})
+
+	results := make([]ApplyResult, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+
+		if !action.Kind.IsDelete() {
+			results = append(results, ApplyResult{PlanAction: action, Status: StatusSkipped})
+			continue
+		}
+
+		if restore {
+			action = restoreAction(action)
+		}
+
+		result := ApplyResult{PlanAction: action, Status: StatusApplied}
+		if err := e.applyAction(ctx, action); err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// restoreAction turns a deletion into the transfer that undoes it.
+func restoreAction(action PlanAction) PlanAction {
+	switch action.Kind {
+	case ActionDeleteRemote:
+		action.Kind = ActionDownload
+		action.Reason = "restored from Drive"
+	case ActionDeleteLocal:
+		action.Kind = ActionUpload
+		action.DriveID = ""
+		action.Reason = "restored to Drive"
+	}
+	return action
+}
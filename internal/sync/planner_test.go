@@ -0,0 +1,286 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeLocal(t *testing.T, root, relPath, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func planKinds(plan *Plan) map[string]PlanActionKind {
+	kinds := map[string]PlanActionKind{}
+	for _, a := range plan.Actions {
+		kinds[a.Path] = a.Kind
+	}
+	return kinds
+}
+
+func TestPlan_ThreeWayClassification(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFolder("sub", "sub", "root")
+	stub.addFile("same", "same.txt", "root", "same")
+	stub.addFile("remote-edit", "remote-edit.txt", "root", "new remote")
+	stub.addFile("local-edit", "local-edit.txt", "root", "base")
+	stub.addFile("both-edit", "both-edit.txt", "root", "remote side")
+	stub.addFile("local-gone", "local-gone.txt", "root", "base")
+	stub.addFile("deep", "deep.txt", "sub", "deep")
+	engine, _, tmpDir := newStubEngine(t, stub)
+
+	for _, s := range []struct{ path, id, base, local string }{
+		{"same.txt", "same", "same", "same"},
+		{"remote-edit.txt", "remote-edit", "base", "base"},
+		{"local-edit.txt", "local-edit", "base", "new local"},
+		{"both-edit.txt", "both-edit", "base", "local side"},
+		{"local-gone.txt", "local-gone", "base", ""},
+		{"remote-gone.txt", "remote-gone", "base", "base"},
+	} {
+		seedSynced(t, engine, s.path, s.id, s.base)
+		if s.local != "" {
+			writeLocal(t, tmpDir, s.path, s.local)
+		}
+	}
+	writeLocal(t, tmpDir, "local-new.txt", "fresh")
+	writeLocal(t, tmpDir, ".DS_Store", "ignored")
+
+	plan, err := engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	want := map[string]PlanActionKind{
+		"remote-edit.txt":                ActionDownload,
+		"local-edit.txt":                 ActionUpload,
+		"both-edit.txt":                  ActionConflict,
+		"local-gone.txt":                 ActionDeleteRemote,
+		"remote-gone.txt":                ActionDeleteLocal,
+		"local-new.txt":                  ActionUpload,
+		filepath.Join("sub", "deep.txt"): ActionDownload,
+	}
+	got := planKinds(plan)
+	if len(got) != len(want) {
+		t.Fatalf("plan = %v, want %v", got, want)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("%s: action = %q, want %q", path, got[path], kind)
+		}
+	}
+}
+
+func TestApply_PullSkipsLocalToRemoteActions(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFolder("sub", "sub", "root")
+	stub.addFile("deep", "deep.txt", "sub", "deep")
+	engine, d, tmpDir := newStubEngine(t, stub)
+	cfgID := engine.config.ID
+
+	seedSynced(t, engine, "remote-gone.txt", "remote-gone", "base")
+	writeLocal(t, tmpDir, "remote-gone.txt", "base")
+	writeLocal(t, tmpDir, "local-new.txt", "fresh")
+
+	plan, err := engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	results, err := engine.Apply(context.Background(), plan, ApplyPull)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	status := map[string]ApplyStatus{}
+	for _, r := range results {
+		if r.Error != "" {
+			t.Errorf("%s: %s", r.Path, r.Error)
+		}
+		status[r.Path] = r.Status
+	}
+
+	deep := filepath.Join("sub", "deep.txt")
+	if status[deep] != StatusApplied || status["remote-gone.txt"] != StatusApplied || status["local-new.txt"] != StatusSkipped {
+		t.Fatalf("statuses = %v", status)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(tmpDir, deep)); string(b) != "deep" {
+		t.Fatalf("downloaded content = %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "remote-gone.txt")); !os.IsNotExist(err) {
+		t.Fatalf("remote-gone.txt still exists: %v", err)
+	}
+	if item, _ := d.GetSyncItem(cfgID, deep); item == nil || item.DriveID != "deep" || item.SyncState != StateSynced {
+		t.Fatalf("deep item = %+v", item)
+	}
+	if item, _ := d.GetSyncItem(cfgID, "remote-gone.txt"); item != nil {
+		t.Fatalf("remote-gone item = %+v, want removed", item)
+	}
+
+	// The next plan only has the skipped upload left.
+	plan, err = engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if got := planKinds(plan); len(got) != 1 || got["local-new.txt"] != ActionUpload {
+		t.Fatalf("plan after pull = %v", got)
+	}
+}
+
+func TestApply_TracksIdenticalFiles(t *testing.T) {
+	stub := newStubDrive()
+	stub.addFile("edited", "edited.txt", "root", "same")
+	stub.addFile("deleted", "deleted.txt", "root", "same")
+	engine, d, tmpDir := newStubEngine(t, stub)
+	writeLocal(t, tmpDir, "edited.txt", "same")
+	writeLocal(t, tmpDir, "deleted.txt", "same")
+
+	plan, err := engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if got := planKinds(plan); len(got) != 2 || got["edited.txt"] != ActionTrack || got["deleted.txt"] != ActionTrack {
+		t.Fatalf("plan = %v, want both tracked", got)
+	}
+
+	results, err := engine.Apply(context.Background(), plan, ApplyPush)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for _, r := range results {
+		if r.Status != StatusApplied {
+			t.Fatalf("%s: status = %s %s", r.Path, r.Status, r.Error)
+		}
+	}
+
+	// Once tracked, the files are in step and later changes are compared
+	// with the recorded state instead of reported as conflicts or new.
+	plan, err = engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Actions) != 0 {
+		t.Fatalf("plan after tracking = %v, want none", planKinds(plan))
+	}
+
+	writeLocal(t, tmpDir, "edited.txt", "edited")
+	if err := os.Remove(filepath.Join(tmpDir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	got := planKinds(plan)
+	if got["edited.txt"] != ActionUpload || got["deleted.txt"] != ActionDeleteRemote {
+		t.Fatalf("plan after local changes = %v", got)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, "edited.txt"); item == nil || item.DriveID != "edited" || item.SyncState != StateSynced {
+		t.Fatalf("edited item = %+v", item)
+	}
+}
+
+func TestApplyModeIncludes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mode ApplyMode
+		kind PlanActionKind
+		want bool
+	}{
+		{ApplyPush, ActionUpload, true},
+		{ApplyPush, ActionDeleteRemote, true},
+		{ApplyPush, ActionDownload, false},
+		{ApplyPush, ActionConflict, false},
+		{ApplyPull, ActionDownload, true},
+		{ApplyPull, ActionDeleteLocal, true},
+		{ApplyPull, ActionUpload, false},
+		{ApplyPull, ActionConflict, false},
+		{ApplyAll, ActionConflict, true},
+		{ApplyPush, ActionTrack, true},
+		{ApplyPull, ActionTrack, true},
+		{ApplyAll, ActionTrack, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.Includes(tt.kind); got != tt.want {
+			t.Errorf("%s.Includes(%s) = %v, want %v", tt.mode, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestOneShotEngineCannotStart(t *testing.T) {
+	engine, _, _ := newStubEngine(t, newStubDrive())
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Fatal("Start on a one-shot engine succeeded")
+	}
+}
+
+func TestApply_DeleteGuardPausesUntilResume(t *testing.T) {
+	engine, d, tmpDir := newStubEngine(t, newStubDrive())
+	engine.guard = NewDeleteGuard(2, 0)
+	cfgID := engine.config.ID
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		seedSynced(t, engine, name, "id-"+name, "base")
+		writeLocal(t, tmpDir, name, "base")
+	}
+
+	plan, err := engine.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	if _, err := engine.Apply(context.Background(), plan, ApplyPull); !errors.Is(err, ErrPaused) {
+		t.Fatalf("Apply = %v, want ErrPaused", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "a.txt")); err != nil {
+		t.Fatalf("a.txt deleted despite the guard: %v", err)
+	}
+	if cfg, _ := d.GetConfigByID(cfgID); cfg.PausedReason == "" {
+		t.Fatal("config not paused")
+	}
+
+	results, err := engine.Resume(context.Background(), plan, false)
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	for _, r := range results {
+		if r.Status != StatusApplied || r.Kind != ActionDeleteLocal {
+			t.Errorf("result = %+v", r)
+		}
+	}
+
+	if cfg, _ := d.GetConfigByID(cfgID); cfg.PausedReason != "" {
+		t.Fatalf("config still paused: %q", cfg.PausedReason)
+	}
+	if entries, _ := ListTrash(tmpDir); len(entries) != 3 {
+		t.Fatalf("trash = %+v, want the 3 deleted files", entries)
+	}
+}
+
+func TestRestoreAction(t *testing.T) {
+	t.Parallel()
+
+	up := restoreAction(PlanAction{Kind: ActionDeleteLocal, Path: "a.txt", DriveID: "stale"})
+	if up.Kind != ActionUpload || up.DriveID != "" {
+		t.Fatalf("restore of delete_local = %+v", up)
+	}
+
+	down := restoreAction(PlanAction{Kind: ActionDeleteRemote, Path: "b.txt", DriveID: "id-b"})
+	if down.Kind != ActionDownload || down.DriveID != "id-b" {
+		t.Fatalf("restore of delete_remote = %+v", down)
+	}
+}
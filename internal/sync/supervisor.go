@@ -0,0 +1,261 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gosync "sync"
+	"time"
+
+	"github.com/automagik-dev/workit/internal/ui"
+)
+
+// EngineState is the supervisor's view of one sync engine.
+type EngineState string
+
+const (
+	// EngineRunning means the engine is syncing.
+	EngineRunning EngineState = "running"
+	// EngineBackoff means the engine stopped with an error and is waiting
+	// to be restarted.
+	EngineBackoff EngineState = "backoff"
+	// EngineFailed means the engine cannot start (e.g. no account, or sync
+	// paused by the delete guard) and is not retried.
+	EngineFailed EngineState = "failed"
+)
+
+// EngineHealth is the health of one engine run by a Supervisor.
+type EngineHealth struct {
+	ConfigID    int64       `json:"config_id"`
+	State       EngineState `json:"state"`
+	Account     string      `json:"account,omitempty"`
+	Restarts    int         `json:"restarts"`
+	LastError   string      `json:"last_error,omitempty"`
+	StartedAt   time.Time   `json:"started_at,omitempty"`
+	NextRetryAt time.Time   `json:"next_retry_at,omitempty"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// RunFunc runs the sync engine for cfg until ctx is cancelled. Errors
+// wrapped with Permanent are not retried.
+type RunFunc func(ctx context.Context, cfg *SyncConfig) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an engine error as one a restart cannot fix.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// SupervisorOptions configures a Supervisor.
+type SupervisorOptions struct {
+	DB      *DB
+	Configs []SyncConfig
+	Run     RunFunc
+	// MinBackoff is the delay before the first restart (default 1s); it
+	// doubles on each consecutive failure up to MaxBackoff (default 5m).
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// StableAfter resets the backoff once an engine has run this long
+	// (default 1m).
+	StableAfter time.Duration
+	// RescanInterval is how often the configurations are re-read so that
+	// ones added or removed after Start are picked up (default 1m).
+	RescanInterval time.Duration
+}
+
+// Supervisor runs one engine per sync configuration in a single process,
+// restarting engines that stop with an error.
+type Supervisor struct {
+	db          *DB
+	configs     []SyncConfig
+	run         RunFunc
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	stableAfter time.Duration
+	rescan      time.Duration
+}
+
+// NewSupervisor creates a supervisor for the given configurations.
+func NewSupervisor(opts SupervisorOptions) *Supervisor {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(5*time.Minute, opts.MinBackoff)
+	}
+	if opts.StableAfter <= 0 {
+		opts.StableAfter = time.Minute
+	}
+	if opts.RescanInterval <= 0 {
+		opts.RescanInterval = time.Minute
+	}
+
+	return &Supervisor{
+		db:          opts.DB,
+		configs:     opts.Configs,
+		run:         opts.Run,
+		minBackoff:  opts.MinBackoff,
+		maxBackoff:  opts.MaxBackoff,
+		stableAfter: opts.StableAfter,
+		rescan:      opts.RescanInterval,
+	}
+}
+
+// Start runs every engine and re-reads the configurations every
+// RescanInterval, starting engines for new ones and stopping those of
+// removed ones. Blocks until ctx is cancelled; engine health is cleared on
+// return.
+func (s *Supervisor) Start(ctx context.Context) error {
+	if len(s.configs) == 0 {
+		return fmt.Errorf("no sync configurations (use 'wk sync init' first)")
+	}
+
+	if err := s.db.ClearEngineHealth(); err != nil {
+		return err
+	}
+	defer func() { _ = s.db.ClearEngineHealth() }()
+
+	var wg gosync.WaitGroup
+	running := make(map[int64]context.CancelFunc, len(s.configs))
+	start := func(cfg SyncConfig) {
+		engineCtx, cancel := context.WithCancel(ctx)
+		running[cfg.ID] = cancel
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.supervise(engineCtx, &cfg)
+		}()
+	}
+	for _, cfg := range s.configs {
+		start(cfg)
+	}
+
+	ticker := time.NewTicker(s.rescan)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case <-ticker.C:
+			s.rescanConfigs(ctx, running, start)
+		}
+	}
+}
+
+// rescanConfigs starts engines for configurations added since the last scan
+// and stops the engines of removed ones.
+func (s *Supervisor) rescanConfigs(ctx context.Context, running map[int64]context.CancelFunc, start func(SyncConfig)) {
+	configs, err := s.db.ListConfigs()
+	if err != nil {
+		logSupervisor(ctx, "list sync configurations: %v", err)
+		return
+	}
+
+	current := make(map[int64]bool, len(configs))
+	for _, cfg := range configs {
+		current[cfg.ID] = true
+		if _, ok := running[cfg.ID]; !ok {
+			logSupervisor(ctx, "sync %s: new configuration, starting", cfg.LocalPath)
+			start(cfg)
+		}
+	}
+
+	for id, cancel := range running {
+		if !current[id] {
+			cancel()
+			delete(running, id)
+		}
+	}
+}
+
+// supervise runs one engine, restarting it with exponential backoff.
+func (s *Supervisor) supervise(ctx context.Context, cfg *SyncConfig) {
+	health := EngineHealth{ConfigID: cfg.ID, Account: cfg.Account}
+	backoff := s.minBackoff
+
+	for {
+		health.State = EngineRunning
+		health.StartedAt = time.Now()
+		health.NextRetryAt = time.Time{}
+		s.setHealth(&health)
+
+		err := s.runEngine(ctx, cfg)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("engine stopped unexpectedly")
+		}
+
+		health.LastError = err.Error()
+		_ = s.db.AddLogEntry(cfg.ID, "error", "", map[string]any{
+			"source": "supervisor",
+			"error":  err.Error(),
+		})
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || errors.Is(err, ErrPaused) {
+			health.State = EngineFailed
+			s.setHealth(&health)
+			logSupervisor(ctx, "sync %s: %v (not restarting)", cfg.LocalPath, err)
+
+			<-ctx.Done()
+			return
+		}
+
+		if time.Since(health.StartedAt) >= s.stableAfter {
+			backoff = s.minBackoff
+		}
+
+		health.State = EngineBackoff
+		health.NextRetryAt = time.Now().Add(backoff)
+		s.setHealth(&health)
+		logSupervisor(ctx, "sync %s: %v (restarting in %s)", cfg.LocalPath, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		health.Restarts++
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// runEngine calls the RunFunc, turning a panic into an error so one
+// engine cannot take the others down.
+func (s *Supervisor) runEngine(ctx context.Context, cfg *SyncConfig) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("engine panic: %v", r)
+		}
+	}()
+
+	return s.run(ctx, cfg)
+}
+
+func (s *Supervisor) setHealth(h *EngineHealth) {
+	h.UpdatedAt = time.Now()
+	if err := s.db.SetEngineHealth(*h); err != nil {
+		_ = s.db.AddLogEntry(h.ConfigID, "error", "", map[string]any{
+			"source": "supervisor",
+			"error":  err.Error(),
+		})
+	}
+}
+
+func logSupervisor(ctx context.Context, format string, args ...any) {
+	if u := ui.FromContext(ctx); u != nil {
+		u.Err().Printf(format, args...)
+	}
+}
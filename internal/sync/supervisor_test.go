@@ -0,0 +1,226 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+)
+
+// waitForHealth polls until cond holds for the config's engine health.
+func waitForHealth(t *testing.T, d *DB, configID int64, cond func(*EngineHealth) bool) *EngineHealth {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h, err := d.GetEngineHealth(configID)
+		if err != nil {
+			t.Fatalf("GetEngineHealth: %v", err)
+		}
+		if h != nil && cond(h) {
+			return h
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h, _ := d.GetEngineHealth(configID)
+	t.Fatalf("engine health never matched; last = %+v", h)
+	return nil
+}
+
+// engineGoroutines counts the running event loops, watchers, pollers and
+// transfer workers of sync engines.
+func engineGoroutines() int {
+	buf := make([]byte, 1<<20)
+	stacks := string(buf[:runtime.Stack(buf, true)])
+
+	n := 0
+	for _, frame := range []string{".(*Engine).eventLoop(", ".(*Watcher).Start(", ".(*DrivePoller).Start(", ".(*Scheduler).work("} {
+		n += strings.Count(stacks, frame)
+	}
+	return n
+}
+
+func TestSupervisor_RestartDrainsFailedEngine(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	cfg := SyncConfig{ID: configID, LocalPath: t.TempDir(), DriveFolderID: "root"}
+	// The stub serves no changes/startPageToken, so every poller fails.
+	svc := newStubService(t, newStubDrive())
+
+	supervisor := NewSupervisor(SupervisorOptions{
+		DB:         d,
+		Configs:    []SyncConfig{cfg},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+		Run: func(ctx context.Context, cfg *SyncConfig) error {
+			engine, err := NewEngine(EngineOptions{DB: d, Config: cfg, DriveService: svc, PollInterval: time.Hour})
+			if err != nil {
+				return err
+			}
+			return engine.Start(ctx)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- supervisor.Start(ctx) }()
+
+	waitForHealth(t, d, configID, func(h *EngineHealth) bool { return h.Restarts >= 3 })
+
+	// At most one engine (watcher, event loop, workers) runs at a time.
+	if n := engineGoroutines(); n > 3+DefaultTransferWorkers {
+		t.Fatalf("%d engine goroutines running after restarts; failed engines were not stopped", n)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start() = %v, want context.Canceled", err)
+	}
+	if n := engineGoroutines(); n != 0 {
+		t.Fatalf("%d engine goroutines still running after Start returned", n)
+	}
+}
+
+func TestSupervisor_RestartsFailedEnginesWithBackoff(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	cfg, _ := d.GetConfigByID(configID)
+
+	var (
+		mu    gosync.Mutex
+		calls int
+	)
+	supervisor := NewSupervisor(SupervisorOptions{
+		DB:         d,
+		Configs:    []SyncConfig{*cfg},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+		Run: func(ctx context.Context, cfg *SyncConfig) error {
+			mu.Lock()
+			calls++
+			n := calls
+			mu.Unlock()
+
+			switch n {
+			case 1:
+				return errors.New("poller: token expired")
+			case 2:
+				panic("boom")
+			default:
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- supervisor.Start(ctx) }()
+
+	h := waitForHealth(t, d, configID, func(h *EngineHealth) bool {
+		return h.State == EngineRunning && h.Restarts == 2
+	})
+	if h.LastError != "engine panic: boom" {
+		t.Fatalf("LastError = %q", h.LastError)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start() = %v, want context.Canceled", err)
+	}
+	if h, _ := d.GetEngineHealth(configID); h != nil {
+		t.Fatalf("health not cleared on exit: %+v", h)
+	}
+}
+
+func TestSupervisor_PermanentErrorIsNotRetried(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	cfg, _ := d.GetConfigByID(configID)
+
+	var (
+		mu    gosync.Mutex
+		calls int
+	)
+	supervisor := NewSupervisor(SupervisorOptions{
+		DB:         d,
+		Configs:    []SyncConfig{*cfg},
+		MinBackoff: time.Millisecond,
+		Run: func(ctx context.Context, cfg *SyncConfig) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return Permanent(errors.New("no account"))
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = supervisor.Start(ctx) }()
+
+	waitForHealth(t, d, configID, func(h *EngineHealth) bool { return h.State == EngineFailed })
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Fatalf("run called %d times, want 1", calls)
+	}
+}
+
+func TestSupervisor_NoConfigs(t *testing.T) {
+	supervisor := NewSupervisor(SupervisorOptions{DB: openTestDB(t)})
+	if err := supervisor.Start(context.Background()); err == nil {
+		t.Fatal("expected error without configurations")
+	}
+}
+
+func TestSupervisor_RescanPicksUpConfigChanges(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	cfg, _ := d.GetConfigByID(configID)
+
+	stopped := make(chan int64, 1)
+	supervisor := NewSupervisor(SupervisorOptions{
+		DB:             d,
+		Configs:        []SyncConfig{*cfg},
+		RescanInterval: 5 * time.Millisecond,
+		Run: func(ctx context.Context, cfg *SyncConfig) error {
+			<-ctx.Done()
+			stopped <- cfg.ID
+			return ctx.Err()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- supervisor.Start(ctx) }()
+
+	waitForHealth(t, d, configID, func(h *EngineHealth) bool { return h.State == EngineRunning })
+
+	added, err := d.CreateConfig("/tmp/test-sync-added", "folder-added", "")
+	if err != nil {
+		t.Fatalf("CreateConfig: %v", err)
+	}
+	waitForHealth(t, d, added.ID, func(h *EngineHealth) bool { return h.State == EngineRunning })
+
+	if err := d.RemoveConfig(added.LocalPath); err != nil {
+		t.Fatalf("RemoveConfig: %v", err)
+	}
+	select {
+	case id := <-stopped:
+		if id != added.ID {
+			t.Fatalf("stopped engine %d, want %d", id, added.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("engine of removed configuration was not stopped")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start() = %v, want context.Canceled", err)
+	}
+}
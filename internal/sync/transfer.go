@@ -0,0 +1,567 @@
+package sync
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi"
+)
+
+// DefaultTransferWorkers is the number of concurrent transfers for new
+// sync configurations.
+const DefaultTransferWorkers = 4
+
+// Transfer retry policy. Failed transfers stay queued in sync_items and are
+// retried with exponential backoff until maxTransferAttempts, after which the
+// item is left in StateError.
+const (
+	maxTransferAttempts = 8
+	retryBaseDelay      = 30 * time.Second
+	retryMaxDelay       = time.Hour
+)
+
+// throttleBurst is the largest read a throttled transfer makes at once.
+const throttleBurst = 64 << 10
+
+// TransferDirection is the direction of a file transfer.
+type TransferDirection string
+
+const (
+	// TransferUpload sends a local file to Drive.
+	TransferUpload TransferDirection = "upload"
+	// TransferDownload writes a Drive file locally.
+	TransferDownload TransferDirection = "download"
+)
+
+// pendingState is the sync state of an item queued in direction dir.
+func (dir TransferDirection) pendingState() SyncState {
+	if dir == TransferDownload {
+		return StatePendingDownload
+	}
+
+	return StatePendingUpload
+}
+
+// TransferStats is a snapshot of an engine's transfer queue, as shown by
+// `wk sync status`.
+type TransferStats struct {
+	Workers int `json:"workers"`
+	Queued  int `json:"queued"`
+	Active  int `json:"active"`
+	// UploadRate and DownloadRate are in bytes per second, averaged since
+	// the previous snapshot.
+	UploadRate      int64     `json:"upload_rate"`
+	DownloadRate    int64     `json:"download_rate"`
+	UploadedBytes   int64     `json:"uploaded_bytes"`
+	DownloadedBytes int64     `json:"downloaded_bytes"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Throttle caps the byte rate of the transfers in one direction and counts
+// the bytes they move. A nil Throttle neither limits nor counts.
+type Throttle struct {
+	limiter *rate.Limiter // nil when unlimited
+	bytes   atomic.Int64
+}
+
+// NewThrottle creates a throttle allowing bytesPerSec bytes per second
+// across all transfers sharing it. Zero means unlimited.
+func NewThrottle(bytesPerSec int64) *Throttle {
+	t := &Throttle{}
+	if bytesPerSec > 0 {
+		t.limiter = rate.NewLimiter(rate.Limit(bytesPerSec), throttleBurst)
+	}
+
+	return t
+}
+
+// Reader wraps r so reads from it count against the throttle.
+func (t *Throttle) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+
+	return &throttledReader{ctx: ctx, r: r, t: t}
+}
+
+// Bytes returns the number of bytes moved through the throttle.
+func (t *Throttle) Bytes() int64 {
+	if t == nil {
+		return 0
+	}
+
+	return t.bytes.Load()
+}
+
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	t   *Throttle
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if r.t.limiter != nil && len(p) > throttleBurst {
+		p = p[:throttleBurst]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.t.bytes.Add(int64(n))
+		if r.t.limiter != nil {
+			if werr := r.t.limiter.WaitN(r.ctx, n); werr != nil {
+				return n, werr
+			}
+		}
+	}
+
+	return n, err
+}
+
+// ParseRate parses a byte rate such as "512K", "2M" or "1.5MB" (binary
+// units, per second). "0" and "" mean unlimited.
+func ParseRate(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "/S"), "B")
+	if s == "" {
+		return 0, nil
+	}
+
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'K':
+		mult = 1 << 10
+	case 'M':
+		mult = 1 << 20
+	case 'G':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q (use bytes per second, e.g. 512K or 2M)", value)
+	}
+
+	return int64(n * mult), nil
+}
+
+// retryDelay is how long to wait before the given attempt at a transfer.
+func retryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, retryMaxDelay)
+}
+
+// transferJob is one queued transfer.
+type transferJob struct {
+	relPath string
+	dir     TransferDirection
+	size    int64
+	seq     uint64
+	run     func(ctx context.Context)
+	index   int // position in the queue heap, -1 when not in it
+}
+
+// transferQueue orders runnable jobs smallest first, then by arrival, so a
+// large file does not hold up many small ones.
+type transferQueue []*transferJob
+
+func (q transferQueue) Len() int { return len(q) }
+
+func (q transferQueue) Less(i, j int) bool {
+	if q[i].size != q[j].size {
+		return q[i].size < q[j].size
+	}
+
+	return q[i].seq < q[j].seq
+}
+
+func (q transferQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *transferQueue) Push(x any) {
+	job := x.(*transferJob)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *transferQueue) Pop() any {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	job.index = -1
+	*q = old[:len(old)-1]
+
+	return job
+}
+
+// Scheduler runs transfers on a fixed number of workers. Transfers of the
+// same path run one at a time, in the order they were queued; a transfer
+// queued behind another in the same direction replaces it, since both would
+// read the file's current content.
+type Scheduler struct {
+	workers int
+
+	mu     gosync.Mutex
+	cond   *gosync.Cond
+	queue  transferQueue
+	paths  map[string][]*transferJob // jobs per path; the first may be running
+	active int
+	seq    uint64
+	closed bool
+}
+
+// NewScheduler creates a scheduler with the given number of workers. Values
+// below one use DefaultTransferWorkers.
+func NewScheduler(workers int) *Scheduler {
+	if workers < 1 {
+		workers = DefaultTransferWorkers
+	}
+
+	s := &Scheduler{
+		workers: workers,
+		paths:   make(map[string][]*transferJob),
+	}
+	s.cond = gosync.NewCond(&s.mu)
+
+	return s
+}
+
+// Enqueue queues run as a transfer of relPath. size orders it against the
+// other queued transfers.
+func (s *Scheduler) Enqueue(dir TransferDirection, relPath string, size int64, run func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := s.paths[relPath]
+	if n := len(jobs); n > 0 {
+		last := jobs[n-1]
+		// Only the first job of a path can be running or in the queue heap.
+		if last.dir == dir && (n > 1 || last.index >= 0) {
+			last.run = run
+			last.size = size
+			if last.index >= 0 {
+				heap.Fix(&s.queue, last.index)
+			}
+			return
+		}
+	}
+
+	s.seq++
+	job := &transferJob{relPath: relPath, dir: dir, size: size, seq: s.seq, run: run, index: -1}
+	s.paths[relPath] = append(jobs, job)
+	if len(jobs) == 0 {
+		heap.Push(&s.queue, job)
+		s.cond.Broadcast()
+	}
+}
+
+// Queued reports whether a transfer of relPath is queued or running.
+func (s *Scheduler) Queued(relPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.paths[relPath]) > 0
+}
+
+// Stats returns the number of workers, and of queued and running transfers.
+func (s *Scheduler) Stats() (workers, queued, active int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, jobs := range s.paths {
+		queued += len(jobs)
+	}
+
+	return s.workers, queued - s.active, s.active
+}
+
+// Run starts the workers and blocks until ctx is cancelled and running
+// transfers have returned. Transfers still queued are dropped.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg gosync.WaitGroup
+	for range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	wg.Wait()
+}
+
+// Wait blocks until no transfers are queued or running.
+func (s *Scheduler) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.paths) > 0 && !s.closed {
+		s.cond.Wait()
+	}
+}
+
+func (s *Scheduler) work(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		for !s.closed && s.queue.Len() == 0 {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		job := heap.Pop(&s.queue).(*transferJob)
+		s.active++
+		s.mu.Unlock()
+
+		job.run(ctx)
+
+		s.mu.Lock()
+		s.active--
+		jobs := s.paths[job.relPath][1:]
+		if len(jobs) == 0 {
+			delete(s.paths, job.relPath)
+		} else {
+			s.paths[job.relPath] = jobs
+			heap.Push(&s.queue, jobs[0])
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// transferTick is how often a running engine retries due transfers and
+// records its transfer stats.
+const transferTick = 5 * time.Second
+
+// Stale reports whether the stats are too old to describe a running engine,
+// e.g. because it was killed.
+func (t *TransferStats) Stale(now time.Time) bool {
+	return now.Sub(t.UpdatedAt) > 3*transferTick
+}
+
+// transfer runs fn, an upload or download of relPath, on a transfer worker,
+// or inline when the engine has no scheduler. Queued transfers are recorded
+// in sync_items, so they survive a restart, and failed ones are retried.
+func (e *Engine) transfer(ctx context.Context, dir TransferDirection, relPath, driveID string, size int64, fn func(ctx context.Context) error) {
+	if e.transfers == nil {
+		_ = fn(ctx)
+		return
+	}
+
+	e.markQueued(dir, relPath, driveID)
+	e.transfers.Enqueue(dir, relPath, size, func(ctx context.Context) {
+		// Transfers queued before the delete guard tripped stay queued
+		// until sync is resumed.
+		if e.isPaused() {
+			return
+		}
+		e.transferDone(ctx, dir, relPath, fn(ctx))
+	})
+}
+
+// markQueued records a queued transfer of relPath in its sync item, creating
+// one for a new file. Conflicts keep their state.
+func (e *Engine) markQueued(dir TransferDirection, relPath, driveID string) {
+	item, err := e.db.GetSyncItem(e.config.ID, relPath)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case item == nil:
+		if err := e.db.CreateSyncItem(e.config.ID, relPath, driveID, "", "", time.Now(), time.Time{}); err != nil {
+			return
+		}
+		if dir == TransferDownload {
+			if item, err = e.db.GetSyncItem(e.config.ID, relPath); err == nil && item != nil {
+				_ = e.db.SetSyncItemState(item.ID, StatePendingDownload, "")
+			}
+		}
+
+	case item.SyncState == StateSynced, item.SyncState == StateError:
+		_ = e.db.SetSyncItemRetry(item.ID, dir.pendingState(), 0, time.Time{}, "")
+	}
+}
+
+// transferDone records the outcome of a queued transfer whose item is still
+// pending. Successful transfers record the item themselves; one that found
+// nothing to do (an unchanged file, or one gone since it was queued) leaves
+// the item as it was before. A failure schedules a retry with backoff, and
+// a transfer cut short by shutdown stays queued for the next start.
+func (e *Engine) transferDone(ctx context.Context, dir TransferDirection, relPath string, err error) {
+	item, gerr := e.db.GetSyncItem(e.config.ID, relPath)
+	if gerr != nil || item == nil || item.SyncState != dir.pendingState() {
+		return
+	}
+
+	switch {
+	case err == nil || transferGone(err):
+		if hasSyncBase(item) {
+			_ = e.db.SetSyncItemRetry(item.ID, StateSynced, 0, time.Time{}, "")
+		} else {
+			_ = e.removeSyncItem(relPath)
+		}
+
+	case ctx.Err() != nil:
+		// Stays queued for the next start.
+
+	default:
+		attempts := item.Attempts + 1
+		state := dir.pendingState()
+		next := time.Now().Add(retryDelay(attempts))
+		if attempts >= maxTransferAttempts {
+			state, next = StateError, time.Time{}
+		}
+		_ = e.db.SetSyncItemRetry(item.ID, state, attempts, next, err.Error())
+	}
+}
+
+// transferGone reports whether a transfer failed because its file no longer
+// exists on the side it reads from.
+func transferGone(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.Is(err, fs.ErrNotExist) || (errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound)
+}
+
+// queueDelete propagates a local deletion, after any transfer of the path
+// still queued or running.
+func (e *Engine) queueDelete(ctx context.Context, relPath string) {
+	if e.transfers == nil || !e.transfers.Queued(relPath) {
+		e.propagateDelete(ctx, relPath)
+		return
+	}
+
+	// Replaces a queued upload of the deleted file.
+	e.transfers.Enqueue(TransferUpload, relPath, 0, func(ctx context.Context) {
+		e.propagateDelete(ctx, relPath)
+	})
+}
+
+// queueRemoveLocal removes a file deleted on Drive, after any transfer of
+// the path still queued or running.
+func (e *Engine) queueRemoveLocal(ctx context.Context, relPath string) {
+	remove := func(context.Context) {
+		if e.allowDeletePath(relPath) {
+			e.removeLocal(relPath)
+		}
+	}
+
+	if e.transfers == nil || !e.transfers.Queued(relPath) {
+		remove(ctx)
+		return
+	}
+
+	// Replaces a queued download of the deleted file.
+	e.transfers.Enqueue(TransferDownload, relPath, 0, remove)
+}
+
+// retryTransfers queues the pending items whose retry time has passed,
+// including those left queued when the engine last stopped.
+func (e *Engine) retryTransfers(ctx context.Context, now time.Time) {
+	items, err := e.db.ListDueTransfers(e.config.ID, now)
+	if err != nil {
+		_ = e.db.AddLogEntry(e.config.ID, "error", "", map[string]any{
+			"action": "retry_transfers",
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	for _, item := range items {
+		if e.transfers.Queued(item.LocalPath) {
+			continue
+		}
+
+		switch item.SyncState {
+		case StatePendingUpload:
+			e.transfer(ctx, TransferUpload, item.LocalPath, "", item.Size, func(ctx context.Context) error {
+				return e.uploadLocal(ctx, item.LocalPath)
+			})
+
+		case StatePendingDownload:
+			e.transfer(ctx, TransferDownload, item.LocalPath, item.DriveID, item.Size, func(ctx context.Context) error {
+				return e.retryDownload(ctx, item)
+			})
+		}
+	}
+}
+
+// retryDownload downloads a pending item again, comparing against the Drive
+// file as it is now.
+func (e *Engine) retryDownload(ctx context.Context, item SyncItem) error {
+	remote, err := e.uploader.RemoteFile(ctx, item.LocalPath, item.DriveID)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		// Deleted on Drive; the poller reports it.
+		return nil
+	}
+
+	change := DriveChange{
+		FileID:    remote.Id,
+		MimeType:  remote.MimeType,
+		Op:        DriveOpModify,
+		Timestamp: time.Now(),
+		RelPath:   item.LocalPath,
+		MD5:       remoteFingerprint(remote),
+	}
+	if t, err := time.Parse(time.RFC3339, remote.ModifiedTime); err == nil {
+		change.Timestamp = t
+	}
+
+	return e.downloadRemote(ctx, change)
+}
+
+// recordTransferStats stores the transfer queue and the throughput since
+// the previous call, for `wk sync status`.
+func (e *Engine) recordTransferStats(now time.Time) {
+	workers, queued, active := e.transfers.Stats()
+	stats := TransferStats{
+		Workers:         workers,
+		Queued:          queued,
+		Active:          active,
+		UploadedBytes:   e.upThrottle.Bytes(),
+		DownloadedBytes: e.downThrottle.Bytes(),
+		UpdatedAt:       now,
+	}
+
+	if elapsed := now.Sub(e.stats.UpdatedAt).Seconds(); !e.stats.UpdatedAt.IsZero() && elapsed > 0 {
+		stats.UploadRate = int64(float64(stats.UploadedBytes-e.stats.UploadedBytes) / elapsed)
+		stats.DownloadRate = int64(float64(stats.DownloadedBytes-e.stats.DownloadedBytes) / elapsed)
+	}
+	e.stats = stats
+
+	_ = e.db.SetTransferStats(e.config.ID, stats)
+}
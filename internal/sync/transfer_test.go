@@ -0,0 +1,279 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// runScheduler runs s until the test ends.
+func runScheduler(t *testing.T, s *Scheduler) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestSchedulerSmallFilesFirst(t *testing.T) {
+	s := NewScheduler(1)
+
+	var (
+		mu    gosync.Mutex
+		order []string
+	)
+	record := func(name string) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+
+	// Hold the only worker so the rest queue up.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.Enqueue(TransferUpload, "first", 1, func(context.Context) {
+		close(started)
+		<-release
+	})
+	runScheduler(t, s)
+	<-started
+
+	s.Enqueue(TransferUpload, "big", 3<<20, record("big"))
+	s.Enqueue(TransferDownload, "small", 10, record("small"))
+	s.Enqueue(TransferUpload, "medium", 1<<20, record("medium"))
+	s.Enqueue(TransferUpload, "tiny", 10, record("tiny"))
+
+	if _, queued, _ := s.Stats(); queued != 4 {
+		t.Fatalf("queued = %d, want 4", queued)
+	}
+
+	close(release)
+	s.Wait()
+
+	want := []string{"small", "tiny", "medium", "big"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestSchedulerOnePathAtATime(t *testing.T) {
+	s := NewScheduler(4)
+	runScheduler(t, s)
+
+	var (
+		mu  gosync.Mutex
+		ran []string
+	)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.Enqueue(TransferUpload, "a.txt", 1, func(context.Context) {
+		close(started)
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, "upload 1")
+	})
+	<-started
+
+	// Queued behind the running upload: the second download replaces the
+	// first, and neither starts before the upload finishes.
+	s.Enqueue(TransferDownload, "a.txt", 1, func(context.Context) { ran = append(ran, "download 1") })
+	s.Enqueue(TransferDownload, "a.txt", 1, func(context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, "download 2")
+	})
+	if !s.Queued("a.txt") || s.Queued("b.txt") {
+		t.Fatal("Queued does not reflect the queue")
+	}
+
+	close(release)
+	s.Wait()
+
+	if want := []string{"upload 1", "download 2"}; !slices.Equal(ran, want) {
+		t.Fatalf("ran = %v, want %v", ran, want)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("x"), 256<<10)
+
+	var unlimited *Throttle
+	if r := unlimited.Reader(ctx, bytes.NewReader(data)); r == nil {
+		t.Fatal("nil throttle returned a nil reader")
+	}
+
+	// 1 MiB/s with a 64 KiB burst: 256 KiB takes at least 180ms.
+	th := NewThrottle(1 << 20)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, th.Reader(ctx, bytes.NewReader(data)))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("throttled copy took %v, want it rate limited", elapsed)
+	}
+	if th.Bytes() != int64(len(data)) {
+		t.Fatalf("Bytes() = %d, want %d", th.Bytes(), len(data))
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"1000", 1000},
+		{"512K", 512 << 10},
+		{"2m", 2 << 20},
+		{"1.5MB", 3 << 19},
+		{"1G/s", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"fast", "-1M", "2T"} {
+		if _, err := ParseRate(in); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if got := retryDelay(1); got != retryBaseDelay {
+		t.Fatalf("retryDelay(1) = %v", got)
+	}
+	if got := retryDelay(3); got != 4*retryBaseDelay {
+		t.Fatalf("retryDelay(3) = %v", got)
+	}
+	if got := retryDelay(50); got != retryMaxDelay {
+		t.Fatalf("retryDelay(50) = %v", got)
+	}
+}
+
+func TestTransferFailureIsRetried(t *testing.T) {
+	d := openTestDB(t)
+	configID := insertTestConfig(t, d)
+	tmpDir := t.TempDir()
+
+	var uploads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			uploads++
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"code":400,"message":"quota"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"files": []any{}})
+	}))
+	t.Cleanup(ts.Close)
+
+	svc, err := drive.NewService(context.Background(),
+		option.WithEndpoint(ts.URL),
+		option.WithHTTPClient(ts.Client()),
+	)
+	if err != nil {
+		t.Fatalf("create drive service: %v", err)
+	}
+
+	engine := &Engine{
+		db:        d,
+		config:    &SyncConfig{ID: configID, LocalPath: tmpDir, DriveFolderID: "root"},
+		service:   svc,
+		uploader:  NewUploader(svc, "root", ""),
+		resolver:  NewConflictResolver(d, configID, ConflictRename),
+		transfers: NewScheduler(2),
+	}
+	runScheduler(t, engine.transfers)
+	ctx := context.Background()
+
+	writeLocal(t, tmpDir, "report.txt", "quarterly numbers")
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "report.txt", Op: OpCreate})
+	engine.transfers.Wait()
+
+	item, _ := d.GetSyncItem(configID, "report.txt")
+	if item == nil || item.SyncState != StatePendingUpload || item.Attempts != 1 || item.LastError == "" {
+		t.Fatalf("failed upload item = %+v, want a queued retry", item)
+	}
+	if wait := time.Until(item.NextRetryAt); wait < retryBaseDelay/2 || wait > retryBaseDelay {
+		t.Fatalf("next retry in %v, want about %v", wait, retryBaseDelay)
+	}
+
+	// Not due yet.
+	engine.retryTransfers(ctx, time.Now())
+	engine.transfers.Wait()
+	if uploads != 1 {
+		t.Fatalf("uploads = %d, want no early retry", uploads)
+	}
+
+	engine.retryTransfers(ctx, time.Now().Add(time.Minute))
+	engine.transfers.Wait()
+	if item, _ = d.GetSyncItem(configID, "report.txt"); uploads != 2 || item.Attempts != 2 {
+		t.Fatalf("after retry: uploads = %d, item = %+v", uploads, item)
+	}
+
+	// Once the file is gone there is nothing left to retry.
+	if err := os.Remove(filepath.Join(tmpDir, "report.txt")); err != nil {
+		t.Fatal(err)
+	}
+	engine.retryTransfers(ctx, time.Now().Add(time.Hour))
+	engine.transfers.Wait()
+	if item, _ = d.GetSyncItem(configID, "report.txt"); item != nil {
+		t.Fatalf("item for deleted file still queued: %+v", item)
+	}
+}
+
+func TestTransfer_QueuedTransfersWaitWhilePaused(t *testing.T) {
+	stub := newStubDrive()
+	engine, d, tmpDir := newStubEngine(t, stub)
+	engine.guard = NewDeleteGuard(2, 0)
+	engine.transfers = NewScheduler(1)
+	ctx := context.Background()
+
+	writeLocal(t, tmpDir, "report.txt", "quarterly numbers")
+	engine.handleLocalEvent(ctx, WatchEvent{RelPath: "report.txt", Op: OpCreate})
+
+	if engine.allowDeletes(3) {
+		t.Fatal("delete guard did not trip")
+	}
+
+	runScheduler(t, engine.transfers)
+	engine.transfers.Wait()
+
+	if len(stub.writes) != 0 {
+		t.Fatalf("writes after pause = %q, want none", stub.writes)
+	}
+	if item, _ := d.GetSyncItem(engine.config.ID, "report.txt"); item == nil || item.SyncState != StatePendingUpload {
+		t.Fatalf("item = %+v, want still queued for resume", item)
+	}
+}
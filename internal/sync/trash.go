@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrashDirName is the folder under a sync root that holds files deleted on
+// Drive. Each deletion batch gets a timestamped subfolder that mirrors the
+// sync root, e.g. .wk-trash/20260102-150405.000/docs/notes.txt.
+const TrashDirName = ".wk-trash"
+
+const trashBatchLayout = "20060102-150405.000"
+
+// TrashEntry is one file in the local trash.
+type TrashEntry struct {
+	// ID is the file's path inside the trash folder (batch/relative path).
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Size      int64     `json:"size"`
+}
+
+// moveToTrash moves relPath under root into a new trash batch. A missing
+// path is not an error. It returns the path inside the trash folder.
+func moveToTrash(root, relPath string, now time.Time) (string, error) {
+	absPath, err := localJoin(root, relPath)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Lstat(absPath); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	id := filepath.Join(now.UTC().Format(trashBatchLayout), relPath)
+	dest := filepath.Join(root, TrashDirName, id)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", fmt.Errorf("create trash folder: %w", err)
+	}
+	if err := os.Rename(absPath, dest); err != nil {
+		return "", fmt.Errorf("move to trash: %w", err)
+	}
+
+	return id, nil
+}
+
+// ListTrash returns the files in the trash of the sync root, newest first.
+func ListTrash(root string) ([]TrashEntry, error) {
+	trashDir := filepath.Join(root, TrashDirName)
+
+	batches, err := os.ReadDir(trashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+
+	var entries []TrashEntry
+	for _, batch := range batches {
+		deletedAt, err := time.Parse(trashBatchLayout, batch.Name())
+		if !batch.IsDir() || err != nil {
+			continue
+		}
+
+		batchDir := filepath.Join(trashDir, batch.Name())
+		err = filepath.WalkDir(batchDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			relPath, err := filepath.Rel(batchDir, path)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, TrashEntry{
+				ID:        filepath.Join(batch.Name(), relPath),
+				Path:      relPath,
+				DeletedAt: deletedAt,
+				Size:      info.Size(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read trash batch %s: %w", batch.Name(), err)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// RestoreTrash moves trashed files back to their original paths. target is
+// a trash entry ID, or an original path (file or folder) relative to the
+// sync root, in which case the newest copy of each file under it is
+// restored. Existing local files are never overwritten.
+func RestoreTrash(root, target string) ([]TrashEntry, error) {
+	target = cleanRelPath(target)
+	if target == "" {
+		return nil, errors.New("empty trash path")
+	}
+
+	entries, err := ListTrash(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		restore []TrashEntry
+		seen    = map[string]bool{}
+	)
+	for _, entry := range entries {
+		if entry.ID == target {
+			restore = []TrashEntry{entry}
+			break
+		}
+		if seen[entry.Path] || (entry.Path != target && !strings.HasPrefix(entry.Path, target+string(filepath.Separator))) {
+			continue
+		}
+		// Entries are newest first, so the first match per path wins.
+		seen[entry.Path] = true
+		restore = append(restore, entry)
+	}
+
+	if len(restore) == 0 {
+		return nil, fmt.Errorf("%s is not in %s", target, TrashDirName)
+	}
+
+	restored := make([]TrashEntry, 0, len(restore))
+	for _, entry := range restore {
+		dest, err := localJoin(root, entry.Path)
+		if err != nil {
+			return restored, err
+		}
+		if _, err := os.Lstat(dest); err == nil {
+			return restored, fmt.Errorf("%s already exists; move it away to restore %s", entry.Path, entry.ID)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return restored, fmt.Errorf("create parent directory: %w", err)
+		}
+		if err := os.Rename(filepath.Join(root, TrashDirName, entry.ID), dest); err != nil {
+			return restored, fmt.Errorf("restore %s: %w", entry.ID, err)
+		}
+		restored = append(restored, entry)
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash removes trash batches older than retention. A zero retention
+// keeps everything.
+func PurgeTrash(root string, retention time.Duration, now time.Time) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	trashDir := filepath.Join(root, TrashDirName)
+	batches, err := os.ReadDir(trashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read trash: %w", err)
+	}
+
+	purged := 0
+	for _, batch := range batches {
+		deletedAt, err := time.Parse(trashBatchLayout, batch.Name())
+		if err != nil || now.Sub(deletedAt) < retention {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(trashDir, batch.Name())); err != nil {
+			return purged, fmt.Errorf("purge trash batch %s: %w", batch.Name(), err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
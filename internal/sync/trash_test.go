@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrash_MoveListRestore(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeLocal(t, root, filepath.Join("docs", "a.txt"), "old a")
+
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := moveToTrash(root, filepath.Join("docs", "a.txt"), older); err != nil {
+		t.Fatalf("moveToTrash: %v", err)
+	}
+
+	writeLocal(t, root, filepath.Join("docs", "a.txt"), "new a")
+	writeLocal(t, root, filepath.Join("docs", "b.txt"), "b")
+	newer := older.Add(time.Hour)
+	id, err := moveToTrash(root, "docs", newer)
+	if err != nil {
+		t.Fatalf("moveToTrash: %v", err)
+	}
+	if id != filepath.Join("20260101-010000.000", "docs") {
+		t.Fatalf("trash id = %q", id)
+	}
+	if _, err := os.Stat(filepath.Join(root, "docs")); !os.IsNotExist(err) {
+		t.Fatalf("docs still exists: %v", err)
+	}
+	if id, err := moveToTrash(root, "missing.txt", newer); err != nil || id != "" {
+		t.Fatalf("moveToTrash(missing) = %q, %v", id, err)
+	}
+
+	entries, err := ListTrash(root)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(entries) != 3 || !entries[0].DeletedAt.Equal(newer) || entries[2].Path != filepath.Join("docs", "a.txt") {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	// The folder restores from the newest batch only.
+	restored, err := RestoreTrash(root, "docs")
+	if err != nil {
+		t.Fatalf("RestoreTrash: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("restored = %+v", restored)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "docs", "a.txt")); string(b) != "new a" {
+		t.Fatalf("a.txt = %q, want newest copy", b)
+	}
+
+	if _, err := RestoreTrash(root, filepath.Join("docs", "a.txt")); err == nil {
+		t.Fatal("restore over an existing file succeeded")
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	writeLocal(t, root, "old.txt", "old")
+	writeLocal(t, root, "new.txt", "new")
+	if _, err := moveToTrash(root, "old.txt", now.Add(-31*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moveToTrash(root, "new.txt", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := PurgeTrash(root, 0, now); err != nil || n != 0 {
+		t.Fatalf("PurgeTrash(0) = %d, %v", n, err)
+	}
+	if n, err := PurgeTrash(root, 30*24*time.Hour, now); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash = %d, %v", n, err)
+	}
+
+	entries, _ := ListTrash(root)
+	if len(entries) != 1 || entries[0].Path != "new.txt" {
+		t.Fatalf("entries after purge = %+v", entries)
+	}
+}
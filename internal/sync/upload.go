@@ -2,15 +2,21 @@ package sync
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	gosync "sync"
 	"time"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 // Uploader handles uploading files to Google Drive.
@@ -18,16 +24,21 @@ import (
 type Uploader struct {
 	service    *drive.Service
 	rootFolder string
-	driveID    string            // For shared drives
-	mu         gosync.Mutex      // Protects folderIDs
-	folderIDs  map[string]string // Maps relative paths to Drive folder IDs
+	driveID    string      // For shared drives
+	folders    *FolderTree // Maps relative paths to Drive folder IDs
+	nativeDocs NativeDocsMode
+	throttle   *Throttle
+	cipher     *Cipher // nil unless the config is encrypted
+
+	folderMu gosync.Mutex // serializes folder creation
 }
 
 // UploadResult contains the result of an upload operation.
 type UploadResult struct {
-	DriveID string
-	MD5     string
-	ModTime time.Time
+	DriveID   string
+	MD5       string
+	RemoteMD5 string // remoteFingerprint of the Drive file
+	ModTime   time.Time
 }
 
 // NewUploader creates a new uploader.
@@ -36,23 +47,42 @@ func NewUploader(service *drive.Service, rootFolder, driveID string) *Uploader {
 		service:    service,
 		rootFolder: rootFolder,
 		driveID:    driveID,
-		folderIDs:  make(map[string]string),
+		folders:    NewFolderTree(rootFolder),
+	}
+}
+
+// SetFolderTree shares a folder tree with the uploader, so folders it
+// creates are known to the poller and remote moves update its cache.
+func (u *Uploader) SetFolderTree(tree *FolderTree) {
+	if tree != nil {
+		u.folders = tree
 	}
 }
 
+// SetNativeDocs sets how local edits to exported Google-native files are
+// imported back.
+func (u *Uploader) SetNativeDocs(mode NativeDocsMode) {
+	u.nativeDocs = mode
+}
+
+// SetThrottle limits and counts the bytes uploaded.
+func (u *Uploader) SetThrottle(t *Throttle) {
+	u.throttle = t
+}
+
+// SetCipher encrypts uploaded contents, and names if the cipher does, with c.
+func (u *Uploader) SetCipher(c *Cipher) {
+	u.cipher = c
+}
+
 // getFolderID returns the cached Drive folder ID for a relative path.
 func (u *Uploader) getFolderID(relPath string) (string, bool) {
-	u.mu.Lock()
-	id, ok := u.folderIDs[relPath]
-	u.mu.Unlock()
-	return id, ok
+	return u.folders.FolderID(relPath)
 }
 
 // setFolderID caches a Drive folder ID for a relative path.
 func (u *Uploader) setFolderID(relPath, id string) {
-	u.mu.Lock()
-	u.folderIDs[relPath] = id
-	u.mu.Unlock()
+	u.folders.Set(id, relPath)
 }
 
 // UploadFile uploads a file to Drive.
@@ -83,34 +113,113 @@ func (u *Uploader) UploadFile(ctx context.Context, relPath, absPath string) (*Up
 	}
 
 	// Check if file already exists in Drive
-	existingID, err := u.findFileByName(ctx, parentID, filepath.Base(relPath))
+	name := u.cipher.DriveName(filepath.Base(relPath))
+	existingID, err := u.findFileByName(ctx, parentID, name)
 	if err != nil {
 		return nil, fmt.Errorf("check existing file: %w", err)
 	}
 
+	// Encrypted files are tracked by the plaintext MD5 locally and by the
+	// ciphertext MD5 on Drive.
+	var content io.Reader = f
+	var media []googleapi.MediaOption
+	remoteHash := md5.New()
+	if u.cipher != nil {
+		encrypted := u.cipher.Encrypt(f)
+		defer encrypted.Close()
+		content = io.TeeReader(encrypted, remoteHash)
+		media = append(media, googleapi.ContentType(encryptedMimeType))
+	}
+
 	var driveFile *drive.File
 
 	if existingID != "" {
 		// Update existing file
-		driveFile, err = u.updateFile(ctx, existingID, f)
+		driveFile, err = u.updateFile(ctx, existingID, u.throttle.Reader(ctx, content), media...)
 	} else {
 		// Create new file
-		driveFile, err = u.createFile(ctx, parentID, filepath.Base(relPath), f)
+		driveFile, err = u.createFile(ctx, parentID, name, u.throttle.Reader(ctx, content), media...)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	remoteMD5 := md5Hash
+	if u.cipher != nil {
+		remoteMD5 = driveFile.Md5Checksum
+		if remoteMD5 == "" {
+			remoteMD5 = hex.EncodeToString(remoteHash.Sum(nil))
+		}
+	}
+
+	return &UploadResult{
+		DriveID:   driveFile.Id,
+		MD5:       md5Hash,
+		RemoteMD5: remoteMD5,
+		ModTime:   info.ModTime(),
+	}, nil
+}
+
+// UploadTo uploads a local file over remote, the Drive file currently
+// backing relPath (nil if none). Exported Google-native files are imported
+// back into the same file ID, which keeps its sharing and comments.
+func (u *Uploader) UploadTo(ctx context.Context, relPath, absPath string, remote *drive.File) (*UploadResult, error) {
+	if remote != nil && isGoogleDocsType(remote.MimeType) {
+		return u.ImportFile(ctx, remote.Id, remote.MimeType, relPath, absPath)
+	}
+
+	return u.UploadFile(ctx, relPath, absPath)
+}
+
+// ImportFile replaces the content of a Google-native file with a local
+// export (e.g. a .docx for a Google Doc), converting it on upload.
+func (u *Uploader) ImportFile(ctx context.Context, fileID, nativeMimeType, relPath, absPath string) (*UploadResult, error) {
+	mediaType, err := u.nativeDocs.importMimeType(relPath, nativeMimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+
+	md5Hash, err := computeMD5(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("compute md5: %w", err)
+	}
+
+	call := u.service.Files.Update(fileID, &drive.File{MimeType: nativeMimeType}).
+		Context(ctx).
+		Media(u.throttle.Reader(ctx, f), googleapi.ContentType(mediaType)).
+		Fields("id,mimeType,modifiedTime")
+
+	if u.driveID != "" {
+		call = call.SupportsAllDrives(true)
+	}
+
+	driveFile, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("import file into Drive: %w", err)
+	}
+
 	return &UploadResult{
-		DriveID: driveFile.Id,
-		MD5:     md5Hash,
-		ModTime: info.ModTime(),
+		DriveID:   driveFile.Id,
+		MD5:       md5Hash,
+		RemoteMD5: remoteFingerprint(driveFile),
+		ModTime:   info.ModTime(),
 	}, nil
 }
 
 // createFile creates a new file in Drive.
-func (u *Uploader) createFile(ctx context.Context, parentID, name string, reader io.Reader) (*drive.File, error) {
+func (u *Uploader) createFile(ctx context.Context, parentID, name string, reader io.Reader, media ...googleapi.MediaOption) (*drive.File, error) {
 	file := &drive.File{
 		Name:    name,
 		Parents: []string{parentID},
@@ -118,7 +227,7 @@ func (u *Uploader) createFile(ctx context.Context, parentID, name string, reader
 
 	call := u.service.Files.Create(file).
 		Context(ctx).
-		Media(reader).
+		Media(reader, media...).
 		Fields("id,md5Checksum")
 
 	if u.driveID != "" {
@@ -134,12 +243,12 @@ func (u *Uploader) createFile(ctx context.Context, parentID, name string, reader
 }
 
 // updateFile updates an existing file in Drive.
-func (u *Uploader) updateFile(ctx context.Context, fileID string, reader io.Reader) (*drive.File, error) {
+func (u *Uploader) updateFile(ctx context.Context, fileID string, reader io.Reader, media ...googleapi.MediaOption) (*drive.File, error) {
 	file := &drive.File{}
 
 	call := u.service.Files.Update(fileID, file).
 		Context(ctx).
-		Media(reader).
+		Media(reader, media...).
 		Fields("id,md5Checksum")
 
 	if u.driveID != "" {
@@ -167,7 +276,7 @@ func (u *Uploader) CreateFolder(ctx context.Context, relPath string) error {
 		return fmt.Errorf("get parent: %w", err)
 	}
 
-	folderName := filepath.Base(relPath)
+	folderName := u.cipher.DriveName(filepath.Base(relPath))
 
 	// Check if folder already exists
 	existingID, err := u.findFolderByName(ctx, parentID, folderName)
@@ -214,7 +323,7 @@ func (u *Uploader) Delete(ctx context.Context, relPath string) error {
 		return fmt.Errorf("get parent folder: %w", err)
 	}
 
-	name := filepath.Base(relPath)
+	name := u.cipher.DriveName(filepath.Base(relPath))
 
 	// Try to find as file first
 	fileID, err := u.findFileByName(ctx, parentID, name)
@@ -235,7 +344,11 @@ func (u *Uploader) Delete(ctx context.Context, relPath string) error {
 		return nil
 	}
 
-	// Trash the file (not permanent delete)
+	return u.Trash(ctx, fileID)
+}
+
+// Trash moves a Drive file or folder to the trash by ID.
+func (u *Uploader) Trash(ctx context.Context, fileID string) error {
 	call := u.service.Files.Update(fileID, &drive.File{Trashed: true}).
 		Context(ctx)
 
@@ -243,14 +356,80 @@ func (u *Uploader) Delete(ctx context.Context, relPath string) error {
 		call = call.SupportsAllDrives(true)
 	}
 
-	_, err = call.Do()
-	if err != nil {
+	if _, err := call.Do(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			// Already gone
+			return nil
+		}
 		return fmt.Errorf("trash file in Drive: %w", err)
 	}
 
 	return nil
 }
 
+// Move renames and reparents the Drive file or folder fileID to newPath in
+// a single update, which keeps its ID, sharing, comments and revision
+// history. Google-native files keep their Drive name without the export
+// extension. It returns the file with what remoteFingerprint needs.
+func (u *Uploader) Move(ctx context.Context, fileID, newPath string) (*drive.File, error) {
+	const fields = "id,name,mimeType,parents,md5Checksum,modifiedTime"
+
+	file, err := u.service.Files.Get(fileID).
+		Context(ctx).
+		Fields(fields).
+		SupportsAllDrives(true).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+
+	parentID, err := u.ensureParentFolders(ctx, newPath)
+	if err != nil {
+		return nil, fmt.Errorf("ensure parent folders: %w", err)
+	}
+
+	name := filepath.Base(newPath)
+	if f, ok := u.nativeDocs.format(file.MimeType); ok && isGoogleDocsType(file.MimeType) &&
+		strings.EqualFold(filepath.Ext(name), f.ext) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	update := &drive.File{}
+	if current, _ := u.cipher.LocalName(file.Name); name != current {
+		update.Name = u.cipher.DriveName(name)
+	}
+
+	call := u.service.Files.Update(fileID, update).
+		Context(ctx).
+		Fields(fields)
+
+	if !slices.Contains(file.Parents, parentID) {
+		call = call.AddParents(parentID)
+		if len(file.Parents) > 0 {
+			call = call.RemoveParents(strings.Join(file.Parents, ","))
+		}
+	} else if update.Name == "" {
+		// Already in place
+		return file, nil
+	}
+
+	if u.driveID != "" {
+		call = call.SupportsAllDrives(true)
+	}
+
+	result, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("move file in Drive: %w", err)
+	}
+
+	if result.MimeType == driveFolderMimeType {
+		u.setFolderID(newPath, result.Id)
+	}
+
+	return result, nil
+}
+
 // ensureParentFolders ensures all parent folders exist and returns the parent ID.
 func (u *Uploader) ensureParentFolders(ctx context.Context, relPath string) (string, error) {
 	dir := filepath.Dir(relPath)
@@ -263,6 +442,10 @@ func (u *Uploader) ensureParentFolders(ctx context.Context, relPath string) (str
 		return id, nil
 	}
 
+	// Concurrent uploads into a new folder must not each create it.
+	u.folderMu.Lock()
+	defer u.folderMu.Unlock()
+
 	// Split path and ensure each component exists
 	parts := strings.Split(dir, string(filepath.Separator))
 	currentID := u.rootFolder
@@ -283,7 +466,8 @@ func (u *Uploader) ensureParentFolders(ctx context.Context, relPath string) (str
 		}
 
 		// Check if folder exists in Drive
-		existingID, err := u.findFolderByName(ctx, currentID, part)
+		driveName := u.cipher.DriveName(part)
+		existingID, err := u.findFolderByName(ctx, currentID, driveName)
 		if err != nil {
 			return "", fmt.Errorf("find folder %s: %w", part, err)
 		}
@@ -297,7 +481,7 @@ func (u *Uploader) ensureParentFolders(ctx context.Context, relPath string) (str
 
 		// Create folder
 		folder := &drive.File{
-			Name:     part,
+			Name:     driveName,
 			MimeType: "application/vnd.google-apps.folder",
 			Parents:  []string{currentID},
 		}
@@ -338,15 +522,57 @@ func (u *Uploader) getParentFolderID(ctx context.Context, relPath string) (strin
 	return u.ensureParentFolders(ctx, relPath)
 }
 
+// RemoteFile returns the Drive file currently backing relPath, with what
+// remoteFingerprint needs, or nil if there is none. driveID is the last known file ID,
+// if any; otherwise the file is looked up by name.
+func (u *Uploader) RemoteFile(ctx context.Context, relPath, driveID string) (*drive.File, error) {
+	if driveID != "" {
+		file, err := u.service.Files.Get(driveID).
+			Context(ctx).
+			Fields("id,mimeType,md5Checksum,modifiedTime,trashed").
+			SupportsAllDrives(true).
+			Do()
+		if err != nil {
+			var apiErr *googleapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("get remote file: %w", err)
+		}
+		if file.Trashed {
+			return nil, nil
+		}
+		return file, nil
+	}
+
+	parentID, err := u.getParentFolderID(ctx, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("get parent folder: %w", err)
+	}
+
+	return u.findFile(ctx, parentID, u.cipher.DriveName(filepath.Base(relPath)))
+}
+
 // findFileByName finds a file by name in a parent folder.
 func (u *Uploader) findFileByName(ctx context.Context, parentID, name string) (string, error) {
+	file, err := u.findFile(ctx, parentID, name)
+	if err != nil || file == nil {
+		return "", err
+	}
+
+	return file.Id, nil
+}
+
+// findFile finds a file by name in a parent folder, returning the fields
+// remoteFingerprint needs.
+func (u *Uploader) findFile(ctx context.Context, parentID, name string) (*drive.File, error) {
 	query := fmt.Sprintf("'%s' in parents and name = '%s' and mimeType != 'application/vnd.google-apps.folder' and trashed = false",
 		parentID, escapeDriveQuery(name))
 
 	call := u.service.Files.List().
 		Context(ctx).
 		Q(query).
-		Fields("files(id)").
+		Fields("files(id,mimeType,md5Checksum,modifiedTime)").
 		PageSize(1)
 
 	if u.driveID != "" {
@@ -358,14 +584,14 @@ func (u *Uploader) findFileByName(ctx context.Context, parentID, name string) (s
 
 	result, err := call.Do()
 	if err != nil {
-		return "", fmt.Errorf("list files: %w", err)
+		return nil, fmt.Errorf("list files: %w", err)
 	}
 
 	if len(result.Files) > 0 {
-		return result.Files[0].Id, nil
+		return result.Files[0], nil
 	}
 
-	return "", nil
+	return nil, nil
 }
 
 // findFolderByName finds a folder by name in a parent folder.
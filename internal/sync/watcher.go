@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 	gosync "sync"
 	"time"
 
@@ -57,6 +57,7 @@ type Watcher struct {
 	events   chan WatchEvent
 	errors   chan error
 	debounce time.Duration
+	rules    *IgnoreRules
 
 	// Debouncing state
 	mu      gosync.Mutex
@@ -70,12 +71,35 @@ type debounceEntry struct {
 
 // NewWatcher creates a new filesystem watcher.
 // debounce specifies how long to wait after the last event before emitting.
+// Paths are filtered by the default ignore rules and root's .wkignore file.
 func NewWatcher(root string, debounce time.Duration) (*Watcher, error) {
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("absolute path: %w", err)
 	}
 
+	rules, err := NewIgnoreRules(absRoot, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWatcherWithRules(absRoot, debounce, rules)
+}
+
+// NewWatcherWithRules creates a filesystem watcher that filters paths with
+// rules. The rules are reloaded whenever the root's .wkignore file changes.
+func NewWatcherWithRules(root string, debounce time.Duration, rules *IgnoreRules) (*Watcher, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("absolute path: %w", err)
+	}
+
+	if rules == nil {
+		if rules, err = NewIgnoreRules(absRoot, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	fsWatcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("create watcher: %w", err)
@@ -87,6 +111,7 @@ func NewWatcher(root string, debounce time.Duration) (*Watcher, error) {
 		events:   make(chan WatchEvent, 100),
 		errors:   make(chan error, 10),
 		debounce: debounce,
+		rules:    rules,
 		pending:  make(map[string]*debounceEntry),
 	}
 
@@ -152,8 +177,19 @@ func (w *Watcher) Stop() error {
 func (w *Watcher) handleEvent(event fsnotify.Event) {
 	path := event.Name
 
-	// Check if this path should be ignored
-	if w.shouldIgnore(path) {
+	if path == filepath.Join(w.root, IgnoreFileName) {
+		w.reloadRules()
+	}
+
+	// Check if this path should be ignored. Removed paths can no longer be
+	// stat'ed, so fall back to whether they were a watched directory.
+	isDir := false
+	if info, err := os.Lstat(path); err == nil {
+		isDir = info.IsDir()
+	} else {
+		isDir = slices.Contains(w.watcher.WatchList(), path)
+	}
+	if w.shouldIgnore(path, isDir) {
 		return
 	}
 
@@ -254,57 +290,23 @@ func (w *Watcher) emitEvent(path string) {
 }
 
 // shouldIgnore returns true if the path should be ignored.
-func (w *Watcher) shouldIgnore(path string) bool {
-	// Get relative path for pattern matching
-	relPath, err := filepath.Rel(w.root, path)
-	if err != nil {
-		relPath = path
-	}
-
-	// Split path into components
-	parts := strings.Split(relPath, string(filepath.Separator))
-
-	for _, part := range parts {
-		// Ignore .git directory
-		if part == ".git" {
-			return true
-		}
-
-		// Ignore .gog-sync directory
-		if part == ".gog-sync" {
-			return true
-		}
-
-		// Ignore node_modules directory
-		if part == "node_modules" {
-			return true
-		}
-
-		// Ignore __pycache__ directory
-		if part == "__pycache__" {
-			return true
-		}
-
-		// Ignore hidden files/directories (starting with .)
-		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
-			return true
-		}
-	}
-
-	// Get the base name for file-specific checks
-	base := filepath.Base(path)
+func (w *Watcher) shouldIgnore(path string, isDir bool) bool {
+	return w.rules.MatchPath(path, isDir)
+}
 
-	// Ignore files ending with ~ (temp files)
-	if strings.HasSuffix(base, "~") {
-		return true
+// reloadRules re-reads .wkignore and watches any directories it no longer
+// excludes.
+func (w *Watcher) reloadRules() {
+	err := w.rules.Reload()
+	if err == nil {
+		err = w.addRecursive(w.root)
 	}
-
-	// Ignore .DS_Store
-	if base == ".DS_Store" {
-		return true
+	if err != nil {
+		select {
+		case w.errors <- fmt.Errorf("reload %s: %w", IgnoreFileName, err):
+		default:
+		}
 	}
-
-	return false
 }
 
 // addRecursive adds a directory and all subdirectories to watch.
@@ -324,7 +326,7 @@ func (w *Watcher) addRecursive(root string) error {
 		}
 
 		// Skip ignored directories
-		if w.shouldIgnore(path) {
+		if w.shouldIgnore(path, true) {
 			return filepath.SkipDir
 		}
 
@@ -261,7 +261,7 @@ func TestWatcher_IgnoreGit(t *testing.T) {
 	}
 }
 
-func TestWatcher_IgnoreHiddenFiles(t *testing.T) {
+func TestWatcher_SyncsDotfiles(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	w, err := NewWatcher(tmpDir, 50*time.Millisecond)
@@ -279,15 +279,15 @@ func TestWatcher_IgnoreHiddenFiles(t *testing.T) {
 
 	time.Sleep(50 * time.Millisecond)
 
-	// Create hidden file (should be ignored)
-	hiddenFile := filepath.Join(tmpDir, ".hidden")
-	if err := os.WriteFile(hiddenFile, []byte("hidden"), 0o644); err != nil {
+	// .DS_Store is still ignored by default
+	dsStore := filepath.Join(tmpDir, ".DS_Store")
+	if err := os.WriteFile(dsStore, []byte("junk"), 0o644); err != nil {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
-	// Create normal file that should trigger event
-	normalFile := filepath.Join(tmpDir, "visible.txt")
-	if err := os.WriteFile(normalFile, []byte("visible"), 0o644); err != nil {
+	// Other dotfiles are synced
+	dotFile := filepath.Join(tmpDir, ".env.example")
+	if err := os.WriteFile(dotFile, []byte("KEY="), 0o644); err != nil {
 		t.Fatalf("WriteFile() error = %v", err)
 	}
 
@@ -295,8 +295,59 @@ func TestWatcher_IgnoreHiddenFiles(t *testing.T) {
 	for {
 		select {
 		case event := <-w.Events():
-			if event.Path == hiddenFile {
-				t.Errorf("received event for hidden file: %v", event.Path)
+			if event.Path == dsStore {
+				t.Errorf("received event for .DS_Store: %v", event.Path)
+			}
+			if event.Path == dotFile {
+				return // success
+			}
+		case err := <-w.Errors():
+			t.Fatalf("unexpected error: %v", err)
+		case <-timeout:
+			t.Fatal("timeout waiting for dotfile event")
+		}
+	}
+}
+
+func TestWatcher_WkignoreReload(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	w, err := NewWatcher(tmpDir, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer w.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = w.Start(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	ignoredFile := filepath.Join(tmpDir, "scratch.tmp")
+	if err := os.WriteFile(ignoredFile, []byte("tmp"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	normalFile := filepath.Join(tmpDir, "kept.txt")
+	if err := os.WriteFile(normalFile, []byte("kept"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	timeout := time.After(500 * time.Millisecond)
+	for {
+		select {
+		case event := <-w.Events():
+			if event.Path == ignoredFile {
+				t.Errorf("received event for file ignored by %s: %v", IgnoreFileName, event.Path)
 			}
 			if event.Path == normalFile {
 				return // success
@@ -304,7 +355,7 @@ func TestWatcher_IgnoreHiddenFiles(t *testing.T) {
 		case err := <-w.Errors():
 			t.Fatalf("unexpected error: %v", err)
 		case <-timeout:
-			t.Fatal("timeout waiting for visible file event")
+			t.Fatal("timeout waiting for normal file event")
 		}
 	}
 }